@@ -24,18 +24,32 @@ import (
 	"github.com/varoOP/shinkro/internal/domain"
 	"github.com/varoOP/shinkro/internal/events"
 	"github.com/varoOP/shinkro/internal/filesystem"
+	"github.com/varoOP/shinkro/internal/health"
 	"github.com/varoOP/shinkro/internal/http"
+	"github.com/varoOP/shinkro/internal/ingest"
+	"github.com/varoOP/shinkro/internal/jobqueue"
+	"github.com/varoOP/shinkro/internal/libraryscan"
 	"github.com/varoOP/shinkro/internal/logger"
 	"github.com/varoOP/shinkro/internal/malauth"
 	"github.com/varoOP/shinkro/internal/mapping"
 	"github.com/varoOP/shinkro/internal/notification"
 	"github.com/varoOP/shinkro/internal/plex"
 	"github.com/varoOP/shinkro/internal/plexsettings"
+	"github.com/varoOP/shinkro/internal/reconcile"
+	"github.com/varoOP/shinkro/internal/reversesync"
 	"github.com/varoOP/shinkro/internal/server"
+	"github.com/varoOP/shinkro/internal/syncrule"
+	"github.com/varoOP/shinkro/internal/tautulli"
 	"github.com/varoOP/shinkro/internal/user"
 	"github.com/varoOP/shinkro/pkg/sse"
 )
 
+// jobQueueDrainTimeout is how long queued MAL updates and notifications may take on shutdown.
+const jobQueueDrainTimeout = 30 * time.Second
+
+// httpShutdownTimeout is how long running requests may take on shutdown.
+const httpShutdownTimeout = 5 * time.Second
+
 const usage = `shinkro
 Sync your Anime watch status in Plex to myanimelist.net!
 Usage:
@@ -114,26 +128,43 @@ func main() {
 
 		// Initialize repositories
 		var (
-			animeRepo        = database.NewAnimeRepo(log, db)
-			animeUpdateRepo  = database.NewAnimeUpdateRepo(log, db)
-			plexRepo         = database.NewPlexRepo(log, db)
-			plexSettingsRepo = database.NewPlexSettingsRepo(log, db)
-			malauthRepo      = database.NewMalAuthRepo(log, db)
-			userRepo         = database.NewUserRepo(log, db)
-			apiRepo          = database.NewAPIRepo(log, db)
-			mappingRepo      = database.NewMappingRepo(log, db)
-			notificationRepo = database.NewNotificationRepo(log, db)
+			animeRepo         = database.NewAnimeRepo(log, db)
+			animeUpdateRepo   = database.NewAnimeUpdateRepo(log, db)
+			plexRepo          = database.NewPlexRepo(log, db)
+			plexSettingsRepo  = database.NewPlexSettingsRepo(log, db)
+			malauthRepo       = database.NewMalAuthRepo(log, db)
+			userRepo          = database.NewUserRepo(log, db)
+			apiRepo           = database.NewAPIRepo(log, db)
+			mappingRepo       = database.NewMappingRepo(log, db)
+			notificationRepo  = database.NewNotificationRepo(log, db)
+			deliveryRepo      = database.NewNotificationDeliveryRepo(log, db)
+			reconcileRepo     = database.NewReconcileRepo(log, db)
+			reverseSyncRepo   = database.NewReverseSyncRepo(log, db)
+			libraryScanRepo   = database.NewLibraryScanRepo(log, db)
+			jobRepo           = database.NewJobRepo(log, db)
+			syncRuleRepo      = database.NewSyncRuleRepo(log, db)
+			plexServerRepo    = database.NewPlexServerRepo(log, db)
+			plexGUIDCacheRepo = database.NewPlexGUIDCacheRepo(log, db)
+			ingestTokenRepo   = database.NewIngestTokenRepo(log, db)
 		)
 
 		// Initialize services
 		var (
+			jobQueue            = jobqueue.NewService(log, cfg.Config, jobRepo)
 			animeService        = anime.NewService(log, animeRepo)
 			malauthService      = malauth.NewService(cfg.Config, log, malauthRepo)
 			mapService          = mapping.NewService(log, mappingRepo)
-			plexSettingsService = plexsettings.NewService(cfg.Config, log, plexSettingsRepo)
-			notificationService = notification.NewService(log, notificationRepo)
+			plexSettingsService = plexsettings.NewService(cfg.Config, log, plexSettingsRepo, plexServerRepo, plexGUIDCacheRepo, bus)
+			notificationService = notification.NewService(log, notificationRepo, deliveryRepo, animeUpdateRepo, jobQueue)
+			syncRuleService     = syncrule.NewService(log, syncRuleRepo)
+			ingestService       = ingest.NewService(log, ingestTokenRepo)
+			healthService       = health.NewService(log, db, animeService, mapService, malauthService, plexSettingsService)
 			animeUpdateService  = animeupdate.NewService(log, animeUpdateRepo, animeService, mapService, malauthService, bus)
-			plexService         = plex.NewService(log, plexSettingsService, plexRepo, animeService, mapService, malauthService, animeUpdateService, bus)
+			plexService         = plex.NewService(log, cfg.Config, plexSettingsService, syncRuleService, plexRepo, animeService, mapService, malauthService, animeUpdateService, bus)
+			tautulliService     = tautulli.NewService(log, plexService)
+			reconcileService    = reconcile.NewService(log, cfg.Config, reconcileRepo, plexSettingsService, plexService, malauthService, animeUpdateService, syncRuleService, bus)
+			reverseSyncService  = reversesync.NewService(log, reverseSyncRepo, plexSettingsService, plexService, malauthService, animeService, mapService)
+			libraryScanService  = libraryscan.NewService(log, libraryScanRepo, plexSettingsService, plexService)
 			userService         = user.NewService(userRepo, log)
 			authService         = auth.NewService(log, userService)
 			apiService          = api.NewService(log, apiRepo)
@@ -141,9 +172,21 @@ func main() {
 		)
 
 		// Register event subscribers
-		events.NewSubscribers(log, bus, notificationService, plexService, animeUpdateService)
+		events.NewSubscribers(log, bus, jobQueue, notificationService, plexService, animeUpdateService)
+
+		// Start the job queue once every job type has a handler
+		if err := jobQueue.Start(context.Background()); err != nil {
+			log.Fatal().Err(err).Msg("could not start job queue")
+		}
+
+		// Libraries were stored by title before they were matched by section id
+		go func() {
+			if err := plexSettingsService.MigrateLibraries(context.Background()); err != nil {
+				log.Warn().Err(err).Msg("could not migrate plex libraries to section ids")
+			}
+		}()
 
-		srv := server.NewServer(log, cfg.Config, animeService, mapService, bus)
+		srv := server.NewServer(log, cfg.Config, animeService, mapService, reconcileService, reverseSyncService, libraryScanService, malauthService, bus)
 		if err := srv.Start(); err != nil {
 			log.Fatal().Stack().Err(err).Msg("could not start server")
 			return
@@ -151,25 +194,33 @@ func main() {
 
 		errorChannel := make(chan error)
 
+		httpServer := http.NewServer(
+			log,
+			cfg,
+			db,
+			version,
+			commit,
+			date,
+			plexService,
+			plexSettingsService,
+			malauthService,
+			apiService,
+			authService,
+			mapService,
+			fsService,
+			notificationService,
+			animeUpdateService,
+			tautulliService,
+			reconcileService,
+			reverseSyncService,
+			libraryScanService,
+			syncRuleService,
+			ingestService,
+			healthService,
+			serverEvents,
+		)
+
 		go func() {
-			httpServer := http.NewServer(
-				log,
-				cfg,
-				db,
-				version,
-				commit,
-				date,
-				plexService,
-				plexSettingsService,
-				malauthService,
-				apiService,
-				authService,
-				mapService,
-				fsService,
-				notificationService,
-				animeUpdateService,
-				serverEvents,
-			)
 			errorChannel <- httpServer.Open()
 		}()
 
@@ -178,6 +229,20 @@ func main() {
 
 		for sig := range sigchnl {
 			log.Info().Msgf("received signal: %v, shutting down server.", sig)
+
+			// Stop taking webhooks first so no jobs are queued while the queue drains
+			httpCtx, httpCancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+			if err := httpServer.Shutdown(httpCtx); err != nil {
+				log.Warn().Err(err).Msg("closed open http connections")
+			}
+			httpCancel()
+
+			drainCtx, cancel := context.WithTimeout(context.Background(), jobQueueDrainTimeout)
+			if err := jobQueue.Shutdown(drainCtx); err != nil {
+				log.Warn().Err(err).Msg("pending jobs will resume on next start")
+			}
+			cancel()
+
 			if err := db.Close(); err != nil {
 				log.Error().Err(err).Msg("failed to close the database connection properly")
 				os.Exit(1)
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -17,6 +18,7 @@ type Service interface {
 	StoreMultiple(ctx context.Context, anime []*domain.Anime) error
 	GetAnime(ctx context.Context) ([]*domain.Anime, error)
 	UpdateAnime(ctx context.Context) error
+	LastUpdated(ctx context.Context) (time.Time, error)
 }
 
 type service struct {
@@ -86,3 +88,8 @@ func (s *service) UpdateAnime(ctx context.Context) error {
 
 	return nil
 }
+
+// LastUpdated returns when the anime were last stored from ShinkroDB, zero when there are none.
+func (s *service) LastUpdated(ctx context.Context) (time.Time, error) {
+	return s.repo.LastUpdated(ctx)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/varoOP/shinkro/internal/domain"
 
@@ -33,6 +34,10 @@ func (m *mockAnimeRepo) StoreMultiple(anime []*domain.Anime) error {
 	return nil
 }
 
+func (m *mockAnimeRepo) LastUpdated(ctx context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
 func TestService_GetByID(t *testing.T) {
 	testAnime := &domain.Anime{
 		MALId:  1575,
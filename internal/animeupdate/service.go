@@ -18,11 +18,14 @@ type Service interface {
 	Store(ctx context.Context, animeupdate *domain.AnimeUpdate) error
 	GetByID(ctx context.Context, req *domain.GetAnimeUpdateRequest) (*domain.AnimeUpdate, error)
 	UpdateAnimeList(ctx context.Context, anime *domain.AnimeUpdate, event domain.PlexEvent) error
+	PreviewAnimeList(ctx context.Context, anime *domain.AnimeUpdate, event domain.PlexEvent) error
+	ResolveMALID(ctx context.Context, anime *domain.AnimeUpdate) error
 	Count(ctx context.Context) (int, error)
 	GetRecentUnique(ctx context.Context, limit int) ([]*domain.AnimeUpdate, error)
 	GetByPlexID(ctx context.Context, plexID int64) (*domain.AnimeUpdate, error)
 	GetByPlexIDs(ctx context.Context, plexIDs []int64) ([]*domain.AnimeUpdate, error)
 	FindAllWithFilters(ctx context.Context, params domain.AnimeUpdateQueryParams) (*domain.FindAnimeUpdatesResponse, error)
+	Revert(ctx context.Context, id int) (*domain.AnimeUpdate, error)
 }
 
 type service struct {
@@ -67,8 +70,11 @@ func (s *service) UpdateAnimeList(ctx context.Context, anime *domain.AnimeUpdate
 	switch event {
 	case domain.PlexRateEvent:
 		err = s.handleEvent(ctx, anime, false)
-	case domain.PlexScrobbleEvent:
+	case domain.PlexScrobbleEvent, domain.PlexStopEvent, domain.PlexPauseEvent:
+		// media.stop and media.pause only get here once playback passed the completion threshold
 		err = s.handleEvent(ctx, anime, true)
+	case domain.PlexLibraryNewEvent:
+		err = s.handleLibraryNew(ctx, anime)
 	}
 
 	if err != nil {
@@ -78,30 +84,153 @@ func (s *service) UpdateAnimeList(ctx context.Context, anime *domain.AnimeUpdate
 	return nil
 }
 
+// PreviewAnimeList resolves the MAL entry for anime and fills ListStatus with the status
+// the update would produce, without changing MyAnimeList or storing anything.
+func (s *service) PreviewAnimeList(ctx context.Context, anime *domain.AnimeUpdate, event domain.PlexEvent) error {
+	var isScrobble bool
+	switch event {
+	case domain.PlexRateEvent:
+		isScrobble = false
+	case domain.PlexScrobbleEvent, domain.PlexStopEvent, domain.PlexPauseEvent:
+		isScrobble = true
+	default:
+		return errors.Errorf("plex event not supported: %v", event)
+	}
+
+	if _, err := s.resolveMALID(ctx, anime, isScrobble); err != nil {
+		return err
+	}
+
+	client, err := s.malauthService.GetMalClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.fetchAnimeDetails(ctx, client, anime); err != nil {
+		return err
+	}
+
+	current := anime.ListDetails
+	options := []mal.UpdateMyAnimeListStatusOption{mal.Score(anime.Plex.Rating)}
+	if isScrobble {
+		options, err = anime.BuildWatchStatusOptions()
+		if err != nil {
+			return err
+		}
+	}
+
+	// BuildWatchStatusOptions advances the list details, keep the current ones for display
+	anime.ListDetails = current
+	anime.ListStatus = domain.ApplyListStatusOptions(current.ListStatus(), options)
+	return nil
+}
+
+// ResolveMALID sets the MAL id and the MAL episode number of a watched episode without contacting MyAnimeList.
+func (s *service) ResolveMALID(ctx context.Context, anime *domain.AnimeUpdate) error {
+	_, err := s.resolveMALID(ctx, anime, true)
+	return err
+}
+
 func (s *service) handleEvent(ctx context.Context, anime *domain.AnimeUpdate, isScrobble bool) error {
+	if errType, err := s.resolveMALID(ctx, anime, isScrobble); err != nil {
+		s.publishAnimeUpdateFailed(anime, errType, err.Error())
+		return err
+	}
+
+	return s.updateAndStore(ctx, anime, isScrobble)
+}
+
+// resolveMALID sets the MAL id from the source id, the anime map or, for season 1, the internal database.
+func (s *service) resolveMALID(ctx context.Context, anime *domain.AnimeUpdate, isScrobble bool) (domain.AnimeUpdateErrorType, error) {
 	if anime.SourceDB == domain.MAL {
 		anime.MALId = anime.SourceId
-		return s.updateAndStore(ctx, anime, isScrobble)
+		applyEpisodeOffset(anime, isScrobble)
+		return "", nil
 	}
 
 	convertedAnime := s.convertAniDBToTVDB(ctx, anime)
 	animeMap, err := s.mapService.CheckForAnimeinMap(ctx, convertedAnime)
 	if err == nil {
 		anime.MALId = animeMap.Malid
-		if isScrobble {
+		if isScrobble && anime.EpisodeOffset == nil {
 			anime.EpisodeNum = animeMap.CalculateEpNum(anime.EpisodeNum)
 		}
-		return s.updateAndStore(ctx, anime, isScrobble)
+		applyEpisodeOffset(anime, isScrobble)
+		return "", nil
 	}
 
 	// Mapping not found - try database lookup for season 1
 	if anime.SeasonNum == 1 {
-		return s.updateFromDBAndStore(ctx, anime, isScrobble)
+		errType, err := s.resolveMALIDFromDB(ctx, anime)
+		if err == nil {
+			applyEpisodeOffset(anime, isScrobble)
+		}
+		return errType, err
 	}
 
-	// Mapping not found and not season 1 - publish error
-	s.publishAnimeUpdateFailed(anime, domain.AnimeUpdateErrorMappingNotFound, err.Error())
-	return err
+	return domain.AnimeUpdateErrorMappingNotFound, err
+}
+
+// applyEpisodeOffset adds the episode offset of a sync rule to the episode number of a scrobble.
+func applyEpisodeOffset(anime *domain.AnimeUpdate, isScrobble bool) {
+	if isScrobble && anime.EpisodeOffset != nil {
+		anime.EpisodeNum += *anime.EpisodeOffset
+	}
+}
+
+// handleLibraryNew adds newly added anime to the MAL list as plan to watch. Anime that is already
+// on the list is left untouched and nothing is stored.
+func (s *service) handleLibraryNew(ctx context.Context, anime *domain.AnimeUpdate) error {
+	if errType, err := s.resolveMALID(ctx, anime, false); err != nil {
+		s.publishAnimeUpdateFailed(anime, errType, err.Error())
+		return err
+	}
+
+	client, err := s.malauthService.GetMalClient(ctx)
+	if err != nil {
+		s.publishAnimeUpdateFailed(anime, domain.AnimeUpdateErrorMALAuthFailed, err.Error())
+		return err
+	}
+
+	if err := s.fetchAnimeDetails(ctx, client, anime); err != nil {
+		s.publishAnimeUpdateFailed(anime, domain.AnimeUpdateErrorMALAPIFetchFailed, err.Error())
+		return err
+	}
+
+	if anime.ListDetails.Status != "" {
+		s.log.Debug().Int("malid", anime.MALId).Str("status", string(anime.ListDetails.Status)).Msg("anime already on list, not adding as plan to watch")
+		return nil
+	}
+
+	if anime.DryRun {
+		anime.ListStatus = domain.ApplyListStatusOptions(*anime.PreviousStatus, []mal.UpdateMyAnimeListStatusOption{mal.AnimeStatusPlanToWatch})
+		anime.Status = domain.AnimeUpdateStatusDryRun
+		s.log.Info().Str("title", anime.ListDetails.Title).Msg("dry run, would add to MyAnimeList as plan to watch")
+		return s.Store(ctx, anime)
+	}
+
+	l, _, err := client.Anime.UpdateMyListStatus(ctx, anime.MALId, mal.AnimeStatusPlanToWatch)
+	if err != nil {
+		s.publishAnimeUpdateFailed(anime, domain.AnimeUpdateErrorMALAPIUpdateFailed, err.Error())
+		return err
+	}
+
+	anime.ListStatus = *l
+	anime.ListDetails.Status = l.Status
+	s.log.Info().Str("title", anime.ListDetails.Title).Msg("added to MyAnimeList as plan to watch")
+
+	anime.Status = domain.AnimeUpdateStatusSuccess
+	if err := s.Store(ctx, anime); err != nil {
+		return err
+	}
+
+	s.bus.Publish(domain.EventAnimeUpdateSuccess, &domain.AnimeUpdateSuccessEvent{
+		PlexID:      anime.PlexId,
+		AnimeUpdate: anime,
+		Timestamp:   time.Now(),
+	})
+
+	return nil
 }
 
 func (s *service) updateAndStore(ctx context.Context, anime *domain.AnimeUpdate, isScrobble bool) error {
@@ -117,6 +246,10 @@ func (s *service) updateAndStore(ctx context.Context, anime *domain.AnimeUpdate,
 		return err
 	}
 
+	if anime.DryRun {
+		return s.storeDryRun(ctx, anime, isScrobble)
+	}
+
 	// Update MAL based on event type
 	if isScrobble {
 		if err := s.updateWatchStatus(ctx, client, anime); err != nil {
@@ -150,7 +283,26 @@ func (s *service) updateAndStore(ctx context.Context, anime *domain.AnimeUpdate,
 	return nil
 }
 
-func (s *service) updateFromDBAndStore(ctx context.Context, anime *domain.AnimeUpdate, isScrobble bool) error {
+// storeDryRun stores the list status the update would produce with a DRY_RUN status, without updating MAL.
+func (s *service) storeDryRun(ctx context.Context, anime *domain.AnimeUpdate, isScrobble bool) error {
+	options := []mal.UpdateMyAnimeListStatusOption{mal.Score(anime.Plex.Rating)}
+	if isScrobble {
+		var err error
+		options, err = anime.BuildWatchStatusOptions()
+		if err != nil {
+			s.publishAnimeUpdateFailed(anime, domain.AnimeUpdateErrorMALAPIUpdateFailed, err.Error())
+			return err
+		}
+	}
+
+	anime.ListStatus = domain.ApplyListStatusOptions(*anime.PreviousStatus, options)
+	anime.Status = domain.AnimeUpdateStatusDryRun
+	s.log.Info().Interface("status", anime.ListStatus).Msg("dry run, MyAnimeList not updated")
+
+	return s.Store(ctx, anime)
+}
+
+func (s *service) resolveMALIDFromDB(ctx context.Context, anime *domain.AnimeUpdate) (domain.AnimeUpdateErrorType, error) {
 	req := &domain.GetAnimeRequest{
 		IDtype: anime.SourceDB,
 		Id:     anime.SourceId,
@@ -158,19 +310,16 @@ func (s *service) updateFromDBAndStore(ctx context.Context, anime *domain.AnimeU
 
 	animeFromDB, err := s.animeService.GetByID(ctx, req)
 	if err != nil {
-		s.publishAnimeUpdateFailed(anime, domain.AnimeUpdateErrorAnimeNotInDB, err.Error())
-		return err
+		return domain.AnimeUpdateErrorAnimeNotInDB, err
 	}
 
 	s.log.Debug().Int("malId", animeFromDB.MALId).Msg("Anime from DB")
 	if animeFromDB.MALId == 0 {
-		errMsg := "could not retrieve malid from internal database"
-		s.publishAnimeUpdateFailed(anime, domain.AnimeUpdateErrorAnimeNotInDB, errMsg)
-		return errors.New(errMsg)
+		return domain.AnimeUpdateErrorAnimeNotInDB, errors.New("could not retrieve malid from internal database")
 	}
 
 	anime.MALId = animeFromDB.MALId
-	return s.updateAndStore(ctx, anime, isScrobble)
+	return "", nil
 }
 
 // publishAnimeUpdateFailed publishes failure event and stores failed anime_update record
@@ -193,13 +342,16 @@ func (s *service) publishAnimeUpdateFailed(anime *domain.AnimeUpdate, errorType
 	})
 }
 
-// fetchAnimeDetails calls MAL API to get current anime list details
+// fetchAnimeDetails calls MAL API to get current anime list details and keeps the list status as a snapshot for reverts
 func (s *service) fetchAnimeDetails(ctx context.Context, client *mal.Client, anime *domain.AnimeUpdate) error {
-	aa, _, err := client.Anime.Details(ctx, anime.MALId, mal.Fields{"num_episodes", "title", "main_picture{medium,large}", "my_list_status{status,num_times_rewatched,num_episodes_watched}"})
+	aa, _, err := client.Anime.Details(ctx, anime.MALId, mal.Fields{"num_episodes", "title", "main_picture{medium,large}", "my_list_status{status,score,num_episodes_watched,is_rewatching,num_times_rewatched,start_date,finish_date}"})
 	if err != nil {
 		return err
 	}
 
+	previous := aa.MyListStatus
+	anime.PreviousStatus = &previous
+
 	details := domain.BuildListDetailsFromMALResponse(
 		aa.MyListStatus.Status,
 		aa.MyListStatus.NumTimesRewatched,
@@ -240,6 +392,71 @@ func (s *service) updateWatchStatus(ctx context.Context, client *mal.Client, ani
 	return nil
 }
 
+// Revert restores the MAL list status from before the update with the given id and stores the revert
+// as a new anime update, which can itself be reverted.
+func (s *service) Revert(ctx context.Context, id int) (*domain.AnimeUpdate, error) {
+	original, err := s.repo.GetByID(ctx, &domain.GetAnimeUpdateRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+
+	if original.Status != domain.AnimeUpdateStatusSuccess {
+		return nil, errors.Wrap(domain.ErrAnimeUpdateNotRevertable, "only successful updates can be reverted")
+	}
+
+	if original.PreviousStatus == nil {
+		return nil, errors.Wrap(domain.ErrAnimeUpdateNotRevertable, "no list status was recorded before the update")
+	}
+
+	client, err := s.malauthService.GetMalClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *original.PreviousStatus
+	revert := &domain.AnimeUpdate{
+		MALId:      original.MALId,
+		SourceDB:   original.SourceDB,
+		SourceId:   original.SourceId,
+		EpisodeNum: previous.NumEpisodesWatched,
+		SeasonNum:  original.SeasonNum,
+		Timestamp:  time.Now(),
+		PlexId:     original.PlexId,
+		RevertOf:   original.ID,
+	}
+
+	// Snapshot the current status so the revert can be undone as well
+	if err := s.fetchAnimeDetails(ctx, client, revert); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch anime details")
+	}
+
+	if previous.Status == "" {
+		// The anime was not on the list before the update
+		if _, err := client.Anime.DeleteMyListItem(ctx, original.MALId); err != nil {
+			return nil, errors.Wrap(err, "failed to remove anime from list")
+		}
+		revert.ListStatus = mal.AnimeListStatus{}
+	} else {
+		l, _, err := client.Anime.UpdateMyListStatus(ctx, original.MALId, domain.RevertOptions(previous)...)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to update list status")
+		}
+		revert.ListStatus = *l
+	}
+
+	revert.ListDetails.Status = revert.ListStatus.Status
+	revert.ListDetails.WatchedNum = revert.ListStatus.NumEpisodesWatched
+	revert.ListDetails.RewatchNum = revert.ListStatus.NumTimesRewatched
+	revert.Status = domain.AnimeUpdateStatusSuccess
+
+	if err := s.Store(ctx, revert); err != nil {
+		return nil, err
+	}
+
+	s.log.Info().Int64("id", original.ID).Str("title", revert.ListDetails.Title).Interface("status", revert.ListStatus).Msg("anime update reverted")
+	return revert, nil
+}
+
 func (s *service) convertAniDBToTVDB(ctx context.Context, anime *domain.AnimeUpdate) *domain.AnimeUpdate {
 	if anime.SourceDB != domain.AniDB {
 		return anime
@@ -3,7 +3,12 @@ package animeupdate
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/nstratos/go-myanimelist/mal"
 	"github.com/varoOP/shinkro/internal/domain"
@@ -12,6 +17,7 @@ import (
 	"github.com/asaskevich/EventBus"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock dependencies
@@ -97,6 +103,32 @@ func (m *mockAnimeUpdateRepo) FindAllWithFilters(ctx context.Context, params dom
 	}, nil
 }
 
+func (m *mockAnimeUpdateRepo) FindAfterID(ctx context.Context, id int64) ([]*domain.AnimeUpdate, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	result := []*domain.AnimeUpdate{}
+	for _, au := range m.animeUpdates {
+		if au.ID > id {
+			result = append(result, au)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockAnimeUpdateRepo) LatestID(ctx context.Context) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	var latest int64
+	for _, au := range m.animeUpdates {
+		if au.ID > latest {
+			latest = au.ID
+		}
+	}
+	return latest, nil
+}
+
 type mockAnimeService struct {
 	anime *domain.Anime
 	err   error
@@ -121,6 +153,10 @@ func (m *mockAnimeService) UpdateAnime(ctx context.Context) error {
 	return nil
 }
 
+func (m *mockAnimeService) LastUpdated(ctx context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
 type mockMappingService struct {
 	details *domain.AnimeMapDetails
 	err     error
@@ -145,12 +181,21 @@ func (m *mockMappingService) CheckForAnimeinMap(ctx context.Context, au *domain.
 	return m.details, nil
 }
 
+func (m *mockMappingService) CheckForMALinMap(ctx context.Context, malID, malEp int) (*domain.MappedEpisode, error) {
+	return nil, nil
+}
+
 func (m *mockMappingService) ValidateMap(ctx context.Context, yamlPath string, isTVDB bool) error {
 	return nil
 }
 
+func (m *mockMappingService) IsCached() bool {
+	return false
+}
+
 type mockMALAuthService struct {
 	err error
+	url string
 }
 
 func (m *mockMALAuthService) Store(ctx context.Context, ma *domain.MalAuth) error {
@@ -169,13 +214,30 @@ func (m *mockMALAuthService) GetMalClient(ctx context.Context) (*mal.Client, err
 	if m.err != nil {
 		return nil, m.err
 	}
-	return nil, nil
+	if m.url == "" {
+		return nil, nil
+	}
+	c := mal.NewClient(http.DefaultClient)
+	c.BaseURL, _ = url.Parse(m.url + "/")
+	return c, nil
 }
 
 func (m *mockMALAuthService) GetDecrypted(ctx context.Context) (*domain.MalAuth, error) {
 	return nil, nil
 }
 
+func (m *mockMALAuthService) CheckToken(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+}
+
+func (m *mockMALAuthService) TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+}
+
+func (m *mockMALAuthService) RateLimitStatus() domain.MALRateLimitStatus {
+	return domain.MALRateLimitStatus{}
+}
+
 func TestService_Store(t *testing.T) {
 	bus := EventBus.New()
 	repo := &mockAnimeUpdateRepo{}
@@ -682,3 +744,127 @@ func TestService_GetByPlexID(t *testing.T) {
 		})
 	}
 }
+
+func TestService_Revert(t *testing.T) {
+	var form url.Values
+	deleted := false
+	malServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/anime/1575":
+			fmt.Fprint(w, `{"id":1575,"title":"Code Geass","num_episodes":25,"my_list_status":{"status":"completed","score":3,"num_episodes_watched":25,"num_times_rewatched":0,"finish_date":"2024-03-15"}}`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/anime/1575/my_list_status":
+			require.NoError(t, r.ParseForm())
+			form = r.PostForm
+			fmt.Fprint(w, `{"status":"watching","score":7,"num_episodes_watched":4,"num_times_rewatched":0,"start_date":"2024-01-05"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/anime/1575/my_list_status":
+			deleted = true
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer malServer.Close()
+
+	newService := func(original *domain.AnimeUpdate) (Service, *mockAnimeUpdateRepo) {
+		repo := &mockAnimeUpdateRepo{animeUpdate: original}
+		return NewService(zerolog.Nop(), repo, &mockAnimeService{}, &mockMappingService{}, &mockMALAuthService{url: malServer.URL}, EventBus.New()), repo
+	}
+
+	t.Run("restores previous list status", func(t *testing.T) {
+		original := testdata.NewMockAnimeUpdate()
+		original.PreviousStatus = &mal.AnimeListStatus{Status: mal.AnimeStatusWatching, Score: 7, NumEpisodesWatched: 4, StartDate: "2024-01-05"}
+		service, repo := newService(original)
+
+		revert, err := service.Revert(context.Background(), int(original.ID))
+		require.NoError(t, err)
+
+		assert.Equal(t, "watching", form.Get("status"))
+		assert.Equal(t, "4", form.Get("num_watched_episodes"))
+		assert.Equal(t, "7", form.Get("score"))
+		assert.Equal(t, "2024-01-05", form.Get("start_date"))
+		assert.Equal(t, "", form.Get("finish_date"))
+
+		assert.Same(t, revert, repo.animeUpdate)
+		assert.Equal(t, original.ID, revert.RevertOf)
+		assert.Equal(t, domain.AnimeUpdateStatusSuccess, revert.Status)
+		assert.Equal(t, mal.AnimeStatusWatching, revert.ListStatus.Status)
+		assert.Equal(t, 4, revert.ListDetails.WatchedNum)
+		require.NotNil(t, revert.PreviousStatus)
+		assert.Equal(t, mal.AnimeStatusCompleted, revert.PreviousStatus.Status)
+	})
+
+	t.Run("removes anime that was not on the list", func(t *testing.T) {
+		original := testdata.NewMockAnimeUpdate()
+		original.PreviousStatus = &mal.AnimeListStatus{}
+		service, repo := newService(original)
+
+		revert, err := service.Revert(context.Background(), int(original.ID))
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		assert.Empty(t, revert.ListStatus.Status)
+		assert.Same(t, revert, repo.animeUpdate)
+	})
+
+	t.Run("no previous status", func(t *testing.T) {
+		service, _ := newService(testdata.NewMockAnimeUpdate())
+
+		_, err := service.Revert(context.Background(), 1)
+		assert.ErrorIs(t, err, domain.ErrAnimeUpdateNotRevertable)
+	})
+
+	t.Run("failed update", func(t *testing.T) {
+		original := testdata.NewMockAnimeUpdateWithStatus(domain.AnimeUpdateStatusFailed, domain.AnimeUpdateErrorMALAPIUpdateFailed)
+		original.PreviousStatus = &mal.AnimeListStatus{Status: mal.AnimeStatusWatching}
+		service, _ := newService(original)
+
+		_, err := service.Revert(context.Background(), 1)
+		assert.ErrorIs(t, err, domain.ErrAnimeUpdateNotRevertable)
+	})
+}
+
+func TestService_UpdateAnimeList_DryRun(t *testing.T) {
+	malServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Fatalf("dry run must not change MyAnimeList: %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{"id":1575,"title":"Code Geass","num_episodes":25,"my_list_status":{"status":"watching","score":8,"num_episodes_watched":4,"start_date":"2024-01-05"}}`)
+	}))
+	defer malServer.Close()
+
+	newAnime := func() *domain.AnimeUpdate {
+		return &domain.AnimeUpdate{
+			SourceDB:   domain.MAL,
+			SourceId:   1575,
+			EpisodeNum: 5,
+			SeasonNum:  1,
+			Plex:       testdata.NewMockPlex(),
+			DryRun:     true,
+		}
+	}
+
+	repo := &mockAnimeUpdateRepo{}
+	bus := EventBus.New()
+	published := false
+	require.NoError(t, bus.Subscribe(domain.EventAnimeUpdateSuccess, func(*domain.AnimeUpdateSuccessEvent) { published = true }))
+	service := NewService(zerolog.Nop(), repo, &mockAnimeService{}, &mockMappingService{}, &mockMALAuthService{url: malServer.URL}, bus)
+
+	t.Run("scrobble", func(t *testing.T) {
+		anime := newAnime()
+		require.NoError(t, service.UpdateAnimeList(context.Background(), anime, domain.PlexScrobbleEvent))
+
+		assert.Same(t, anime, repo.animeUpdate)
+		assert.Equal(t, domain.AnimeUpdateStatusDryRun, anime.Status)
+		assert.Equal(t, mal.AnimeStatusWatching, anime.ListStatus.Status)
+		assert.Equal(t, 5, anime.ListStatus.NumEpisodesWatched)
+		assert.Equal(t, 8, anime.ListStatus.Score)
+		assert.Equal(t, "2024-01-05", anime.ListStatus.StartDate)
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		anime := newAnime()
+		require.NoError(t, service.UpdateAnimeList(context.Background(), anime, domain.PlexRateEvent))
+		assert.Equal(t, domain.AnimeUpdateStatusDryRun, anime.Status)
+		assert.Equal(t, int(anime.Plex.Rating), anime.ListStatus.Score)
+	})
+
+	assert.False(t, published)
+}
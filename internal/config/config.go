@@ -61,6 +61,26 @@ func (c *AppConfig) defaultConfig() {
 		SessionSecret:   api.GenerateSecureToken(16),
 		EncryptionKey:   api.GenerateSecureToken(32),
 		CheckForUpdates: true,
+
+		ReconcileSchedule:    "0 4 * * *",
+		ReconcileAutoCorrect: false,
+
+		ReverseSyncSchedule: "",
+		ReverseSyncDryRun:   false,
+
+		LibraryScanSchedule: "0 * * * *",
+
+		JobWorkers: 4,
+
+		DryRun: false,
+
+		MALRateLimit:               1,
+		MALRateBurst:               5,
+		MALMaxRetries:              3,
+		MALCircuitBreakerThreshold: 5,
+		MALCircuitBreakerCooldown:  300,
+
+		PlexGUIDCacheTTL: 168,
 	}
 }
 
@@ -87,6 +107,46 @@ LogLevel = "INFO"
 #LogMaxBackups = 3
 
 CheckForUpdates = true
+
+###Compare Plex watched episodes with MyAnimeList on a cron schedule (UTC). Set to "" to disable.
+#ReconcileSchedule = "0 4 * * *"
+
+###Update MyAnimeList when it is behind Plex instead of only reporting the discrepancy.
+#ReconcileAutoCorrect = false
+
+###Mark episodes watched in Plex when they are watched on MyAnimeList, on a cron schedule (UTC). Disabled when empty.
+#ReverseSyncSchedule = "*/30 * * * *"
+
+###Only log which Plex episodes reverse sync would mark as watched.
+#ReverseSyncDryRun = false
+
+###Look for anime added to plan to watch libraries on a cron schedule (UTC), in addition to library.new webhooks. Set to "" to disable.
+#LibraryScanSchedule = "0 * * * *"
+
+###Number of background workers for MyAnimeList updates. Updates of the same anime always run one at a time, notifications have their own workers.
+#JobWorkers = 4
+
+###Run the whole pipeline but only record the intended MyAnimeList changes with a DRY_RUN status instead of applying them.
+#DryRun = false
+
+###Maximum MyAnimeList requests per second, with short bursts of up to MALRateBurst requests. Set MALRateLimit to 0 to disable limiting.
+#MALRateLimit = 1.0
+
+#MALRateBurst = 5
+
+###Times a MyAnimeList request is retried with exponential backoff after a 403, 429 or 5xx response.
+#MALMaxRetries = 3
+
+###Stop sending MyAnimeList requests for MALCircuitBreakerCooldown seconds after this many failed requests in a row. Set to 0 to disable.
+#MALCircuitBreakerThreshold = 5
+
+#MALCircuitBreakerCooldown = 300
+
+###Hours the show guids looked up on Plex for the Plex agent are cached. Stale entries are used while Plex is unreachable.
+#PlexGUIDCacheTTL = 168
+
+###IPs or CIDR ranges of reverse proxies in front of shinkro. Their X-Forwarded-For and X-Real-IP headers are used for the IP allow lists of ingest tokens, the headers of other clients are ignored.
+#TrustedProxies = ["127.0.0.1", "172.16.0.0/12"]
 `
 
 func (c *AppConfig) WriteConfig(configPath string, configFile string) error {
@@ -251,6 +311,88 @@ func (c *AppConfig) parseEnv() {
 	if v := os.Getenv(prefix + "CHECK_FOR_UPDATES"); v != "" {
 		c.Config.CheckForUpdates = strings.EqualFold(strings.ToLower(v), "true")
 	}
+
+	if v, ok := os.LookupEnv(prefix + "RECONCILE_SCHEDULE"); ok {
+		c.Config.ReconcileSchedule = v
+	}
+
+	if v := os.Getenv(prefix + "RECONCILE_AUTO_CORRECT"); v != "" {
+		c.Config.ReconcileAutoCorrect = strings.EqualFold(strings.ToLower(v), "true")
+	}
+
+	if v := os.Getenv(prefix + "REVERSE_SYNC_SCHEDULE"); v != "" {
+		c.Config.ReverseSyncSchedule = v
+	}
+
+	if v := os.Getenv(prefix + "REVERSE_SYNC_DRY_RUN"); v != "" {
+		c.Config.ReverseSyncDryRun = strings.EqualFold(strings.ToLower(v), "true")
+	}
+
+	if v, ok := os.LookupEnv(prefix + "LIBRARY_SCAN_SCHEDULE"); ok {
+		c.Config.LibraryScanSchedule = v
+	}
+
+	if v := os.Getenv(prefix + "JOB_WORKERS"); v != "" {
+		i, _ := strconv.ParseInt(v, 10, 32)
+		if i > 0 {
+			c.Config.JobWorkers = int(i)
+		}
+	}
+
+	if v := os.Getenv(prefix + "DRY_RUN"); v != "" {
+		c.Config.DryRun = strings.EqualFold(strings.ToLower(v), "true")
+	}
+
+	if v := os.Getenv(prefix + "MAL_RATE_LIMIT"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil && f >= 0 {
+			c.Config.MALRateLimit = f
+		}
+	}
+
+	if v := os.Getenv(prefix + "MAL_RATE_BURST"); v != "" {
+		i, _ := strconv.ParseInt(v, 10, 32)
+		if i > 0 {
+			c.Config.MALRateBurst = int(i)
+		}
+	}
+
+	if v := os.Getenv(prefix + "MAL_MAX_RETRIES"); v != "" {
+		i, err := strconv.ParseInt(v, 10, 32)
+		if err == nil && i >= 0 {
+			c.Config.MALMaxRetries = int(i)
+		}
+	}
+
+	if v := os.Getenv(prefix + "MAL_CIRCUIT_BREAKER_THRESHOLD"); v != "" {
+		i, err := strconv.ParseInt(v, 10, 32)
+		if err == nil && i >= 0 {
+			c.Config.MALCircuitBreakerThreshold = int(i)
+		}
+	}
+
+	if v := os.Getenv(prefix + "MAL_CIRCUIT_BREAKER_COOLDOWN"); v != "" {
+		i, _ := strconv.ParseInt(v, 10, 32)
+		if i > 0 {
+			c.Config.MALCircuitBreakerCooldown = int(i)
+		}
+	}
+
+	if v := os.Getenv(prefix + "PLEX_GUID_CACHE_TTL"); v != "" {
+		i, _ := strconv.ParseInt(v, 10, 32)
+		if i > 0 {
+			c.Config.PlexGUIDCacheTTL = int(i)
+		}
+	}
+
+	if v := os.Getenv(prefix + "TRUSTED_PROXIES"); v != "" {
+		c.Config.TrustedProxies = nil
+		for _, proxy := range strings.Split(v, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				c.Config.TrustedProxies = append(c.Config.TrustedProxies, proxy)
+			}
+		}
+	}
 }
 
 func (c *AppConfig) DynamicReload(log zerolog.Logger) {
@@ -291,6 +433,9 @@ func (c *AppConfig) DynamicReload(log zerolog.Logger) {
 
 			c.Config.LogPath = k.String("LogPath")
 			c.Config.CheckForUpdates = k.Bool("CheckForUpdates")
+			c.Config.ReconcileAutoCorrect = k.Bool("ReconcileAutoCorrect")
+			c.Config.ReverseSyncDryRun = k.Bool("ReverseSyncDryRun")
+			c.Config.DryRun = k.Bool("DryRun")
 		}
 
 		log.Debug().Msg("config file reloaded!")
@@ -155,6 +155,20 @@ func TestAppConfig_ParseEnv(t *testing.T) {
 		"SHINKRO_SESSION_SECRET",
 		"SHINKRO_ENCRYPTION_KEY",
 		"SHINKRO_CHECK_FOR_UPDATES",
+		"SHINKRO_RECONCILE_SCHEDULE",
+		"SHINKRO_RECONCILE_AUTO_CORRECT",
+		"SHINKRO_REVERSE_SYNC_SCHEDULE",
+		"SHINKRO_REVERSE_SYNC_DRY_RUN",
+		"SHINKRO_LIBRARY_SCAN_SCHEDULE",
+		"SHINKRO_JOB_WORKERS",
+		"SHINKRO_DRY_RUN",
+		"SHINKRO_MAL_RATE_LIMIT",
+		"SHINKRO_MAL_RATE_BURST",
+		"SHINKRO_MAL_MAX_RETRIES",
+		"SHINKRO_MAL_CIRCUIT_BREAKER_THRESHOLD",
+		"SHINKRO_MAL_CIRCUIT_BREAKER_COOLDOWN",
+		"SHINKRO_PLEX_GUID_CACHE_TTL",
+		"SHINKRO_TRUSTED_PROXIES",
 	}
 
 	for _, key := range envVars {
@@ -179,16 +193,30 @@ func TestAppConfig_ParseEnv(t *testing.T) {
 		{
 			name: "set all env vars",
 			envVars: map[string]string{
-				"SHINKRO_HOST":              "0.0.0.0",
-				"SHINKRO_PORT":              "8080",
-				"SHINKRO_BASE_URL":          "/app",
-				"SHINKRO_LOG_LEVEL":         "DEBUG",
-				"SHINKRO_LOG_PATH":          "/var/log/shinkro.log",
-				"SHINKRO_LOG_MAX_SIZE":      "100",
-				"SHINKRO_LOG_MAX_BACKUPS":   "10",
-				"SHINKRO_SESSION_SECRET":    "secret123",
-				"SHINKRO_ENCRYPTION_KEY":    "key123",
-				"SHINKRO_CHECK_FOR_UPDATES": "false",
+				"SHINKRO_HOST":                          "0.0.0.0",
+				"SHINKRO_PORT":                          "8080",
+				"SHINKRO_BASE_URL":                      "/app",
+				"SHINKRO_LOG_LEVEL":                     "DEBUG",
+				"SHINKRO_LOG_PATH":                      "/var/log/shinkro.log",
+				"SHINKRO_LOG_MAX_SIZE":                  "100",
+				"SHINKRO_LOG_MAX_BACKUPS":               "10",
+				"SHINKRO_SESSION_SECRET":                "secret123",
+				"SHINKRO_ENCRYPTION_KEY":                "key123",
+				"SHINKRO_CHECK_FOR_UPDATES":             "false",
+				"SHINKRO_RECONCILE_SCHEDULE":            "0 */6 * * *",
+				"SHINKRO_RECONCILE_AUTO_CORRECT":        "true",
+				"SHINKRO_REVERSE_SYNC_SCHEDULE":         "*/15 * * * *",
+				"SHINKRO_REVERSE_SYNC_DRY_RUN":          "true",
+				"SHINKRO_LIBRARY_SCAN_SCHEDULE":         "*/10 * * * *",
+				"SHINKRO_JOB_WORKERS":                   "8",
+				"SHINKRO_DRY_RUN":                       "true",
+				"SHINKRO_MAL_RATE_LIMIT":                "0.5",
+				"SHINKRO_MAL_RATE_BURST":                "2",
+				"SHINKRO_MAL_MAX_RETRIES":               "0",
+				"SHINKRO_MAL_CIRCUIT_BREAKER_THRESHOLD": "10",
+				"SHINKRO_MAL_CIRCUIT_BREAKER_COOLDOWN":  "60",
+				"SHINKRO_PLEX_GUID_CACHE_TTL":           "24",
+				"SHINKRO_TRUSTED_PROXIES":               "127.0.0.1, 172.16.0.0/12",
 			},
 			validate: func(t *testing.T, cfg *AppConfig) {
 				assert.Equal(t, "0.0.0.0", cfg.Config.Host)
@@ -201,6 +229,29 @@ func TestAppConfig_ParseEnv(t *testing.T) {
 				assert.Equal(t, "secret123", cfg.Config.SessionSecret)
 				assert.Equal(t, "key123", cfg.Config.EncryptionKey)
 				assert.False(t, cfg.Config.CheckForUpdates)
+				assert.Equal(t, "0 */6 * * *", cfg.Config.ReconcileSchedule)
+				assert.True(t, cfg.Config.ReconcileAutoCorrect)
+				assert.Equal(t, "*/15 * * * *", cfg.Config.ReverseSyncSchedule)
+				assert.True(t, cfg.Config.ReverseSyncDryRun)
+				assert.Equal(t, "*/10 * * * *", cfg.Config.LibraryScanSchedule)
+				assert.Equal(t, 8, cfg.Config.JobWorkers)
+				assert.True(t, cfg.Config.DryRun)
+				assert.Equal(t, 0.5, cfg.Config.MALRateLimit)
+				assert.Equal(t, 2, cfg.Config.MALRateBurst)
+				assert.Equal(t, 0, cfg.Config.MALMaxRetries)
+				assert.Equal(t, 10, cfg.Config.MALCircuitBreakerThreshold)
+				assert.Equal(t, 60, cfg.Config.MALCircuitBreakerCooldown)
+				assert.Equal(t, 24, cfg.Config.PlexGUIDCacheTTL)
+				assert.Equal(t, []string{"127.0.0.1", "172.16.0.0/12"}, cfg.Config.TrustedProxies)
+			},
+		},
+		{
+			name: "disable reconcile schedule",
+			envVars: map[string]string{
+				"SHINKRO_RECONCILE_SCHEDULE": "",
+			},
+			validate: func(t *testing.T, cfg *AppConfig) {
+				assert.Empty(t, cfg.Config.ReconcileSchedule)
 			},
 		},
 		{
@@ -302,6 +353,19 @@ func TestAppConfig_DefaultConfig(t *testing.T) {
 	assert.NotEmpty(t, cfg.Config.SessionSecret)
 	assert.NotEmpty(t, cfg.Config.EncryptionKey)
 	assert.True(t, cfg.Config.CheckForUpdates)
+	assert.Equal(t, "0 4 * * *", cfg.Config.ReconcileSchedule)
+	assert.False(t, cfg.Config.ReconcileAutoCorrect)
+	assert.Empty(t, cfg.Config.ReverseSyncSchedule)
+	assert.False(t, cfg.Config.ReverseSyncDryRun)
+	assert.Equal(t, "0 * * * *", cfg.Config.LibraryScanSchedule)
+	assert.Equal(t, 4, cfg.Config.JobWorkers)
+	assert.False(t, cfg.Config.DryRun)
+	assert.Equal(t, 1.0, cfg.Config.MALRateLimit)
+	assert.Equal(t, 5, cfg.Config.MALRateBurst)
+	assert.Equal(t, 3, cfg.Config.MALMaxRetries)
+	assert.Equal(t, 5, cfg.Config.MALCircuitBreakerThreshold)
+	assert.Equal(t, 300, cfg.Config.MALCircuitBreakerCooldown)
+	assert.Equal(t, 168, cfg.Config.PlexGUIDCacheTTL)
 }
 
 func TestAppConfig_WriteConfig(t *testing.T) {
@@ -372,4 +436,3 @@ func TestAppConfig_WriteConfig(t *testing.T) {
 		assert.Equal(t, existingContent, string(content))
 	})
 }
-
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/pkg/errors"
@@ -111,6 +112,35 @@ func (repo *AnimeRepo) GetByID(ctx context.Context, req *domain.GetAnimeRequest)
 	return &anime, nil
 }
 
+// LastUpdated returns the newest updated_at of the anime, every anime is replaced by a ShinkroDB update.
+func (repo *AnimeRepo) LastUpdated(ctx context.Context) (time.Time, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("MAX(updated_at)").
+		From("anime")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "anime.lastUpdated").Msgf("query: '%s', args: '%v'", query, args)
+	var updated sql.NullString
+	if err := repo.db.handler.QueryRowContext(ctx, query, args...).Scan(&updated); err != nil {
+		return time.Time{}, errors.Wrap(err, "error executing query")
+	}
+
+	if !updated.Valid {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.DateTime, updated.String)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "error parsing updated_at")
+	}
+
+	return t, nil
+}
+
 func (repo *AnimeRepo) StoreMultiple(anime []*domain.Anime) error {
 	tx, err := repo.db.handler.Begin()
 	if err != nil {
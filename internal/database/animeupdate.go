@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/nstratos/go-myanimelist/mal"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/varoOP/shinkro/internal/domain"
@@ -36,10 +37,19 @@ func (repo *AnimeUpdateRepo) Store(ctx context.Context, r *domain.AnimeUpdate) e
 		return errors.Wrap(err, "failed to marshal listStatus")
 	}
 
+	var previousStatus sql.NullString
+	if r.PreviousStatus != nil {
+		ps, err := json.Marshal(r.PreviousStatus)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal previousStatus")
+		}
+		previousStatus = sql.NullString{String: string(ps), Valid: true}
+	}
+
 	queryBuilder := repo.db.squirrel.
 		Insert("anime_update").
-		Columns("mal_id", "source_db", "source_id", "episode_num", "season_num", "time_stamp", "list_details", "list_status", "plex_id", "status", "error_type", "error_message").
-		Values(r.MALId, r.SourceDB, r.SourceId, r.EpisodeNum, r.SeasonNum, r.Timestamp, string(listDetails), string(listStatus), r.PlexId, r.Status, r.ErrorType, r.ErrorMessage).
+		Columns("mal_id", "source_db", "source_id", "episode_num", "season_num", "time_stamp", "list_details", "list_status", "plex_id", "status", "error_type", "error_message", "previous_status", "revert_of").
+		Values(r.MALId, r.SourceDB, r.SourceId, r.EpisodeNum, r.SeasonNum, r.Timestamp, string(listDetails), string(listStatus), r.PlexId, r.Status, r.ErrorType, r.ErrorMessage, previousStatus, sql.NullInt64{Int64: r.RevertOf, Valid: r.RevertOf != 0}).
 		Suffix("RETURNING id").RunWith(repo.db.handler)
 
 	var retID int64
@@ -55,7 +65,19 @@ func (repo *AnimeUpdateRepo) Store(ctx context.Context, r *domain.AnimeUpdate) e
 }
 
 func (repo *AnimeUpdateRepo) GetByID(ctx context.Context, req *domain.GetAnimeUpdateRequest) (*domain.AnimeUpdate, error) {
-	return nil, nil
+	queryBuilder := repo.db.squirrel.
+		Select("id, mal_id, source_db, source_id, episode_num, season_num, time_stamp, list_details, list_status, plex_id, status, error_type, error_message, previous_status, revert_of").
+		From("anime_update").
+		Where(sq.Eq{"id": req.Id})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "animeupdate.getByID").Msgf("query: '%s', args: '%v'", query, args)
+
+	return scanAnimeUpdate(repo.db.handler.QueryRowContext(ctx, query, args...))
 }
 
 func (repo *AnimeUpdateRepo) Count(ctx context.Context) (int, error) {
@@ -90,7 +112,7 @@ func (repo *AnimeUpdateRepo) GetRecentUnique(ctx context.Context, limit int) ([]
 		GroupBy("mal_id")
 
 	queryBuilder := repo.db.squirrel.
-		Select("au.id, au.mal_id, au.source_db, au.source_id, au.episode_num, au.season_num, au.time_stamp, au.list_details, au.list_status, au.plex_id, au.status, au.error_type, au.error_message").
+		Select("au.id, au.mal_id, au.source_db, au.source_id, au.episode_num, au.season_num, au.time_stamp, au.list_details, au.list_status, au.plex_id, au.status, au.error_type, au.error_message, au.previous_status, au.revert_of").
 		FromSelect(latest, "latest").
 		Join("anime_update au ON latest.mal_id = au.mal_id AND latest.max_ts = au.time_stamp").
 		Where(sq.Eq{"au.status": string(domain.AnimeUpdateStatusSuccess)}).
@@ -110,35 +132,18 @@ func (repo *AnimeUpdateRepo) GetRecentUnique(ctx context.Context, limit int) ([]
 
 	updates := make([]*domain.AnimeUpdate, 0)
 	for rows.Next() {
-		var au domain.AnimeUpdate
-		var listDetailsBytes, listStatusBytes []byte
-		var status, errorType, errorMessage sql.NullString
-		if err := rows.Scan(&au.ID, &au.MALId, &au.SourceDB, &au.SourceId, &au.EpisodeNum, &au.SeasonNum, &au.Timestamp, &listDetailsBytes, &listStatusBytes, &au.PlexId, &status, &errorType, &errorMessage); err != nil {
-			return nil, errors.Wrap(err, "error scanning row")
-		}
-		if err := json.Unmarshal(listDetailsBytes, &au.ListDetails); err != nil {
-			return nil, errors.Wrap(err, "error unmarshalling list_details")
+		au, err := scanAnimeUpdate(rows)
+		if err != nil {
+			return nil, err
 		}
-		if err := json.Unmarshal(listStatusBytes, &au.ListStatus); err != nil {
-			return nil, errors.Wrap(err, "error unmarshalling list_status")
-		}
-		if status.Valid {
-			au.Status = domain.AnimeUpdateStatusType(status.String)
-		}
-		if errorType.Valid {
-			au.ErrorType = domain.AnimeUpdateErrorType(errorType.String)
-		}
-		if errorMessage.Valid {
-			au.ErrorMessage = errorMessage.String
-		}
-		updates = append(updates, &au)
+		updates = append(updates, au)
 	}
 	return updates, nil
 }
 
 func (repo *AnimeUpdateRepo) GetByPlexID(ctx context.Context, plexID int64) (*domain.AnimeUpdate, error) {
 	queryBuilder := repo.db.squirrel.
-		Select("id, mal_id, source_db, source_id, episode_num, season_num, time_stamp, list_details, list_status, plex_id, status, error_type, error_message").
+		Select("id, mal_id, source_db, source_id, episode_num, season_num, time_stamp, list_details, list_status, plex_id, status, error_type, error_message, previous_status, revert_of").
 		From("anime_update").
 		Where("plex_id = ?", plexID).
 		OrderBy("time_stamp DESC").
@@ -149,32 +154,14 @@ func (repo *AnimeUpdateRepo) GetByPlexID(ctx context.Context, plexID int64) (*do
 		return nil, errors.Wrap(err, "error building query")
 	}
 
-	row := repo.db.handler.QueryRowContext(ctx, query, args...)
-	var au domain.AnimeUpdate
-	var listDetailsBytes, listStatusBytes []byte
-	var status, errorType, errorMessage sql.NullString
-	if err := row.Scan(&au.ID, &au.MALId, &au.SourceDB, &au.SourceId, &au.EpisodeNum, &au.SeasonNum, &au.Timestamp, &listDetailsBytes, &listStatusBytes, &au.PlexId, &status, &errorType, &errorMessage); err != nil {
-		if err == sql.ErrNoRows {
+	au, err := scanAnimeUpdate(repo.db.handler.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // No update for this plex_id
 		}
-		return nil, errors.Wrap(err, "error scanning row")
-	}
-	if err := json.Unmarshal(listDetailsBytes, &au.ListDetails); err != nil {
-		return nil, errors.Wrap(err, "error unmarshalling list_details")
+		return nil, err
 	}
-	if err := json.Unmarshal(listStatusBytes, &au.ListStatus); err != nil {
-		return nil, errors.Wrap(err, "error unmarshalling list_status")
-	}
-	if status.Valid {
-		au.Status = domain.AnimeUpdateStatusType(status.String)
-	}
-	if errorType.Valid {
-		au.ErrorType = domain.AnimeUpdateErrorType(errorType.String)
-	}
-	if errorMessage.Valid {
-		au.ErrorMessage = errorMessage.String
-	}
-	return &au, nil
+	return au, nil
 }
 
 func (repo *AnimeUpdateRepo) GetByPlexIDs(ctx context.Context, plexIDs []int64) ([]*domain.AnimeUpdate, error) {
@@ -183,7 +170,7 @@ func (repo *AnimeUpdateRepo) GetByPlexIDs(ctx context.Context, plexIDs []int64)
 	}
 
 	queryBuilder := repo.db.squirrel.
-		Select("id, mal_id, source_db, source_id, episode_num, season_num, time_stamp, list_details, list_status, plex_id, status, error_type, error_message").
+		Select("id, mal_id, source_db, source_id, episode_num, season_num, time_stamp, list_details, list_status, plex_id, status, error_type, error_message, previous_status, revert_of").
 		From("anime_update").
 		Where(sq.Eq{"plex_id": plexIDs}).
 		OrderBy("time_stamp DESC")
@@ -203,28 +190,11 @@ func (repo *AnimeUpdateRepo) GetByPlexIDs(ctx context.Context, plexIDs []int64)
 
 	var updates []*domain.AnimeUpdate
 	for rows.Next() {
-		var au domain.AnimeUpdate
-		var listDetailsBytes, listStatusBytes []byte
-		var status, errorType, errorMessage sql.NullString
-		if err := rows.Scan(&au.ID, &au.MALId, &au.SourceDB, &au.SourceId, &au.EpisodeNum, &au.SeasonNum, &au.Timestamp, &listDetailsBytes, &listStatusBytes, &au.PlexId, &status, &errorType, &errorMessage); err != nil {
-			return nil, errors.Wrap(err, "error scanning row")
-		}
-		if err := json.Unmarshal(listDetailsBytes, &au.ListDetails); err != nil {
-			return nil, errors.Wrap(err, "error unmarshalling list_details")
+		au, err := scanAnimeUpdate(rows)
+		if err != nil {
+			return nil, err
 		}
-		if err := json.Unmarshal(listStatusBytes, &au.ListStatus); err != nil {
-			return nil, errors.Wrap(err, "error unmarshalling list_status")
-		}
-		if status.Valid {
-			au.Status = domain.AnimeUpdateStatusType(status.String)
-		}
-		if errorType.Valid {
-			au.ErrorType = domain.AnimeUpdateErrorType(errorType.String)
-		}
-		if errorMessage.Valid {
-			au.ErrorMessage = errorMessage.String
-		}
-		updates = append(updates, &au)
+		updates = append(updates, au)
 	}
 
 	return updates, nil
@@ -322,7 +292,7 @@ func (repo *AnimeUpdateRepo) FindAllWithFilters(ctx context.Context, params doma
 		Select(
 			"au.id", "au.mal_id", "au.source_db", "au.source_id", "au.episode_num", "au.season_num",
 			"au.time_stamp", "au.list_details", "au.list_status", "au.plex_id",
-			"au.status", "au.error_type", "au.error_message",
+			"au.status", "au.error_type", "au.error_message", "au.previous_status", "au.revert_of",
 		).
 		From("anime_update au").
 		OrderBy("au.id DESC")
@@ -367,43 +337,114 @@ func (repo *AnimeUpdateRepo) FindAllWithFilters(ctx context.Context, params doma
 	defer rows.Close()
 
 	for rows.Next() {
-		var au domain.AnimeUpdate
-		var listDetailsBytes, listStatusBytes []byte
-		var status, errorType, errorMessage sql.NullString
-
-		if err := rows.Scan(
-			&au.ID, &au.MALId, &au.SourceDB, &au.SourceId, &au.EpisodeNum, &au.SeasonNum,
-			&au.Timestamp, &listDetailsBytes, &listStatusBytes, &au.PlexId,
-			&status, &errorType, &errorMessage,
-		); err != nil {
-			return resp, errors.Wrap(err, "error scanning row")
-		}
-
-		if err := json.Unmarshal(listDetailsBytes, &au.ListDetails); err != nil {
-			return resp, errors.Wrap(err, "error unmarshalling list_details")
-		}
-		if err := json.Unmarshal(listStatusBytes, &au.ListStatus); err != nil {
-			return resp, errors.Wrap(err, "error unmarshalling list_status")
-		}
-
-		if status.Valid {
-			au.Status = domain.AnimeUpdateStatusType(status.String)
-		}
-		if errorType.Valid {
-			au.ErrorType = domain.AnimeUpdateErrorType(errorType.String)
-		}
-		if errorMessage.Valid {
-			au.ErrorMessage = errorMessage.String
+		au, err := scanAnimeUpdate(rows)
+		if err != nil {
+			return resp, err
 		}
 
 		resp.Data = append(resp.Data, domain.AnimeUpdateListItem{
-			AnimeUpdate: &au,
+			AnimeUpdate: au,
 		})
 	}
 
 	return resp, nil
 }
 
+// FindAfterID returns the updates stored after the update with id, oldest first.
+func (repo *AnimeUpdateRepo) FindAfterID(ctx context.Context, id int64) ([]*domain.AnimeUpdate, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("id, mal_id, source_db, source_id, episode_num, season_num, time_stamp, list_details, list_status, plex_id, status, error_type, error_message, previous_status, revert_of").
+		From("anime_update").
+		Where(sq.Gt{"id": id}).
+		OrderBy("id ASC")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "animeupdate.findAfterID").Msgf("query: '%s', args: '%v'", query, args)
+
+	rows, err := repo.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+	defer rows.Close()
+
+	updates := []*domain.AnimeUpdate{}
+	for rows.Next() {
+		au, err := scanAnimeUpdate(rows)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, au)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findAfterID")
+	}
+
+	return updates, nil
+}
+
+// LatestID returns the id of the newest update, 0 when there is none.
+func (repo *AnimeUpdateRepo) LatestID(ctx context.Context) (int64, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("COALESCE(MAX(id), 0)").
+		From("anime_update")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return 0, errors.Wrap(err, "error building query")
+	}
+
+	var id int64
+	if err := repo.db.handler.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, errors.Wrap(err, "error scanning row")
+	}
+
+	return id, nil
+}
+
+// scanAnimeUpdate scans a row selected with the anime_update columns in table order.
+func scanAnimeUpdate(row rowScanner) (*domain.AnimeUpdate, error) {
+	var au domain.AnimeUpdate
+	var listDetailsBytes, listStatusBytes []byte
+	var status, errorType, errorMessage, previousStatus sql.NullString
+	var revertOf sql.NullInt64
+	if err := row.Scan(&au.ID, &au.MALId, &au.SourceDB, &au.SourceId, &au.EpisodeNum, &au.SeasonNum, &au.Timestamp, &listDetailsBytes, &listStatusBytes, &au.PlexId, &status, &errorType, &errorMessage, &previousStatus, &revertOf); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "error scanning row")
+	}
+	if err := json.Unmarshal(listDetailsBytes, &au.ListDetails); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling list_details")
+	}
+	if err := json.Unmarshal(listStatusBytes, &au.ListStatus); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling list_status")
+	}
+	au.Status = domain.AnimeUpdateStatusType(status.String)
+	au.ErrorType = domain.AnimeUpdateErrorType(errorType.String)
+	au.ErrorMessage = errorMessage.String
+	if err := setRevertFields(&au, previousStatus, revertOf); err != nil {
+		return nil, err
+	}
+	return &au, nil
+}
+
+// setRevertFields sets the list status snapshot taken before the update and the update it reverted, if any.
+func setRevertFields(au *domain.AnimeUpdate, previousStatus sql.NullString, revertOf sql.NullInt64) error {
+	if previousStatus.Valid && previousStatus.String != "" {
+		au.PreviousStatus = &mal.AnimeListStatus{}
+		if err := json.Unmarshal([]byte(previousStatus.String), au.PreviousStatus); err != nil {
+			return errors.Wrap(err, "error unmarshalling previous_status")
+		}
+	}
+	au.RevertOf = revertOf.Int64
+	return nil
+}
+
 // parseMALID attempts to parse a string as MAL ID (numeric)
 func parseMALID(s string) int {
 	id, err := strconv.Atoi(strings.TrimSpace(s))
@@ -100,6 +100,16 @@ func (db *DB) Ping() error {
 	return db.handler.Ping()
 }
 
+// SchemaVersion returns the schema version of the database and the version this build migrates to.
+func (db *DB) SchemaVersion(ctx context.Context) (int, int, error) {
+	var version int
+	if err := db.handler.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return 0, 0, errors.Wrap(err, "failed to query schema version")
+	}
+
+	return version, len(migrations), nil
+}
+
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 	tx, err := db.handler.BeginTx(ctx, opts)
 	if err != nil {
@@ -4,12 +4,15 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/varoOP/shinkro/internal/domain"
 	"github.com/varoOP/shinkro/internal/testdata"
 
+	"github.com/nstratos/go-myanimelist/mal"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -189,6 +192,12 @@ func TestAnimeRepo_Integration(t *testing.T) {
 	repo := NewAnimeRepo(log, db)
 	ctx := context.Background()
 
+	t.Run("last updated without anime", func(t *testing.T) {
+		updated, err := repo.LastUpdated(ctx)
+		require.NoError(t, err)
+		assert.True(t, updated.IsZero())
+	})
+
 	t.Run("store multiple anime", func(t *testing.T) {
 		anime := []*domain.Anime{
 			{MALId: 1575, TVDBId: 81797, TMDBId: 37854, MainTitle: "One Piece"},
@@ -197,6 +206,10 @@ func TestAnimeRepo_Integration(t *testing.T) {
 
 		err := repo.StoreMultiple(anime)
 		assert.NoError(t, err)
+
+		updated, err := repo.LastUpdated(ctx)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), updated, time.Minute)
 	})
 
 	t.Run("get anime by MAL ID", func(t *testing.T) {
@@ -297,9 +310,37 @@ func TestAnimeUpdateRepo_Integration(t *testing.T) {
 
 		req := &domain.GetAnimeUpdateRequest{Id: int(update.ID)}
 		result, err := repo.GetByID(ctx, req)
-		// Note: GetByID is not fully implemented, so this might return nil
-		_ = result
-		_ = err
+		require.NoError(t, err)
+		assert.Equal(t, update.ID, result.ID)
+		assert.Equal(t, update.MALId, result.MALId)
+		assert.Equal(t, update.ListDetails, result.ListDetails)
+		assert.Nil(t, result.PreviousStatus)
+		assert.Zero(t, result.RevertOf)
+	})
+
+	t.Run("get missing anime update by ID", func(t *testing.T) {
+		_, err := repo.GetByID(ctx, &domain.GetAnimeUpdateRequest{Id: 999999})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("store previous status and revert", func(t *testing.T) {
+		update := testdata.NewMockAnimeUpdate()
+		update.PlexId = dummyPlex.ID
+		update.PreviousStatus = &mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: 4, Score: 7, StartDate: "2024-01-05"}
+		require.NoError(t, repo.Store(ctx, update))
+
+		revert := testdata.NewMockAnimeUpdate()
+		revert.PlexId = dummyPlex.ID
+		revert.RevertOf = update.ID
+		require.NoError(t, repo.Store(ctx, revert))
+
+		result, err := repo.GetByID(ctx, &domain.GetAnimeUpdateRequest{Id: int(update.ID)})
+		require.NoError(t, err)
+		assert.Equal(t, update.PreviousStatus, result.PreviousStatus)
+
+		result, err = repo.GetByID(ctx, &domain.GetAnimeUpdateRequest{Id: int(revert.ID)})
+		require.NoError(t, err)
+		assert.Equal(t, update.ID, result.RevertOf)
 	})
 
 	t.Run("count anime updates", func(t *testing.T) {
@@ -422,6 +463,31 @@ func TestAnimeUpdateRepo_Integration(t *testing.T) {
 		assert.NotNil(t, results)
 		assert.GreaterOrEqual(t, len(results.Data), 1)
 	})
+
+	t.Run("find anime updates after ID", func(t *testing.T) {
+		latest, err := repo.LatestID(ctx)
+		require.NoError(t, err)
+		assert.NotZero(t, latest)
+
+		update1 := testdata.NewMockAnimeUpdate()
+		update1.PlexId = dummyPlex.ID
+		require.NoError(t, repo.Store(ctx, update1))
+
+		update2 := testdata.NewMockAnimeUpdate()
+		update2.PlexId = dummyPlex.ID
+		require.NoError(t, repo.Store(ctx, update2))
+
+		results, err := repo.FindAfterID(ctx, latest)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, update1.ID, results[0].ID)
+		assert.Equal(t, update2.ID, results[1].ID)
+		assert.Equal(t, update1.ListDetails, results[0].ListDetails)
+
+		latest, err = repo.LatestID(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, update2.ID, latest)
+	})
 }
 
 func TestPlexRepo_Integration(t *testing.T) {
@@ -631,6 +697,530 @@ func TestAPIRepo_Integration(t *testing.T) {
 	})
 }
 
+func TestReconcileRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewReconcileRepo(log, db)
+	ctx := context.Background()
+
+	t.Run("store and find", func(t *testing.T) {
+		item := &domain.ReconcileItem{
+			MALId:         52991,
+			Title:         "Frieren",
+			PlexRatingKey: "100",
+			SourceDB:      domain.TVDB,
+			SourceId:      424536,
+			SeasonNum:     1,
+			PlexWatched:   5,
+			MALWatched:    2,
+			MALStatus:     mal.AnimeStatusWatching,
+			Status:        domain.ReconcileStatusDiscrepancy,
+		}
+
+		err := repo.Store(ctx, item)
+		require.NoError(t, err)
+		assert.NotZero(t, item.ID)
+
+		items, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "Frieren", items[0].Title)
+		assert.Equal(t, 5, items[0].PlexWatched)
+		assert.Equal(t, mal.AnimeStatusWatching, items[0].MALStatus)
+		assert.False(t, items[0].Ignored)
+	})
+
+	t.Run("store keeps ignored flag", func(t *testing.T) {
+		err := repo.SetIgnored(ctx, 52991, true)
+		require.NoError(t, err)
+
+		item := &domain.ReconcileItem{
+			MALId:       52991,
+			Title:       "Frieren",
+			PlexWatched: 6,
+			Status:      domain.ReconcileStatusDiscrepancy,
+		}
+		err = repo.Store(ctx, item)
+		require.NoError(t, err)
+		assert.True(t, item.Ignored)
+
+		items, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, 6, items[0].PlexWatched)
+		assert.True(t, items[0].Ignored)
+	})
+
+	t.Run("delete stale keeps ignored", func(t *testing.T) {
+		err := repo.Store(ctx, &domain.ReconcileItem{MALId: 57334, Title: "Dandadan", Status: domain.ReconcileStatusDiscrepancy})
+		require.NoError(t, err)
+
+		err = repo.DeleteStale(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+
+		items, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, 52991, items[0].MALId)
+	})
+}
+
+func TestReverseSyncRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewReverseSyncRepo(log, db)
+	ctx := context.Background()
+
+	err := repo.Store(ctx, &domain.ReverseSyncProgress{MALId: 52991, EpisodesWatched: 3})
+	require.NoError(t, err)
+
+	err = repo.Store(ctx, &domain.ReverseSyncProgress{MALId: 52991, EpisodesWatched: 5})
+	require.NoError(t, err)
+
+	err = repo.Store(ctx, &domain.ReverseSyncProgress{MALId: 57334, EpisodesWatched: 1})
+	require.NoError(t, err)
+
+	items, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	progress := make(map[int]int)
+	for _, item := range items {
+		progress[item.MALId] = item.EpisodesWatched
+		assert.False(t, item.UpdatedAt.IsZero())
+	}
+	assert.Equal(t, map[int]int{52991: 5, 57334: 1}, progress)
+}
+
+func TestLibraryScanRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewLibraryScanRepo(log, db)
+	ctx := context.Background()
+
+	scannedAt, err := repo.GetScannedAt(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, scannedAt.IsZero())
+
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, repo.StoreScannedAt(ctx, "1", first))
+	require.NoError(t, repo.StoreScannedAt(ctx, "1", first.Add(time.Hour)))
+
+	scannedAt, err = repo.GetScannedAt(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, first.Add(time.Hour).Equal(scannedAt))
+
+	scannedAt, err = repo.GetScannedAt(ctx, "2")
+	require.NoError(t, err)
+	assert.True(t, scannedAt.IsZero())
+}
+
+func TestJobRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewJobRepo(log, db)
+	ctx := context.Background()
+
+	first := &domain.Job{Type: domain.JobTypeAnimeUpdate, Key: "myanimelist-21", Payload: []byte(`{"plex_id":1}`)}
+	require.NoError(t, repo.Store(ctx, first))
+	assert.NotZero(t, first.ID)
+
+	second := &domain.Job{Type: domain.JobTypeNotification, Payload: []byte(`{"event":"SUCCESS"}`)}
+	require.NoError(t, repo.Store(ctx, second))
+
+	jobs, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, first.ID, jobs[0].ID)
+	assert.Equal(t, "myanimelist-21", jobs[0].Key)
+	assert.JSONEq(t, `{"plex_id":1}`, string(jobs[0].Payload))
+	assert.Equal(t, domain.JobTypeNotification, jobs[1].Type)
+	assert.Empty(t, jobs[1].Key)
+
+	second.Attempts = 1
+	second.Error = "connection refused"
+	require.NoError(t, repo.Update(ctx, second))
+	require.NoError(t, repo.Delete(ctx, first.ID))
+
+	jobs, err = repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, second.ID, jobs[0].ID)
+	assert.Equal(t, 1, jobs[0].Attempts)
+	assert.Equal(t, "connection refused", jobs[0].Error)
+
+	// Failed jobs are kept but not run again
+	failedAt := time.Now().UTC()
+	second.FailedAt = &failedAt
+	require.NoError(t, repo.Update(ctx, second))
+
+	jobs, err = repo.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	var count int
+	require.NoError(t, db.handler.QueryRowContext(ctx, "SELECT COUNT(*) FROM job WHERE failed_at IS NOT NULL").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestSyncRuleRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewSyncRuleRepo(log, db)
+	ctx := context.Background()
+
+	offset := -12
+	library := &domain.SyncRule{Library: "Anime", Events: []domain.PlexEvent{domain.PlexScrobbleEvent}}
+	show := &domain.SyncRule{RatingKey: "1234", ForceMALID: 52991, EpisodeOffset: &offset, Provider: domain.TMDB}
+	require.NoError(t, repo.Store(ctx, library))
+	require.NoError(t, repo.Store(ctx, show))
+	assert.NotZero(t, library.ID)
+	assert.NotEqual(t, library.ID, show.ID)
+
+	rules, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, []domain.PlexEvent{domain.PlexScrobbleEvent}, rules[0].Events)
+	assert.Nil(t, rules[0].EpisodeOffset)
+	assert.Equal(t, 52991, rules[1].ForceMALID)
+	require.NotNil(t, rules[1].EpisodeOffset)
+	assert.Equal(t, -12, *rules[1].EpisodeOffset)
+	assert.Equal(t, domain.TMDB, rules[1].Provider)
+
+	library.Ignore = true
+	library.Events = nil
+	require.NoError(t, repo.Update(ctx, library))
+	assert.ErrorIs(t, repo.Update(ctx, &domain.SyncRule{ID: 999, Library: "Anime"}), sql.ErrNoRows)
+
+	require.NoError(t, repo.Delete(ctx, show.ID))
+
+	rules, err = repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.True(t, rules[0].Ignore)
+	assert.Empty(t, rules[0].Events)
+}
+
+func TestPlexServerRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewPlexServerRepo(log, db)
+	ctx := context.Background()
+
+	server := &domain.PlexServer{
+		Name:           "Friend",
+		ServerUUID:     "friend-uuid",
+		Host:           "friend.example.com",
+		Port:           32400,
+		TLS:            true,
+		Token:          []byte("encrypted"),
+		TokenIV:        []byte("iv"),
+		AnimeLibraries: []string{"5"},
+	}
+	require.NoError(t, repo.Store(ctx, server))
+	assert.NotZero(t, server.ID)
+
+	got, err := repo.GetByUUID(ctx, "friend-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "friend.example.com", got.Host)
+	assert.Equal(t, []byte("encrypted"), got.Token)
+	assert.Equal(t, []string{"5"}, got.AnimeLibraries)
+	assert.Empty(t, got.PlanToWatchLibs)
+
+	_, err = repo.GetByUUID(ctx, "unknown")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Updating without a token keeps the stored one
+	server.Token, server.TokenIV = nil, nil
+	server.Port = 32401
+	require.NoError(t, repo.Update(ctx, server))
+
+	servers, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, 32401, servers[0].Port)
+	assert.Equal(t, []byte("encrypted"), servers[0].Token)
+
+	require.NoError(t, repo.Delete(ctx, server.ID))
+	servers, err = repo.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+}
+
+func TestPlexGUIDCacheRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewPlexGUIDCacheRepo(log, db)
+	ctx := context.Background()
+
+	_, err := repo.Get(ctx, "server-uuid", "100")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	entry := &domain.PlexGUIDCache{
+		ServerUUID: "server-uuid",
+		RatingKey:  "100",
+		UpdatedAt:  time.Now().Add(-time.Hour).Truncate(time.Second),
+	}
+	entry.GUID.GUID = "plex://show/abc"
+	entry.GUID.GUIDS = append(entry.GUID.GUIDS, struct {
+		ID string `json:"id"`
+	}{ID: "tvdb://12345"})
+	require.NoError(t, repo.Store(ctx, entry))
+
+	got, err := repo.Get(ctx, "server-uuid", "100")
+	require.NoError(t, err)
+	assert.Equal(t, "plex://show/abc", got.GUID.GUID)
+	require.Len(t, got.GUID.GUIDS, 1)
+	assert.Equal(t, "tvdb://12345", got.GUID.GUIDS[0].ID)
+	assert.True(t, entry.UpdatedAt.Equal(got.UpdatedAt))
+
+	// Storing again replaces the entry
+	entry.GUID.GUID = "plex://show/def"
+	require.NoError(t, repo.Store(ctx, entry))
+	got, err = repo.Get(ctx, "server-uuid", "100")
+	require.NoError(t, err)
+	assert.Equal(t, "plex://show/def", got.GUID.GUID)
+
+	require.NoError(t, repo.Store(ctx, &domain.PlexGUIDCache{ServerUUID: "server-uuid", RatingKey: "200", UpdatedAt: time.Now()}))
+	require.NoError(t, repo.Delete(ctx, "server-uuid", "100"))
+	_, err = repo.Get(ctx, "server-uuid", "100")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, repo.DeleteAll(ctx))
+	_, err = repo.Get(ctx, "server-uuid", "200")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestIngestTokenRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewIngestTokenRepo(log, db)
+	ctx := context.Background()
+
+	token := &domain.IngestToken{Name: "Plex", Source: domain.IngestSourcePlex, Token: "secret", AllowedIPs: []string{"10.0.0.0/8"}}
+	require.NoError(t, repo.Store(ctx, token))
+	assert.NotZero(t, token.ID)
+
+	got, err := repo.GetByToken(ctx, domain.IngestSourcePlex, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "Plex", got.Name)
+	assert.Equal(t, []string{"10.0.0.0/8"}, got.AllowedIPs)
+	assert.Nil(t, got.LastSeen)
+
+	_, err = repo.GetByToken(ctx, domain.IngestSourceTautulli, "secret")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	seen := time.Now().Truncate(time.Second)
+	require.NoError(t, repo.UpdateLastSeen(ctx, token.ID, seen))
+	got, err = repo.GetByToken(ctx, domain.IngestSourcePlex, "secret")
+	require.NoError(t, err)
+	require.NotNil(t, got.LastSeen)
+	assert.True(t, seen.Equal(*got.LastSeen))
+
+	// Rotating replaces the token and resets last seen
+	require.NoError(t, repo.UpdateToken(ctx, token.ID, "rotated"))
+	_, err = repo.GetByToken(ctx, domain.IngestSourcePlex, "secret")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	token.Name = "Plex server"
+	token.AllowedIPs = nil
+	require.NoError(t, repo.Update(ctx, token))
+
+	tokens, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "Plex server", tokens[0].Name)
+	assert.Equal(t, "rotated", tokens[0].Token)
+	assert.Empty(t, tokens[0].AllowedIPs)
+	assert.Nil(t, tokens[0].LastSeen)
+
+	assert.ErrorIs(t, repo.UpdateToken(ctx, 99, "x"), sql.ErrNoRows)
+
+	require.NoError(t, repo.Delete(ctx, token.ID))
+	tokens, err = repo.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestNotificationRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewNotificationRepo(log, db)
+	ctx := context.Background()
+
+	n := &domain.Notification{
+		Name:     "Home Assistant",
+		Type:     domain.NotificationTypeWebhook,
+		Enabled:  true,
+		Events:   []string{string(domain.NotificationEventSuccess)},
+		Webhook:  "http://homeassistant.local/api/webhook/shinkro",
+		Token:    "secret",
+		Headers:  "X-Api-Key: abc",
+		Timeout:  10,
+		Retries:  3,
+		Password: "hunter2",
+		Devices:  "phone",
+		Targets:  "user@example.com",
+		Rooms:    "!room:example.org",
+		Port:     587,
+		TLSMode:  "STARTTLS",
+		From:     "shinkro@example.com",
+
+		TitleTemplate:  "{{.MediaName}}",
+		BodyTemplate:   "{{.EpisodesWatched}}/{{.EpisodesTotal}}",
+		DigestSchedule: "0 20 * * SUN",
+	}
+	require.NoError(t, repo.Store(ctx, n))
+	assert.NotZero(t, n.ID)
+
+	list, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "X-Api-Key: abc", list[0].Headers)
+	assert.Equal(t, 10, list[0].Timeout)
+	assert.Equal(t, 3, list[0].Retries)
+	assert.Equal(t, "hunter2", list[0].Password)
+	assert.Equal(t, "phone", list[0].Devices)
+	assert.Equal(t, "user@example.com", list[0].Targets)
+	assert.Equal(t, "!room:example.org", list[0].Rooms)
+	assert.Equal(t, 587, list[0].Port)
+	assert.Equal(t, "STARTTLS", list[0].TLSMode)
+	assert.Equal(t, "shinkro@example.com", list[0].From)
+	assert.Equal(t, "{{.MediaName}}", list[0].TitleTemplate)
+	assert.Equal(t, "{{.EpisodesWatched}}/{{.EpisodesTotal}}", list[0].BodyTemplate)
+	assert.Equal(t, "0 20 * * SUN", list[0].DigestSchedule)
+	assert.Zero(t, list[0].DigestCursor)
+
+	require.NoError(t, repo.UpdateDigestCursor(ctx, n.ID, 42))
+
+	n.Headers = ""
+	n.Retries = 0
+	n.TitleTemplate = ""
+	require.NoError(t, repo.Update(ctx, n))
+
+	found, count, err := repo.Find(ctx, domain.NotificationQueryParams{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.Len(t, found, 1)
+	assert.Empty(t, found[0].Headers)
+	assert.Equal(t, 10, found[0].Timeout)
+	assert.Zero(t, found[0].Retries)
+	assert.Equal(t, "hunter2", found[0].Password)
+	assert.Equal(t, "user@example.com", found[0].Targets)
+	assert.Equal(t, "shinkro@example.com", found[0].From)
+	assert.Equal(t, "!room:example.org", found[0].Rooms)
+	assert.Empty(t, found[0].TitleTemplate)
+	assert.Equal(t, "{{.EpisodesWatched}}/{{.EpisodesTotal}}", found[0].BodyTemplate)
+	assert.Equal(t, "0 20 * * SUN", found[0].DigestSchedule)
+	assert.Equal(t, int64(42), found[0].DigestCursor)
+}
+
+func TestNotificationDeliveryRepo_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	notificationRepo := NewNotificationRepo(log, db)
+	repo := NewNotificationDeliveryRepo(log, db)
+	ctx := context.Background()
+
+	n := &domain.Notification{Name: "Discord", Type: domain.NotificationTypeDiscord, Enabled: true, Events: []string{string(domain.NotificationEventSuccess)}}
+	require.NoError(t, notificationRepo.Store(ctx, n))
+
+	for i := 0; i < notificationDeliveryRetention+5; i++ {
+		require.NoError(t, repo.Store(ctx, &domain.NotificationDelivery{
+			NotificationID: n.ID,
+			Event:          domain.NotificationEventSuccess,
+			Status:         domain.NotificationDeliveryStatusSuccess,
+			Attempt:        1,
+		}))
+	}
+
+	failed := &domain.NotificationDelivery{
+		NotificationID: n.ID,
+		Event:          domain.NotificationEventAnimeUpdateError,
+		Status:         domain.NotificationDeliveryStatusFailed,
+		Attempt:        3,
+		HTTPStatus:     502,
+		Error:          "unexpected status: 502 body: bad gateway",
+	}
+	require.NoError(t, repo.Store(ctx, failed))
+	assert.NotZero(t, failed.ID)
+
+	require.NoError(t, repo.Store(ctx, &domain.NotificationDelivery{NotificationID: n.ID + 1, Event: domain.NotificationEventSuccess, Status: domain.NotificationDeliveryStatusSuccess, Attempt: 1}))
+
+	deliveries, err := repo.FindByNotificationID(ctx, n.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, notificationDeliveryRetention)
+	assert.Equal(t, failed.ID, deliveries[0].ID)
+	assert.Equal(t, domain.NotificationDeliveryStatusFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempt)
+	assert.Equal(t, 502, deliveries[0].HTTPStatus)
+	assert.Equal(t, "unexpected status: 502 body: bad gateway", deliveries[0].Error)
+	assert.False(t, deliveries[0].CreatedAt.IsZero())
+
+	require.NoError(t, notificationRepo.Delete(ctx, n.ID))
+
+	deliveries, err = repo.FindByNotificationID(ctx, n.ID)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	deliveries, err = repo.FindByNotificationID(ctx, n.ID+1)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+}
+
+func TestPlexSettingsRepo_Update(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	log := zerolog.Nop()
+	repo := NewPlexSettingsRepo(log, db)
+	ctx := context.Background()
+
+	_, err := repo.Store(ctx, domain.PlexSettings{Host: "localhost", Port: 32400, PlexUser: "TestUser", AnimeLibraries: []string{"Anime", "Anime Movies"}})
+	require.NoError(t, err)
+
+	ps, err := repo.Get(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ps.PlanToWatchLibs)
+	assert.Empty(t, ps.DryRunLibs)
+
+	ps.PlanToWatchLibs = []string{"Anime"}
+	ps.CompletionThreshold = 80
+	ps.CompleteAtCredits = true
+	ps.DryRunLibs = []string{"Anime Movies"}
+	ps.ServerUUID = "server-uuid"
+	_, err = repo.Update(ctx, *ps)
+	require.NoError(t, err)
+
+	ps, err = repo.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Anime"}, ps.PlanToWatchLibs)
+	assert.Equal(t, 80, ps.CompletionThreshold)
+	assert.True(t, ps.CompleteAtCredits)
+	assert.Equal(t, []string{"Anime Movies"}, ps.DryRunLibs)
+	assert.Equal(t, "server-uuid", ps.ServerUUID)
+}
+
 func TestAnimeUpdateRepo_ForeignKeyConstraint(t *testing.T) {
 	db := setupTestDB(t)
 	defer cleanupTestDB(t, db)
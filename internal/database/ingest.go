@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type IngestTokenRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewIngestTokenRepo(log zerolog.Logger, db *DB) domain.IngestTokenRepo {
+	return &IngestTokenRepo{
+		log: log.With().Str("repo", "ingest_token").Logger(),
+		db:  db,
+	}
+}
+
+func (repo *IngestTokenRepo) Store(ctx context.Context, token *domain.IngestToken) error {
+	queryBuilder := repo.db.squirrel.
+		Insert("ingest_token").
+		Columns("name", "source", "token", "allowed_ips").
+		Values(token.Name, token.Source, token.Token, pq.Array(libraries(token.AllowedIPs))).
+		Suffix("RETURNING id, created_at").
+		RunWith(repo.db.handler)
+
+	if err := queryBuilder.QueryRowContext(ctx).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *IngestTokenRepo) Update(ctx context.Context, token *domain.IngestToken) error {
+	queryBuilder := repo.db.squirrel.
+		Update("ingest_token").
+		Set("name", token.Name).
+		Set("allowed_ips", pq.Array(libraries(token.AllowedIPs))).
+		Where(sq.Eq{"id": token.ID})
+
+	return repo.update(ctx, "ingestToken.update", queryBuilder)
+}
+
+func (repo *IngestTokenRepo) UpdateToken(ctx context.Context, id int, token string) error {
+	queryBuilder := repo.db.squirrel.
+		Update("ingest_token").
+		Set("token", token).
+		Set("last_seen_at", nil).
+		Where(sq.Eq{"id": id})
+
+	return repo.update(ctx, "ingestToken.updateToken", queryBuilder)
+}
+
+func (repo *IngestTokenRepo) UpdateLastSeen(ctx context.Context, id int, lastSeen time.Time) error {
+	queryBuilder := repo.db.squirrel.
+		Update("ingest_token").
+		Set("last_seen_at", lastSeen.UTC().Format(time.RFC3339)).
+		Where(sq.Eq{"id": id})
+
+	return repo.update(ctx, "ingestToken.updateLastSeen", queryBuilder)
+}
+
+func (repo *IngestTokenRepo) update(ctx context.Context, name string, queryBuilder sq.UpdateBuilder) error {
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", name).Msgf("query: '%s', args: '%v'", query, args)
+	result, err := repo.db.handler.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (repo *IngestTokenRepo) Delete(ctx context.Context, id int) error {
+	queryBuilder := repo.db.squirrel.
+		Delete("ingest_token").
+		Where(sq.Eq{"id": id})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "ingestToken.delete").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *IngestTokenRepo) FindAll(ctx context.Context) ([]*domain.IngestToken, error) {
+	queryBuilder := repo.selectTokens().OrderBy("id ASC")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "ingestToken.findAll").Msgf("query: '%s', args: '%v'", query, args)
+	rows, err := repo.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	defer rows.Close()
+
+	tokens := make([]*domain.IngestToken, 0)
+	for rows.Next() {
+		token, err := scanIngestToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findAll")
+	}
+
+	return tokens, nil
+}
+
+func (repo *IngestTokenRepo) GetByToken(ctx context.Context, source domain.IngestSource, token string) (*domain.IngestToken, error) {
+	queryBuilder := repo.selectTokens().Where(sq.Eq{"source": source, "token": token})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "ingestToken.getByToken").Msgf("query: '%s', args: '%v'", query, args)
+	row := repo.db.handler.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	return scanIngestToken(row)
+}
+
+func (repo *IngestTokenRepo) selectTokens() sq.SelectBuilder {
+	return repo.db.squirrel.
+		Select("id", "name", "source", "token", "allowed_ips", "last_seen_at", "created_at").
+		From("ingest_token")
+}
+
+func scanIngestToken(row rowScanner) (*domain.IngestToken, error) {
+	var token domain.IngestToken
+	var lastSeen sql.NullTime
+	if err := row.Scan(&token.ID, &token.Name, &token.Source, &token.Token, pq.Array(&token.AllowedIPs), &lastSeen, &token.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "error scanning row")
+	}
+
+	if lastSeen.Valid {
+		token.LastSeen = &lastSeen.Time
+	}
+
+	return &token, nil
+}
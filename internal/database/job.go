@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type JobRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewJobRepo(log zerolog.Logger, db *DB) domain.JobRepo {
+	return &JobRepo{
+		log: log.With().Str("repo", "job").Logger(),
+		db:  db,
+	}
+}
+
+func (repo *JobRepo) Store(ctx context.Context, job *domain.Job) error {
+	job.CreatedAt = time.Now().UTC()
+	queryBuilder := repo.db.squirrel.
+		Insert("job").
+		Columns("type", "job_key", "payload", "created_at").
+		Values(job.Type, job.Key, string(job.Payload), job.CreatedAt).
+		Suffix("RETURNING id").
+		RunWith(repo.db.handler)
+
+	if err := queryBuilder.QueryRowContext(ctx).Scan(&job.ID); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+// Update stores the attempts of a failed job.
+func (repo *JobRepo) Update(ctx context.Context, job *domain.Job) error {
+	queryBuilder := repo.db.squirrel.
+		Update("job").
+		Set("attempts", job.Attempts).
+		Set("error", job.Error).
+		Set("failed_at", job.FailedAt).
+		Where(sq.Eq{"id": job.ID})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "job.update").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *JobRepo) Delete(ctx context.Context, id int64) error {
+	queryBuilder := repo.db.squirrel.
+		Delete("job").
+		Where(sq.Eq{"id": id})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "job.delete").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+// FindAll returns the pending jobs in the order they were queued, failed jobs are left out.
+func (repo *JobRepo) FindAll(ctx context.Context) ([]*domain.Job, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("id", "type", "job_key", "payload", "attempts", "error", "created_at").
+		From("job").
+		Where(sq.Eq{"failed_at": nil}).
+		OrderBy("id ASC")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "job.findAll").Msgf("query: '%s', args: '%v'", query, args)
+	rows, err := repo.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	defer rows.Close()
+
+	jobs := make([]*domain.Job, 0)
+	for rows.Next() {
+		var job domain.Job
+		var payload string
+		if err := rows.Scan(&job.ID, &job.Type, &job.Key, &payload, &job.Attempts, &job.Error, &job.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "error scanning row")
+		}
+
+		job.Payload = []byte(payload)
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findAll")
+	}
+
+	return jobs, nil
+}
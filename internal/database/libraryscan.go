@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type LibraryScanRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewLibraryScanRepo(log zerolog.Logger, db *DB) domain.LibraryScanRepo {
+	return &LibraryScanRepo{
+		log: log.With().Str("repo", "libraryscan").Logger(),
+		db:  db,
+	}
+}
+
+// GetScannedAt returns the zero time for a library that was never scanned.
+func (repo *LibraryScanRepo) GetScannedAt(ctx context.Context, sectionKey string) (time.Time, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("scanned_at").
+		From("library_scan").
+		Where(sq.Eq{"section_key": sectionKey})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "libraryscan.getScannedAt").Msgf("query: '%s', args: '%v'", query, args)
+
+	var scannedAt time.Time
+	if err := repo.db.handler.QueryRowContext(ctx, query, args...).Scan(&scannedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, errors.Wrap(err, "error scanning row")
+	}
+
+	return scannedAt, nil
+}
+
+func (repo *LibraryScanRepo) StoreScannedAt(ctx context.Context, sectionKey string, scannedAt time.Time) error {
+	queryBuilder := repo.db.squirrel.
+		Insert("library_scan").
+		Columns("section_key", "scanned_at").
+		Values(sectionKey, scannedAt.UTC()).
+		Suffix("ON CONFLICT (section_key) DO UPDATE SET scanned_at = excluded.scanned_at")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
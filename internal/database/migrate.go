@@ -60,7 +60,9 @@ CREATE TABLE anime_update
 			ON DELETE CASCADE,
 	status          TEXT,
 	error_type      TEXT,
-	error_message   TEXT
+	error_message   TEXT,
+	previous_status TEXT,
+	revert_of       INTEGER
 );
 
 CREATE TABLE plex_payload
@@ -96,11 +98,56 @@ CREATE TABLE plex_settings
 	token_iv					BLOB,
 	username					TEXT NOT NULL,
 	anime_libraries             TEXT []   DEFAULT '{}' NOT NULL,
+	plan_to_watch_libraries     TEXT []   DEFAULT '{}' NOT NULL,
 	plex_client_enabled			BOOLEAN DEFAULT false NOT NULL,
 	client_id				    TEXT,
+	completion_threshold        INTEGER DEFAULT 0 NOT NULL,
+	complete_at_credits         BOOLEAN DEFAULT false NOT NULL,
+	dry_run_libraries           TEXT []   DEFAULT '{}' NOT NULL,
+	server_uuid                 TEXT DEFAULT '' NOT NULL,
 	time_stamp                  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE plex_server
+(
+	id                      INTEGER PRIMARY KEY,
+	name                    TEXT NOT NULL DEFAULT '',
+	server_uuid             TEXT NOT NULL UNIQUE,
+	host                    TEXT NOT NULL,
+	port                    INTEGER NOT NULL,
+	tls                     BOOLEAN DEFAULT false NOT NULL,
+	tls_skip_verify         BOOLEAN DEFAULT false NOT NULL,
+	token                   BLOB,
+	token_iv                BLOB,
+	client_id               TEXT NOT NULL DEFAULT '',
+	anime_libraries         TEXT []   DEFAULT '{}' NOT NULL,
+	plan_to_watch_libraries TEXT []   DEFAULT '{}' NOT NULL,
+	dry_run_libraries       TEXT []   DEFAULT '{}' NOT NULL,
+	created_at              TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at              TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE plex_guid_cache
+(
+	server_uuid TEXT NOT NULL DEFAULT '',
+	rating_key  TEXT NOT NULL,
+	guid_string TEXT NOT NULL DEFAULT '',
+	guids       TEXT NOT NULL DEFAULT '[]',
+	updated_at  TIMESTAMP NOT NULL,
+	PRIMARY KEY (server_uuid, rating_key)
+);
+
+CREATE TABLE ingest_token
+(
+	id           INTEGER PRIMARY KEY,
+	name         TEXT NOT NULL DEFAULT '',
+	source       TEXT NOT NULL,
+	token        TEXT NOT NULL UNIQUE,
+	allowed_ips  TEXT []   DEFAULT '{}' NOT NULL,
+	last_seen_at TIMESTAMP,
+	created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE mapping_settings
 (
 	id						        INTEGER PRIMARY KEY,
@@ -132,10 +179,92 @@ CREATE TABLE notification
 	devices    TEXT,
 	topic      TEXT,
 	priority   INTEGER DEFAULT 0,
+	headers    TEXT,
+	timeout    INTEGER DEFAULT 0 NOT NULL,
+	retries    INTEGER DEFAULT 0 NOT NULL,
+	port       INTEGER DEFAULT 0 NOT NULL,
+	tls_mode   TEXT,
+	from_addr  TEXT,
+	title_template TEXT,
+	body_template TEXT,
+	digest_schedule TEXT,
+	digest_cursor INTEGER DEFAULT 0 NOT NULL,
 	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE notification_delivery
+(
+	id              INTEGER PRIMARY KEY,
+	notification_id INTEGER NOT NULL,
+	event           TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	attempt         INTEGER DEFAULT 1 NOT NULL,
+	http_status     INTEGER DEFAULT 0 NOT NULL,
+	error           TEXT NOT NULL DEFAULT '',
+	created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX notification_delivery_notification_id_index
+	ON notification_delivery (notification_id);
+
+CREATE TABLE reconcile
+(
+	id              INTEGER PRIMARY KEY,
+	mal_id          INTEGER NOT NULL UNIQUE,
+	title           TEXT,
+	plex_rating_key TEXT,
+	source_db       TEXT NOT NULL DEFAULT '',
+	source_id       INTEGER NOT NULL DEFAULT 0,
+	season_num      INTEGER NOT NULL DEFAULT 0,
+	plex_watched    INTEGER NOT NULL DEFAULT 0,
+	mal_watched     INTEGER NOT NULL DEFAULT 0,
+	mal_status      TEXT NOT NULL DEFAULT '',
+	status          TEXT NOT NULL DEFAULT '',
+	message         TEXT,
+	ignored         BOOLEAN DEFAULT false NOT NULL,
+	updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE reverse_sync
+(
+	mal_id           INTEGER PRIMARY KEY,
+	episodes_watched INTEGER NOT NULL DEFAULT 0,
+	updated_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE library_scan
+(
+	section_key TEXT PRIMARY KEY,
+	scanned_at  TIMESTAMP NOT NULL
+);
+
+CREATE TABLE job
+(
+	id         INTEGER PRIMARY KEY,
+	type       TEXT NOT NULL,
+	job_key    TEXT NOT NULL DEFAULT '',
+	payload    TEXT NOT NULL,
+	attempts   INTEGER DEFAULT 0 NOT NULL,
+	error      TEXT NOT NULL DEFAULT '',
+	failed_at  TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE sync_rule
+(
+	id             INTEGER PRIMARY KEY,
+	library        TEXT NOT NULL DEFAULT '',
+	rating_key     TEXT NOT NULL DEFAULT '',
+	mal_id         INTEGER NOT NULL DEFAULT 0,
+	events         TEXT []   DEFAULT '{}' NOT NULL,
+	ignored        BOOLEAN DEFAULT false NOT NULL,
+	force_mal_id   INTEGER NOT NULL DEFAULT 0,
+	episode_offset INTEGER,
+	provider       TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
 `
 
 var migrations = []string{
@@ -172,4 +301,134 @@ ALTER TABLE anime_update ADD COLUMN error_message TEXT;
 
 -- Set status to SUCCESS for existing anime_update records
 UPDATE anime_update SET status = 'SUCCESS' WHERE status IS NULL;`,
+	`CREATE TABLE reconcile
+(
+	id              INTEGER PRIMARY KEY,
+	mal_id          INTEGER NOT NULL UNIQUE,
+	title           TEXT,
+	plex_rating_key TEXT,
+	source_db       TEXT NOT NULL DEFAULT '',
+	source_id       INTEGER NOT NULL DEFAULT 0,
+	season_num      INTEGER NOT NULL DEFAULT 0,
+	plex_watched    INTEGER NOT NULL DEFAULT 0,
+	mal_watched     INTEGER NOT NULL DEFAULT 0,
+	mal_status      TEXT NOT NULL DEFAULT '',
+	status          TEXT NOT NULL DEFAULT '',
+	message         TEXT,
+	ignored         BOOLEAN DEFAULT false NOT NULL,
+	updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	`CREATE TABLE reverse_sync
+(
+	mal_id           INTEGER PRIMARY KEY,
+	episodes_watched INTEGER NOT NULL DEFAULT 0,
+	updated_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	`ALTER TABLE plex_settings ADD COLUMN plan_to_watch_libraries TEXT [] DEFAULT '{}' NOT NULL;`,
+	`CREATE TABLE library_scan
+(
+	section_key TEXT PRIMARY KEY,
+	scanned_at  TIMESTAMP NOT NULL
+);
+`,
+	`ALTER TABLE plex_settings ADD COLUMN completion_threshold INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE plex_settings ADD COLUMN complete_at_credits BOOLEAN DEFAULT false NOT NULL;`,
+	`CREATE TABLE job
+(
+	id         INTEGER PRIMARY KEY,
+	type       TEXT NOT NULL,
+	job_key    TEXT NOT NULL DEFAULT '',
+	payload    TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	`ALTER TABLE anime_update ADD COLUMN previous_status TEXT;
+ALTER TABLE anime_update ADD COLUMN revert_of INTEGER;`,
+	`ALTER TABLE plex_settings ADD COLUMN dry_run_libraries TEXT [] DEFAULT '{}' NOT NULL;`,
+	`CREATE TABLE sync_rule
+(
+	id             INTEGER PRIMARY KEY,
+	library        TEXT NOT NULL DEFAULT '',
+	rating_key     TEXT NOT NULL DEFAULT '',
+	mal_id         INTEGER NOT NULL DEFAULT 0,
+	events         TEXT []   DEFAULT '{}' NOT NULL,
+	ignored        BOOLEAN DEFAULT false NOT NULL,
+	force_mal_id   INTEGER NOT NULL DEFAULT 0,
+	episode_offset INTEGER,
+	provider       TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	`ALTER TABLE plex_settings ADD COLUMN server_uuid TEXT DEFAULT '' NOT NULL;`,
+	`CREATE TABLE plex_server
+(
+	id                      INTEGER PRIMARY KEY,
+	name                    TEXT NOT NULL DEFAULT '',
+	server_uuid             TEXT NOT NULL UNIQUE,
+	host                    TEXT NOT NULL,
+	port                    INTEGER NOT NULL,
+	tls                     BOOLEAN DEFAULT false NOT NULL,
+	tls_skip_verify         BOOLEAN DEFAULT false NOT NULL,
+	token                   BLOB,
+	token_iv                BLOB,
+	client_id               TEXT NOT NULL DEFAULT '',
+	anime_libraries         TEXT []   DEFAULT '{}' NOT NULL,
+	plan_to_watch_libraries TEXT []   DEFAULT '{}' NOT NULL,
+	dry_run_libraries       TEXT []   DEFAULT '{}' NOT NULL,
+	created_at              TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at              TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	`CREATE TABLE plex_guid_cache
+(
+	server_uuid TEXT NOT NULL DEFAULT '',
+	rating_key  TEXT NOT NULL,
+	guid_string TEXT NOT NULL DEFAULT '',
+	guids       TEXT NOT NULL DEFAULT '[]',
+	updated_at  TIMESTAMP NOT NULL,
+	PRIMARY KEY (server_uuid, rating_key)
+);
+`,
+	`CREATE TABLE ingest_token
+(
+	id           INTEGER PRIMARY KEY,
+	name         TEXT NOT NULL DEFAULT '',
+	source       TEXT NOT NULL,
+	token        TEXT NOT NULL UNIQUE,
+	allowed_ips  TEXT []   DEFAULT '{}' NOT NULL,
+	last_seen_at TIMESTAMP,
+	created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	`ALTER TABLE notification ADD COLUMN headers TEXT;
+ALTER TABLE notification ADD COLUMN timeout INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE notification ADD COLUMN retries INTEGER DEFAULT 0 NOT NULL;`,
+	`ALTER TABLE notification ADD COLUMN port INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE notification ADD COLUMN tls_mode TEXT;
+ALTER TABLE notification ADD COLUMN from_addr TEXT;`,
+	`ALTER TABLE notification ADD COLUMN title_template TEXT;
+ALTER TABLE notification ADD COLUMN body_template TEXT;`,
+	`CREATE TABLE notification_delivery
+(
+	id              INTEGER PRIMARY KEY,
+	notification_id INTEGER NOT NULL,
+	event           TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	attempt         INTEGER DEFAULT 1 NOT NULL,
+	http_status     INTEGER DEFAULT 0 NOT NULL,
+	error           TEXT NOT NULL DEFAULT '',
+	created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX notification_delivery_notification_id_index
+	ON notification_delivery (notification_id);
+`,
+	`ALTER TABLE notification ADD COLUMN digest_schedule TEXT;
+ALTER TABLE notification ADD COLUMN digest_cursor INTEGER DEFAULT 0 NOT NULL;`,
+	`ALTER TABLE job ADD COLUMN attempts INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE job ADD COLUMN error TEXT NOT NULL DEFAULT '';
+ALTER TABLE job ADD COLUMN failed_at TIMESTAMP;`,
 }
@@ -24,7 +24,7 @@ func NewNotificationRepo(log zerolog.Logger, db *DB) domain.NotificationRepo {
 
 func (r *NotificationRepo) Find(ctx context.Context, params domain.NotificationQueryParams) ([]domain.Notification, int, error) {
 	queryBuilder := r.db.squirrel.
-		Select("id", "name", "type", "enabled", "events", "webhook", "token", "api_key", "channel", "priority", "topic", "host", "username", "created_at", "updated_at", "COUNT(*) OVER() AS total_count").
+		Select("id", "name", "type", "enabled", "events", "webhook", "token", "api_key", "channel", "priority", "topic", "host", "username", "password", "rooms", "targets", "devices", "headers", "timeout", "retries", "port", "tls_mode", "from_addr", "title_template", "body_template", "digest_schedule", "digest_cursor", "created_at", "updated_at", "COUNT(*) OVER() AS total_count").
 		From("notification").
 		OrderBy("name")
 
@@ -45,9 +45,9 @@ func (r *NotificationRepo) Find(ctx context.Context, params domain.NotificationQ
 	for rows.Next() {
 		var n domain.Notification
 
-		var webhook, token, apiKey, channel, host, topic, username sql.NullString
+		var webhook, token, apiKey, channel, host, topic, username, password, rooms, targets, devices, headers, tlsMode, from, titleTemplate, bodyTemplate, digestSchedule sql.NullString
 
-		if err := rows.Scan(&n.ID, &n.Name, &n.Type, &n.Enabled, pq.Array(&n.Events), &webhook, &token, &apiKey, &channel, &n.Priority, &topic, &host, &username, &n.CreatedAt, &n.UpdatedAt, &totalCount); err != nil {
+		if err := rows.Scan(&n.ID, &n.Name, &n.Type, &n.Enabled, pq.Array(&n.Events), &webhook, &token, &apiKey, &channel, &n.Priority, &topic, &host, &username, &password, &rooms, &targets, &devices, &headers, &n.Timeout, &n.Retries, &n.Port, &tlsMode, &from, &titleTemplate, &bodyTemplate, &digestSchedule, &n.DigestCursor, &n.CreatedAt, &n.UpdatedAt, &totalCount); err != nil {
 			return nil, 0, errors.Wrap(err, "error scanning row")
 		}
 
@@ -58,6 +58,16 @@ func (r *NotificationRepo) Find(ctx context.Context, params domain.NotificationQ
 		n.Topic = topic.String
 		n.Host = host.String
 		n.Username = username.String
+		n.Password = password.String
+		n.Rooms = rooms.String
+		n.Targets = targets.String
+		n.Devices = devices.String
+		n.Headers = headers.String
+		n.TLSMode = tlsMode.String
+		n.From = from.String
+		n.TitleTemplate = titleTemplate.String
+		n.BodyTemplate = bodyTemplate.String
+		n.DigestSchedule = digestSchedule.String
 
 		notifications = append(notifications, n)
 	}
@@ -69,7 +79,7 @@ func (r *NotificationRepo) Find(ctx context.Context, params domain.NotificationQ
 }
 
 func (r *NotificationRepo) List(ctx context.Context) ([]domain.Notification, error) {
-	rows, err := r.db.handler.QueryContext(ctx, "SELECT id, name, type, enabled, events, token, api_key,  webhook, title, icon, host, username, password, channel, targets, devices, priority, topic, created_at, updated_at FROM notification ORDER BY name ASC")
+	rows, err := r.db.handler.QueryContext(ctx, "SELECT id, name, type, enabled, events, token, api_key,  webhook, title, icon, host, username, password, channel, rooms, targets, devices, priority, topic, headers, timeout, retries, port, tls_mode, from_addr, title_template, body_template, digest_schedule, digest_cursor, created_at, updated_at FROM notification ORDER BY name ASC")
 	if err != nil {
 		return nil, errors.Wrap(err, "error executing query")
 	}
@@ -81,8 +91,8 @@ func (r *NotificationRepo) List(ctx context.Context) ([]domain.Notification, err
 		var n domain.Notification
 		//var eventsSlice []string
 
-		var token, apiKey, webhook, title, icon, host, username, password, channel, targets, devices, topic sql.NullString
-		if err := rows.Scan(&n.ID, &n.Name, &n.Type, &n.Enabled, pq.Array(&n.Events), &token, &apiKey, &webhook, &title, &icon, &host, &username, &password, &channel, &targets, &devices, &n.Priority, &topic, &n.CreatedAt, &n.UpdatedAt); err != nil {
+		var token, apiKey, webhook, title, icon, host, username, password, channel, rooms, targets, devices, topic, headers, tlsMode, from, titleTemplate, bodyTemplate, digestSchedule sql.NullString
+		if err := rows.Scan(&n.ID, &n.Name, &n.Type, &n.Enabled, pq.Array(&n.Events), &token, &apiKey, &webhook, &title, &icon, &host, &username, &password, &channel, &rooms, &targets, &devices, &n.Priority, &topic, &headers, &n.Timeout, &n.Retries, &n.Port, &tlsMode, &from, &titleTemplate, &bodyTemplate, &digestSchedule, &n.DigestCursor, &n.CreatedAt, &n.UpdatedAt); err != nil {
 			return nil, errors.Wrap(err, "error scanning row")
 		}
 
@@ -96,9 +106,16 @@ func (r *NotificationRepo) List(ctx context.Context) ([]domain.Notification, err
 		n.Username = username.String
 		n.Password = password.String
 		n.Channel = channel.String
+		n.Rooms = rooms.String
 		n.Targets = targets.String
 		n.Devices = devices.String
 		n.Topic = topic.String
+		n.Headers = headers.String
+		n.TLSMode = tlsMode.String
+		n.From = from.String
+		n.TitleTemplate = titleTemplate.String
+		n.BodyTemplate = bodyTemplate.String
+		n.DigestSchedule = digestSchedule.String
 
 		notifications = append(notifications, n)
 	}
@@ -125,6 +142,19 @@ func (r *NotificationRepo) Store(ctx context.Context, notification *domain.Notif
 			"topic",
 			"host",
 			"username",
+			"password",
+			"devices",
+			"headers",
+			"timeout",
+			"retries",
+			"rooms",
+			"targets",
+			"port",
+			"tls_mode",
+			"from_addr",
+			"title_template",
+			"body_template",
+			"digest_schedule",
 		).
 		Values(
 			notification.Name,
@@ -139,6 +169,19 @@ func (r *NotificationRepo) Store(ctx context.Context, notification *domain.Notif
 			toNullString(notification.Topic),
 			toNullString(notification.Host),
 			toNullString(notification.Username),
+			toNullString(notification.Password),
+			toNullString(notification.Devices),
+			toNullString(notification.Headers),
+			notification.Timeout,
+			notification.Retries,
+			toNullString(notification.Rooms),
+			toNullString(notification.Targets),
+			notification.Port,
+			toNullString(notification.TLSMode),
+			toNullString(notification.From),
+			toNullString(notification.TitleTemplate),
+			toNullString(notification.BodyTemplate),
+			toNullString(notification.DigestSchedule),
 		).
 		Suffix("RETURNING id").RunWith(r.db.handler)
 
@@ -166,6 +209,19 @@ func (r *NotificationRepo) Update(ctx context.Context, notification *domain.Noti
 		Set("topic", toNullString(notification.Topic)).
 		Set("host", toNullString(notification.Host)).
 		Set("username", toNullString(notification.Username)).
+		Set("password", toNullString(notification.Password)).
+		Set("devices", toNullString(notification.Devices)).
+		Set("headers", toNullString(notification.Headers)).
+		Set("timeout", notification.Timeout).
+		Set("retries", notification.Retries).
+		Set("rooms", toNullString(notification.Rooms)).
+		Set("targets", toNullString(notification.Targets)).
+		Set("port", notification.Port).
+		Set("tls_mode", toNullString(notification.TLSMode)).
+		Set("from_addr", toNullString(notification.From)).
+		Set("title_template", toNullString(notification.TitleTemplate)).
+		Set("body_template", toNullString(notification.BodyTemplate)).
+		Set("digest_schedule", toNullString(notification.DigestSchedule)).
 		Set("updated_at", sq.Expr("CURRENT_TIMESTAMP")).
 		Where(sq.Eq{"id": notification.ID})
 
@@ -183,6 +239,25 @@ func (r *NotificationRepo) Update(ctx context.Context, notification *domain.Noti
 	return nil
 }
 
+// UpdateDigestCursor stores the id of the last anime update sent in a digest, it is not changed by Update.
+func (r *NotificationRepo) UpdateDigestCursor(ctx context.Context, notificationID int, cursor int64) error {
+	queryBuilder := r.db.squirrel.
+		Update("notification").
+		Set("digest_cursor", cursor).
+		Where(sq.Eq{"id": notificationID})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	if _, err = r.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
 func (r *NotificationRepo) Delete(ctx context.Context, notificationID int) error {
 	queryBuilder := r.db.squirrel.
 		Delete("notification").
@@ -197,6 +272,18 @@ func (r *NotificationRepo) Delete(ctx context.Context, notificationID int) error
 		return errors.Wrap(err, "error executing query")
 	}
 
+	deliveryQuery, deliveryArgs, err := r.db.squirrel.
+		Delete("notification_delivery").
+		Where(sq.Eq{"notification_id": notificationID}).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	if _, err = r.db.handler.ExecContext(ctx, deliveryQuery, deliveryArgs...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
 	r.log.Debug().Msgf("notification.delete: successfully deleted: %v", notificationID)
 
 	return nil
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+// notificationDeliveryRetention is the number of deliveries kept per notification.
+const notificationDeliveryRetention = 100
+
+type NotificationDeliveryRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewNotificationDeliveryRepo(log zerolog.Logger, db *DB) domain.NotificationDeliveryRepo {
+	return &NotificationDeliveryRepo{
+		log: log.With().Str("repo", "notification_delivery").Logger(),
+		db:  db,
+	}
+}
+
+// Store saves the delivery and removes the oldest deliveries of the notification beyond the retention.
+func (r *NotificationDeliveryRepo) Store(ctx context.Context, delivery *domain.NotificationDelivery) error {
+	delivery.CreatedAt = time.Now().UTC()
+	queryBuilder := r.db.squirrel.
+		Insert("notification_delivery").
+		Columns("notification_id", "event", "status", "attempt", "http_status", "error", "created_at").
+		Values(delivery.NotificationID, delivery.Event, delivery.Status, delivery.Attempt, delivery.HTTPStatus, delivery.Error, delivery.CreatedAt).
+		Suffix("RETURNING id").
+		RunWith(r.db.handler)
+
+	if err := queryBuilder.QueryRowContext(ctx).Scan(&delivery.ID); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	// built with ? placeholders, the outer query numbers them
+	keep := sq.
+		Select("id").
+		From("notification_delivery").
+		Where(sq.Eq{"notification_id": delivery.NotificationID}).
+		OrderBy("id DESC").
+		Limit(notificationDeliveryRetention)
+
+	keepQuery, keepArgs, err := keep.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	deleteBuilder := r.db.squirrel.
+		Delete("notification_delivery").
+		Where(sq.Eq{"notification_id": delivery.NotificationID}).
+		Where(sq.Expr("id NOT IN ("+keepQuery+")", keepArgs...))
+
+	query, args, err := deleteBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	r.log.Trace().Str("database", "notification_delivery.prune").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := r.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+// FindByNotificationID returns the deliveries of a notification, newest first.
+func (r *NotificationDeliveryRepo) FindByNotificationID(ctx context.Context, notificationID int) ([]domain.NotificationDelivery, error) {
+	queryBuilder := r.db.squirrel.
+		Select("id", "notification_id", "event", "status", "attempt", "http_status", "error", "created_at").
+		From("notification_delivery").
+		Where(sq.Eq{"notification_id": notificationID}).
+		OrderBy("id DESC")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	r.log.Trace().Str("database", "notification_delivery.findByNotificationID").Msgf("query: '%s', args: '%v'", query, args)
+	rows, err := r.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]domain.NotificationDelivery, 0)
+	for rows.Next() {
+		var d domain.NotificationDelivery
+		if err := rows.Scan(&d.ID, &d.NotificationID, &d.Event, &d.Status, &d.Attempt, &d.HTTPStatus, &d.Error, &d.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "error scanning row")
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findByNotificationID")
+	}
+
+	return deliveries, nil
+}
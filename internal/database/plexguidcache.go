@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type PlexGUIDCacheRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewPlexGUIDCacheRepo(log zerolog.Logger, db *DB) domain.PlexGUIDCacheRepo {
+	return &PlexGUIDCacheRepo{
+		log: log.With().Str("repo", "plex_guid_cache").Logger(),
+		db:  db,
+	}
+}
+
+func (repo *PlexGUIDCacheRepo) Get(ctx context.Context, serverUUID, ratingKey string) (*domain.PlexGUIDCache, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("server_uuid", "rating_key", "guid_string", "guids", "updated_at").
+		From("plex_guid_cache").
+		Where(sq.Eq{"server_uuid": serverUUID, "rating_key": ratingKey})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "plexGUIDCache.get").Msgf("query: '%s', args: '%v'", query, args)
+	row := repo.db.handler.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	var c domain.PlexGUIDCache
+	var guids string
+	if err := row.Scan(&c.ServerUUID, &c.RatingKey, &c.GUID.GUID, &guids, &c.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "error scanning row")
+	}
+
+	if err := json.Unmarshal([]byte(guids), &c.GUID.GUIDS); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling guids")
+	}
+
+	return &c, nil
+}
+
+func (repo *PlexGUIDCacheRepo) Store(ctx context.Context, c *domain.PlexGUIDCache) error {
+	guids, err := json.Marshal(c.GUID.GUIDS)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling guids")
+	}
+
+	queryBuilder := repo.db.squirrel.
+		Replace("plex_guid_cache").
+		Columns("server_uuid", "rating_key", "guid_string", "guids", "updated_at").
+		Values(c.ServerUUID, c.RatingKey, c.GUID.GUID, string(guids), c.UpdatedAt.Format(time.RFC3339))
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "plexGUIDCache.store").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *PlexGUIDCacheRepo) Delete(ctx context.Context, serverUUID, ratingKey string) error {
+	return repo.delete(ctx, sq.Eq{"server_uuid": serverUUID, "rating_key": ratingKey})
+}
+
+func (repo *PlexGUIDCacheRepo) DeleteAll(ctx context.Context) error {
+	return repo.delete(ctx, nil)
+}
+
+func (repo *PlexGUIDCacheRepo) delete(ctx context.Context, where sq.Sqlizer) error {
+	queryBuilder := repo.db.squirrel.Delete("plex_guid_cache")
+	if where != nil {
+		queryBuilder = queryBuilder.Where(where)
+	}
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "plexGUIDCache.delete").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
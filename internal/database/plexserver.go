@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type PlexServerRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewPlexServerRepo(log zerolog.Logger, db *DB) domain.PlexServerRepo {
+	return &PlexServerRepo{
+		log: log.With().Str("repo", "plex_server").Logger(),
+		db:  db,
+	}
+}
+
+func (repo *PlexServerRepo) Store(ctx context.Context, server *domain.PlexServer) error {
+	queryBuilder := repo.db.squirrel.
+		Insert("plex_server").
+		Columns("name", "server_uuid", "host", "port", "tls", "tls_skip_verify", "token", "token_iv", "client_id", "anime_libraries", "plan_to_watch_libraries", "dry_run_libraries").
+		Values(server.Name, server.ServerUUID, server.Host, server.Port, server.TLS, server.TLSSkip, server.Token, server.TokenIV, server.ClientID, pq.Array(libraries(server.AnimeLibraries)), pq.Array(libraries(server.PlanToWatchLibs)), pq.Array(libraries(server.DryRunLibs))).
+		Suffix("RETURNING id").
+		RunWith(repo.db.handler)
+
+	if err := queryBuilder.QueryRowContext(ctx).Scan(&server.ID); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *PlexServerRepo) Update(ctx context.Context, server *domain.PlexServer) error {
+	queryBuilder := repo.db.squirrel.
+		Update("plex_server").
+		Set("name", server.Name).
+		Set("server_uuid", server.ServerUUID).
+		Set("host", server.Host).
+		Set("port", server.Port).
+		Set("tls", server.TLS).
+		Set("tls_skip_verify", server.TLSSkip).
+		Set("client_id", server.ClientID).
+		Set("anime_libraries", pq.Array(libraries(server.AnimeLibraries))).
+		Set("plan_to_watch_libraries", pq.Array(libraries(server.PlanToWatchLibs))).
+		Set("dry_run_libraries", pq.Array(libraries(server.DryRunLibs))).
+		Set("updated_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": server.ID})
+
+	if len(server.Token) > 0 {
+		queryBuilder = queryBuilder.Set("token", server.Token)
+	}
+	if len(server.TokenIV) > 0 {
+		queryBuilder = queryBuilder.Set("token_iv", server.TokenIV)
+	}
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "plexServer.update").Msgf("query: '%s', args: '%v'", query, args)
+	result, err := repo.db.handler.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (repo *PlexServerRepo) Delete(ctx context.Context, id int) error {
+	queryBuilder := repo.db.squirrel.
+		Delete("plex_server").
+		Where(sq.Eq{"id": id})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "plexServer.delete").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *PlexServerRepo) FindAll(ctx context.Context) ([]*domain.PlexServer, error) {
+	queryBuilder := repo.selectServers().OrderBy("id ASC")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "plexServer.findAll").Msgf("query: '%s', args: '%v'", query, args)
+	rows, err := repo.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	defer rows.Close()
+
+	servers := make([]*domain.PlexServer, 0)
+	for rows.Next() {
+		server, err := scanPlexServer(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		servers = append(servers, server)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findAll")
+	}
+
+	return servers, nil
+}
+
+func (repo *PlexServerRepo) GetByUUID(ctx context.Context, uuid string) (*domain.PlexServer, error) {
+	queryBuilder := repo.selectServers().Where(sq.Eq{"server_uuid": uuid})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "plexServer.getByUUID").Msgf("query: '%s', args: '%v'", query, args)
+	row := repo.db.handler.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	return scanPlexServer(row)
+}
+
+func (repo *PlexServerRepo) selectServers() sq.SelectBuilder {
+	return repo.db.squirrel.
+		Select("id", "name", "server_uuid", "host", "port", "tls", "tls_skip_verify", "token", "token_iv", "client_id", "anime_libraries", "plan_to_watch_libraries", "dry_run_libraries").
+		From("plex_server")
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPlexServer(row rowScanner) (*domain.PlexServer, error) {
+	var server domain.PlexServer
+	if err := row.Scan(&server.ID, &server.Name, &server.ServerUUID, &server.Host, &server.Port, &server.TLS, &server.TLSSkip, &server.Token, &server.TokenIV, &server.ClientID, pq.Array(&server.AnimeLibraries), pq.Array(&server.PlanToWatchLibs), pq.Array(&server.DryRunLibs)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "error scanning row")
+	}
+
+	return &server, nil
+}
@@ -27,8 +27,8 @@ func (repo *PlexSettingsRepo) Store(ctx context.Context, ps domain.PlexSettings)
 
 	queryBuilder := repo.db.squirrel.
 		Replace("plex_settings").
-		Columns("id", "host", "port", "tls", "tls_skip_verify", "token", "token_iv", "username", "anime_libraries", "plex_client_enabled", "client_id").
-		Values(1, ps.Host, ps.Port, ps.TLS, ps.TLSSkip, ps.Token, ps.TokenIV, ps.PlexUser, pq.Array(ps.AnimeLibraries), ps.PlexClientEnabled, ps.ClientID).
+		Columns("id", "host", "port", "tls", "tls_skip_verify", "token", "token_iv", "username", "anime_libraries", "plan_to_watch_libraries", "plex_client_enabled", "client_id", "completion_threshold", "complete_at_credits", "dry_run_libraries", "server_uuid").
+		Values(1, ps.Host, ps.Port, ps.TLS, ps.TLSSkip, ps.Token, ps.TokenIV, ps.PlexUser, pq.Array(ps.AnimeLibraries), pq.Array(libraries(ps.PlanToWatchLibs)), ps.PlexClientEnabled, ps.ClientID, ps.CompletionThreshold, ps.CompleteAtCredits, pq.Array(libraries(ps.DryRunLibs)), ps.ServerUUID).
 		RunWith(repo.db.handler)
 
 	_, err := queryBuilder.ExecContext(ctx)
@@ -68,11 +68,19 @@ func (repo *PlexSettingsRepo) Update(ctx context.Context, ps domain.PlexSettings
 		queryBuilder = queryBuilder.Set("anime_libraries", pq.Array(ps.AnimeLibraries))
 	}
 
+	queryBuilder = queryBuilder.Set("plan_to_watch_libraries", pq.Array(libraries(ps.PlanToWatchLibs)))
+	queryBuilder = queryBuilder.Set("dry_run_libraries", pq.Array(libraries(ps.DryRunLibs)))
+
 	queryBuilder = queryBuilder.Set("plex_client_enabled", ps.PlexClientEnabled)
+	queryBuilder = queryBuilder.Set("completion_threshold", ps.CompletionThreshold)
+	queryBuilder = queryBuilder.Set("complete_at_credits", ps.CompleteAtCredits)
 
 	if ps.ClientID != "" {
 		queryBuilder = queryBuilder.Set("client_id", ps.ClientID)
 	}
+	if ps.ServerUUID != "" {
+		queryBuilder = queryBuilder.Set("server_uuid", ps.ServerUUID)
+	}
 
 	sqlQuery, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -93,7 +101,7 @@ func (repo *PlexSettingsRepo) Update(ctx context.Context, ps domain.PlexSettings
 
 func (repo *PlexSettingsRepo) Get(ctx context.Context) (*domain.PlexSettings, error) {
 	queryBuilder := repo.db.squirrel.
-		Select("ps.host", "ps.port", "ps.tls", "ps.tls_skip_verify", "ps.token", "ps.token_iv", "ps.username", "ps.anime_libraries", "ps.plex_client_enabled", "client_id").
+		Select("ps.host", "ps.port", "ps.tls", "ps.tls_skip_verify", "ps.token", "ps.token_iv", "ps.username", "ps.anime_libraries", "ps.plan_to_watch_libraries", "ps.plex_client_enabled", "client_id", "ps.completion_threshold", "ps.complete_at_credits", "ps.dry_run_libraries", "ps.server_uuid").
 		From("plex_settings ps").
 		Where(sq.Eq{"ps.id": 1}).
 		RunWith(repo.db.handler)
@@ -110,13 +118,13 @@ func (repo *PlexSettingsRepo) Get(ctx context.Context) (*domain.PlexSettings, er
 		return nil, errors.Wrap(err, "error rows get plex settings")
 	}
 
-	var host, username, clientID string
+	var host, username, clientID, serverUUID string
 	var token, tokenIV []byte
-	var port int
-	var tls, tls_skip_verify, plex_client_enabled bool
-	var anime_libraries []string
+	var port, completion_threshold int
+	var tls, tls_skip_verify, plex_client_enabled, complete_at_credits bool
+	var anime_libraries, plan_to_watch_libraries, dry_run_libraries []string
 
-	if err := row.Scan(&host, &port, &tls, &tls_skip_verify, &token, &tokenIV, &username, pq.Array(&anime_libraries), &plex_client_enabled, &clientID); err != nil {
+	if err := row.Scan(&host, &port, &tls, &tls_skip_verify, &token, &tokenIV, &username, pq.Array(&anime_libraries), pq.Array(&plan_to_watch_libraries), &plex_client_enabled, &clientID, &completion_threshold, &complete_at_credits, pq.Array(&dry_run_libraries), &serverUUID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, err
 		}
@@ -124,6 +132,11 @@ func (repo *PlexSettingsRepo) Get(ctx context.Context) (*domain.PlexSettings, er
 	}
 
 	ps := domain.NewPlexSettings(host, username, clientID, token, tokenIV, port, anime_libraries, plex_client_enabled, tls, tls_skip_verify)
+	ps.PlanToWatchLibs = plan_to_watch_libraries
+	ps.CompletionThreshold = completion_threshold
+	ps.CompleteAtCredits = complete_at_credits
+	ps.DryRunLibs = dry_run_libraries
+	ps.ServerUUID = serverUUID
 
 	return ps, nil
 }
@@ -145,3 +158,11 @@ func (repo *PlexSettingsRepo) Delete(ctx context.Context) error {
 
 	return nil
 }
+
+// libraries keeps an unset library list from being stored as NULL.
+func libraries(l []string) []string {
+	if l == nil {
+		return []string{}
+	}
+	return l
+}
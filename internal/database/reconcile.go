@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type ReconcileRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewReconcileRepo(log zerolog.Logger, db *DB) domain.ReconcileRepo {
+	return &ReconcileRepo{
+		log: log.With().Str("repo", "reconcile").Logger(),
+		db:  db,
+	}
+}
+
+// Store inserts or refreshes the report entry for a MAL id, keeping its ignore flag.
+func (repo *ReconcileRepo) Store(ctx context.Context, item *domain.ReconcileItem) error {
+	item.UpdatedAt = time.Now().UTC()
+	queryBuilder := repo.db.squirrel.
+		Insert("reconcile").
+		Columns("mal_id", "title", "plex_rating_key", "source_db", "source_id", "season_num", "plex_watched", "mal_watched", "mal_status", "status", "message", "updated_at").
+		Values(item.MALId, item.Title, item.PlexRatingKey, item.SourceDB, item.SourceId, item.SeasonNum, item.PlexWatched, item.MALWatched, item.MALStatus, item.Status, toNullString(item.Message), item.UpdatedAt).
+		Suffix(`ON CONFLICT (mal_id) DO UPDATE SET
+			title = excluded.title,
+			plex_rating_key = excluded.plex_rating_key,
+			source_db = excluded.source_db,
+			source_id = excluded.source_id,
+			season_num = excluded.season_num,
+			plex_watched = excluded.plex_watched,
+			mal_watched = excluded.mal_watched,
+			mal_status = excluded.mal_status,
+			status = excluded.status,
+			message = excluded.message,
+			updated_at = excluded.updated_at
+		RETURNING id, ignored`).
+		RunWith(repo.db.handler)
+
+	if err := queryBuilder.QueryRowContext(ctx).Scan(&item.ID, &item.Ignored); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *ReconcileRepo) FindAll(ctx context.Context) ([]*domain.ReconcileItem, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("id", "mal_id", "title", "plex_rating_key", "source_db", "source_id", "season_num", "plex_watched", "mal_watched", "mal_status", "status", "message", "ignored", "updated_at").
+		From("reconcile").
+		OrderBy("ignored ASC", "title ASC")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "reconcile.findAll").Msgf("query: '%s', args: '%v'", query, args)
+	rows, err := repo.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	defer rows.Close()
+
+	items := make([]*domain.ReconcileItem, 0)
+	for rows.Next() {
+		var item domain.ReconcileItem
+		var title, ratingKey, message sql.NullString
+		if err := rows.Scan(&item.ID, &item.MALId, &title, &ratingKey, &item.SourceDB, &item.SourceId, &item.SeasonNum, &item.PlexWatched, &item.MALWatched, &item.MALStatus, &item.Status, &message, &item.Ignored, &item.UpdatedAt); err != nil {
+			return nil, errors.Wrap(err, "error scanning row")
+		}
+
+		item.Title = title.String
+		item.PlexRatingKey = ratingKey.String
+		item.Message = message.String
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findAll")
+	}
+
+	return items, nil
+}
+
+// DeleteStale removes entries that were not reported again since before, unless they are ignored.
+func (repo *ReconcileRepo) DeleteStale(ctx context.Context, before time.Time) error {
+	queryBuilder := repo.db.squirrel.
+		Delete("reconcile").
+		Where(sq.Lt{"updated_at": before.UTC()}).
+		Where(sq.Eq{"ignored": false})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *ReconcileRepo) SetIgnored(ctx context.Context, malID int, ignored bool) error {
+	queryBuilder := repo.db.squirrel.
+		Insert("reconcile").
+		Columns("mal_id", "ignored").
+		Values(malID, ignored).
+		Suffix("ON CONFLICT (mal_id) DO UPDATE SET ignored = excluded.ignored")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type ReverseSyncRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewReverseSyncRepo(log zerolog.Logger, db *DB) domain.ReverseSyncRepo {
+	return &ReverseSyncRepo{
+		log: log.With().Str("repo", "reversesync").Logger(),
+		db:  db,
+	}
+}
+
+func (repo *ReverseSyncRepo) Store(ctx context.Context, progress *domain.ReverseSyncProgress) error {
+	progress.UpdatedAt = time.Now().UTC()
+	queryBuilder := repo.db.squirrel.
+		Insert("reverse_sync").
+		Columns("mal_id", "episodes_watched", "updated_at").
+		Values(progress.MALId, progress.EpisodesWatched, progress.UpdatedAt).
+		Suffix("ON CONFLICT (mal_id) DO UPDATE SET episodes_watched = excluded.episodes_watched, updated_at = excluded.updated_at")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "reversesync.store").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *ReverseSyncRepo) FindAll(ctx context.Context) ([]*domain.ReverseSyncProgress, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("mal_id", "episodes_watched", "updated_at").
+		From("reverse_sync")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "reversesync.findAll").Msgf("query: '%s', args: '%v'", query, args)
+	rows, err := repo.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	defer rows.Close()
+
+	items := make([]*domain.ReverseSyncProgress, 0)
+	for rows.Next() {
+		var item domain.ReverseSyncProgress
+		if err := rows.Scan(&item.MALId, &item.EpisodesWatched, &item.UpdatedAt); err != nil {
+			return nil, errors.Wrap(err, "error scanning row")
+		}
+
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findAll")
+	}
+
+	return items, nil
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type SyncRuleRepo struct {
+	log zerolog.Logger
+	db  *DB
+}
+
+func NewSyncRuleRepo(log zerolog.Logger, db *DB) domain.SyncRuleRepo {
+	return &SyncRuleRepo{
+		log: log.With().Str("repo", "sync_rule").Logger(),
+		db:  db,
+	}
+}
+
+func (repo *SyncRuleRepo) Store(ctx context.Context, rule *domain.SyncRule) error {
+	queryBuilder := repo.db.squirrel.
+		Insert("sync_rule").
+		Columns("library", "rating_key", "mal_id", "events", "ignored", "force_mal_id", "episode_offset", "provider").
+		Values(rule.Library, rule.RatingKey, rule.MALID, pq.Array(events(rule.Events)), rule.Ignore, rule.ForceMALID, episodeOffset(rule.EpisodeOffset), rule.Provider).
+		Suffix("RETURNING id").
+		RunWith(repo.db.handler)
+
+	if err := queryBuilder.QueryRowContext(ctx).Scan(&rule.ID); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *SyncRuleRepo) Update(ctx context.Context, rule *domain.SyncRule) error {
+	queryBuilder := repo.db.squirrel.
+		Update("sync_rule").
+		Set("library", rule.Library).
+		Set("rating_key", rule.RatingKey).
+		Set("mal_id", rule.MALID).
+		Set("events", pq.Array(events(rule.Events))).
+		Set("ignored", rule.Ignore).
+		Set("force_mal_id", rule.ForceMALID).
+		Set("episode_offset", episodeOffset(rule.EpisodeOffset)).
+		Set("provider", rule.Provider).
+		Set("updated_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": rule.ID})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "syncRule.update").Msgf("query: '%s', args: '%v'", query, args)
+	result, err := repo.db.handler.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (repo *SyncRuleRepo) Delete(ctx context.Context, id int) error {
+	queryBuilder := repo.db.squirrel.
+		Delete("sync_rule").
+		Where(sq.Eq{"id": id})
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "syncRule.delete").Msgf("query: '%s', args: '%v'", query, args)
+	if _, err := repo.db.handler.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "error executing query")
+	}
+
+	return nil
+}
+
+func (repo *SyncRuleRepo) FindAll(ctx context.Context) ([]*domain.SyncRule, error) {
+	queryBuilder := repo.db.squirrel.
+		Select("id", "library", "rating_key", "mal_id", "events", "ignored", "force_mal_id", "episode_offset", "provider").
+		From("sync_rule").
+		OrderBy("id ASC")
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building query")
+	}
+
+	repo.log.Trace().Str("database", "syncRule.findAll").Msgf("query: '%s', args: '%v'", query, args)
+	rows, err := repo.db.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+
+	defer rows.Close()
+
+	rules := make([]*domain.SyncRule, 0)
+	for rows.Next() {
+		var rule domain.SyncRule
+		var ruleEvents []string
+		var offset sql.NullInt64
+		if err := rows.Scan(&rule.ID, &rule.Library, &rule.RatingKey, &rule.MALID, pq.Array(&ruleEvents), &rule.Ignore, &rule.ForceMALID, &offset, &rule.Provider); err != nil {
+			return nil, errors.Wrap(err, "error scanning row")
+		}
+
+		rule.Events = make([]domain.PlexEvent, 0, len(ruleEvents))
+		for _, event := range ruleEvents {
+			rule.Events = append(rule.Events, domain.PlexEvent(event))
+		}
+
+		if offset.Valid {
+			o := int(offset.Int64)
+			rule.EpisodeOffset = &o
+		}
+
+		rules = append(rules, &rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error rows findAll")
+	}
+
+	return rules, nil
+}
+
+func events(e []domain.PlexEvent) []string {
+	l := make([]string, 0, len(e))
+	for _, event := range e {
+		l = append(l, string(event))
+	}
+	return l
+}
+
+func episodeOffset(o *int) sql.NullInt64 {
+	if o == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*o), Valid: true}
+}
@@ -2,11 +2,13 @@ package domain
 
 import (
 	"context"
+	"time"
 )
 
 type AnimeRepo interface {
 	GetByID(ctx context.Context, req *GetAnimeRequest) (*Anime, error)
 	StoreMultiple(anime []*Anime) error
+	LastUpdated(ctx context.Context) (time.Time, error)
 }
 
 type Anime struct {
@@ -17,6 +17,8 @@ type AnimeUpdateRepo interface {
 	GetByPlexID(ctx context.Context, plexID int64) (*AnimeUpdate, error)
 	GetByPlexIDs(ctx context.Context, plexIDs []int64) ([]*AnimeUpdate, error)
 	FindAllWithFilters(ctx context.Context, params AnimeUpdateQueryParams) (*FindAnimeUpdatesResponse, error)
+	FindAfterID(ctx context.Context, id int64) ([]*AnimeUpdate, error)
+	LatestID(ctx context.Context) (int64, error)
 }
 
 type AnimeUpdate struct {
@@ -35,6 +37,14 @@ type AnimeUpdate struct {
 	Status        AnimeUpdateStatusType `json:"status,omitempty"`
 	ErrorType     AnimeUpdateErrorType  `json:"errorType,omitempty"`
 	ErrorMessage  string                `json:"errorMessage,omitempty"`
+	// PreviousStatus is the MAL list status before this update, used to revert it
+	PreviousStatus *mal.AnimeListStatus `json:"previousStatus,omitempty"`
+	// RevertOf is the id of the update this entry reverted
+	RevertOf int64 `json:"revertOf,omitempty"`
+	// DryRun records the intended change with a DRY_RUN status instead of updating MAL
+	DryRun bool `json:"dryRun,omitempty"`
+	// EpisodeOffset set by a sync rule is added to the episode number instead of the anime map offset
+	EpisodeOffset *int `json:"episodeOffset,omitempty"`
 }
 
 type ListDetails struct {
@@ -53,6 +63,7 @@ const (
 	AnimeUpdateStatusPending AnimeUpdateStatusType = "PENDING"
 	AnimeUpdateStatusSuccess AnimeUpdateStatusType = "SUCCESS"
 	AnimeUpdateStatusFailed  AnimeUpdateStatusType = "FAILED"
+	AnimeUpdateStatusDryRun  AnimeUpdateStatusType = "DRY_RUN"
 )
 
 // AnimeUpdateErrorType represents the type of error that occurred during anime update
@@ -67,6 +78,8 @@ const (
 	AnimeUpdateErrorUnknown             AnimeUpdateErrorType = "UNKNOWN_ERROR"
 )
 
+var ErrAnimeUpdateNotRevertable = errors.New("anime update cannot be reverted")
+
 type Key string
 
 const (
@@ -207,3 +220,63 @@ func (ls *ListDetails) isFirstEpisode(episodeNum int) bool {
 func (ls *ListDetails) isAnimeWatching(episodeNum int) bool {
 	return (episodeNum < ls.TotalEpisodeNum || ls.TotalEpisodeNum == 0) && episodeNum >= 1
 }
+
+// ListStatus returns the current MAL list status described by the list details.
+func (ls ListDetails) ListStatus() mal.AnimeListStatus {
+	return mal.AnimeListStatus{
+		Status:             ls.Status,
+		NumEpisodesWatched: ls.WatchedNum,
+		NumTimesRewatched:  ls.RewatchNum,
+	}
+}
+
+// ApplyListStatusOptions returns the list status MAL would hold after applying options to status.
+// This is a pure transformation function - no I/O.
+func ApplyListStatusOptions(status mal.AnimeListStatus, options []mal.UpdateMyAnimeListStatusOption) mal.AnimeListStatus {
+	for _, option := range options {
+		switch o := option.(type) {
+		case mal.AnimeStatus:
+			status.Status = o
+		case mal.NumEpisodesWatched:
+			status.NumEpisodesWatched = int(o)
+		case mal.NumTimesRewatched:
+			status.NumTimesRewatched = int(o)
+		case mal.IsRewatching:
+			status.IsRewatching = bool(o)
+		case mal.Score:
+			status.Score = int(o)
+		case mal.StartDate:
+			status.StartDate = time.Time(o).Format("2006-01-02")
+		case mal.FinishDate:
+			status.FinishDate = time.Time(o).Format("2006-01-02")
+		}
+	}
+
+	return status
+}
+
+// RevertOptions returns the options that restore status on MAL, including clearing dates that were not set.
+// This is a pure transformation function - no I/O.
+func RevertOptions(status mal.AnimeListStatus) []mal.UpdateMyAnimeListStatusOption {
+	return []mal.UpdateMyAnimeListStatusOption{
+		status.Status,
+		mal.NumEpisodesWatched(status.NumEpisodesWatched),
+		mal.NumTimesRewatched(status.NumTimesRewatched),
+		mal.IsRewatching(status.IsRewatching),
+		mal.Score(status.Score),
+		mal.StartDate(parseListDate(status.StartDate)),
+		mal.FinishDate(parseListDate(status.FinishDate)),
+	}
+}
+
+// parseListDate parses a MAL list date, which may only contain the year or the year and month.
+// An empty or invalid date returns the zero time, which clears the date on MAL.
+func parseListDate(date string) time.Time {
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
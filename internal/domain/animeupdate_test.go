@@ -2,6 +2,7 @@ package domain
 
 import (
 	"testing"
+	"time"
 
 	"github.com/nstratos/go-myanimelist/mal"
 	"github.com/stretchr/testify/assert"
@@ -716,3 +717,91 @@ func TestAnimeUpdate_BuildWatchStatusOptions_NoOptions(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, options, "options should never be empty for valid input")
 }
+
+func TestApplyListStatusOptions(t *testing.T) {
+	finished := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		status   mal.AnimeListStatus
+		options  []mal.UpdateMyAnimeListStatusOption
+		expected mal.AnimeListStatus
+	}{
+		{
+			name:   "episode progress",
+			status: mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: 4},
+			options: []mal.UpdateMyAnimeListStatusOption{
+				mal.NumEpisodesWatched(5),
+				mal.AnimeStatusWatching,
+			},
+			expected: mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: 5},
+		},
+		{
+			name:   "completion sets finish date",
+			status: mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: 11},
+			options: []mal.UpdateMyAnimeListStatusOption{
+				mal.FinishDate(finished),
+				mal.NumEpisodesWatched(12),
+				mal.AnimeStatusCompleted,
+			},
+			expected: mal.AnimeListStatus{Status: mal.AnimeStatusCompleted, NumEpisodesWatched: 12, FinishDate: "2024-03-15"},
+		},
+		{
+			name:   "rewatch",
+			status: mal.AnimeListStatus{Status: mal.AnimeStatusCompleted, NumEpisodesWatched: 12, NumTimesRewatched: 1},
+			options: []mal.UpdateMyAnimeListStatusOption{
+				mal.NumTimesRewatched(2),
+				mal.IsRewatching(false),
+				mal.NumEpisodesWatched(12),
+				mal.AnimeStatusCompleted,
+			},
+			expected: mal.AnimeListStatus{Status: mal.AnimeStatusCompleted, NumEpisodesWatched: 12, NumTimesRewatched: 2},
+		},
+		{
+			name:     "score",
+			status:   mal.AnimeListStatus{Status: mal.AnimeStatusCompleted, Score: 6},
+			options:  []mal.UpdateMyAnimeListStatusOption{mal.Score(9)},
+			expected: mal.AnimeListStatus{Status: mal.AnimeStatusCompleted, Score: 9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ApplyListStatusOptions(tt.status, tt.options))
+		})
+	}
+}
+
+func TestRevertOptions(t *testing.T) {
+	previous := mal.AnimeListStatus{
+		Status:             mal.AnimeStatusWatching,
+		NumEpisodesWatched: 4,
+		NumTimesRewatched:  1,
+		IsRewatching:       true,
+		Score:              7,
+		StartDate:          "2024-01-05",
+		FinishDate:         "2023-12-24",
+	}
+
+	current := mal.AnimeListStatus{Status: mal.AnimeStatusCompleted, NumEpisodesWatched: 12, Score: 3, FinishDate: "2024-03-15"}
+	assert.Equal(t, previous, ApplyListStatusOptions(current, RevertOptions(previous)))
+}
+
+func TestParseListDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     string
+		expected time.Time
+	}{
+		{name: "full date", date: "2024-03-15", expected: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "year and month", date: "2024-03", expected: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "year", date: "2024", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "empty clears the date", date: "", expected: time.Time{}},
+		{name: "invalid clears the date", date: "soon", expected: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseListDate(tt.date))
+		})
+	}
+}
@@ -13,6 +13,28 @@ type Config struct {
 	LogMaxSize      int    `koanf:"LogMaxSize"`
 	LogMaxBackups   int    `koanf:"LogMaxBackups"`
 	CheckForUpdates bool   `koanf:"CheckForUpdates"`
+
+	ReconcileSchedule    string `koanf:"ReconcileSchedule"`
+	ReconcileAutoCorrect bool   `koanf:"ReconcileAutoCorrect"`
+
+	ReverseSyncSchedule string `koanf:"ReverseSyncSchedule"`
+	ReverseSyncDryRun   bool   `koanf:"ReverseSyncDryRun"`
+
+	LibraryScanSchedule string `koanf:"LibraryScanSchedule"`
+
+	JobWorkers int `koanf:"JobWorkers"`
+
+	DryRun bool `koanf:"DryRun"`
+
+	MALRateLimit               float64 `koanf:"MALRateLimit"`
+	MALRateBurst               int     `koanf:"MALRateBurst"`
+	MALMaxRetries              int     `koanf:"MALMaxRetries"`
+	MALCircuitBreakerThreshold int     `koanf:"MALCircuitBreakerThreshold"`
+	MALCircuitBreakerCooldown  int     `koanf:"MALCircuitBreakerCooldown"`
+
+	PlexGUIDCacheTTL int `koanf:"PlexGUIDCacheTTL"`
+
+	TrustedProxies []string `koanf:"TrustedProxies"`
 }
 
 type ConfigUpdate struct {
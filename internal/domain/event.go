@@ -9,6 +9,7 @@ const (
 	EventNotificationSend     = "notification:send"
 	EventAnimeUpdateSuccess   = "animeupdate:success"
 	EventAnimeUpdateFailed    = "animeupdate:failed"
+	EventPlexGUIDResolved     = "plex:guid:resolved"
 )
 
 // PlexProcessedSuccessEvent is published when a Plex payload is successfully processed (extraction complete, before MAL update)
@@ -28,6 +29,14 @@ type PlexProcessedErrorEvent struct {
 	Timestamp    time.Time
 }
 
+// PlexGUIDResolvedEvent is published when the guids of a show were looked up in the background after
+// Plex could not be reached, Plex holds the payloads that failed without them.
+type PlexGUIDResolvedEvent struct {
+	RatingKey string
+	Plex      []*Plex
+	Timestamp time.Time
+}
+
 // NotificationSendEvent is published when a notification should be sent
 type NotificationSendEvent struct {
 	Event   NotificationEvent
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+type HealthStatus string
+
+const (
+	HealthOK       HealthStatus = "ok"
+	HealthDegraded HealthStatus = "degraded"
+	HealthFailed   HealthStatus = "failed"
+	// HealthSkipped is reported for components that are not configured.
+	HealthSkipped HealthStatus = "skipped"
+)
+
+type ComponentHealth struct {
+	Name    string       `json:"name"`
+	Status  HealthStatus `json:"status"`
+	Message string       `json:"message,omitempty"`
+	// Critical components fail the readiness check, the others only degrade it.
+	Critical bool `json:"critical"`
+}
+
+type Readiness struct {
+	Status     HealthStatus      `json:"status"`
+	Components []ComponentHealth `json:"components"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// NewReadiness sums up the components: failed when a critical component failed, degraded when
+// any other component is not healthy.
+func NewReadiness(components []ComponentHealth, checkedAt time.Time) *Readiness {
+	r := &Readiness{
+		Status:     HealthOK,
+		Components: components,
+		CheckedAt:  checkedAt,
+	}
+
+	for _, c := range components {
+		switch {
+		case c.Status == HealthOK || c.Status == HealthSkipped:
+		case c.Critical:
+			r.Status = HealthFailed
+			return r
+		default:
+			r.Status = HealthDegraded
+		}
+	}
+
+	return r
+}
+
+func (r *Readiness) IsReady() bool {
+	return r.Status != HealthFailed
+}
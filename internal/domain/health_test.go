@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []ComponentHealth
+		want       HealthStatus
+		ready      bool
+	}{
+		{
+			name: "all ok",
+			components: []ComponentHealth{
+				{Name: "database", Status: HealthOK, Critical: true},
+				{Name: "plex", Status: HealthSkipped},
+			},
+			want:  HealthOK,
+			ready: true,
+		},
+		{
+			name: "optional component degraded",
+			components: []ComponentHealth{
+				{Name: "database", Status: HealthOK, Critical: true},
+				{Name: "mal", Status: HealthDegraded},
+			},
+			want:  HealthDegraded,
+			ready: true,
+		},
+		{
+			name: "critical component failed",
+			components: []ComponentHealth{
+				{Name: "mal", Status: HealthDegraded},
+				{Name: "database", Status: HealthFailed, Critical: true},
+			},
+			want:  HealthFailed,
+			ready: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReadiness(tt.components, time.Now())
+			assert.Equal(t, tt.want, r.Status)
+			assert.Equal(t, tt.ready, r.IsReady())
+		})
+	}
+}
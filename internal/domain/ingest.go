@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type IngestTokenRepo interface {
+	Store(ctx context.Context, token *IngestToken) error
+	Update(ctx context.Context, token *IngestToken) error
+	UpdateToken(ctx context.Context, id int, token string) error
+	UpdateLastSeen(ctx context.Context, id int, lastSeen time.Time) error
+	Delete(ctx context.Context, id int) error
+	FindAll(ctx context.Context) ([]*IngestToken, error)
+	GetByToken(ctx context.Context, source IngestSource, token string) (*IngestToken, error)
+}
+
+var (
+	ErrIngestTokenInvalid = errors.New("invalid ingest token")
+	ErrIngestIPNotAllowed = errors.New("source ip not allowed for ingest token")
+)
+
+type IngestSource string
+
+const (
+	IngestSourcePlex     IngestSource = "plex"
+	IngestSourceTautulli IngestSource = "tautulli"
+)
+
+// PayloadSource returns the payload source webhooks of the ingest source arrive as.
+func (s IngestSource) PayloadSource() PlexPayloadSource {
+	switch s {
+	case IngestSourcePlex:
+		return PlexWebhook
+	case IngestSourceTautulli:
+		return TautulliWebhook
+	}
+	return ""
+}
+
+// IngestToken authenticates webhooks sent to /api/ingest/{source}/{token}. Unlike API keys it only
+// accepts payloads from its source, so leaking the url from Plex logs grants nothing else.
+type IngestToken struct {
+	ID     int          `json:"id"`
+	Name   string       `json:"name"`
+	Source IngestSource `json:"source"`
+	Token  string       `json:"token"`
+	// AllowedIPs are addresses or CIDR ranges webhooks must come from, any address is allowed when empty.
+	AllowedIPs []string   `json:"allowed_ips"`
+	LastSeen   *time.Time `json:"last_seen"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *IngestToken) Validate() error {
+	if t.Source.PayloadSource() == "" {
+		return fmt.Errorf("unsupported ingest source: %q", t.Source)
+	}
+
+	for _, entry := range t.AllowedIPs {
+		if _, _, err := net.ParseCIDR(entry); err == nil {
+			continue
+		}
+
+		if net.ParseIP(entry) == nil {
+			return fmt.Errorf("invalid allowed ip: %q", entry)
+		}
+	}
+
+	return nil
+}
+
+// AllowsIP reports whether a webhook from addr, an ip with an optional port, is accepted.
+func (t *IngestToken) AllowsIP(addr string) bool {
+	if len(t.AllowedIPs) == 0 {
+		return true
+	}
+
+	return MatchesIP(t.AllowedIPs, addr)
+}
+
+// MatchesIP reports whether addr, an ip with an optional port, is one of entries, given as ips and
+// CIDR ranges.
+func MatchesIP(entries []string, addr string) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestToken_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   IngestToken
+		wantErr bool
+	}{
+		{name: "plex", token: IngestToken{Source: IngestSourcePlex}},
+		{name: "tautulli with allow list", token: IngestToken{Source: IngestSourceTautulli, AllowedIPs: []string{"192.168.1.10", "10.0.0.0/8", "::1"}}},
+		{name: "unsupported source", token: IngestToken{Source: "jellyfin"}, wantErr: true},
+		{name: "invalid ip", token: IngestToken{Source: IngestSourcePlex, AllowedIPs: []string{"plex.local"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.token.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIngestToken_AllowsIP(t *testing.T) {
+	open := IngestToken{}
+	assert.True(t, open.AllowsIP("203.0.113.5:32400"))
+
+	token := IngestToken{AllowedIPs: []string{"192.168.1.10", "10.0.0.0/8"}}
+	assert.True(t, token.AllowsIP("192.168.1.10"))
+	assert.True(t, token.AllowsIP("192.168.1.10:54321"))
+	assert.True(t, token.AllowsIP("10.20.30.40:1234"))
+	assert.False(t, token.AllowsIP("192.168.1.11"))
+	assert.False(t, token.AllowsIP("not-an-ip"))
+}
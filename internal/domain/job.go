@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type JobRepo interface {
+	Store(ctx context.Context, job *Job) error
+	Update(ctx context.Context, job *Job) error
+	Delete(ctx context.Context, id int64) error
+	FindAll(ctx context.Context) ([]*Job, error)
+}
+
+type JobType string
+
+const (
+	JobTypeAnimeUpdate  JobType = "ANIME_UPDATE"
+	JobTypeNotification JobType = "NOTIFICATION"
+)
+
+// Job is a unit of background work that is persisted until it ran. Jobs with the same Key run one
+// after another in the order they were queued, jobs without a Key run as soon as a worker is free.
+// Failing jobs are retried, jobs that used up their attempts are kept with FailedAt set.
+type Job struct {
+	ID        int64           `json:"id"`
+	Type      JobType         `json:"type"`
+	Key       string          `json:"key"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	Error     string          `json:"error"`
+	FailedAt  *time.Time      `json:"failed_at"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	// RunAt holds back a retry until the backoff passed, it is not persisted
+	RunAt time.Time `json:"-"`
+}
+
+type JobHandler func(ctx context.Context, payload json.RawMessage) error
+
+// AnimeUpdateJob updates the MAL list for a processed Plex payload. The payload itself is loaded
+// from the database by PlexID when the job runs.
+type AnimeUpdateJob struct {
+	PlexID      int64        `json:"plex_id"`
+	Event       PlexEvent    `json:"event"`
+	AnimeUpdate *AnimeUpdate `json:"anime_update"`
+}
+
+type NotificationJob struct {
+	Event   NotificationEvent   `json:"event"`
+	Payload NotificationPayload `json:"payload"`
+}
@@ -0,0 +1,12 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LibraryScanRepo keeps track of when each plan to watch library was last scanned for new media.
+type LibraryScanRepo interface {
+	GetScannedAt(ctx context.Context, sectionKey string) (time.Time, error)
+	StoreScannedAt(ctx context.Context, sectionKey string, scannedAt time.Time) error
+}
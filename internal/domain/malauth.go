@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"golang.org/x/oauth2"
 )
@@ -40,3 +41,44 @@ func NewMalAuth(clientID, clientSecret string, accessToken, tokenIV []byte) *Mal
 		TokenIV:     tokenIV,
 	}
 }
+
+type MalAuthTokenState string
+
+const (
+	MalAuthTokenValid    MalAuthTokenState = "VALID"
+	MalAuthTokenExpiring MalAuthTokenState = "EXPIRING"
+	MalAuthTokenInvalid  MalAuthTokenState = "INVALID"
+)
+
+// MalAuthTokenStatus is the result of a scheduled token check. Expiring means the token could not be
+// refreshed ahead of Expiry, Invalid means MyAnimeList rejected the refresh token.
+type MalAuthTokenStatus struct {
+	State     MalAuthTokenState
+	Expiry    time.Time
+	Refreshed bool
+	Error     string
+}
+
+type MALCircuitState string
+
+const (
+	MALCircuitClosed   MALCircuitState = "CLOSED"
+	MALCircuitOpen     MALCircuitState = "OPEN"
+	MALCircuitHalfOpen MALCircuitState = "HALF_OPEN"
+)
+
+// MALRateLimitStatus reports the state of the rate limited transport used for MyAnimeList requests.
+type MALRateLimitStatus struct {
+	RequestsPerSecond   float64         `json:"requests_per_second"`
+	Burst               int             `json:"burst"`
+	AvailableTokens     float64         `json:"available_tokens"`
+	MaxRetries          int             `json:"max_retries"`
+	CircuitState        MALCircuitState `json:"circuit_state"`
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	CircuitOpenUntil    *time.Time      `json:"circuit_open_until,omitempty"`
+	TotalRequests       int64           `json:"total_requests"`
+	TotalRetries        int64           `json:"total_retries"`
+	TotalThrottled      int64           `json:"total_throttled"`
+	TotalRejected       int64           `json:"total_rejected"`
+	LastThrottledAt     *time.Time      `json:"last_throttled_at,omitempty"`
+}
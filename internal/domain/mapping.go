@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 type MappingRepo interface {
 	Store(ctx context.Context, m *MapSettings) error
@@ -229,6 +232,109 @@ func (ad *AnimeMapDetails) CalculateEpNum(oldEpNum int) int {
 	return oldEpNum - ad.Start + 1
 }
 
+// ReverseEpNum is the inverse of CalculateEpNum, it returns the source episode number of a MAL episode.
+func (ad *AnimeMapDetails) ReverseEpNum(malEp int) (int, bool) {
+	start := ad.Start
+	if start == 0 {
+		start = 1
+	}
+
+	if ad.MappingType == "explicit" && ad.ExplicitEpisodes != nil {
+		for sourceEp, ep := range ad.ExplicitEpisodes {
+			if ep == malEp {
+				return sourceEp, true
+			}
+		}
+	}
+
+	if len(ad.SkipMalEpisodes) > 0 {
+		if malEp < start || slices.Contains(ad.SkipMalEpisodes, malEp) {
+			return 0, false
+		}
+
+		position := 0
+		for ep := start; ep <= malEp; ep++ {
+			if !slices.Contains(ad.SkipMalEpisodes, ep) {
+				position++
+			}
+		}
+		return position, true
+	}
+
+	if ad.UseMapping {
+		if malEp < start {
+			return 0, false
+		}
+		return malEp - start + 1, true
+	}
+
+	if malEp < 1 {
+		return 0, false
+	}
+
+	return malEp + start - 1, true
+}
+
+// MappedEpisode is the source database episode a MAL episode maps to. Season and Episode are unset for movies.
+type MappedEpisode struct {
+	SourceDB PlexSupportedDBs
+	SourceId int
+	Season   int
+	Episode  int
+}
+
+// ReverseMap finds the TVDB season and episode of a MAL episode. For entries spread over several
+// TVDB seasons the mapping with the highest start at or before the episode is used.
+func (s *AnimeTVShows) ReverseMap(malid, malEp int) (bool, *MappedEpisode) {
+	for _, anime := range s.Anime {
+		if anime.Malid != malid {
+			continue
+		}
+
+		if !anime.UseMapping {
+			ad := AnimeMapDetails{Start: anime.Start}
+			if ep, ok := ad.ReverseEpNum(malEp); ok {
+				return true, &MappedEpisode{SourceDB: TVDB, SourceId: anime.Tvdbid, Season: anime.TvdbSeason, Episode: ep}
+			}
+			continue
+		}
+
+		mappings := slices.Clone(anime.AnimeMapping)
+		slices.SortFunc(mappings, func(a, b AnimeMapping) int {
+			return b.Start - a.Start
+		})
+
+		for _, m := range mappings {
+			if m.MappingType != "explicit" && m.Start > malEp {
+				continue
+			}
+
+			ad := AnimeMapDetails{
+				Start:            m.Start,
+				UseMapping:       true,
+				MappingType:      m.MappingType,
+				ExplicitEpisodes: m.ExplicitEpisodes,
+				SkipMalEpisodes:  m.SkipMalEpisodes,
+			}
+			if ep, ok := ad.ReverseEpNum(malEp); ok {
+				return true, &MappedEpisode{SourceDB: TVDB, SourceId: anime.Tvdbid, Season: m.TvdbSeason, Episode: ep}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (am *AnimeMovies) ReverseMap(malid int) (bool, *MappedEpisode) {
+	for _, animeMovie := range am.AnimeMovie {
+		if animeMovie.MALID == malid {
+			return true, &MappedEpisode{SourceDB: TMDB, SourceId: animeMovie.TMDBID}
+		}
+	}
+
+	return false, nil
+}
+
 func NewMapSettings(tvdb, tmdb bool, tvdbPath, tmdbPath string) *MapSettings {
 	return &MapSettings{
 		TVDBEnabled:       tvdb,
@@ -615,3 +615,167 @@ func TestAnimeMovies_CheckMap(t *testing.T) {
 		})
 	}
 }
+
+func TestReverseEpNum(t *testing.T) {
+	tests := []struct {
+		name        string
+		details     *AnimeMapDetails
+		malEpisode  int
+		expectedEp  int
+		expectFound bool
+	}{
+		{
+			name:        "DanMachi S4-2",
+			details:     &AnimeMapDetails{Malid: 53111, Start: 12},
+			malEpisode:  11,
+			expectedEp:  22,
+			expectFound: true,
+		},
+		{
+			name:        "start unset",
+			details:     &AnimeMapDetails{Malid: 47164, Start: 0},
+			malEpisode:  5,
+			expectedEp:  5,
+			expectFound: true,
+		},
+		{
+			name:        "use mapping",
+			details:     &AnimeMapDetails{Malid: 1, Start: 26, UseMapping: true},
+			malEpisode:  30,
+			expectedEp:  5,
+			expectFound: true,
+		},
+		{
+			name:        "use mapping before start",
+			details:     &AnimeMapDetails{Malid: 1, Start: 26, UseMapping: true},
+			malEpisode:  3,
+			expectFound: false,
+		},
+		{
+			name: "Monogatari S3-1",
+			details: &AnimeMapDetails{
+				Malid:            17074,
+				UseMapping:       true,
+				MappingType:      "explicit",
+				ExplicitEpisodes: map[int]int{7: 6, 8: 11, 9: 16},
+			},
+			malEpisode:  11,
+			expectedEp:  8,
+			expectFound: true,
+		},
+		{
+			name: "Monogatari S3-2",
+			details: &AnimeMapDetails{
+				Malid:           17074,
+				Start:           1,
+				UseMapping:      true,
+				MappingType:     "range",
+				SkipMalEpisodes: []int{6, 11, 16},
+			},
+			malEpisode:  26,
+			expectedEp:  23,
+			expectFound: true,
+		},
+		{
+			name: "skipped MAL episode",
+			details: &AnimeMapDetails{
+				Malid:           17074,
+				Start:           1,
+				UseMapping:      true,
+				MappingType:     "range",
+				SkipMalEpisodes: []int{6, 11, 16},
+			},
+			malEpisode:  11,
+			expectFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep, found := tt.details.ReverseEpNum(tt.malEpisode)
+			assert.Equal(t, tt.expectFound, found)
+			if tt.expectFound {
+				assert.Equal(t, tt.expectedEp, ep)
+				assert.Equal(t, tt.malEpisode, tt.details.CalculateEpNum(ep))
+			}
+		})
+	}
+}
+
+func TestAnimeTVShows_ReverseMap(t *testing.T) {
+	animeMap := &AnimeTVShows{
+		Anime: []AnimeTV{
+			{Malid: 47164, Tvdbid: 289882, TvdbSeason: 4, Start: 0},
+			{Malid: 53111, Tvdbid: 289882, TvdbSeason: 4, Start: 12},
+			{
+				Malid:      21,
+				Tvdbid:     81797,
+				UseMapping: true,
+				AnimeMapping: []AnimeMapping{
+					{TvdbSeason: 1, Start: 1},
+					{TvdbSeason: 2, Start: 62},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		malid       int
+		malEpisode  int
+		expectFound bool
+		expected    *MappedEpisode
+	}{
+		{
+			name:        "split cour",
+			malid:       53111,
+			malEpisode:  3,
+			expectFound: true,
+			expected:    &MappedEpisode{SourceDB: TVDB, SourceId: 289882, Season: 4, Episode: 14},
+		},
+		{
+			name:        "mapping first season",
+			malid:       21,
+			malEpisode:  61,
+			expectFound: true,
+			expected:    &MappedEpisode{SourceDB: TVDB, SourceId: 81797, Season: 1, Episode: 61},
+		},
+		{
+			name:        "mapping second season",
+			malid:       21,
+			malEpisode:  62,
+			expectFound: true,
+			expected:    &MappedEpisode{SourceDB: TVDB, SourceId: 81797, Season: 2, Episode: 1},
+		},
+		{
+			name:        "not in map",
+			malid:       99999,
+			malEpisode:  1,
+			expectFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, ep := animeMap.ReverseMap(tt.malid, tt.malEpisode)
+			assert.Equal(t, tt.expectFound, found)
+			assert.Equal(t, tt.expected, ep)
+		})
+	}
+}
+
+func TestAnimeMovies_ReverseMap(t *testing.T) {
+	movies := &AnimeMovies{
+		AnimeMovie: []AnimeMovie{
+			{MainTitle: "Test Movie 1", TMDBID: 12345, MALID: 67890},
+		},
+	}
+
+	found, movie := movies.ReverseMap(67890)
+	require.True(t, found)
+	assert.Equal(t, &MappedEpisode{SourceDB: TMDB, SourceId: 12345}, movie)
+
+	found, movie = movies.ReverseMap(1)
+	assert.False(t, found)
+	assert.Nil(t, movie)
+}
@@ -3,6 +3,13 @@ package domain
 import (
 	"context"
 	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNotificationTemplateInvalid       = errors.New("invalid notification template")
+	ErrNotificationDigestScheduleInvalid = errors.New("invalid notification digest schedule")
 )
 
 type NotificationRepo interface {
@@ -11,6 +18,12 @@ type NotificationRepo interface {
 	Store(ctx context.Context, notification *Notification) error
 	Update(ctx context.Context, notification *Notification) error
 	Delete(ctx context.Context, notificationID int) error
+	UpdateDigestCursor(ctx context.Context, notificationID int, cursor int64) error
+}
+
+type NotificationDeliveryRepo interface {
+	Store(ctx context.Context, delivery *NotificationDelivery) error
+	FindByNotificationID(ctx context.Context, notificationID int) ([]NotificationDelivery, error)
 }
 
 type NotificationSender interface {
@@ -20,27 +33,39 @@ type NotificationSender interface {
 }
 
 type Notification struct {
-	ID        int              `json:"id"`
-	Name      string           `json:"name"`
-	Type      NotificationType `json:"type"`
-	Enabled   bool             `json:"enabled"`
-	Events    []string         `json:"events"`
-	Token     string           `json:"token"`
-	APIKey    string           `json:"api_key"`
-	Webhook   string           `json:"webhook"`
-	Title     string           `json:"title"`
-	Icon      string           `json:"icon"`
-	Username  string           `json:"username"`
-	Host      string           `json:"host"`
-	Password  string           `json:"password"`
-	Channel   string           `json:"channel"`
-	Rooms     string           `json:"rooms"`
-	Targets   string           `json:"targets"`
-	Devices   string           `json:"devices"`
-	Priority  int32            `json:"priority"`
-	Topic     string           `json:"topic"`
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
+	ID            int              `json:"id"`
+	Name          string           `json:"name"`
+	Type          NotificationType `json:"type"`
+	Enabled       bool             `json:"enabled"`
+	Events        []string         `json:"events"`
+	Token         string           `json:"token"`
+	APIKey        string           `json:"api_key"`
+	Webhook       string           `json:"webhook"`
+	Title         string           `json:"title"`
+	Icon          string           `json:"icon"`
+	Username      string           `json:"username"`
+	Host          string           `json:"host"`
+	Password      string           `json:"password"`
+	Channel       string           `json:"channel"`
+	Rooms         string           `json:"rooms"`
+	Targets       string           `json:"targets"`
+	Devices       string           `json:"devices"`
+	Priority      int32            `json:"priority"`
+	Topic         string           `json:"topic"`
+	Headers       string           `json:"headers"`
+	Timeout       int              `json:"timeout"`
+	Retries       int              `json:"retries"`
+	Port          int              `json:"port"`
+	TLSMode       string           `json:"tls_mode"`
+	From          string           `json:"from"`
+	TitleTemplate string           `json:"title_template"`
+	BodyTemplate  string           `json:"body_template"`
+	// DigestSchedule is a cron schedule, when set successful updates are sent as one digest
+	DigestSchedule string `json:"digest_schedule"`
+	// DigestCursor is the id of the last anime update included in a digest
+	DigestCursor int64     `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 type NotificationPayload struct {
@@ -62,13 +87,59 @@ type NotificationPayload struct {
 	PlexSource      PlexPayloadSource
 	Timestamp       time.Time
 	Sender          string
+	// ErrorType is the AnimeUpdateErrorType or PlexErrorType of error events.
+	ErrorType string
+	// PlexID is the id of the stored plex payload the event belongs to.
+	PlexID      int64
+	AnimeUpdate *AnimeUpdate
+	// Digest is the summary of digest events.
+	Digest *NotificationDigest
+}
+
+// NotificationDigest summarizes the anime updates since the previous digest of a notification.
+type NotificationDigest struct {
+	Shows     int                       `json:"shows"`
+	Episodes  int                       `json:"episodes"`
+	Completed int                       `json:"completed"`
+	Failed    int                       `json:"failed"`
+	Entries   []NotificationDigestEntry `json:"entries"`
+}
+
+type NotificationDigestEntry struct {
+	MALID         int    `json:"mal_id"`
+	Title         string `json:"title"`
+	Episodes      int    `json:"episodes"`
+	Progress      int    `json:"progress"`
+	TotalEpisodes int    `json:"total_episodes"`
+	Completed     bool   `json:"completed"`
+}
+
+// NotificationPreviewRequest renders the templates for a sample payload of Event, or for a stored
+// anime update when AnimeUpdateID is set.
+type NotificationPreviewRequest struct {
+	TitleTemplate string            `json:"title_template"`
+	BodyTemplate  string            `json:"body_template"`
+	Event         NotificationEvent `json:"event"`
+	AnimeUpdateID int               `json:"anime_update_id"`
+}
+
+type NotificationPreview struct {
+	Event NotificationEvent `json:"event"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
 }
 
 type NotificationType string
 
 const (
-	NotificationTypeDiscord NotificationType = "DISCORD"
-	NotificationTypeGotify  NotificationType = "GOTIFY"
+	NotificationTypeDiscord  NotificationType = "DISCORD"
+	NotificationTypeGotify   NotificationType = "GOTIFY"
+	NotificationTypeTelegram NotificationType = "TELEGRAM"
+	NotificationTypeNtfy     NotificationType = "NTFY"
+	NotificationTypePushover NotificationType = "PUSHOVER"
+	NotificationTypeWebhook  NotificationType = "WEBHOOK"
+	NotificationTypeEmail    NotificationType = "EMAIL"
+	NotificationTypeMatrix   NotificationType = "MATRIX"
 )
 
 type NotificationEvent string
@@ -79,6 +150,9 @@ const (
 	NotificationEventPlexProcessingError NotificationEvent = "PLEX_PROCESSING_ERROR"
 	NotificationEventAnimeUpdateError    NotificationEvent = "ANIME_UPDATE_ERROR"
 	NotificationEventTest                NotificationEvent = "TEST"
+	NotificationEventDigest              NotificationEvent = "DIGEST"
+	NotificationEventMalAuthExpiring     NotificationEvent = "MAL_AUTH_EXPIRING"
+	NotificationEventMalAuthInvalid      NotificationEvent = "MAL_AUTH_INVALID"
 )
 
 type NotificationEventArr []NotificationEvent
@@ -93,3 +167,24 @@ type NotificationQueryParams struct {
 	}
 	Search string
 }
+
+type NotificationDeliveryStatus string
+
+const (
+	NotificationDeliveryStatusSuccess  NotificationDeliveryStatus = "SUCCESS"
+	NotificationDeliveryStatusRetrying NotificationDeliveryStatus = "RETRYING"
+	NotificationDeliveryStatusFailed   NotificationDeliveryStatus = "FAILED"
+)
+
+// NotificationDelivery is one attempt to send an event with a notification. HTTPStatus is 0 when
+// the attempt failed without a response.
+type NotificationDelivery struct {
+	ID             int64                      `json:"id"`
+	NotificationID int                        `json:"notification_id"`
+	Event          NotificationEvent          `json:"event"`
+	Status         NotificationDeliveryStatus `json:"status"`
+	Attempt        int                        `json:"attempt"`
+	HTTPStatus     int                        `json:"http_status"`
+	Error          string                     `json:"error,omitempty"`
+	CreatedAt      time.Time                  `json:"created_at"`
+}
@@ -51,6 +51,10 @@ type Plex struct {
 	Success   *bool         `json:"success,omitempty"`
 	ErrorType PlexErrorType `json:"errorType,omitempty"`
 	ErrorMsg  string        `json:"errorMsg,omitempty"`
+
+	// syncRules are the sync rules loaded while handling this payload, see SetSyncRules
+	syncRules       []*SyncRule
+	syncRulesLoaded bool
 }
 
 type PlexErrorType string
@@ -58,6 +62,8 @@ type PlexErrorType string
 const (
 	PlexErrorAgentNotSupported PlexErrorType = "AGENT_NOT_SUPPORTED"
 	PlexErrorExtractionFailed  PlexErrorType = "EXTRACTION_FAILED"
+	PlexErrorSkippedByRule     PlexErrorType = "SKIPPED_BY_RULE"
+	PlexErrorAlreadyCompleted  PlexErrorType = "ALREADY_COMPLETED"
 	PlexErrorUnknown           PlexErrorType = "UNKNOWN_ERROR"
 )
 
@@ -77,6 +83,7 @@ type Metadata struct {
 	LibrarySectionTitle   string        `json:"librarySectionTitle"`
 	LibrarySectionID      int           `json:"librarySectionID"`
 	LibrarySectionKey     string        `json:"librarySectionKey"`
+	LibrarySectionUUID    string        `json:"librarySectionUUID"`
 	GrandparentTitle      string        `json:"grandparentTitle"`
 	ParentTitle           string        `json:"parentTitle"`
 	OriginalTitle         string        `json:"originalTitle"`
@@ -93,6 +100,7 @@ type Metadata struct {
 	GrandparentThumb      string        `json:"grandparentThumb"`
 	GrandparentArt        string        `json:"grandparentArt"`
 	Duration              int           `json:"duration"`
+	ViewOffset            int           `json:"viewOffset"`
 	OriginallyAvailableAt string        `json:"originallyAvailableAt"`
 	AddedAt               int           `json:"addedAt"`
 	UpdatedAt             int           `json:"updatedAt"`
@@ -185,6 +193,11 @@ type Metadata struct {
 		Role   string `json:"role"`
 		Thumb  string `json:"thumb,omitempty"`
 	} `json:"Role"`
+	Marker []struct {
+		Type            string `json:"type"`
+		StartTimeOffset int    `json:"startTimeOffset"`
+		EndTimeOffset   int    `json:"endTimeOffset"`
+	} `json:"Marker"`
 }
 
 type PlexResponse struct {
@@ -214,6 +227,9 @@ type PlexPayloadSource string
 const (
 	PlexWebhook     PlexPayloadSource = "Plex Webhook"
 	TautulliWebhook PlexPayloadSource = "Tautulli"
+	TautulliImport  PlexPayloadSource = "Tautulli Import"
+	Reconciliation  PlexPayloadSource = "Reconciliation"
+	LibraryScan     PlexPayloadSource = "Library Scan"
 )
 
 type PlexEvent string
@@ -221,6 +237,11 @@ type PlexEvent string
 const (
 	PlexScrobbleEvent PlexEvent = "media.scrobble"
 	PlexRateEvent     PlexEvent = "media.rate"
+	// PlexStopEvent and PlexPauseEvent count as a scrobble once playback passed the completion threshold.
+	PlexStopEvent  PlexEvent = "media.stop"
+	PlexPauseEvent PlexEvent = "media.pause"
+	// PlexLibraryNewEvent is sent when media is added to a library, it adds the anime to the MAL list as plan to watch.
+	PlexLibraryNewEvent PlexEvent = "library.new"
 )
 
 type PlexMediaType string
@@ -228,6 +249,8 @@ type PlexMediaType string
 const (
 	PlexEpisode PlexMediaType = "episode"
 	PlexMovie   PlexMediaType = "movie"
+	PlexShow    PlexMediaType = "show"
+	PlexSeason  PlexMediaType = "season"
 )
 
 type GetPlexRequest struct {
@@ -291,7 +314,44 @@ func (p *Plex) GetPlexEvent() PlexEvent {
 }
 
 func (p *Plex) IsEventAllowed() bool {
-	return p.Event == PlexRateEvent || p.Event == PlexScrobbleEvent
+	switch p.Event {
+	case PlexRateEvent, PlexScrobbleEvent, PlexLibraryNewEvent, PlexStopEvent, PlexPauseEvent:
+		return true
+	}
+
+	return false
+}
+
+// IsPlaybackEvent reports whether the event marks an item as watched once it is accepted.
+func (p *Plex) IsPlaybackEvent() bool {
+	return p.Event == PlexScrobbleEvent || p.Event == PlexStopEvent || p.Event == PlexPauseEvent
+}
+
+// IsPlaybackComplete reports whether a media.stop or media.pause was sent after the completion
+// threshold of ps or, when enabled, after the credits marker. Other events are always complete.
+func (p *Plex) IsPlaybackComplete(ps *PlexSettings) bool {
+	if p.Event != PlexStopEvent && p.Event != PlexPauseEvent {
+		return true
+	}
+
+	offset := p.Metadata.ViewOffset
+	if offset <= 0 {
+		return false
+	}
+
+	if ps.CompleteAtCredits {
+		for _, marker := range p.Metadata.Marker {
+			if marker.Type == "credits" && offset >= marker.StartTimeOffset {
+				return true
+			}
+		}
+	}
+
+	if ps.CompletionThreshold <= 0 || p.Metadata.Duration <= 0 {
+		return false
+	}
+
+	return offset*100 >= p.Metadata.Duration*ps.CompletionThreshold
 }
 
 func (p *Plex) IsRatingAllowed() bool {
@@ -303,18 +363,80 @@ func (p *Plex) IsPlexUserAllowed(ps *PlexSettings) bool {
 }
 
 func (p *Plex) IsAnimeLibrary(ps *PlexSettings) bool {
-	for _, library := range ps.AnimeLibraries {
-		if library == p.Metadata.LibrarySectionTitle {
-			return true
-		}
-	}
-	return false
+	return p.hasLibrary(ps.AnimeLibraries)
 }
 
 func (p *Plex) IsMediaTypeAllowed() bool {
+	if p.Event == PlexLibraryNewEvent && (p.Metadata.Type == PlexShow || p.Metadata.Type == PlexSeason) {
+		return true
+	}
+
 	return p.Metadata.Type == PlexEpisode || p.Metadata.Type == PlexMovie
 }
 
+func (p *Plex) IsPlanToWatchLibrary(ps *PlexSettings) bool {
+	return p.hasLibrary(ps.PlanToWatchLibs)
+}
+
+func (p *Plex) hasLibrary(libraries []string) bool {
+	return hasLibrary(libraries, p.Metadata.LibrarySectionID, p.Metadata.LibrarySectionUUID, p.Metadata.LibrarySectionTitle)
+}
+
+// ShowRatingKey returns the rating key of the show an episode belongs to, or the rating key of the item itself.
+func (p *Plex) ShowRatingKey() string {
+	switch p.Metadata.Type {
+	case PlexEpisode:
+		return p.Metadata.GrandparentRatingKey
+	case PlexSeason:
+		return p.Metadata.ParentRatingKey
+	}
+	return p.Metadata.RatingKey
+}
+
+// SyncRules returns the sync rules set for this payload and whether they were set.
+func (p *Plex) SyncRules() ([]*SyncRule, bool) {
+	return p.syncRules, p.syncRulesLoaded
+}
+
+// SetSyncRules keeps the sync rules with the payload, so checking and processing one webhook
+// loads them once.
+func (p *Plex) SetSyncRules(rules []*SyncRule) {
+	p.syncRules = rules
+	p.syncRulesLoaded = true
+}
+
+func (p *Plex) IsDryRunLibrary(ps *PlexSettings) bool {
+	return p.hasLibrary(ps.DryRunLibs)
+}
+
+// LibraryNewAsEpisode returns a copy of a library.new payload for a show or season that describes
+// its first episode, so it can be resolved like a watched episode. Other payloads are returned as is.
+func (p *Plex) LibraryNewAsEpisode() *Plex {
+	if p.Event != PlexLibraryNewEvent {
+		return p
+	}
+
+	np := *p
+	switch p.Metadata.Type {
+	case PlexShow:
+		np.Metadata.GrandparentKey = "/library/metadata/" + p.Metadata.RatingKey
+		np.Metadata.GrandparentRatingKey = p.Metadata.RatingKey
+		np.Metadata.GrandparentTitle = p.Metadata.Title
+		np.Metadata.ParentIndex = 1
+		np.Metadata.Index = 1
+		np.Metadata.Type = PlexEpisode
+	case PlexSeason:
+		np.Metadata.GrandparentKey = p.Metadata.ParentKey
+		np.Metadata.GrandparentRatingKey = p.Metadata.ParentRatingKey
+		np.Metadata.GrandparentTitle = p.Metadata.ParentTitle
+		np.Metadata.ParentIndex = p.Metadata.Index
+		np.Metadata.Index = 1
+		np.Metadata.Type = PlexEpisode
+	}
+
+	return &np
+}
+
 func (p *Plex) SetAnimeFields(source PlexSupportedDBs, id int) AnimeUpdate {
 	// Extract title from Plex metadata
 	var title string
@@ -430,6 +552,23 @@ func (g *GUID) PlexAgent(mediaType PlexMediaType) (PlexSupportedDBs, int, error)
 	return "", -1, errors.New("no supported online database found")
 }
 
+// PlexAgentProvider returns the id of provider from the Plex agent GUIDs.
+func (g *GUID) PlexAgentProvider(provider PlexSupportedDBs) (PlexSupportedDBs, int, error) {
+	for _, gid := range g.GUIDS {
+		dbid := strings.Split(gid.ID, "://")
+		if len(dbid) == 2 && dbid[0] == string(provider) {
+			id, err := strconv.Atoi(dbid[1])
+			if err != nil {
+				return "", -1, errors.Wrap(err, "id conversion failed")
+			}
+
+			return provider, id, nil
+		}
+	}
+
+	return "", -1, errors.Errorf("no %s id found", provider)
+}
+
 type PlexHistoryRequest struct {
 	Limit int `json:"limit"`
 }
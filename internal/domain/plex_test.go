@@ -214,6 +214,27 @@ func TestPlex_IsEventAllowed(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "allows library new event",
+			plex: &Plex{
+				Event: PlexLibraryNewEvent,
+			},
+			expected: true,
+		},
+		{
+			name: "allows stop event",
+			plex: &Plex{
+				Event: PlexStopEvent,
+			},
+			expected: true,
+		},
+		{
+			name: "allows pause event",
+			plex: &Plex{
+				Event: PlexPauseEvent,
+			},
+			expected: true,
+		},
 		{
 			name: "disallows unknown event",
 			plex: &Plex{
@@ -238,6 +259,79 @@ func TestPlex_IsEventAllowed(t *testing.T) {
 	}
 }
 
+func TestPlex_IsPlaybackComplete(t *testing.T) {
+	withCredits := func(p *Plex) *Plex {
+		p.Metadata.Marker = append(p.Metadata.Marker, struct {
+			Type            string `json:"type"`
+			StartTimeOffset int    `json:"startTimeOffset"`
+			EndTimeOffset   int    `json:"endTimeOffset"`
+		}{Type: "credits", StartTimeOffset: 1300000, EndTimeOffset: 1440000})
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		plex     *Plex
+		settings *PlexSettings
+		expected bool
+	}{
+		{
+			name:     "scrobble is always complete",
+			plex:     &Plex{Event: PlexScrobbleEvent},
+			settings: &PlexSettings{},
+			expected: true,
+		},
+		{
+			name:     "stop is never complete when disabled",
+			plex:     &Plex{Event: PlexStopEvent, Metadata: Metadata{Duration: 1440000, ViewOffset: 1440000}},
+			settings: &PlexSettings{},
+			expected: false,
+		},
+		{
+			name:     "stop past threshold",
+			plex:     &Plex{Event: PlexStopEvent, Metadata: Metadata{Duration: 1440000, ViewOffset: 1200000}},
+			settings: &PlexSettings{CompletionThreshold: 80},
+			expected: true,
+		},
+		{
+			name:     "pause before threshold",
+			plex:     &Plex{Event: PlexPauseEvent, Metadata: Metadata{Duration: 1440000, ViewOffset: 1000000}},
+			settings: &PlexSettings{CompletionThreshold: 80},
+			expected: false,
+		},
+		{
+			name:     "stop without duration",
+			plex:     &Plex{Event: PlexStopEvent, Metadata: Metadata{ViewOffset: 1000000}},
+			settings: &PlexSettings{CompletionThreshold: 80},
+			expected: false,
+		},
+		{
+			name:     "stop at credits marker",
+			plex:     withCredits(&Plex{Event: PlexStopEvent, Metadata: Metadata{Duration: 1440000, ViewOffset: 1310000}}),
+			settings: &PlexSettings{CompleteAtCredits: true},
+			expected: true,
+		},
+		{
+			name:     "stop before credits marker",
+			plex:     withCredits(&Plex{Event: PlexStopEvent, Metadata: Metadata{Duration: 1440000, ViewOffset: 1200000}}),
+			settings: &PlexSettings{CompleteAtCredits: true},
+			expected: false,
+		},
+		{
+			name:     "credits marker ignored when disabled",
+			plex:     withCredits(&Plex{Event: PlexStopEvent, Metadata: Metadata{Duration: 1440000, ViewOffset: 1310000}}),
+			settings: &PlexSettings{CompletionThreshold: 95},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.plex.IsPlaybackComplete(tt.settings))
+		})
+	}
+}
+
 func TestPlex_IsRatingAllowed(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -401,6 +495,45 @@ func TestPlex_IsAnimeLibrary(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "allows renamed library by section id",
+			plex: &Plex{
+				Metadata: Metadata{
+					LibrarySectionTitle: "Animation",
+					LibrarySectionID:    3,
+				},
+			},
+			settings: &PlexSettings{
+				AnimeLibraries: []string{"3"},
+			},
+			expected: true,
+		},
+		{
+			name: "allows library by section uuid",
+			plex: &Plex{
+				Metadata: Metadata{
+					LibrarySectionTitle: "Animation",
+					LibrarySectionUUID:  "a2b7e2c4-1b2c-4d3e-9f00-123456789abc",
+				},
+			},
+			settings: &PlexSettings{
+				AnimeLibraries: []string{"a2b7e2c4-1b2c-4d3e-9f00-123456789abc"},
+			},
+			expected: true,
+		},
+		{
+			name: "disallows other section id",
+			plex: &Plex{
+				Metadata: Metadata{
+					LibrarySectionTitle: "TV Shows",
+					LibrarySectionID:    4,
+				},
+			},
+			settings: &PlexSettings{
+				AnimeLibraries: []string{"3"},
+			},
+			expected: false,
+		},
 		{
 			name: "disallows non-matching library",
 			plex: &Plex{
@@ -489,6 +622,26 @@ func TestPlex_IsMediaTypeAllowed(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "allows show type for library new",
+			plex: &Plex{
+				Event: PlexLibraryNewEvent,
+				Metadata: Metadata{
+					Type: PlexShow,
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "allows season type for library new",
+			plex: &Plex{
+				Event: PlexLibraryNewEvent,
+				Metadata: Metadata{
+					Type: PlexSeason,
+				},
+			},
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -499,6 +652,121 @@ func TestPlex_IsMediaTypeAllowed(t *testing.T) {
 	}
 }
 
+func TestPlex_IsPlanToWatchLibrary(t *testing.T) {
+	settings := &PlexSettings{
+		AnimeLibraries:  []string{"Anime", "Anime Movies"},
+		PlanToWatchLibs: []string{"Anime"},
+	}
+
+	assert.True(t, (&Plex{Metadata: Metadata{LibrarySectionTitle: "Anime"}}).IsPlanToWatchLibrary(settings))
+	assert.False(t, (&Plex{Metadata: Metadata{LibrarySectionTitle: "Anime Movies"}}).IsPlanToWatchLibrary(settings))
+	assert.False(t, (&Plex{Metadata: Metadata{LibrarySectionTitle: "Anime"}}).IsPlanToWatchLibrary(&PlexSettings{}))
+}
+
+func TestPlex_IsDryRunLibrary(t *testing.T) {
+	settings := &PlexSettings{
+		AnimeLibraries: []string{"Anime", "Anime Movies"},
+		DryRunLibs:     []string{"Anime Movies"},
+	}
+
+	assert.True(t, (&Plex{Metadata: Metadata{LibrarySectionTitle: "Anime Movies"}}).IsDryRunLibrary(settings))
+	assert.False(t, (&Plex{Metadata: Metadata{LibrarySectionTitle: "Anime"}}).IsDryRunLibrary(settings))
+	assert.False(t, (&Plex{Metadata: Metadata{LibrarySectionTitle: "Anime Movies"}}).IsDryRunLibrary(&PlexSettings{}))
+}
+
+func TestPlex_LibraryNewAsEpisode(t *testing.T) {
+	tests := []struct {
+		name     string
+		plex     *Plex
+		expected Metadata
+	}{
+		{
+			name: "show becomes its first episode",
+			plex: &Plex{
+				Event: PlexLibraryNewEvent,
+				Metadata: Metadata{
+					RatingKey: "100",
+					Title:     "Frieren",
+					Type:      PlexShow,
+				},
+			},
+			expected: Metadata{
+				RatingKey:            "100",
+				Title:                "Frieren",
+				Type:                 PlexEpisode,
+				GrandparentKey:       "/library/metadata/100",
+				GrandparentRatingKey: "100",
+				GrandparentTitle:     "Frieren",
+				ParentIndex:          1,
+				Index:                1,
+			},
+		},
+		{
+			name: "season becomes its first episode",
+			plex: &Plex{
+				Event: PlexLibraryNewEvent,
+				Metadata: Metadata{
+					RatingKey:       "101",
+					ParentRatingKey: "100",
+					ParentKey:       "/library/metadata/100",
+					ParentTitle:     "Frieren",
+					Index:           2,
+					Type:            PlexSeason,
+				},
+			},
+			expected: Metadata{
+				RatingKey:            "101",
+				ParentRatingKey:      "100",
+				ParentKey:            "/library/metadata/100",
+				ParentTitle:          "Frieren",
+				Type:                 PlexEpisode,
+				GrandparentKey:       "/library/metadata/100",
+				GrandparentRatingKey: "100",
+				GrandparentTitle:     "Frieren",
+				ParentIndex:          2,
+				Index:                1,
+			},
+		},
+		{
+			name: "movie is unchanged",
+			plex: &Plex{
+				Event: PlexLibraryNewEvent,
+				Metadata: Metadata{
+					Title: "Your Name",
+					Type:  PlexMovie,
+				},
+			},
+			expected: Metadata{
+				Title: "Your Name",
+				Type:  PlexMovie,
+			},
+		},
+		{
+			name: "other events are unchanged",
+			plex: &Plex{
+				Event: PlexScrobbleEvent,
+				Metadata: Metadata{
+					Title: "Frieren",
+					Type:  PlexShow,
+				},
+			},
+			expected: Metadata{
+				Title: "Frieren",
+				Type:  PlexShow,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.plex.Metadata
+			result := tt.plex.LibraryNewAsEpisode()
+			assert.Equal(t, tt.expected, result.Metadata)
+			assert.Equal(t, original, tt.plex.Metadata)
+		})
+	}
+}
+
 func TestPlex_SetAnimeFields(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type PlexGUIDCacheRepo interface {
+	Get(ctx context.Context, serverUUID, ratingKey string) (*PlexGUIDCache, error)
+	Store(ctx context.Context, c *PlexGUIDCache) error
+	Delete(ctx context.Context, serverUUID, ratingKey string) error
+	DeleteAll(ctx context.Context) error
+}
+
+// PlexGUIDCache is the guid list of a show looked up on a Plex server for the Plex agent.
+type PlexGUIDCache struct {
+	ServerUUID string    `json:"server_uuid"`
+	RatingKey  string    `json:"rating_key"`
+	GUID       GUID      `json:"guid"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// IsFresh reports whether the entry was looked up less than ttl ago.
+func (c *PlexGUIDCache) IsFresh(ttl time.Duration, now time.Time) bool {
+	return now.Sub(c.UpdatedAt) < ttl
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+)
+
+type PlexServerRepo interface {
+	Store(ctx context.Context, server *PlexServer) error
+	Update(ctx context.Context, server *PlexServer) error
+	Delete(ctx context.Context, id int) error
+	FindAll(ctx context.Context) ([]*PlexServer, error)
+	GetByUUID(ctx context.Context, uuid string) (*PlexServer, error)
+}
+
+// PlexServer is an additional Plex server connection, like a server shared by a friend. Webhooks
+// from the server are matched by ServerUUID and use its connection and libraries, the user and
+// the completion settings come from the main Plex settings.
+type PlexServer struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	ServerUUID string `json:"server_uuid"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	TLS        bool   `json:"tls"`
+	TLSSkip    bool   `json:"tls_skip"`
+	// PlainToken is the access token for the server as sent by the client, it is encrypted into Token
+	// and never returned.
+	PlainToken      string   `json:"token,omitempty"`
+	Token           []byte   `json:"-"`
+	TokenIV         []byte   `json:"-"`
+	ClientID        string   `json:"client_id"`
+	AnimeLibraries  []string `json:"anime_libs"`
+	PlanToWatchLibs []string `json:"plan_to_watch_libs"`
+	DryRunLibs      []string `json:"dry_run_libs"`
+}
+
+func (s *PlexServer) Validate() error {
+	if s.ServerUUID == "" {
+		return fmt.Errorf("plex server uuid is required")
+	}
+
+	if s.Host == "" || s.Port <= 0 {
+		return fmt.Errorf("plex server host and port are required")
+	}
+
+	return nil
+}
+
+// MigrateLibraries replaces library titles with the section id of the library with that title and
+// reports whether anything changed.
+func (s *PlexServer) MigrateLibraries(sectionIDs map[string]string) bool {
+	return migrateLibraries(sectionIDs, s.AnimeLibraries, s.PlanToWatchLibs, s.DryRunLibs)
+}
+
+// HasLibraryTitles reports whether any library is still stored by title or uuid instead of section id.
+func (s *PlexServer) HasLibraryTitles() bool {
+	return hasLibraryTitles(s.AnimeLibraries, s.PlanToWatchLibs, s.DryRunLibs)
+}
+
+// Settings returns a copy of ps with the connection and libraries of the server.
+func (s *PlexServer) Settings(ps *PlexSettings) *PlexSettings {
+	settings := *ps
+	settings.Host = s.Host
+	settings.Port = s.Port
+	settings.TLS = s.TLS
+	settings.TLSSkip = s.TLSSkip
+	settings.Token = s.Token
+	settings.TokenIV = s.TokenIV
+	settings.ClientID = s.ClientID
+	settings.AnimeLibraries = s.AnimeLibraries
+	settings.PlanToWatchLibs = s.PlanToWatchLibs
+	settings.DryRunLibs = s.DryRunLibs
+	settings.ServerUUID = s.ServerUUID
+
+	return &settings
+}
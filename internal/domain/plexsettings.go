@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"strconv"
+)
 
 type PlexSettingsRepo interface {
 	Store(ctx context.Context, ps PlexSettings) (*PlexSettings, error)
@@ -9,17 +12,98 @@ type PlexSettingsRepo interface {
 	Delete(ctx context.Context) error
 }
 
+// PlexSettings holds the connection to the Plex server. AnimeLibraries, PlanToWatchLibs and DryRunLibs
+// hold library section ids, titles stored before libraries were matched by id keep matching until
+// MigrateLibraries replaces them.
 type PlexSettings struct {
 	Host              string   `json:"host"`
 	Port              int      `json:"port"`
 	TLS               bool     `json:"tls"`
 	TLSSkip           bool     `json:"tls_skip"`
 	AnimeLibraries    []string `json:"anime_libs"`
+	PlanToWatchLibs   []string `json:"plan_to_watch_libs"`
 	PlexUser          string   `json:"plex_user"`
 	PlexClientEnabled bool     `json:"plex_client_enabled"`
 	Token             []byte   `json:"-"`
 	TokenIV           []byte   `json:"-"`
 	ClientID          string   `json:"client_id"`
+	// CompletionThreshold is the percentage of an item that has to be played for media.stop and
+	// media.pause to count as watched, 0 disables it.
+	CompletionThreshold int `json:"completion_threshold"`
+	// CompleteAtCredits counts media.stop and media.pause as watched once the credits marker is reached.
+	CompleteAtCredits bool `json:"complete_at_credits"`
+	// DryRunLibs are libraries whose updates are only recorded with a DRY_RUN status, MAL is left untouched.
+	DryRunLibs []string `json:"dry_run_libs"`
+	// ServerUUID is the machine identifier of the Plex server, webhooks from other servers are rejected.
+	ServerUUID string `json:"server_uuid"`
+}
+
+// IsAnimeLibrary reports whether the library section is set as an anime library.
+func (ps *PlexSettings) IsAnimeLibrary(sectionID int, uuid, title string) bool {
+	return hasLibrary(ps.AnimeLibraries, sectionID, uuid, title)
+}
+
+// IsServerAllowed reports whether payloads from the server are accepted. Payloads without a server,
+// like Tautulli webhooks, and settings without a server are always accepted.
+func (ps *PlexSettings) IsServerAllowed(uuid string) bool {
+	return ps.ServerUUID == "" || uuid == "" || ps.ServerUUID == uuid
+}
+
+// MigrateLibraries replaces library titles with the section id of the library with that title and
+// reports whether anything changed.
+func (ps *PlexSettings) MigrateLibraries(sectionIDs map[string]string) bool {
+	return migrateLibraries(sectionIDs, ps.AnimeLibraries, ps.PlanToWatchLibs, ps.DryRunLibs)
+}
+
+// HasLibraryTitles reports whether any library is still stored by title or uuid instead of section id.
+func (ps *PlexSettings) HasLibraryTitles() bool {
+	return hasLibraryTitles(ps.AnimeLibraries, ps.PlanToWatchLibs, ps.DryRunLibs)
+}
+
+func migrateLibraries(sectionIDs map[string]string, lists ...[]string) bool {
+	changed := false
+	for _, libraries := range lists {
+		for i, library := range libraries {
+			if id, ok := sectionIDs[library]; ok && id != library {
+				libraries[i] = id
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+func hasLibraryTitles(lists ...[]string) bool {
+	for _, libraries := range lists {
+		for _, library := range libraries {
+			if _, err := strconv.Atoi(library); library != "" && err != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasLibrary reports whether libraries contains the section by id, uuid or title.
+func hasLibrary(libraries []string, sectionID int, uuid, title string) bool {
+	id := ""
+	if sectionID > 0 {
+		id = strconv.Itoa(sectionID)
+	}
+
+	for _, library := range libraries {
+		if library == "" {
+			continue
+		}
+
+		if library == id || library == uuid || library == title {
+			return true
+		}
+	}
+
+	return false
 }
 
 func NewPlexSettings(host, plexUser, clientID string, token, tokenIV []byte, port int, animeLibs []string, pce, tls, tlsSkip bool) *PlexSettings {
@@ -187,3 +187,36 @@ func TestNewPlexSettings(t *testing.T) {
 	}
 }
 
+func TestPlexSettings_MigrateLibraries(t *testing.T) {
+	ps := &PlexSettings{
+		AnimeLibraries:  []string{"Anime", "Anime Movies", "7"},
+		PlanToWatchLibs: []string{"Anime"},
+		DryRunLibs:      []string{"Removed"},
+	}
+
+	changed := ps.MigrateLibraries(map[string]string{"Anime": "1", "Anime Movies": "2", "TV Shows": "3"})
+	assert.True(t, changed)
+	assert.Equal(t, []string{"1", "2", "7"}, ps.AnimeLibraries)
+	assert.Equal(t, []string{"1"}, ps.PlanToWatchLibs)
+	assert.Equal(t, []string{"Removed"}, ps.DryRunLibs)
+
+	assert.False(t, ps.MigrateLibraries(map[string]string{"Anime": "1"}))
+}
+
+func TestPlexSettings_HasLibraryTitles(t *testing.T) {
+	ps := &PlexSettings{AnimeLibraries: []string{"1", "2"}, DryRunLibs: []string{""}}
+	assert.False(t, ps.HasLibraryTitles())
+
+	ps.PlanToWatchLibs = []string{"Anime"}
+	assert.True(t, ps.HasLibraryTitles())
+}
+
+func TestPlexSettings_IsServerAllowed(t *testing.T) {
+	ps := &PlexSettings{}
+	assert.True(t, ps.IsServerAllowed("server-a"))
+
+	ps.ServerUUID = "server-a"
+	assert.True(t, ps.IsServerAllowed("server-a"))
+	assert.True(t, ps.IsServerAllowed(""))
+	assert.False(t, ps.IsServerAllowed("server-b"))
+}
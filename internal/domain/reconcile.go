@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+)
+
+type ReconcileRepo interface {
+	Store(ctx context.Context, item *ReconcileItem) error
+	FindAll(ctx context.Context) ([]*ReconcileItem, error)
+	DeleteStale(ctx context.Context, before time.Time) error
+	SetIgnored(ctx context.Context, malID int, ignored bool) error
+}
+
+// ReconcileStatus represents the outcome of comparing a show's Plex watch progress with MAL.
+type ReconcileStatus string
+
+const (
+	ReconcileStatusDiscrepancy ReconcileStatus = "DISCREPANCY"
+	ReconcileStatusQueued      ReconcileStatus = "QUEUED"
+	ReconcileStatusFailed      ReconcileStatus = "FAILED"
+)
+
+// ReconcileItem is a MAL entry whose watched episodes lag behind the Plex watched episodes.
+// Ignored entries are kept between runs and are never corrected.
+type ReconcileItem struct {
+	ID            int64            `json:"id"`
+	MALId         int              `json:"malid"`
+	Title         string           `json:"title"`
+	PlexRatingKey string           `json:"plexRatingKey"`
+	SourceDB      PlexSupportedDBs `json:"sourceDB"`
+	SourceId      int              `json:"sourceID"`
+	SeasonNum     int              `json:"seasonNum"`
+	PlexWatched   int              `json:"plexWatched"`
+	MALWatched    int              `json:"malWatched"`
+	MALStatus     mal.AnimeStatus  `json:"malStatus"`
+	Status        ReconcileStatus  `json:"status"`
+	Message       string           `json:"message,omitempty"`
+	Ignored       bool             `json:"ignored"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+}
+
+type ReconcileIgnoreRequest struct {
+	Ignored bool `json:"ignored"`
+}
+
+// IsMALBehind reports whether MAL has fewer episodes watched than Plex. Completed entries are
+// never considered behind, since a lower Plex count usually means the show is being rewatched.
+func IsMALBehind(plexWatched int, status *mal.AnimeListStatus) bool {
+	if plexWatched <= 0 {
+		return false
+	}
+
+	if status == nil {
+		return true
+	}
+
+	if status.Status == mal.AnimeStatusCompleted {
+		return false
+	}
+
+	return status.NumEpisodesWatched < plexWatched
+}
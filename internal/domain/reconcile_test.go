@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMALBehind(t *testing.T) {
+	tests := []struct {
+		name        string
+		plexWatched int
+		status      *mal.AnimeListStatus
+		expected    bool
+	}{
+		{
+			name:        "nothing watched on plex",
+			plexWatched: 0,
+			status:      nil,
+			expected:    false,
+		},
+		{
+			name:        "not on MAL list",
+			plexWatched: 3,
+			status:      nil,
+			expected:    true,
+		},
+		{
+			name:        "MAL behind",
+			plexWatched: 5,
+			status:      &mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: 2},
+			expected:    true,
+		},
+		{
+			name:        "MAL up to date",
+			plexWatched: 5,
+			status:      &mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: 5},
+			expected:    false,
+		},
+		{
+			name:        "MAL ahead",
+			plexWatched: 5,
+			status:      &mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: 8},
+			expected:    false,
+		},
+		{
+			name:        "completed on MAL",
+			plexWatched: 5,
+			status:      &mal.AnimeListStatus{Status: mal.AnimeStatusCompleted, NumEpisodesWatched: 0},
+			expected:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsMALBehind(tt.plexWatched, tt.status))
+		})
+	}
+}
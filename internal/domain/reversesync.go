@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type ReverseSyncRepo interface {
+	Store(ctx context.Context, progress *ReverseSyncProgress) error
+	FindAll(ctx context.Context) ([]*ReverseSyncProgress, error)
+}
+
+// ReverseSyncProgress is the number of watched MAL episodes already pushed to Plex. Only progress
+// beyond it is synced, so episodes marked unwatched in Plex afterwards are left alone.
+type ReverseSyncProgress struct {
+	MALId           int       `json:"malid"`
+	EpisodesWatched int       `json:"episodesWatched"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+type ReverseSyncRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// ReverseSyncAction is a Plex item that was, or in a dry run would be, marked as watched.
+type ReverseSyncAction struct {
+	MALId         int    `json:"malid"`
+	Title         string `json:"title"`
+	MALEpisode    int    `json:"malEpisode"`
+	PlexRatingKey string `json:"plexRatingKey"`
+	PlexTitle     string `json:"plexTitle"`
+	SeasonNum     int    `json:"seasonNum"`
+	EpisodeNum    int    `json:"episodeNum"`
+	DryRun        bool   `json:"dryRun"`
+	Error         string `json:"error,omitempty"`
+}
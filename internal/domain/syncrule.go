@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+)
+
+type SyncRuleRepo interface {
+	Store(ctx context.Context, rule *SyncRule) error
+	Update(ctx context.Context, rule *SyncRule) error
+	Delete(ctx context.Context, id int) error
+	FindAll(ctx context.Context) ([]*SyncRule, error)
+}
+
+// SyncRule changes how updates of a library or a single show are synced. A rule matches either a
+// library, a show by Plex rating key or a show by MAL id. When several rules match, the rule for
+// the MAL id wins over the rule for the rating key, which wins over the library rule.
+type SyncRule struct {
+	ID int `json:"id"`
+	// Library is the section id of the library, or its title.
+	Library   string `json:"library,omitempty"`
+	RatingKey string `json:"rating_key,omitempty"`
+	MALID     int    `json:"mal_id,omitempty"`
+	// Events limits syncing to these events, all events are synced when empty.
+	// media.stop and media.pause follow media.scrobble.
+	Events []PlexEvent `json:"events"`
+	// Ignore skips every update matched by the rule.
+	Ignore bool `json:"ignore"`
+	// ForceMALID updates this MAL entry instead of resolving one from the metadata agent.
+	ForceMALID int `json:"force_mal_id,omitempty"`
+	// EpisodeOffset is added to the Plex episode number instead of the offset from the anime map.
+	EpisodeOffset *int `json:"episode_offset,omitempty"`
+	// Provider selects the online database used for shows matched by the Plex agent.
+	Provider PlexSupportedDBs `json:"provider,omitempty"`
+}
+
+func (r *SyncRule) Validate() error {
+	set := 0
+	for _, ok := range []bool{r.Library != "", r.RatingKey != "", r.MALID > 0} {
+		if ok {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return fmt.Errorf("sync rule must match exactly one of library, rating_key or mal_id")
+	}
+
+	for _, event := range r.Events {
+		if event != PlexScrobbleEvent && event != PlexRateEvent && event != PlexLibraryNewEvent {
+			return fmt.Errorf("sync rule event not supported: %s", event)
+		}
+	}
+
+	if r.ForceMALID < 0 {
+		return fmt.Errorf("sync rule force_mal_id must be a positive MAL id")
+	}
+
+	switch r.Provider {
+	case "", TVDB, TMDB:
+	default:
+		return fmt.Errorf("sync rule provider must be %s or %s", TVDB, TMDB)
+	}
+
+	return nil
+}
+
+// Allows reports whether the rule lets the event of p sync.
+func (r *SyncRule) Allows(p *Plex) bool {
+	if r.Ignore {
+		return false
+	}
+
+	if len(r.Events) == 0 {
+		return true
+	}
+
+	event := p.Event
+	if p.IsPlaybackEvent() {
+		event = PlexScrobbleEvent
+	}
+
+	for _, e := range r.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchSyncRule returns the most specific rule for p, or nil when no rule matches. malID is only
+// compared with MAL id rules when it is greater than 0.
+func MatchSyncRule(rules []*SyncRule, p *Plex, malID int) *SyncRule {
+	var library, show *SyncRule
+	ratingKey := p.ShowRatingKey()
+	for _, rule := range rules {
+		switch {
+		case rule.MALID > 0:
+			if malID > 0 && rule.MALID == malID {
+				return rule
+			}
+		case rule.RatingKey != "":
+			if ratingKey != "" && rule.RatingKey == ratingKey {
+				show = rule
+			}
+		case p.hasLibrary([]string{rule.Library}):
+			library = rule
+		}
+	}
+
+	if show != nil {
+		return show
+	}
+
+	return library
+}
+
+// HasMALIDRules reports whether any rule matches by MAL id, which requires resolving the MAL id.
+func HasMALIDRules(rules []*SyncRule) bool {
+	for _, rule := range rules {
+		if rule.MALID > 0 {
+			return true
+		}
+	}
+	return false
+}
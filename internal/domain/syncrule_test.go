@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    SyncRule
+		wantErr bool
+	}{
+		{name: "library", rule: SyncRule{Library: "Anime"}},
+		{name: "rating key with events", rule: SyncRule{RatingKey: "1234", Events: []PlexEvent{PlexScrobbleEvent, PlexRateEvent}}},
+		{name: "MAL id with provider", rule: SyncRule{MALID: 52991, Provider: TMDB}},
+		{name: "no match", rule: SyncRule{Ignore: true}, wantErr: true},
+		{name: "two matches", rule: SyncRule{Library: "Anime", MALID: 52991}, wantErr: true},
+		{name: "unsupported event", rule: SyncRule{Library: "Anime", Events: []PlexEvent{PlexPauseEvent}}, wantErr: true},
+		{name: "negative force MAL id", rule: SyncRule{Library: "Anime", ForceMALID: -1}, wantErr: true},
+		{name: "unsupported provider", rule: SyncRule{Library: "Anime", Provider: AniDB}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSyncRule_Allows(t *testing.T) {
+	scrobble := &Plex{Event: PlexScrobbleEvent}
+	stop := &Plex{Event: PlexStopEvent}
+	rate := &Plex{Event: PlexRateEvent}
+
+	all := &SyncRule{Library: "Anime"}
+	assert.True(t, all.Allows(scrobble))
+	assert.True(t, all.Allows(rate))
+
+	ignored := &SyncRule{Library: "Anime", Ignore: true}
+	assert.False(t, ignored.Allows(scrobble))
+
+	scrobbleOnly := &SyncRule{Library: "Anime", Events: []PlexEvent{PlexScrobbleEvent}}
+	assert.True(t, scrobbleOnly.Allows(scrobble))
+	assert.True(t, scrobbleOnly.Allows(stop))
+	assert.False(t, scrobbleOnly.Allows(rate))
+}
+
+func TestMatchSyncRule(t *testing.T) {
+	library := &SyncRule{ID: 1, Library: "Anime"}
+	show := &SyncRule{ID: 2, RatingKey: "100"}
+	malID := &SyncRule{ID: 3, MALID: 52991}
+	rules := []*SyncRule{malID, show, library}
+
+	episode := &Plex{Metadata: Metadata{Type: PlexEpisode, LibrarySectionTitle: "Anime", RatingKey: "102", GrandparentRatingKey: "100"}}
+	other := &Plex{Metadata: Metadata{Type: PlexEpisode, LibrarySectionTitle: "Anime", RatingKey: "202", GrandparentRatingKey: "200"}}
+	movies := &Plex{Metadata: Metadata{Type: PlexMovie, LibrarySectionTitle: "Anime Movies", RatingKey: "300"}}
+
+	assert.Equal(t, malID, MatchSyncRule(rules, episode, 52991))
+	assert.Equal(t, show, MatchSyncRule(rules, episode, 0))
+	assert.Equal(t, show, MatchSyncRule(rules, episode, 1))
+	assert.Equal(t, library, MatchSyncRule(rules, other, 0))
+	assert.Nil(t, MatchSyncRule(rules, movies, 0))
+
+	assert.True(t, HasMALIDRules(rules))
+	assert.False(t, HasMALIDRules([]*SyncRule{show, library}))
+}
@@ -16,6 +16,7 @@ type Tautulli struct {
 		GUID                GUID          `json:"guid"`
 		Index               string        `json:"index"`
 		LibrarySectionTitle string        `json:"librarySectionTitle"`
+		LibrarySectionID    string        `json:"librarySectionID"`
 		ParentIndex         string        `json:"parentIndex"`
 		Title               string        `json:"title"`
 		Type                PlexMediaType `json:"type"`
@@ -49,6 +50,9 @@ func ToPlex(b []byte) (*Plex, error) {
 		return nil, err
 	}
 
+	// The section id is optional, CheckPlex looks it up by library title for payloads without it
+	sectionID, _ := strconv.Atoi(t.Metadata.LibrarySectionID)
+
 	return &Plex{
 		Event:     t.Event,
 		Source:    TautulliWebhook,
@@ -66,9 +70,42 @@ func ToPlex(b []byte) (*Plex, error) {
 			GUID:                t.Metadata.GUID,
 			Index:               index,
 			LibrarySectionTitle: t.Metadata.LibrarySectionTitle,
+			LibrarySectionID:    sectionID,
 			ParentIndex:         parentIndex,
 			Title:               t.Metadata.Title,
 			Type:                t.Metadata.Type,
 		},
 	}, nil
 }
+
+type TautulliImportRequest struct {
+	Host          string `json:"host"`
+	APIKey        string `json:"api_key"`
+	TLSSkipVerify bool   `json:"tls_skip"`
+	After         string `json:"after"`
+	RowIDs        []int  `json:"row_ids"`
+}
+
+// TautulliImportItem is a single Tautulli history entry converted into a Plex payload,
+// along with the MAL change it results in (preview) or the reason it was skipped.
+type TautulliImportItem struct {
+	RowID       int          `json:"row_id"`
+	Plex        *Plex        `json:"plex"`
+	AnimeUpdate *AnimeUpdate `json:"animeUpdate,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// IsSelected reports whether the row was picked for import. An empty selection imports everything.
+func (r *TautulliImportRequest) IsSelected(rowID int) bool {
+	if len(r.RowIDs) == 0 {
+		return true
+	}
+
+	for _, id := range r.RowIDs {
+		if id == rowID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -89,6 +89,15 @@ func TestToPlex(t *testing.T) {
 				assert.Equal(t, "Attack on Titan", plex.Metadata.GrandparentTitle)
 			},
 		},
+		{
+			name:          "library section id",
+			payload:       `{"Account":{"title":"TestUser"},"event":"media.scrobble","Metadata":{"title":"Episode 5","type":"episode","parentIndex":"1","index":"5","guid":"com.plexapp.agents.hama://anidb-12345/1/1?lang=en","librarySectionTitle":"Animation","librarySectionID":"3"}}`,
+			expectedError: false,
+			validate: func(t *testing.T, plex *Plex) {
+				assert.Equal(t, 3, plex.Metadata.LibrarySectionID)
+				assert.Equal(t, "Animation", plex.Metadata.LibrarySectionTitle)
+			},
+		},
 		{
 			name:          "valid movie conversion",
 			payload:       `{"Account":{"title":"TestUser"},"event":"media.rate","Metadata":{"title":"Your Name","type":"movie","parentIndex":"1","index":"1","guid":[{"id":"tmdb://372058"}],"grandparentKey":"","grandparentTitle":"","librarySectionTitle":"Anime Movies"}}`,
@@ -201,3 +210,12 @@ func TestToPlex_IndexConversion(t *testing.T) {
 		})
 	}
 }
+
+func TestTautulliImportRequest_IsSelected(t *testing.T) {
+	all := &TautulliImportRequest{}
+	assert.True(t, all.IsSelected(42))
+
+	some := &TautulliImportRequest{RowIDs: []int{1, 3}}
+	assert.True(t, some.IsSelected(3))
+	assert.False(t, some.IsSelected(2))
+}
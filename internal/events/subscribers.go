@@ -2,12 +2,16 @@ package events
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 
 	"github.com/asaskevich/EventBus"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/varoOP/shinkro/internal/animeupdate"
 	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/jobqueue"
 	"github.com/varoOP/shinkro/internal/notification"
 	"github.com/varoOP/shinkro/internal/plex"
 )
@@ -15,16 +19,18 @@ import (
 type Subscriber struct {
 	log      zerolog.Logger
 	eventbus EventBus.Bus
+	jobQueue jobqueue.Service
 
 	notificationService notification.Service
 	plexService         plex.Service
 	animeUpdateService  animeupdate.Service
 }
 
-func NewSubscribers(log zerolog.Logger, eventbus EventBus.Bus, notificationSvc notification.Service, plexSvc plex.Service, animeUpdateSvc animeupdate.Service) *Subscriber {
+func NewSubscribers(log zerolog.Logger, eventbus EventBus.Bus, jobQueue jobqueue.Service, notificationSvc notification.Service, plexSvc plex.Service, animeUpdateSvc animeupdate.Service) *Subscriber {
 	s := &Subscriber{
 		log:                 log.With().Str("module", "events").Logger(),
 		eventbus:            eventbus,
+		jobQueue:            jobQueue,
 		notificationService: notificationSvc,
 		plexService:         plexSvc,
 		animeUpdateService:  animeUpdateSvc,
@@ -41,6 +47,9 @@ func (s *Subscriber) Register() {
 	s.eventbus.Subscribe(domain.EventNotificationSend, s.handleNotificationSend)
 	s.eventbus.Subscribe(domain.EventAnimeUpdateSuccess, s.handleAnimeUpdateSuccess)
 	s.eventbus.Subscribe(domain.EventAnimeUpdateFailed, s.handleAnimeUpdateFailed)
+	s.eventbus.Subscribe(domain.EventPlexGUIDResolved, s.handlePlexGUIDResolved)
+
+	s.jobQueue.Register(domain.JobTypeAnimeUpdate, s.handleAnimeUpdateJob)
 }
 
 func (s *Subscriber) handlePlexProcessedSuccess(event *domain.PlexProcessedSuccessEvent) {
@@ -54,15 +63,81 @@ func (s *Subscriber) handlePlexProcessedSuccess(event *domain.PlexProcessedSucce
 		s.log.Error().Err(err).Msg("failed to store plex success status")
 	}
 
-	// Trigger MAL update asynchronously as a side effect
+	// Queue the MAL update, updates of the same anime run one after another
 	if event.AnimeUpdate != nil && event.Plex != nil {
-		go func() {
-			bgCtx := context.Background()
-			if err := s.animeUpdateService.UpdateAnimeList(bgCtx, event.AnimeUpdate, event.Plex.Event); err != nil {
-				// Error is already handled and published by UpdateAnimeList
-				s.log.Debug().Err(err).Msg("MAL update failed")
-			}
-		}()
+		job := domain.AnimeUpdateJob{
+			PlexID:      event.PlexID,
+			Event:       event.Plex.Event,
+			AnimeUpdate: event.AnimeUpdate,
+		}
+
+		if err := s.jobQueue.Enqueue(context.Background(), domain.JobTypeAnimeUpdate, s.animeUpdateJobKey(event.AnimeUpdate), job); err != nil {
+			s.log.Error().Err(err).Int64("plexID", event.PlexID).Msg("could not queue MAL update")
+		}
+	}
+}
+
+// animeUpdateJobKey serializes updates by MAL id, or by source id when the MAL id cannot be
+// resolved without contacting MyAnimeList.
+func (s *Subscriber) animeUpdateJobKey(anime *domain.AnimeUpdate) string {
+	resolved := *anime
+	if err := s.animeUpdateService.ResolveMALID(context.Background(), &resolved); err == nil && resolved.MALId > 0 {
+		return fmt.Sprintf("%s-%d", domain.MAL, resolved.MALId)
+	}
+
+	return fmt.Sprintf("%s-%d", anime.SourceDB, anime.SourceId)
+}
+
+func (s *Subscriber) handleAnimeUpdateJob(ctx context.Context, data json.RawMessage) error {
+	var job domain.AnimeUpdateJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return errors.Wrap(err, "could not unmarshal anime update job")
+	}
+
+	if job.AnimeUpdate == nil {
+		return errors.New("anime update job without anime update")
+	}
+
+	plex, err := s.plexService.Get(ctx, &domain.GetPlexRequest{Id: int(job.PlexID)})
+	if err != nil {
+		return errors.Wrapf(err, "could not find plex payload %d", job.PlexID)
+	}
+
+	job.AnimeUpdate.Plex = plex
+	if err := s.animeUpdateService.UpdateAnimeList(ctx, job.AnimeUpdate, job.Event); err != nil {
+		// Error is already handled and published by UpdateAnimeList, the episode may be sent again
+		s.log.Debug().Err(err).Msg("MAL update failed")
+		s.plexService.ClearCompleted(plex.Metadata.RatingKey)
+
+		// MyAnimeList could not be reached, the job queue retries the update
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handlePlexGUIDResolved processes payloads again that failed because the show guids could not be
+// looked up, unless they were processed successfully in the meantime.
+func (s *Subscriber) handlePlexGUIDResolved(event *domain.PlexGUIDResolvedEvent) {
+	ctx := context.Background()
+	for _, plex := range event.Plex {
+		stored, err := s.plexService.Get(ctx, &domain.GetPlexRequest{Id: int(plex.ID)})
+		if err != nil {
+			s.log.Debug().Err(err).Int64("plexID", plex.ID).Msg("could not find plex payload to process again")
+			continue
+		}
+
+		if stored.ErrorType != domain.PlexErrorExtractionFailed {
+			continue
+		}
+
+		s.log.Debug().Int64("plexID", plex.ID).Str("ratingKey", event.RatingKey).Msg("processing plex payload again")
+		if err := s.plexService.ProcessPlex(ctx, plex); err != nil {
+			s.log.Error().Err(err).Int64("plexID", plex.ID).Msg("could not process plex payload again")
+		}
 	}
 }
 
@@ -86,10 +161,12 @@ func (s *Subscriber) handlePlexProcessedError(event *domain.PlexProcessedErrorEv
 	payload := domain.NotificationPayload{
 		Message:      message,
 		Subject:      subject,
+		ErrorType:    string(event.ErrorType),
 		AnimeLibrary: event.Plex.Metadata.LibrarySectionTitle,
 		MediaName:    s.getPlexTitle(event.Plex),
 		PlexEvent:    event.Plex.Event,
 		PlexSource:   event.Plex.Source,
+		PlexID:       event.PlexID,
 		Timestamp:    event.Timestamp,
 	}
 	s.notificationService.Send(domain.NotificationEventPlexProcessingError, payload)
@@ -122,6 +199,8 @@ func (s *Subscriber) handleAnimeUpdateSuccess(event *domain.AnimeUpdateSuccessEv
 			Score:           event.AnimeUpdate.ListStatus.Score,
 			PlexEvent:       event.AnimeUpdate.Plex.Event,
 			PlexSource:      event.AnimeUpdate.Plex.Source,
+			PlexID:          event.PlexID,
+			AnimeUpdate:     event.AnimeUpdate,
 			Timestamp:       event.Timestamp,
 		}
 		s.notificationService.Send(domain.NotificationEventSuccess, payload)
@@ -147,11 +226,14 @@ func (s *Subscriber) handleAnimeUpdateFailed(event *domain.AnimeUpdateFailedEven
 	payload := domain.NotificationPayload{
 		Message:      message,
 		Subject:      subject,
+		ErrorType:    string(event.ErrorType),
 		AnimeLibrary: event.AnimeUpdate.Plex.Metadata.LibrarySectionTitle,
 		MediaName:    animeTitle,
 		MALID:        event.AnimeUpdate.MALId,
 		PlexEvent:    event.AnimeUpdate.Plex.Event,
 		PlexSource:   event.AnimeUpdate.Plex.Source,
+		PlexID:       event.AnimeUpdate.PlexId,
+		AnimeUpdate:  event.AnimeUpdate,
 		Timestamp:    event.Timestamp,
 	}
 	s.notificationService.Send(domain.NotificationEventAnimeUpdateError, payload)
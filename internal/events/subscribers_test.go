@@ -2,6 +2,8 @@ package events
 
 import (
 	"context"
+	"encoding/json"
+	"net"
 	"testing"
 	"time"
 
@@ -9,10 +11,47 @@ import (
 	"github.com/varoOP/shinkro/internal/testdata"
 
 	"github.com/asaskevich/EventBus"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+type mockJobQueue struct {
+	handlers map[domain.JobType]domain.JobHandler
+	jobs     []*domain.Job
+}
+
+func newMockJobQueue() *mockJobQueue {
+	return &mockJobQueue{handlers: make(map[domain.JobType]domain.JobHandler)}
+}
+
+func (m *mockJobQueue) Register(jobType domain.JobType, handler domain.JobHandler) {
+	m.handlers[jobType] = handler
+}
+
+func (m *mockJobQueue) RegisterWorkers(jobType domain.JobType, handler domain.JobHandler, workers int) {
+	m.handlers[jobType] = handler
+}
+
+func (m *mockJobQueue) Enqueue(ctx context.Context, jobType domain.JobType, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	m.jobs = append(m.jobs, &domain.Job{Type: jobType, Key: key, Payload: data})
+	return nil
+}
+
+func (m *mockJobQueue) Start(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockJobQueue) Shutdown(ctx context.Context) error {
+	return nil
+}
+
 // Mock services
 type mockNotificationService struct {
 	sentEvents   []domain.NotificationEvent
@@ -44,12 +83,22 @@ func (m *mockNotificationService) Test(ctx context.Context, notification *domain
 	return nil
 }
 
+func (m *mockNotificationService) Preview(ctx context.Context, req *domain.NotificationPreviewRequest) (*domain.NotificationPreview, error) {
+	return nil, nil
+}
+
+func (m *mockNotificationService) FindDeliveries(ctx context.Context, notificationID int) ([]domain.NotificationDelivery, error) {
+	return nil, nil
+}
+
 type mockPlexService struct {
+	plex             *domain.Plex
 	updateStatusErr  error
 	lastPlexID       int64
 	lastSuccess      *bool
 	lastErrorType    string
 	lastErrorMessage string
+	processed        []int64
 }
 
 func (m *mockPlexService) Store(ctx context.Context, plex *domain.Plex) error {
@@ -57,17 +106,34 @@ func (m *mockPlexService) Store(ctx context.Context, plex *domain.Plex) error {
 }
 
 func (m *mockPlexService) Get(ctx context.Context, req *domain.GetPlexRequest) (*domain.Plex, error) {
-	return nil, nil
+	return m.plex, nil
 }
 
 func (m *mockPlexService) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
+	m.processed = append(m.processed, plex.ID)
 	return nil
 }
 
+func (m *mockPlexService) PreviewPlex(ctx context.Context, plex *domain.Plex) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) SuppressScrobble(ratingKey string) {}
+
+func (m *mockPlexService) ClearCompleted(ratingKey string) {}
+
 func (m *mockPlexService) GetPlexSettings(ctx context.Context) (*domain.PlexSettings, error) {
 	return nil, nil
 }
 
+func (m *mockPlexService) GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.GetPlexSettings(ctx)
+}
+
+func (m *mockPlexService) CheckPlexPreview(ctx context.Context, p *domain.Plex, ps *domain.PlexSettings) error {
+	return nil
+}
+
 func (m *mockPlexService) CheckPlex(ctx context.Context, p *domain.Plex, ps *domain.PlexSettings) error {
 	return nil
 }
@@ -101,6 +167,7 @@ func (m *mockPlexService) UpdateStatus(ctx context.Context, plexID int64, succes
 }
 
 type mockAnimeUpdateService struct {
+	malID     int
 	updateErr error
 	lastAnime *domain.AnimeUpdate
 	lastEvent domain.PlexEvent
@@ -120,6 +187,15 @@ func (m *mockAnimeUpdateService) UpdateAnimeList(ctx context.Context, anime *dom
 	return m.updateErr
 }
 
+func (m *mockAnimeUpdateService) PreviewAnimeList(ctx context.Context, anime *domain.AnimeUpdate, event domain.PlexEvent) error {
+	return nil
+}
+
+func (m *mockAnimeUpdateService) ResolveMALID(ctx context.Context, anime *domain.AnimeUpdate) error {
+	anime.MALId = m.malID
+	return nil
+}
+
 func (m *mockAnimeUpdateService) Count(ctx context.Context) (int, error) {
 	return 0, nil
 }
@@ -140,6 +216,10 @@ func (m *mockAnimeUpdateService) FindAllWithFilters(ctx context.Context, params
 	return nil, nil
 }
 
+func (m *mockAnimeUpdateService) Revert(ctx context.Context, id int) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
 func TestSubscriber_GetAnimeTitle(t *testing.T) {
 	subscriber := &Subscriber{
 		log: zerolog.Nop(),
@@ -485,7 +565,7 @@ func TestSubscriber_HandlePlexProcessedSuccess(t *testing.T) {
 	plexSvc := &mockPlexService{}
 	animeUpdateSvc := &mockAnimeUpdateService{}
 
-	_ = NewSubscribers(zerolog.Nop(), bus, notificationSvc, plexSvc, animeUpdateSvc)
+	_ = NewSubscribers(zerolog.Nop(), bus, newMockJobQueue(), notificationSvc, plexSvc, animeUpdateSvc)
 
 	event := &domain.PlexProcessedSuccessEvent{
 		PlexID:      12345,
@@ -506,13 +586,71 @@ func TestSubscriber_HandlePlexProcessedSuccess(t *testing.T) {
 	assert.True(t, *plexSvc.lastSuccess)
 }
 
+func TestSubscriber_HandlePlexProcessedSuccess_QueuesAnimeUpdate(t *testing.T) {
+	bus := EventBus.New()
+	jobQueue := newMockJobQueue()
+	plexSvc := &mockPlexService{plex: testdata.NewMockPlex()}
+	animeUpdateSvc := &mockAnimeUpdateService{malID: 21}
+
+	_ = NewSubscribers(zerolog.Nop(), bus, jobQueue, &mockNotificationService{}, plexSvc, animeUpdateSvc)
+
+	animeUpdate := testdata.NewMockAnimeUpdate()
+	animeUpdate.MALId = 0
+	bus.Publish(domain.EventPlexProcessedSuccess, &domain.PlexProcessedSuccessEvent{
+		PlexID:      12345,
+		Plex:        testdata.NewMockPlex(),
+		AnimeUpdate: animeUpdate,
+		Timestamp:   time.Now(),
+	})
+
+	require.Len(t, jobQueue.jobs, 1)
+	job := jobQueue.jobs[0]
+	assert.Equal(t, domain.JobTypeAnimeUpdate, job.Type)
+	assert.Equal(t, "myanimelist-21", job.Key)
+	// The MAL id is only resolved for the key, the update resolves it again
+	assert.Equal(t, 0, animeUpdate.MALId)
+	assert.Nil(t, animeUpdateSvc.lastAnime)
+
+	err := jobQueue.handlers[domain.JobTypeAnimeUpdate](context.Background(), job.Payload)
+	require.NoError(t, err)
+
+	require.NotNil(t, animeUpdateSvc.lastAnime)
+	assert.Equal(t, domain.PlexScrobbleEvent, animeUpdateSvc.lastEvent)
+	assert.Equal(t, animeUpdate.SourceId, animeUpdateSvc.lastAnime.SourceId)
+	assert.Equal(t, plexSvc.plex, animeUpdateSvc.lastAnime.Plex)
+}
+
+func TestSubscriber_HandleAnimeUpdateJob_RetriesNetworkErrors(t *testing.T) {
+	jobQueue := newMockJobQueue()
+	animeUpdateSvc := &mockAnimeUpdateService{malID: 21}
+
+	_ = NewSubscribers(zerolog.Nop(), EventBus.New(), jobQueue, &mockNotificationService{}, &mockPlexService{plex: testdata.NewMockPlex()}, animeUpdateSvc)
+
+	payload, err := json.Marshal(domain.AnimeUpdateJob{PlexID: 12345, Event: domain.PlexScrobbleEvent, AnimeUpdate: testdata.NewMockAnimeUpdate()})
+	require.NoError(t, err)
+
+	// Errors of MyAnimeList itself are not retried
+	animeUpdateSvc.updateErr = errors.New("anime not found")
+	assert.NoError(t, jobQueue.handlers[domain.JobTypeAnimeUpdate](context.Background(), payload))
+
+	animeUpdateSvc.updateErr = errors.Wrap(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, "could not update MAL")
+	assert.Error(t, jobQueue.handlers[domain.JobTypeAnimeUpdate](context.Background(), payload))
+}
+
+func TestSubscriber_AnimeUpdateJobKey_FallsBackToSource(t *testing.T) {
+	subscriber := &Subscriber{animeUpdateService: &mockAnimeUpdateService{}}
+
+	key := subscriber.animeUpdateJobKey(&domain.AnimeUpdate{SourceDB: domain.TVDB, SourceId: 81797})
+	assert.Equal(t, "tvdb-81797", key)
+}
+
 func TestSubscriber_HandlePlexProcessedError(t *testing.T) {
 	bus := EventBus.New()
 	notificationSvc := &mockNotificationService{}
 	plexSvc := &mockPlexService{}
 	animeUpdateSvc := &mockAnimeUpdateService{}
 
-	_ = NewSubscribers(zerolog.Nop(), bus, notificationSvc, plexSvc, animeUpdateSvc)
+	_ = NewSubscribers(zerolog.Nop(), bus, newMockJobQueue(), notificationSvc, plexSvc, animeUpdateSvc)
 
 	event := &domain.PlexProcessedErrorEvent{
 		PlexID:       12345,
@@ -540,13 +678,30 @@ func TestSubscriber_HandlePlexProcessedError(t *testing.T) {
 	assert.Equal(t, domain.NotificationEventPlexProcessingError, notificationSvc.sentEvents[0])
 }
 
+func TestSubscriber_HandlePlexGUIDResolved(t *testing.T) {
+	bus := EventBus.New()
+	plexSvc := &mockPlexService{plex: &domain.Plex{ErrorType: domain.PlexErrorExtractionFailed}}
+
+	_ = NewSubscribers(zerolog.Nop(), bus, newMockJobQueue(), &mockNotificationService{}, plexSvc, &mockAnimeUpdateService{})
+
+	failed := testdata.NewMockPlex()
+	failed.ID = 7
+	bus.Publish(domain.EventPlexGUIDResolved, &domain.PlexGUIDResolvedEvent{RatingKey: "100", Plex: []*domain.Plex{failed}})
+	assert.Equal(t, []int64{7}, plexSvc.processed)
+
+	// payloads processed in the meantime are left alone
+	plexSvc.plex = &domain.Plex{}
+	bus.Publish(domain.EventPlexGUIDResolved, &domain.PlexGUIDResolvedEvent{RatingKey: "100", Plex: []*domain.Plex{failed}})
+	assert.Equal(t, []int64{7}, plexSvc.processed)
+}
+
 func TestSubscriber_HandleNotificationSend(t *testing.T) {
 	bus := EventBus.New()
 	notificationSvc := &mockNotificationService{}
 	plexSvc := &mockPlexService{}
 	animeUpdateSvc := &mockAnimeUpdateService{}
 
-	_ = NewSubscribers(zerolog.Nop(), bus, notificationSvc, plexSvc, animeUpdateSvc)
+	_ = NewSubscribers(zerolog.Nop(), bus, newMockJobQueue(), notificationSvc, plexSvc, animeUpdateSvc)
 
 	payload := domain.NotificationPayload{
 		Subject: "Test Subject",
@@ -577,7 +732,7 @@ func TestSubscriber_HandleAnimeUpdateSuccess(t *testing.T) {
 	plexSvc := &mockPlexService{}
 	animeUpdateSvc := &mockAnimeUpdateService{}
 
-	_ = NewSubscribers(zerolog.Nop(), bus, notificationSvc, plexSvc, animeUpdateSvc)
+	_ = NewSubscribers(zerolog.Nop(), bus, newMockJobQueue(), notificationSvc, plexSvc, animeUpdateSvc)
 
 	animeUpdate := testdata.NewMockAnimeUpdate()
 	animeUpdate.ListDetails = domain.ListDetails{
@@ -613,7 +768,7 @@ func TestSubscriber_HandleAnimeUpdateFailed(t *testing.T) {
 	plexSvc := &mockPlexService{}
 	animeUpdateSvc := &mockAnimeUpdateService{}
 
-	_ = NewSubscribers(zerolog.Nop(), bus, notificationSvc, plexSvc, animeUpdateSvc)
+	_ = NewSubscribers(zerolog.Nop(), bus, newMockJobQueue(), notificationSvc, plexSvc, animeUpdateSvc)
 
 	animeUpdate := testdata.NewMockAnimeUpdate()
 	animeUpdate.Plex = testdata.NewMockPlex()
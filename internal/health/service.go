@@ -0,0 +1,213 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/plex"
+)
+
+const (
+	// checkTimeout limits each component check, so a hanging dependency cannot stall probes.
+	checkTimeout = 10 * time.Second
+
+	// animeMaxAge is how old the ShinkroDB data may get, it is updated weekly.
+	animeMaxAge = 14 * 24 * time.Hour
+)
+
+type Service interface {
+	Readiness(ctx context.Context) *domain.Readiness
+}
+
+type database interface {
+	Ping() error
+	SchemaVersion(ctx context.Context) (int, int, error)
+}
+
+type animeService interface {
+	LastUpdated(ctx context.Context) (time.Time, error)
+}
+
+type mappingService interface {
+	IsCached() bool
+}
+
+type malauthService interface {
+	TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error)
+}
+
+type plexsettingsService interface {
+	Get(ctx context.Context) (*domain.PlexSettings, error)
+	GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error)
+}
+
+type service struct {
+	log          zerolog.Logger
+	db           database
+	anime        animeService
+	mapping      mappingService
+	malauth      malauthService
+	plexsettings plexsettingsService
+}
+
+func NewService(log zerolog.Logger, db database, animeSvc animeService, mappingSvc mappingService, malauthSvc malauthService, plexsettingsSvc plexsettingsService) Service {
+	return &service{
+		log:          log.With().Str("module", "health").Logger(),
+		db:           db,
+		anime:        animeSvc,
+		mapping:      mappingSvc,
+		malauth:      malauthSvc,
+		plexsettings: plexsettingsSvc,
+	}
+}
+
+type check struct {
+	name     string
+	critical bool
+	run      func(ctx context.Context) (domain.HealthStatus, string)
+}
+
+// Readiness checks the dependencies of shinkro concurrently. It is served without authentication,
+// so messages never contain error details, those are only logged.
+func (s *service) Readiness(ctx context.Context) *domain.Readiness {
+	checks := []check{
+		{name: "database", critical: true, run: s.checkDatabase},
+		{name: "migrations", critical: true, run: s.checkMigrations},
+		{name: "mal", run: s.checkMAL},
+		{name: "plex", run: s.checkPlex},
+		{name: "mapping", run: s.checkMapping},
+		{name: "shinkrodb", run: s.checkShinkroDB},
+	}
+
+	components := make([]domain.ComponentHealth, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			status, msg := c.run(ctx)
+			if status != domain.HealthOK && status != domain.HealthSkipped {
+				s.log.Debug().Str("component", c.name).Str("status", string(status)).Msg(msg)
+			}
+
+			components[i] = domain.ComponentHealth{
+				Name:     c.name,
+				Status:   status,
+				Message:  msg,
+				Critical: c.critical,
+			}
+		}()
+	}
+	wg.Wait()
+
+	return domain.NewReadiness(components, time.Now())
+}
+
+func (s *service) checkDatabase(ctx context.Context) (domain.HealthStatus, string) {
+	if err := s.db.Ping(); err != nil {
+		s.log.Warn().Err(err).Msg("database check failed")
+		return domain.HealthFailed, "database unavailable"
+	}
+
+	return domain.HealthOK, ""
+}
+
+func (s *service) checkMigrations(ctx context.Context) (domain.HealthStatus, string) {
+	version, expected, err := s.db.SchemaVersion(ctx)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("migrations check failed")
+		return domain.HealthFailed, "schema version unknown"
+	}
+
+	if version != expected {
+		return domain.HealthFailed, fmt.Sprintf("schema version %d, expected %d", version, expected)
+	}
+
+	return domain.HealthOK, fmt.Sprintf("schema version %d", version)
+}
+
+// checkMAL reads the state of the stored token, it never refreshes the token.
+func (s *service) checkMAL(ctx context.Context) (domain.HealthStatus, string) {
+	status, err := s.malauth.TokenStatus(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.HealthSkipped, "not authenticated"
+		}
+		s.log.Warn().Err(err).Msg("mal check failed")
+		return domain.HealthDegraded, "token unavailable"
+	}
+
+	switch status.State {
+	case domain.MalAuthTokenInvalid:
+		s.log.Warn().Str("error", status.Error).Msg("mal token is invalid")
+		return domain.HealthDegraded, "re-authentication required"
+	case domain.MalAuthTokenExpiring:
+		s.log.Warn().Str("error", status.Error).Msg("mal token could not be refreshed")
+		return domain.HealthDegraded, "token expiring"
+	}
+
+	return domain.HealthOK, ""
+}
+
+func (s *service) checkPlex(ctx context.Context) (domain.HealthStatus, string) {
+	ps, err := s.plexsettings.Get(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.HealthSkipped, "not configured"
+		}
+		s.log.Warn().Err(err).Msg("plex check failed")
+		return domain.HealthDegraded, "settings unavailable"
+	}
+
+	pc, err := s.plexsettings.GetClient(ctx, ps)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("plex check failed")
+		return domain.HealthDegraded, "client unavailable"
+	}
+
+	if err := pc.TestConnection(ctx); err != nil {
+		s.log.Warn().Err(err).Msg("plex check failed")
+		return domain.HealthDegraded, "server unreachable"
+	}
+
+	return domain.HealthOK, ""
+}
+
+// checkMapping reports the anime map cache, which is loaded on first use.
+func (s *service) checkMapping(ctx context.Context) (domain.HealthStatus, string) {
+	if s.mapping.IsCached() {
+		return domain.HealthOK, "loaded"
+	}
+
+	return domain.HealthOK, "not loaded yet"
+}
+
+func (s *service) checkShinkroDB(ctx context.Context) (domain.HealthStatus, string) {
+	updated, err := s.anime.LastUpdated(ctx)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("shinkrodb check failed")
+		return domain.HealthDegraded, "anime database unavailable"
+	}
+
+	if updated.IsZero() {
+		return domain.HealthDegraded, "anime database is empty"
+	}
+
+	msg := fmt.Sprintf("last updated %s", updated.UTC().Format(time.RFC3339))
+	if time.Since(updated) > animeMaxAge {
+		return domain.HealthDegraded, msg
+	}
+
+	return domain.HealthOK, msg
+}
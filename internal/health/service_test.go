@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/plex"
+)
+
+type mockDatabase struct {
+	pingErr  error
+	version  int
+	expected int
+}
+
+func (m *mockDatabase) Ping() error {
+	return m.pingErr
+}
+
+func (m *mockDatabase) SchemaVersion(ctx context.Context) (int, int, error) {
+	return m.version, m.expected, nil
+}
+
+type mockAnimeService struct {
+	updated time.Time
+}
+
+func (m *mockAnimeService) LastUpdated(ctx context.Context) (time.Time, error) {
+	return m.updated, nil
+}
+
+type mockMappingService struct {
+	cached bool
+}
+
+func (m *mockMappingService) IsCached() bool {
+	return m.cached
+}
+
+type mockMalauthService struct {
+	state domain.MalAuthTokenState
+	err   error
+}
+
+func (m *mockMalauthService) TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.state == "" {
+		return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+	}
+	return &domain.MalAuthTokenStatus{State: m.state, Error: "invalid_grant"}, nil
+}
+
+type mockPlexsettingsService struct{}
+
+func (m *mockPlexsettingsService) Get(ctx context.Context) (*domain.PlexSettings, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockPlexsettingsService) GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error) {
+	return nil, errors.New("not configured")
+}
+
+func components(r *domain.Readiness) map[string]domain.ComponentHealth {
+	m := make(map[string]domain.ComponentHealth)
+	for _, c := range r.Components {
+		m[c.Name] = c
+	}
+	return m
+}
+
+func TestService_Readiness(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		svc := NewService(zerolog.Nop(), &mockDatabase{version: 3, expected: 3}, &mockAnimeService{updated: time.Now()}, &mockMappingService{cached: true}, &mockMalauthService{}, &mockPlexsettingsService{})
+
+		r := svc.Readiness(context.Background())
+		assert.Equal(t, domain.HealthOK, r.Status)
+
+		c := components(r)
+		assert.Len(t, c, 6)
+		assert.Equal(t, domain.HealthOK, c["mal"].Status)
+		assert.Equal(t, domain.HealthSkipped, c["plex"].Status)
+		assert.Equal(t, "loaded", c["mapping"].Message)
+		assert.True(t, c["database"].Critical)
+	})
+
+	t.Run("degraded by optional components", func(t *testing.T) {
+		svc := NewService(zerolog.Nop(), &mockDatabase{version: 3, expected: 3}, &mockAnimeService{updated: time.Now().Add(-30 * 24 * time.Hour)}, &mockMappingService{}, &mockMalauthService{state: domain.MalAuthTokenInvalid}, &mockPlexsettingsService{})
+
+		r := svc.Readiness(context.Background())
+		assert.Equal(t, domain.HealthDegraded, r.Status)
+		assert.True(t, r.IsReady())
+
+		c := components(r)
+		assert.Equal(t, domain.HealthDegraded, c["mal"].Status)
+		assert.Equal(t, "re-authentication required", c["mal"].Message)
+		assert.Equal(t, domain.HealthDegraded, c["shinkrodb"].Status)
+	})
+
+	t.Run("not authenticated with MAL", func(t *testing.T) {
+		svc := NewService(zerolog.Nop(), &mockDatabase{version: 3, expected: 3}, &mockAnimeService{updated: time.Now()}, &mockMappingService{}, &mockMalauthService{err: sql.ErrNoRows}, &mockPlexsettingsService{})
+
+		r := svc.Readiness(context.Background())
+		assert.Equal(t, domain.HealthOK, r.Status)
+		assert.Equal(t, domain.HealthSkipped, components(r)["mal"].Status)
+	})
+
+	t.Run("failed by database", func(t *testing.T) {
+		svc := NewService(zerolog.Nop(), &mockDatabase{pingErr: errors.New("database is closed"), version: 2, expected: 3}, &mockAnimeService{}, &mockMappingService{}, &mockMalauthService{}, &mockPlexsettingsService{})
+
+		r := svc.Readiness(context.Background())
+		assert.Equal(t, domain.HealthFailed, r.Status)
+		assert.False(t, r.IsReady())
+
+		c := components(r)
+		assert.Equal(t, domain.HealthFailed, c["database"].Status)
+		assert.Equal(t, "database unavailable", c["database"].Message)
+		assert.Equal(t, "schema version 2, expected 3", c["migrations"].Message)
+		assert.Equal(t, "anime database is empty", c["shinkrodb"].Message)
+	})
+}
@@ -2,10 +2,12 @@ package http
 
 import (
 	"context"
+	"database/sql"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
 	"github.com/varoOP/shinkro/internal/domain"
 )
 
@@ -14,6 +16,7 @@ type animeupdateService interface {
 	GetRecentUnique(ctx context.Context, limit int) ([]*domain.AnimeUpdate, error)
 	GetByPlexID(ctx context.Context, plexID int64) (*domain.AnimeUpdate, error)
 	FindAllWithFilters(ctx context.Context, params domain.AnimeUpdateQueryParams) (*domain.FindAnimeUpdatesResponse, error)
+	Revert(ctx context.Context, id int) (*domain.AnimeUpdate, error)
 }
 
 type animeupdateHandler struct {
@@ -33,6 +36,7 @@ func (h animeupdateHandler) Routes(r chi.Router) {
 	r.Get("/recent", h.getRecent)
 	r.Get("/byPlexId", h.getByPlexID)
 	r.Get("/list", h.getList)
+	r.Post("/{id}/revert", h.revert)
 }
 
 func (h animeupdateHandler) getCount(w http.ResponseWriter, r *http.Request) {
@@ -169,3 +173,32 @@ func (h animeupdateHandler) getList(w http.ResponseWriter, r *http.Request) {
 
 	h.encoder.StatusResponse(w, http.StatusOK, resp)
 }
+
+func (h animeupdateHandler) revert(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid id param"})
+		return
+	}
+
+	update, err := h.service.Revert(r.Context(), id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		h.encoder.NotFoundErr(w, errors.New("anime update not found"))
+		return
+	case errors.Is(err, domain.ErrAnimeUpdateNotRevertable):
+		h.encoder.StatusResponse(w, http.StatusConflict, map[string]interface{}{
+			"code":    "ANIME_UPDATE_NOT_REVERTABLE",
+			"message": err.Error(),
+		})
+		return
+	case err != nil:
+		h.encoder.StatusResponse(w, http.StatusInternalServerError, map[string]interface{}{
+			"code":    "ANIME_UPDATE_REVERT_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, update)
+}
@@ -76,6 +76,13 @@ func setupTestHTTPServer(t *testing.T) (*httptest.Server, *database.DB, api.Serv
 		nil, // fsService
 		nil, // notificationService
 		nil, // animeUpdateService
+		nil, // tautulliService
+		nil, // reconcileService
+		nil, // reverseSyncService
+		nil, // libraryScanService
+		nil, // syncRuleService
+		nil, // ingestService
+		nil, // healthService
 		serverEvents,
 	)
 
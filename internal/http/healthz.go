@@ -1,7 +1,10 @@
 package http
 
 import (
+	"context"
 	"net/http"
+
+	"github.com/varoOP/shinkro/internal/domain"
 )
 
 // LivenessHandler handles the /healthz/liveness endpoint
@@ -11,3 +14,32 @@ func LivenessHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(`{"status":"ok"}`))
 }
+
+type healthService interface {
+	Readiness(ctx context.Context) *domain.Readiness
+}
+
+type healthzHandler struct {
+	encoder encoder
+	service healthService
+}
+
+func newHealthzHandler(encoder encoder, service healthService) *healthzHandler {
+	return &healthzHandler{
+		encoder: encoder,
+		service: service,
+	}
+}
+
+// readiness handles the /healthz/readiness endpoint, it answers 503 when a critical dependency like
+// the database failed and 200 otherwise, with the status of every component in the body.
+func (h healthzHandler) readiness(w http.ResponseWriter, r *http.Request) {
+	readiness := h.service.Readiness(r.Context())
+
+	status := http.StatusOK
+	if !readiness.IsReady() {
+		status = http.StatusServiceUnavailable
+	}
+
+	h.encoder.StatusResponse(w, status, readiness)
+}
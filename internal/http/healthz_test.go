@@ -1,11 +1,16 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varoOP/shinkro/internal/domain"
 )
 
 func TestLivenessHandler(t *testing.T) {
@@ -35,3 +40,57 @@ func TestLivenessHandler_MultipleCalls(t *testing.T) {
 	}
 }
 
+type mockHealthService struct {
+	components []domain.ComponentHealth
+}
+
+func (m *mockHealthService) Readiness(ctx context.Context) *domain.Readiness {
+	return domain.NewReadiness(m.components, time.Now())
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []domain.ComponentHealth
+		wantCode   int
+		wantStatus domain.HealthStatus
+	}{
+		{
+			name:       "ready",
+			components: []domain.ComponentHealth{{Name: "database", Status: domain.HealthOK, Critical: true}},
+			wantCode:   http.StatusOK,
+			wantStatus: domain.HealthOK,
+		},
+		{
+			name: "degraded is still ready",
+			components: []domain.ComponentHealth{
+				{Name: "database", Status: domain.HealthOK, Critical: true},
+				{Name: "plex", Status: domain.HealthDegraded, Message: "network error"},
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: domain.HealthDegraded,
+		},
+		{
+			name:       "database down",
+			components: []domain.ComponentHealth{{Name: "database", Status: domain.HealthFailed, Critical: true}},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: domain.HealthFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/healthz/readiness", nil)
+			w := httptest.NewRecorder()
+
+			newHealthzHandler(encoder{}, &mockHealthService{components: tt.components}).readiness(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			var readiness domain.Readiness
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&readiness))
+			assert.Equal(t, tt.wantStatus, readiness.Status)
+			assert.Len(t, readiness.Components, len(tt.components))
+		})
+	}
+}
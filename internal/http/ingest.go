@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/hlog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type ingestService interface {
+	List(ctx context.Context) ([]*domain.IngestToken, error)
+	Store(ctx context.Context, token *domain.IngestToken) error
+	Update(ctx context.Context, token *domain.IngestToken) error
+	Rotate(ctx context.Context, id int) (string, error)
+	Delete(ctx context.Context, id int) error
+	Authenticate(ctx context.Context, source domain.IngestSource, token, remoteAddr string) (*domain.IngestToken, error)
+}
+
+type ingestHandler struct {
+	encoder        encoder
+	service        ingestService
+	trustedProxies []string
+}
+
+func newIngestHandler(encoder encoder, service ingestService, trustedProxies []string) *ingestHandler {
+	return &ingestHandler{
+		encoder:        encoder,
+		service:        service,
+		trustedProxies: trustedProxies,
+	}
+}
+
+func (h ingestHandler) Routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.store)
+
+	r.Route("/{tokenID}", func(r chi.Router) {
+		r.Put("/", h.update)
+		r.Delete("/", h.delete)
+		r.Post("/rotate", h.rotate)
+	})
+}
+
+// authenticate lets webhooks sent to /ingest/{source}/{token} through when the token belongs to the
+// source and the payload is the one the source sends. The allow list of the token is checked against
+// the connection address, forwarded headers only count when they come from a trusted proxy.
+func (h ingestHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := domain.IngestSource(chi.URLParam(r, "source"))
+		if source.PayloadSource() == "" {
+			h.encoder.NotFoundErr(w, errors.Errorf("unsupported ingest source: %s", source))
+			return
+		}
+
+		_, err := h.service.Authenticate(r.Context(), source, chi.URLParam(r, "token"), clientAddr(r, h.trustedProxies))
+		if err != nil {
+			hlog.FromRequest(r).Debug().Err(err).Str("source", string(source)).Msg("ingest request rejected")
+
+			switch {
+			case errors.Is(err, domain.ErrIngestTokenInvalid):
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			case errors.Is(err, domain.ErrIngestIPNotAllowed):
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			default:
+				h.encoder.Error(w, err)
+			}
+			return
+		}
+
+		if contentType(r) != source.PayloadSource() {
+			h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+				"code":    "BAD_REQUEST",
+				"message": "payload does not match the ingest source",
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h ingestHandler) list(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.service.List(r.Context())
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, tokens)
+}
+
+func (h ingestHandler) store(w http.ResponseWriter, r *http.Request) {
+	var token domain.IngestToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	if err := h.service.Store(r.Context(), &token); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "INGEST_TOKEN_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusCreated, token)
+}
+
+func (h ingestHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	var token domain.IngestToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	token.ID = id
+	if err := h.service.Update(r.Context(), &token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.encoder.NotFoundErr(w, errors.New("ingest token not found"))
+			return
+		}
+
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "INGEST_TOKEN_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
+
+func (h ingestHandler) rotate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	token, err := h.service.Rotate(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.encoder.NotFoundErr(w, errors.New("ingest token not found"))
+			return
+		}
+
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, map[string]interface{}{
+		"token": token,
+	})
+}
+
+func (h ingestHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type libraryScanService interface {
+	Scan(ctx context.Context) error
+}
+
+type libraryScanHandler struct {
+	encoder encoder
+	service libraryScanService
+}
+
+func newLibraryScanHandler(encoder encoder, service libraryScanService) *libraryScanHandler {
+	return &libraryScanHandler{
+		encoder: encoder,
+		service: service,
+	}
+}
+
+func (h libraryScanHandler) Routes(r chi.Router) {
+	r.Post("/run", h.run)
+}
+
+func (h libraryScanHandler) run(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Scan(r.Context()); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "LIBRARY_SCAN_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
@@ -19,6 +19,7 @@ type malauthService interface {
 	Delete(ctx context.Context) error
 	GetMalClient(ctx context.Context) (*mal.Client, error)
 	GetDecrypted(ctx context.Context) (*domain.MalAuth, error)
+	RateLimitStatus() domain.MALRateLimitStatus
 }
 
 type maConfig struct {
@@ -42,6 +43,7 @@ func newmalauthHandler(encoder encoder, service malauthService, cookieStore *ses
 
 func (h malauthHandler) Routes(r chi.Router) {
 	r.Get("/test", h.test)
+	r.Get("/ratelimit", h.rateLimit)
 	r.Get("/", h.get)
 	r.Post("/", h.startOauth)
 	r.Delete("/", h.delete)
@@ -217,3 +219,7 @@ func (h malauthHandler) test(w http.ResponseWriter, r *http.Request) {
 	h.encoder.StatusResponseMessage(w, http.StatusOK, "mal auth test success")
 
 }
+
+func (h malauthHandler) rateLimit(w http.ResponseWriter, r *http.Request) {
+	h.encoder.StatusResponse(w, http.StatusOK, h.service.RateLimitStatus())
+}
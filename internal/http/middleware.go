@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -83,6 +84,49 @@ func (s Server) IsAuthenticated(next http.Handler) http.Handler {
 	})
 }
 
+type peer string
+
+const peerkey peer = "peer"
+
+// connectionAddr keeps the address of the connection, before middleware.RealIP replaces r.RemoteAddr
+// with the forwarded headers any client can send.
+func connectionAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), peerkey, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientAddr returns the address of the client that sent r. The forwarded headers are only used when
+// the connection comes from one of trustedProxies, X-Forwarded-For is read from the right and stops
+// at the first address that is not a trusted proxy.
+func clientAddr(r *http.Request, trustedProxies []string) string {
+	addr, ok := r.Context().Value(peerkey).(string)
+	if !ok {
+		addr = r.RemoteAddr
+	}
+
+	if !domain.MatchesIP(trustedProxies, addr) {
+		return addr
+	}
+
+	if xff := strings.Join(r.Header.Values("X-Forwarded-For"), ","); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if i == 0 || !domain.MatchesIP(trustedProxies, hop) {
+				return hop
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+
+	return addr
+}
+
 func parsePlexPayload(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log := hlog.FromRequest(r)
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientAddr(t *testing.T) {
+	trusted := []string{"172.16.0.0/12"}
+
+	newRequest := func(peer string, header http.Header) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/ingest/plex/token", nil)
+		r.Header = header
+		// RealIP has already replaced RemoteAddr with the forwarded address
+		r.RemoteAddr = "10.1.2.3"
+		return r.WithContext(context.WithValue(r.Context(), peerkey, peer))
+	}
+
+	tests := []struct {
+		name     string
+		peer     string
+		header   http.Header
+		expected string
+	}{
+		{
+			name:     "direct connection",
+			peer:     "192.168.1.5:40000",
+			header:   http.Header{},
+			expected: "192.168.1.5:40000",
+		},
+		{
+			name:     "spoofed headers from untrusted client",
+			peer:     "203.0.113.7:40000",
+			header:   http.Header{"X-Forwarded-For": {"10.1.2.3"}, "X-Real-Ip": {"10.1.2.3"}},
+			expected: "203.0.113.7:40000",
+		},
+		{
+			name:     "forwarded by trusted proxy",
+			peer:     "172.18.0.2:40000",
+			header:   http.Header{"X-Forwarded-For": {"10.1.2.3, 192.168.1.5"}},
+			expected: "192.168.1.5",
+		},
+		{
+			name:     "chain of trusted proxies",
+			peer:     "172.18.0.2:40000",
+			header:   http.Header{"X-Forwarded-For": {"192.168.1.5, 172.18.0.3"}},
+			expected: "192.168.1.5",
+		},
+		{
+			name:     "real ip from trusted proxy",
+			peer:     "172.18.0.2:40000",
+			header:   http.Header{"X-Real-Ip": {"192.168.1.5"}},
+			expected: "192.168.1.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, clientAddr(newRequest(tt.peer, tt.header), trusted))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -9,6 +10,7 @@ import (
 	"github.com/varoOP/shinkro/internal/domain"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
 )
 
 type notificationService interface {
@@ -17,6 +19,8 @@ type notificationService interface {
 	Update(ctx context.Context, notification *domain.Notification) error
 	Delete(ctx context.Context, id int) error
 	Test(ctx context.Context, notification *domain.Notification) error
+	Preview(ctx context.Context, req *domain.NotificationPreviewRequest) (*domain.NotificationPreview, error)
+	FindDeliveries(ctx context.Context, notificationID int) ([]domain.NotificationDelivery, error)
 }
 
 type notificationHandler struct {
@@ -35,10 +39,12 @@ func (h notificationHandler) Routes(r chi.Router) {
 	r.Get("/", h.list)
 	r.Post("/", h.store)
 	r.Post("/test", h.test)
+	r.Post("/preview", h.preview)
 
 	r.Route("/{notificationID}", func(r chi.Router) {
 		r.Put("/", h.update)
 		r.Delete("/", h.delete)
+		r.Get("/deliveries", h.deliveries)
 	})
 }
 
@@ -60,6 +66,9 @@ func (h notificationHandler) store(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err := h.service.Store(r.Context(), data)
+	if h.settingsError(w, err) {
+		return
+	}
 	if err != nil {
 		h.encoder.Error(w, err)
 		return
@@ -76,6 +85,9 @@ func (h notificationHandler) update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err := h.service.Update(r.Context(), data)
+	if h.settingsError(w, err) {
+		return
+	}
 	if err != nil {
 		h.encoder.Error(w, err)
 		return
@@ -99,6 +111,22 @@ func (h notificationHandler) delete(w http.ResponseWriter, r *http.Request) {
 	h.encoder.StatusResponse(w, http.StatusNoContent, nil)
 }
 
+func (h notificationHandler) deliveries(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := strconv.Atoi(chi.URLParam(r, "notificationID"))
+	if err != nil {
+		h.encoder.StatusError(w, http.StatusBadRequest, errors.Wrap(err, "invalid notification id"))
+		return
+	}
+
+	deliveries, err := h.service.FindDeliveries(r.Context(), notificationID)
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, deliveries)
+}
+
 func (h notificationHandler) test(w http.ResponseWriter, r *http.Request) {
 	var data *domain.Notification
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
@@ -113,3 +141,46 @@ func (h notificationHandler) test(w http.ResponseWriter, r *http.Request) {
 
 	h.encoder.NoContent(w)
 }
+
+func (h notificationHandler) preview(w http.ResponseWriter, r *http.Request) {
+	var data *domain.NotificationPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.encoder.StatusError(w, http.StatusBadRequest, errors.Wrap(err, "could not decode json"))
+		return
+	}
+
+	preview, err := h.service.Preview(r.Context(), data)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		h.encoder.NotFoundErr(w, errors.New("anime update not found"))
+		return
+	case h.settingsError(w, err):
+		return
+	case err != nil:
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, preview)
+}
+
+// settingsError responds with bad request for invalid templates or digest schedules and reports
+// whether it did.
+func (h notificationHandler) settingsError(w http.ResponseWriter, err error) bool {
+	var code string
+	switch {
+	case errors.Is(err, domain.ErrNotificationTemplateInvalid):
+		code = "NOTIFICATION_TEMPLATE_ERROR"
+	case errors.Is(err, domain.ErrNotificationDigestScheduleInvalid):
+		code = "NOTIFICATION_DIGEST_SCHEDULE_ERROR"
+	default:
+		return false
+	}
+
+	h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+		"code":    code,
+		"message": err.Error(),
+	})
+
+	return true
+}
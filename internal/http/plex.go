@@ -15,7 +15,7 @@ type plexService interface {
 	Store(ctx context.Context, plex *domain.Plex) error
 	Get(ctx context.Context, req *domain.GetPlexRequest) (*domain.Plex, error)
 	ProcessPlex(ctx context.Context, plex *domain.Plex) error
-	GetPlexSettings(ctx context.Context) (*domain.PlexSettings, error)
+	GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error)
 	CheckPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error
 	CountScrobbleEvents(ctx context.Context) (int, error)
 	CountRateEvents(ctx context.Context) (int, error)
@@ -95,7 +95,7 @@ func (h plexHandler) postPlex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	plexSettings, err := h.service.GetPlexSettings(r.Context())
+	plexSettings, err := h.service.GetPlexSettingsForServer(r.Context(), plex.Server.UUID)
 	if err != nil {
 		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
 			"code":    "BAD_REQUEST",
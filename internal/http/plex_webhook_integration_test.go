@@ -23,11 +23,14 @@ import (
 	"github.com/varoOP/shinkro/internal/database"
 	"github.com/varoOP/shinkro/internal/domain"
 	"github.com/varoOP/shinkro/internal/events"
+	"github.com/varoOP/shinkro/internal/ingest"
+	"github.com/varoOP/shinkro/internal/jobqueue"
 	"github.com/varoOP/shinkro/internal/malauth"
 	"github.com/varoOP/shinkro/internal/mapping"
 	"github.com/varoOP/shinkro/internal/notification"
 	"github.com/varoOP/shinkro/internal/plex"
 	"github.com/varoOP/shinkro/internal/plexsettings"
+	"github.com/varoOP/shinkro/internal/syncrule"
 	"github.com/varoOP/shinkro/internal/testdata"
 	"github.com/varoOP/shinkro/internal/user"
 	"github.com/varoOP/shinkro/pkg/sse"
@@ -75,16 +78,20 @@ func setupTestHTTPServerForPlex(t *testing.T) (*httptest.Server, *database.DB, *
 	animeService := anime.NewService(log, animeRepo)
 	malauthService := malauth.NewService(testConfig, log, malauthRepo)
 	mapService := mapping.NewService(log, mappingRepo)
-	plexSettingsService := plexsettings.NewService(testConfig, log, plexSettingsRepo)
-	notificationService := notification.NewService(log, notificationRepo)
+	plexSettingsService := plexsettings.NewService(testConfig, log, plexSettingsRepo, database.NewPlexServerRepo(log, db), database.NewPlexGUIDCacheRepo(log, db), EventBus.New())
+	jobQueue := jobqueue.NewService(log, &domain.Config{JobWorkers: 2}, database.NewJobRepo(log, db))
+	notificationService := notification.NewService(log, notificationRepo, database.NewNotificationDeliveryRepo(log, db), animeUpdateRepo, jobQueue)
 	animeUpdateService := animeupdate.NewService(log, animeUpdateRepo, animeService, mapService, malauthService, bus)
-	plexService := plex.NewService(log, plexSettingsService, plexRepo, animeService, mapService, malauthService, animeUpdateService, bus)
+	syncRuleService := syncrule.NewService(log, database.NewSyncRuleRepo(log, db))
+	plexService := plex.NewService(log, testConfig, plexSettingsService, syncRuleService, plexRepo, animeService, mapService, malauthService, animeUpdateService, bus)
 	userService := user.NewService(userRepo, log)
 	authService := auth.NewService(log, userService)
 	apiService := api.NewService(log, apiRepo)
+	ingestService := ingest.NewService(log, database.NewIngestTokenRepo(log, db))
 
 	// Register event subscribers
-	events.NewSubscribers(log, bus, notificationService, plexService, animeUpdateService)
+	events.NewSubscribers(log, bus, jobQueue, notificationService, plexService, animeUpdateService)
+	require.NoError(t, jobQueue.Start(context.Background()))
 
 	// Create test config
 	cfg := &config.AppConfig{
@@ -111,6 +118,13 @@ func setupTestHTTPServerForPlex(t *testing.T) (*httptest.Server, *database.DB, *
 		nil, // fsService
 		notificationService,
 		animeUpdateService,
+		nil, // tautulliService
+		nil, // reconcileService
+		nil, // reverseSyncService
+		nil, // libraryScanService
+		nil, // syncRuleService
+		ingestService,
+		nil, // healthService
 		serverEvents,
 	)
 
@@ -119,6 +133,9 @@ func setupTestHTTPServerForPlex(t *testing.T) (*httptest.Server, *database.DB, *
 
 	cleanup := func() {
 		ts.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = jobQueue.Shutdown(ctx)
 		db.Close()
 	}
 
@@ -137,7 +154,7 @@ func TestPlexWebhookIntegration_Flow(t *testing.T) {
 		EncryptionKey: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", // 64 hex chars = 32 bytes
 	}
 	plexSettingsRepo := database.NewPlexSettingsRepo(log, db)
-	plexSettingsService := plexsettings.NewService(testConfig, log, plexSettingsRepo)
+	plexSettingsService := plexsettings.NewService(testConfig, log, plexSettingsRepo, database.NewPlexServerRepo(log, db), database.NewPlexGUIDCacheRepo(log, db), EventBus.New())
 
 	// Generate a 12-byte IV for AES-GCM (required for encryption)
 	tokenIV := make([]byte, 12)
@@ -241,7 +258,7 @@ func TestPlexWebhookIntegration_EventFlow(t *testing.T) {
 		EncryptionKey: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", // 64 hex chars = 32 bytes
 	}
 	plexSettingsRepo := database.NewPlexSettingsRepo(log, db)
-	plexSettingsService := plexsettings.NewService(testConfig, log, plexSettingsRepo)
+	plexSettingsService := plexsettings.NewService(testConfig, log, plexSettingsRepo, database.NewPlexServerRepo(log, db), database.NewPlexGUIDCacheRepo(log, db), EventBus.New())
 
 	// Generate a 12-byte IV for AES-GCM (required for encryption)
 	tokenIV := make([]byte, 12)
@@ -384,3 +401,86 @@ func TestPlexWebhookIntegration_EventFlow(t *testing.T) {
 		require.Greater(t, len(plexPayloads), 0)
 	})
 }
+
+func TestPlexWebhookIntegration_Ingest(t *testing.T) {
+	ts, db, _, _, cleanup := setupTestHTTPServerForPlex(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	log := zerolog.Nop()
+
+	testConfig := &domain.Config{
+		EncryptionKey: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", // 64 hex chars = 32 bytes
+	}
+	plexSettingsService := plexsettings.NewService(testConfig, log, database.NewPlexSettingsRepo(log, db), database.NewPlexServerRepo(log, db), database.NewPlexGUIDCacheRepo(log, db), EventBus.New())
+	_, err := plexSettingsService.Store(ctx, domain.PlexSettings{
+		Host:           "localhost",
+		Port:           32400,
+		PlexUser:       "TestUser",
+		AnimeLibraries: []string{"Anime"},
+		Token:          []byte("test-token"),
+		TokenIV:        make([]byte, 12),
+	})
+	require.NoError(t, err)
+
+	ingestService := ingest.NewService(log, database.NewIngestTokenRepo(log, db))
+	token := &domain.IngestToken{Name: "Plex", Source: domain.IngestSourcePlex}
+	require.NoError(t, ingestService.Store(ctx, token))
+	require.NotEmpty(t, token.Token)
+
+	restricted := &domain.IngestToken{Name: "Restricted", Source: domain.IngestSourcePlex, AllowedIPs: []string{"10.0.0.0/8"}}
+	require.NoError(t, ingestService.Store(ctx, restricted))
+
+	postWithHeader := func(path string, header http.Header) int {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("payload", testdata.RawPlexWebhookHAMAEpisode()))
+		writer.Close()
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path, body)
+		require.NoError(t, err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+	post := func(path string) int {
+		return postWithHeader(path, nil)
+	}
+
+	assert.Equal(t, http.StatusCreated, post("/api/ingest/plex/"+token.Token))
+	assert.Equal(t, http.StatusUnauthorized, post("/api/ingest/plex/wrong-token"))
+	assert.Equal(t, http.StatusUnauthorized, post("/api/ingest/tautulli/"+token.Token))
+	assert.Equal(t, http.StatusNotFound, post("/api/ingest/unknown/"+token.Token))
+	assert.Equal(t, http.StatusForbidden, post("/api/ingest/plex/"+restricted.Token))
+	// forwarded headers are ignored without a trusted proxy
+	assert.Equal(t, http.StatusForbidden, postWithHeader("/api/ingest/plex/"+restricted.Token, http.Header{
+		"X-Forwarded-For": {"10.1.2.3"},
+		"X-Real-Ip":       {"10.1.2.3"},
+	}))
+
+	tokens, err := ingestService.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.NotNil(t, tokens[0].LastSeen)
+	assert.Nil(t, tokens[1].LastSeen)
+
+	// Rotating the token disables the old url
+	rotated, err := ingestService.Rotate(ctx, token.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, post("/api/ingest/plex/"+token.Token))
+	assert.Equal(t, http.StatusCreated, post("/api/ingest/plex/"+rotated))
+
+	// Managing tokens needs authentication
+	resp, err := http.Get(ts.URL + "/api/ingest/tokens")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
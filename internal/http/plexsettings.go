@@ -10,6 +10,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
@@ -23,6 +24,11 @@ type plexsettingsService interface {
 	Get(ctx context.Context) (*domain.PlexSettings, error)
 	Delete(ctx context.Context) error
 	GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error)
+	ListServers(ctx context.Context) ([]*domain.PlexServer, error)
+	StoreServer(ctx context.Context, server *domain.PlexServer) error
+	UpdateServer(ctx context.Context, server *domain.PlexServer) error
+	DeleteServer(ctx context.Context, id int) error
+	ClearGUIDCache(ctx context.Context) error
 }
 
 type plexsettingsHandler struct {
@@ -47,6 +53,14 @@ func (h plexsettingsHandler) Routes(r chi.Router) {
 	r.Get("/oauth", h.pollOAuth)
 	r.Post("/servers", h.getServers)
 	r.Post("/libraries", h.getLibraries)
+	r.Delete("/guidcache", h.clearGUIDCache)
+
+	r.Route("/connections", func(r chi.Router) {
+		r.Get("/", h.listServers)
+		r.Post("/", h.storeServer)
+		r.Put("/{serverID}", h.updateServer)
+		r.Delete("/{serverID}", h.deleteServer)
+	})
 }
 
 func (h plexsettingsHandler) getPlexSettings(w http.ResponseWriter, r *http.Request) {
@@ -377,3 +391,85 @@ func (h plexsettingsHandler) getLibraries(w http.ResponseWriter, r *http.Request
 
 	h.encoder.StatusResponse(w, http.StatusOK, libraries)
 }
+
+func (h plexsettingsHandler) listServers(w http.ResponseWriter, r *http.Request) {
+	servers, err := h.service.ListServers(r.Context())
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, servers)
+}
+
+func (h plexsettingsHandler) storeServer(w http.ResponseWriter, r *http.Request) {
+	var server domain.PlexServer
+	if err := json.NewDecoder(r.Body).Decode(&server); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	if err := h.service.StoreServer(r.Context(), &server); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "PLEX_SERVER_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusCreated, server)
+}
+
+func (h plexsettingsHandler) updateServer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "serverID"))
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	var server domain.PlexServer
+	if err := json.NewDecoder(r.Body).Decode(&server); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	server.ID = id
+	if err := h.service.UpdateServer(r.Context(), &server); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.encoder.NotFoundErr(w, errors.New("plex server not found"))
+			return
+		}
+
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "PLEX_SERVER_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, server)
+}
+
+func (h plexsettingsHandler) deleteServer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "serverID"))
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	if err := h.service.DeleteServer(r.Context(), id); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
+
+func (h plexsettingsHandler) clearGUIDCache(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.ClearGUIDCache(r.Context()); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type reconcileService interface {
+	Run(ctx context.Context) error
+	FindAll(ctx context.Context) ([]*domain.ReconcileItem, error)
+	SetIgnored(ctx context.Context, malID int, ignored bool) error
+}
+
+type reconcileHandler struct {
+	encoder encoder
+	service reconcileService
+}
+
+func newReconcileHandler(encoder encoder, service reconcileService) *reconcileHandler {
+	return &reconcileHandler{
+		encoder: encoder,
+		service: service,
+	}
+}
+
+func (h reconcileHandler) Routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/run", h.run)
+	r.Put("/{malID}/ignore", h.setIgnored)
+}
+
+func (h reconcileHandler) list(w http.ResponseWriter, r *http.Request) {
+	items, err := h.service.FindAll(r.Context())
+	if err != nil {
+		h.encoder.StatusResponse(w, http.StatusInternalServerError, map[string]interface{}{
+			"code":    "INTERNAL_SERVER_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, items)
+}
+
+func (h reconcileHandler) run(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Run(r.Context()); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "RECONCILE_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
+
+func (h reconcileHandler) setIgnored(w http.ResponseWriter, r *http.Request) {
+	malID, err := strconv.Atoi(chi.URLParam(r, "malID"))
+	if err != nil || malID <= 0 {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "BAD_REQUEST",
+			"message": "invalid mal id",
+		})
+		return
+	}
+
+	var data domain.ReconcileIgnoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "BAD_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.SetIgnored(r.Context(), malID, data.Ignored); err != nil {
+		h.encoder.StatusResponse(w, http.StatusInternalServerError, map[string]interface{}{
+			"code":    "INTERNAL_SERVER_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type reverseSyncService interface {
+	Run(ctx context.Context, dryRun bool) ([]*domain.ReverseSyncAction, error)
+}
+
+type reverseSyncHandler struct {
+	encoder encoder
+	service reverseSyncService
+}
+
+func newReverseSyncHandler(encoder encoder, service reverseSyncService) *reverseSyncHandler {
+	return &reverseSyncHandler{
+		encoder: encoder,
+		service: service,
+	}
+}
+
+func (h reverseSyncHandler) Routes(r chi.Router) {
+	r.Post("/run", h.run)
+}
+
+func (h reverseSyncHandler) run(w http.ResponseWriter, r *http.Request) {
+	var data domain.ReverseSyncRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+				"code":    "BAD_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	actions, err := h.service.Run(r.Context(), data.DryRun)
+	if err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "REVERSE_SYNC_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, actions)
+}
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -36,10 +38,18 @@ type Server struct {
 	fsService           filesystemService
 	notificationService notificationService
 	animeUpdateService  animeupdateService
+	tautulliService     tautulliService
+	reconcileService    reconcileService
+	reverseSyncService  reverseSyncService
+	libraryScanService  libraryScanService
+	syncRuleService     syncRuleService
+	ingestService       ingestService
+	healthService       healthService
 	sse                 *sse.Server
+	httpServer          *http.Server
 }
 
-func NewServer(log zerolog.Logger, config *config.AppConfig, db *database.DB, version string, commit string, date string, plexSvc plexService, plexsettingsSvc plexsettingsService, malauthSvc malauthService, apiSvc apikeyService, authSvc authService, mappingSvc mappingService, fsSvc filesystemService, notificationSvc notificationService, animeUpdateSvc animeupdateService, sseServer *sse.Server) Server {
+func NewServer(log zerolog.Logger, config *config.AppConfig, db *database.DB, version string, commit string, date string, plexSvc plexService, plexsettingsSvc plexsettingsService, malauthSvc malauthService, apiSvc apikeyService, authSvc authService, mappingSvc mappingService, fsSvc filesystemService, notificationSvc notificationService, animeUpdateSvc animeupdateService, tautulliSvc tautulliService, reconcileSvc reconcileService, reverseSyncSvc reverseSyncService, libraryScanSvc libraryScanService, syncRuleSvc syncRuleService, ingestSvc ingestService, healthSvc healthService, sseServer *sse.Server) Server {
 	return Server{
 		log:                 log.With().Str("module", "http").Logger(),
 		config:              config,
@@ -57,7 +67,15 @@ func NewServer(log zerolog.Logger, config *config.AppConfig, db *database.DB, ve
 		fsService:           fsSvc,
 		notificationService: notificationSvc,
 		animeUpdateService:  animeUpdateSvc,
+		tautulliService:     tautulliSvc,
+		reconcileService:    reconcileSvc,
+		reverseSyncService:  reverseSyncSvc,
+		libraryScanService:  libraryScanSvc,
+		syncRuleService:     syncRuleSvc,
+		ingestService:       ingestSvc,
+		healthService:       healthSvc,
 		sse:                 sseServer,
+		httpServer:          &http.Server{ReadHeaderTimeout: time.Second * 15},
 	}
 }
 
@@ -68,14 +86,26 @@ func (s Server) Open() error {
 		return err
 	}
 
-	server := http.Server{
-		Handler:           s.Handler(),
-		ReadHeaderTimeout: time.Second * 15,
-	}
+	s.httpServer.Handler = s.Handler()
 
 	s.log.Info().Msgf("Starting server. Listening on %s", listener.Addr().String())
 
-	return server.Serve(listener)
+	if err := s.httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown stops accepting requests and waits for the running ones, connections still open when
+// ctx ends, like log streams, are closed.
+func (s Server) Shutdown(ctx context.Context) error {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.httpServer.Close()
+		return err
+	}
+
+	return nil
 }
 
 func (s Server) Handler() http.Handler {
@@ -83,6 +113,7 @@ func (s Server) Handler() http.Handler {
 
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
+	r.Use(connectionAddr)
 	r.Use(middleware.RealIP)
 	r.Use(hlog.NewHandler(s.log))
 
@@ -106,10 +137,22 @@ func (s Server) Handler() http.Handler {
 	// Public health check endpoint (no authentication required)
 	apiRouter.Route("/healthz", func(r chi.Router) {
 		r.Get("/liveness", LivenessHandler)
+		r.Get("/readiness", newHealthzHandler(encoder, s.healthService).readiness)
 	})
 
 	apiRouter.Route("/auth", newAuthHandler(encoder, s.log, s, s.config.Config, s.cookieStore, s.authService).Routes)
 
+	ingestHandler := newIngestHandler(encoder, s.ingestService, s.config.Config.TrustedProxies)
+	apiRouter.Route("/ingest", func(r chi.Router) {
+		// Webhooks authenticated by the token in the url, for senders like Plex that cannot set headers
+		r.With(ingestHandler.authenticate, parsePlexPayload).Post("/{source}/{token}", newPlexHandler(encoder, s.plexService).postPlex)
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.IsAuthenticated)
+			r.Route("/tokens", ingestHandler.Routes)
+		})
+	})
+
 	apiRouter.Group(func(r chi.Router) {
 		r.Use(s.IsAuthenticated)
 		r.Route("/config", newConfigHandler(encoder, s, s.config).Routes)
@@ -121,6 +164,11 @@ func (s Server) Handler() http.Handler {
 		r.Route("/fs", newFilesystemHandler(encoder, s.fsService).Routes)
 		r.Route("/notification", newNotificationHandler(encoder, s.notificationService).Routes)
 		r.Route("/animeupdate", newAnimeupdateHandler(encoder, s.animeUpdateService).Routes)
+		r.Route("/tautulli", newTautulliHandler(encoder, s.tautulliService).Routes)
+		r.Route("/reconcile", newReconcileHandler(encoder, s.reconcileService).Routes)
+		r.Route("/reversesync", newReverseSyncHandler(encoder, s.reverseSyncService).Routes)
+		r.Route("/libraryscan", newLibraryScanHandler(encoder, s.libraryScanService).Routes)
+		r.Route("/syncrules", newSyncRuleHandler(encoder, s.syncRuleService).Routes)
 		r.Get("/updates/latest", GetLatestReleaseHandler)
 
 		// SSE events endpoint
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type syncRuleService interface {
+	List(ctx context.Context) ([]*domain.SyncRule, error)
+	Store(ctx context.Context, rule *domain.SyncRule) error
+	Update(ctx context.Context, rule *domain.SyncRule) error
+	Delete(ctx context.Context, id int) error
+}
+
+type syncRuleHandler struct {
+	encoder encoder
+	service syncRuleService
+}
+
+func newSyncRuleHandler(encoder encoder, service syncRuleService) *syncRuleHandler {
+	return &syncRuleHandler{
+		encoder: encoder,
+		service: service,
+	}
+}
+
+func (h syncRuleHandler) Routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.store)
+
+	r.Route("/{ruleID}", func(r chi.Router) {
+		r.Put("/", h.update)
+		r.Delete("/", h.delete)
+	})
+}
+
+func (h syncRuleHandler) list(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.List(r.Context())
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, rules)
+}
+
+func (h syncRuleHandler) store(w http.ResponseWriter, r *http.Request) {
+	var rule domain.SyncRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	if err := h.service.Store(r.Context(), &rule); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "SYNC_RULE_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusCreated, rule)
+}
+
+func (h syncRuleHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	var rule domain.SyncRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	rule.ID = id
+	if err := h.service.Update(r.Context(), &rule); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.encoder.NotFoundErr(w, errors.New("sync rule not found"))
+			return
+		}
+
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "SYNC_RULE_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, rule)
+}
+
+func (h syncRuleHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		h.encoder.Error(w, err)
+		return
+	}
+
+	h.encoder.NoContent(w)
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type tautulliService interface {
+	Preview(ctx context.Context, req *domain.TautulliImportRequest) ([]domain.TautulliImportItem, error)
+	Import(ctx context.Context, req *domain.TautulliImportRequest) ([]domain.TautulliImportItem, error)
+}
+
+type tautulliHandler struct {
+	encoder encoder
+	service tautulliService
+}
+
+func newTautulliHandler(encoder encoder, service tautulliService) *tautulliHandler {
+	return &tautulliHandler{
+		encoder: encoder,
+		service: service,
+	}
+}
+
+func (h tautulliHandler) Routes(r chi.Router) {
+	r.Post("/import/preview", h.preview)
+	r.Post("/import", h.importHistory)
+}
+
+func (h tautulliHandler) preview(w http.ResponseWriter, r *http.Request) {
+	var req domain.TautulliImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "BAD_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	items, err := h.service.Preview(r.Context(), &req)
+	if err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "TAUTULLI_IMPORT_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, items)
+}
+
+func (h tautulliHandler) importHistory(w http.ResponseWriter, r *http.Request) {
+	var req domain.TautulliImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "BAD_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	items, err := h.service.Import(r.Context(), &req)
+	if err != nil {
+		h.encoder.StatusResponse(w, http.StatusBadRequest, map[string]interface{}{
+			"code":    "TAUTULLI_IMPORT_ERROR",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.encoder.StatusResponse(w, http.StatusOK, items)
+}
@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/api"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+// tokenLength is the number of random bytes of an ingest token.
+const tokenLength = 24
+
+type Service interface {
+	List(ctx context.Context) ([]*domain.IngestToken, error)
+	Store(ctx context.Context, token *domain.IngestToken) error
+	Update(ctx context.Context, token *domain.IngestToken) error
+	Rotate(ctx context.Context, id int) (string, error)
+	Delete(ctx context.Context, id int) error
+	Authenticate(ctx context.Context, source domain.IngestSource, token, remoteAddr string) (*domain.IngestToken, error)
+}
+
+type service struct {
+	log  zerolog.Logger
+	repo domain.IngestTokenRepo
+}
+
+func NewService(log zerolog.Logger, repo domain.IngestTokenRepo) Service {
+	return &service{
+		log:  log.With().Str("module", "ingest").Logger(),
+		repo: repo,
+	}
+}
+
+func (s *service) List(ctx context.Context) ([]*domain.IngestToken, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *service) Store(ctx context.Context, token *domain.IngestToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+
+	token.Token = api.GenerateSecureToken(tokenLength)
+	if token.Token == "" {
+		return errors.New("could not generate ingest token")
+	}
+
+	if err := s.repo.Store(ctx, token); err != nil {
+		s.log.Error().Err(err).Msgf("could not store ingest token: %s", token.Name)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) Update(ctx context.Context, token *domain.IngestToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(ctx, token); err != nil {
+		s.log.Error().Err(err).Msgf("could not update ingest token: %d", token.ID)
+		return err
+	}
+
+	return nil
+}
+
+// Rotate replaces the token of an ingest url, the old url stops working immediately.
+func (s *service) Rotate(ctx context.Context, id int) (string, error) {
+	token := api.GenerateSecureToken(tokenLength)
+	if token == "" {
+		return "", errors.New("could not generate ingest token")
+	}
+
+	if err := s.repo.UpdateToken(ctx, id, token); err != nil {
+		s.log.Error().Err(err).Msgf("could not rotate ingest token: %d", id)
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *service) Delete(ctx context.Context, id int) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Error().Err(err).Msgf("could not delete ingest token: %d", id)
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate checks a webhook sent to the ingest url of source from remoteAddr and records when
+// the token was last used.
+func (s *service) Authenticate(ctx context.Context, source domain.IngestSource, token, remoteAddr string) (*domain.IngestToken, error) {
+	if token == "" {
+		return nil, domain.ErrIngestTokenInvalid
+	}
+
+	t, err := s.repo.GetByToken(ctx, source, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrIngestTokenInvalid
+		}
+		return nil, err
+	}
+
+	if !t.AllowsIP(remoteAddr) {
+		s.log.Warn().Str("remoteAddr", remoteAddr).Int("token", t.ID).Msg("ingest request from ip not on the allow list")
+		return nil, domain.ErrIngestIPNotAllowed
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateLastSeen(ctx, t.ID, now); err != nil {
+		s.log.Error().Err(err).Int("token", t.ID).Msg("could not update ingest token last seen")
+	}
+	t.LastSeen = &now
+
+	return t, nil
+}
@@ -0,0 +1,329 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+// maxJobAttempts is the number of times a failing job runs before it is marked failed.
+const maxJobAttempts = 5
+
+// jobRetryDelay is doubled after every failed attempt.
+var jobRetryDelay = 30 * time.Second
+
+type Service interface {
+	Register(jobType domain.JobType, handler domain.JobHandler)
+	RegisterWorkers(jobType domain.JobType, handler domain.JobHandler, workers int)
+	Enqueue(ctx context.Context, jobType domain.JobType, key string, payload interface{}) error
+	Start(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+type service struct {
+	log      zerolog.Logger
+	repo     domain.JobRepo
+	workers  int
+	handlers map[domain.JobType]domain.JobHandler
+	// lanes holds the number of own workers of job types that do not run on the shared ones
+	lanes map[domain.JobType]int
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  []*domain.Job
+	active   map[string]bool
+	started  bool
+	stopping bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewService(log zerolog.Logger, config *domain.Config, repo domain.JobRepo) Service {
+	workers := config.JobWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &service{
+		log:      log.With().Str("module", "jobqueue").Logger(),
+		repo:     repo,
+		workers:  workers,
+		handlers: make(map[domain.JobType]domain.JobHandler),
+		lanes:    make(map[domain.JobType]int),
+		active:   make(map[string]bool),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	return s
+}
+
+// Register sets the handler for a job type, handlers have to be registered before Start.
+func (s *service) Register(jobType domain.JobType, handler domain.JobHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[jobType] = handler
+}
+
+// RegisterWorkers sets the handler for a job type that runs on its own workers, so slow jobs of
+// the type cannot hold up the shared workers.
+func (s *service) RegisterWorkers(jobType domain.JobType, handler domain.JobHandler, workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[jobType] = handler
+	s.lanes[jobType] = workers
+}
+
+// Enqueue persists a job and hands it to the workers. Jobs queued before Start are picked up by
+// Start, jobs queued during shutdown only run after the next start.
+func (s *service) Enqueue(ctx context.Context, jobType domain.JobType, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal job payload")
+	}
+
+	// The lock is held while storing so Start cannot load the job a second time.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := &domain.Job{Type: jobType, Key: key, Payload: data}
+	if err := s.repo.Store(ctx, job); err != nil {
+		return errors.Wrap(err, "could not store job")
+	}
+
+	if !s.started {
+		return nil
+	}
+
+	if s.stopping {
+		s.log.Debug().Int64("id", job.ID).Str("type", string(jobType)).Msg("job queue is shutting down, job runs after restart")
+		return nil
+	}
+
+	s.pending = append(s.pending, job)
+	s.cond.Broadcast()
+	return nil
+}
+
+// Start queues the jobs left over from the last run and starts the workers.
+func (s *service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return errors.New("job queue already started")
+	}
+
+	jobs, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not load pending jobs")
+	}
+
+	s.started = true
+	s.pending = jobs
+	if len(jobs) > 0 {
+		s.log.Info().Int("jobs", len(jobs)).Msg("resuming pending jobs")
+	}
+
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.work("")
+	}
+
+	for jobType, workers := range s.lanes {
+		for i := 0; i < workers; i++ {
+			s.wg.Add(1)
+			go s.work(jobType)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown stops taking new jobs and waits until the queued jobs ran, jobs waiting for a retry are
+// left for the next start. When ctx ends first the running jobs are cancelled and everything left
+// runs after the next start.
+func (s *service) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopping = true
+	left := len(s.pending)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.log.Info().Int("jobs", left).Msg("draining job queue")
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.cancel()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		return errors.Wrap(ctx.Err(), "job queue did not drain")
+	}
+}
+
+// work runs the jobs of lane, the shared workers use the empty lane.
+func (s *service) work(lane domain.JobType) {
+	defer s.wg.Done()
+
+	for {
+		job := s.next(lane)
+		if job == nil {
+			return
+		}
+
+		err := s.run(job)
+		s.finish(job, err)
+	}
+}
+
+// next blocks until a job of lane can run and returns nil once the queue is drained or cancelled.
+// Jobs waiting for a retry hold back the later jobs of their key, on shutdown they are not waited
+// for and run after the next start.
+func (s *service) next(lane domain.JobType) *domain.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.ctx.Err() != nil {
+			return nil
+		}
+
+		now := time.Now()
+		retrying := make(map[string]bool)
+		waiting := false
+		for i, job := range s.pending {
+			if job.RunAt.After(now) {
+				if job.Key != "" {
+					retrying[job.Key] = true
+				}
+				continue
+			}
+
+			if job.Key != "" && retrying[job.Key] {
+				continue
+			}
+
+			if s.lane(job.Type) != lane {
+				continue
+			}
+
+			if job.Key != "" && s.active[job.Key] {
+				waiting = true
+				continue
+			}
+
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			if job.Key != "" {
+				s.active[job.Key] = true
+			}
+			return job
+		}
+
+		if s.stopping && !waiting {
+			return nil
+		}
+
+		s.cond.Wait()
+	}
+}
+
+// lane returns the job type when it has its own workers, and the empty shared lane otherwise.
+func (s *service) lane(jobType domain.JobType) domain.JobType {
+	if _, ok := s.lanes[jobType]; ok {
+		return jobType
+	}
+
+	return ""
+}
+
+func (s *service) run(job *domain.Job) error {
+	s.mu.Lock()
+	handler, ok := s.handlers[job.Type]
+	s.mu.Unlock()
+
+	if !ok {
+		return errors.Errorf("no handler for job type %s", job.Type)
+	}
+
+	return handler(s.ctx, job.Payload)
+}
+
+// finish removes a job that ran and queues a failed one again until it used up its attempts.
+func (s *service) finish(job *domain.Job, err error) {
+	// A job cancelled by a forced shutdown stays in the database and runs again after restart.
+	retry := false
+	if s.ctx.Err() == nil {
+		if err != nil {
+			retry = s.fail(job, err)
+		} else if err := s.repo.Delete(context.Background(), job.ID); err != nil {
+			s.log.Error().Err(err).Int64("id", job.ID).Msg("could not delete finished job")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The retry goes first so later jobs of its key keep waiting for it
+	if retry {
+		s.pending = append([]*domain.Job{job}, s.pending...)
+		time.AfterFunc(time.Until(job.RunAt), func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.cond.Broadcast()
+		})
+	}
+
+	if job.Key != "" {
+		delete(s.active, job.Key)
+	}
+	s.cond.Broadcast()
+}
+
+// fail records the failed attempt of job and reports whether it runs again. Jobs that used up
+// their attempts are kept in the database marked as failed.
+func (s *service) fail(job *domain.Job, err error) bool {
+	job.Attempts++
+	job.Error = err.Error()
+
+	retry := job.Attempts < maxJobAttempts
+	if retry {
+		delay := jobRetryDelay << (job.Attempts - 1)
+		job.RunAt = time.Now().Add(delay)
+		s.log.Warn().Err(err).Int64("id", job.ID).Str("type", string(job.Type)).Int("attempt", job.Attempts).Msgf("job failed, retrying in %v", delay)
+	} else {
+		failedAt := time.Now().UTC()
+		job.FailedAt = &failedAt
+		s.log.Error().Err(err).Int64("id", job.ID).Str("type", string(job.Type)).Int("attempts", job.Attempts).Msg("job failed, giving up")
+	}
+
+	if err := s.repo.Update(context.Background(), job); err != nil {
+		s.log.Error().Err(err).Int64("id", job.ID).Msg("could not store failed job")
+	}
+
+	return retry
+}
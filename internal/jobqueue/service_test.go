@@ -0,0 +1,331 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type mockJobRepo struct {
+	mu     sync.Mutex
+	nextID int64
+	jobs   map[int64]*domain.Job
+}
+
+func newMockJobRepo(jobs ...*domain.Job) *mockJobRepo {
+	m := &mockJobRepo{jobs: make(map[int64]*domain.Job)}
+	for _, job := range jobs {
+		m.nextID++
+		job.ID = m.nextID
+		m.jobs[job.ID] = job
+	}
+	return m
+}
+
+func (m *mockJobRepo) Store(ctx context.Context, job *domain.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	job.ID = m.nextID
+	m.jobs[job.ID] = job
+	return nil
+}
+
+func (m *mockJobRepo) Update(ctx context.Context, job *domain.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *job
+	m.jobs[job.ID] = &stored
+	return nil
+}
+
+func (m *mockJobRepo) Delete(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+	return nil
+}
+
+func (m *mockJobRepo) FindAll(ctx context.Context) ([]*domain.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]*domain.Job, 0, len(m.jobs))
+	for id := int64(1); id <= m.nextID; id++ {
+		if job, ok := m.jobs[id]; ok && job.FailedAt == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *mockJobRepo) get(id int64) *domain.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[id]
+}
+
+func (m *mockJobRepo) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.jobs)
+}
+
+const testJob domain.JobType = "TEST"
+
+func newTestService(repo domain.JobRepo, workers int) Service {
+	return NewService(zerolog.Nop(), &domain.Config{JobWorkers: workers}, repo)
+}
+
+func TestService_SerializesJobsWithSameKey(t *testing.T) {
+	repo := newMockJobRepo()
+	s := newTestService(repo, 4)
+
+	var mu sync.Mutex
+	running := make(map[string]int)
+	order := make(map[string][]int)
+	overlap := false
+
+	s.Register(testJob, func(ctx context.Context, payload json.RawMessage) error {
+		var p struct {
+			Key string
+			N   int
+		}
+		require.NoError(t, json.Unmarshal(payload, &p))
+
+		mu.Lock()
+		running[p.Key]++
+		if running[p.Key] > 1 {
+			overlap = true
+		}
+		order[p.Key] = append(order[p.Key], p.N)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running[p.Key]--
+		mu.Unlock()
+		return nil
+	})
+
+	require.NoError(t, s.Start(context.Background()))
+
+	for n := 1; n <= 5; n++ {
+		for _, key := range []string{"myanimelist-1", "myanimelist-2"} {
+			require.NoError(t, s.Enqueue(context.Background(), testJob, key, map[string]interface{}{"Key": key, "N": n}))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	assert.False(t, overlap, "jobs with the same key ran at the same time")
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, order["myanimelist-1"])
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, order["myanimelist-2"])
+	assert.Equal(t, 0, repo.count())
+}
+
+func TestService_ResumesPendingJobs(t *testing.T) {
+	repo := newMockJobRepo(
+		&domain.Job{Type: testJob, Payload: json.RawMessage(`1`)},
+		&domain.Job{Type: testJob, Payload: json.RawMessage(`2`)},
+	)
+	s := newTestService(repo, 1)
+
+	var ran []string
+	s.Register(testJob, func(ctx context.Context, payload json.RawMessage) error {
+		ran = append(ran, string(payload))
+		return nil
+	})
+
+	// Queued before start, picked up once with the jobs of the last run
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "", 3))
+	require.NoError(t, s.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	assert.Equal(t, []string{"1", "2", "3"}, ran)
+	assert.Equal(t, 0, repo.count())
+}
+
+func TestService_ShutdownTimeoutKeepsJobs(t *testing.T) {
+	repo := newMockJobRepo()
+	s := newTestService(repo, 1)
+
+	started := make(chan struct{})
+	s.Register(testJob, func(ctx context.Context, payload json.RawMessage) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	require.NoError(t, s.Start(context.Background()))
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "a", 1))
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "a", 2))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "job queue did not drain")
+
+	// Queued after shutdown started, persisted for the next start
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "", 3))
+
+	assert.Eventually(t, func() bool { return repo.count() == 3 }, time.Second, 5*time.Millisecond)
+}
+
+func setRetryDelay(t *testing.T, delay time.Duration) {
+	previous := jobRetryDelay
+	jobRetryDelay = delay
+	t.Cleanup(func() { jobRetryDelay = previous })
+}
+
+func TestService_FailedJobIsRetried(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+
+	repo := newMockJobRepo()
+	s := newTestService(repo, 2)
+
+	var mu sync.Mutex
+	var ran []int
+	s.Register(testJob, func(ctx context.Context, payload json.RawMessage) error {
+		var n int
+		require.NoError(t, json.Unmarshal(payload, &n))
+
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, n)
+		if n == 1 && len(ran) < 3 {
+			return assert.AnError
+		}
+		return nil
+	})
+
+	require.NoError(t, s.Start(context.Background()))
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "a", 1))
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "a", 2))
+
+	assert.Eventually(t, func() bool { return repo.count() == 0 }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	// The later job of the key waits until the retries of the first one succeeded
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{1, 1, 1, 2}, ran)
+}
+
+func TestService_FailedJobIsKept(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+
+	repo := newMockJobRepo()
+	s := newTestService(repo, 1)
+
+	s.Register(testJob, func(ctx context.Context, payload json.RawMessage) error {
+		return assert.AnError
+	})
+
+	require.NoError(t, s.Start(context.Background()))
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "", 1))
+
+	assert.Eventually(t, func() bool {
+		job := repo.get(1)
+		return job != nil && job.FailedAt != nil
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	job := repo.get(1)
+	assert.Equal(t, maxJobAttempts, job.Attempts)
+	assert.Equal(t, assert.AnError.Error(), job.Error)
+
+	// Failed jobs are not resumed on the next start
+	jobs, err := repo.FindAll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestService_ShutdownLeavesRetries(t *testing.T) {
+	setRetryDelay(t, time.Hour)
+
+	repo := newMockJobRepo()
+	s := newTestService(repo, 1)
+
+	failed := make(chan struct{})
+	s.Register(testJob, func(ctx context.Context, payload json.RawMessage) error {
+		close(failed)
+		return assert.AnError
+	})
+
+	require.NoError(t, s.Start(context.Background()))
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "", 1))
+	<-failed
+
+	assert.Eventually(t, func() bool {
+		job := repo.get(1)
+		return job != nil && job.Attempts == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	jobs, err := repo.FindAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 1, jobs[0].Attempts)
+}
+
+func TestService_RegisterWorkersKeepsSharedWorkersFree(t *testing.T) {
+	const slowJob domain.JobType = "SLOW"
+
+	repo := newMockJobRepo()
+	s := newTestService(repo, 1)
+
+	release := make(chan struct{})
+	s.RegisterWorkers(slowJob, func(ctx context.Context, payload json.RawMessage) error {
+		<-release
+		return nil
+	}, 1)
+
+	done := make(chan struct{})
+	s.Register(testJob, func(ctx context.Context, payload json.RawMessage) error {
+		close(done)
+		return nil
+	})
+
+	require.NoError(t, s.Start(context.Background()))
+	require.NoError(t, s.Enqueue(context.Background(), slowJob, "", 1))
+	require.NoError(t, s.Enqueue(context.Background(), slowJob, "", 2))
+	require.NoError(t, s.Enqueue(context.Background(), testJob, "", 3))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job on the shared worker waited for the slow jobs")
+	}
+
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.Equal(t, 0, repo.count())
+}
@@ -0,0 +1,164 @@
+package libraryscan
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/plex"
+	"github.com/varoOP/shinkro/internal/plexsettings"
+	plexclient "github.com/varoOP/shinkro/pkg/plex"
+)
+
+type Service interface {
+	Scan(ctx context.Context) error
+}
+
+type service struct {
+	log                 zerolog.Logger
+	repo                domain.LibraryScanRepo
+	plexsettingsService plexsettings.Service
+	plexService         plex.Service
+
+	running sync.Mutex
+}
+
+func NewService(log zerolog.Logger, repo domain.LibraryScanRepo, plexsettingsSvc plexsettings.Service, plexSvc plex.Service) Service {
+	return &service{
+		log:                 log.With().Str("module", "libraryscan").Logger(),
+		repo:                repo,
+		plexsettingsService: plexsettingsSvc,
+		plexService:         plexSvc,
+	}
+}
+
+// Scan looks for shows and movies added to the plan to watch libraries since the last scan and
+// processes them like a library.new webhook. The first scan of a library only records when it was
+// scanned, so media that was already in the library is not added.
+func (s *service) Scan(ctx context.Context) error {
+	if !s.running.TryLock() {
+		return errors.New("library scan already running")
+	}
+	defer s.running.Unlock()
+
+	ps, err := s.plexsettingsService.Get(ctx)
+	if err != nil {
+		return errors.Wrap(err, "plex settings not found")
+	}
+
+	if len(ps.PlanToWatchLibs) == 0 {
+		return nil
+	}
+
+	if !ps.PlexClientEnabled {
+		return errors.New("plex client is not enabled")
+	}
+
+	pc, err := s.plexsettingsService.GetClient(ctx, ps)
+	if err != nil {
+		return err
+	}
+
+	libraries, err := pc.GetLibraries(ctx)
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, section := range libraries.MediaContainer.Directory {
+		if section.Type != "show" && section.Type != "movie" {
+			continue
+		}
+
+		p := &domain.Plex{Metadata: domain.Metadata{LibrarySectionTitle: section.Title, LibrarySectionID: section.SectionID(), LibrarySectionUUID: section.UUID}}
+		if !p.IsAnimeLibrary(ps) || !p.IsPlanToWatchLibrary(ps) {
+			continue
+		}
+
+		n, err := s.scanLibrary(ctx, pc, ps, section)
+		if err != nil {
+			s.log.Error().Err(err).Str("library", section.Title).Msg("library scan failed")
+			continue
+		}
+
+		added += n
+	}
+
+	s.log.Info().Int("added", added).Msg("library scan finished")
+	return nil
+}
+
+func (s *service) scanLibrary(ctx context.Context, pc *plexclient.Client, ps *domain.PlexSettings, section plexclient.Directory) (int, error) {
+	scannedAt, err := s.repo.GetScannedAt(ctx, section.Key)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if scannedAt.IsZero() {
+		s.log.Debug().Str("library", section.Title).Msg("first scan of library, only new media from now on is added")
+		return 0, s.repo.StoreScannedAt(ctx, section.Key, now)
+	}
+
+	resp, err := pc.GetLibraryItems(ctx, section.Key)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, item := range resp.MediaContainer.Metadata {
+		if !time.Unix(int64(item.AddedAt), 0).After(scannedAt) {
+			continue
+		}
+
+		if item.Type == "" {
+			item.Type = section.Type
+		}
+
+		p := newPayload(item, section, ps)
+		if err := s.plexService.Store(ctx, p); err != nil {
+			return added, errors.Wrapf(err, "could not store %v", item.Title)
+		}
+
+		if err := s.plexService.ProcessPlex(ctx, p); err != nil {
+			s.log.Error().Err(err).Str("title", item.Title).Msg("could not process new library item")
+			continue
+		}
+
+		added++
+	}
+
+	return added, s.repo.StoreScannedAt(ctx, section.Key, now)
+}
+
+// newPayload describes a library item the way Plex does in a library.new webhook.
+func newPayload(item plexclient.Metadata, section plexclient.Directory, ps *domain.PlexSettings) *domain.Plex {
+	p := &domain.Plex{
+		TimeStamp: time.Now(),
+		Event:     domain.PlexLibraryNewEvent,
+		Source:    domain.LibraryScan,
+		Owner:     true,
+		Metadata: domain.Metadata{
+			RatingKey: item.RatingKey,
+			Key:       item.Key,
+			GUID: domain.GUID{
+				GUID:  item.GUID.GUID,
+				GUIDS: item.GUID.GUIDS,
+			},
+			Type:                domain.PlexMediaType(item.Type),
+			Title:               item.Title,
+			LibrarySectionTitle: section.Title,
+			LibrarySectionID:    section.SectionID(),
+			LibrarySectionUUID:  section.UUID,
+			LibrarySectionKey:   section.Key,
+			Year:                item.Year,
+		},
+	}
+
+	p.Account.Title = ps.PlexUser
+
+	return p
+}
@@ -0,0 +1,248 @@
+package libraryscan
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/plex"
+)
+
+type mockLibraryScanRepo struct {
+	scannedAt map[string]time.Time
+}
+
+func (m *mockLibraryScanRepo) GetScannedAt(ctx context.Context, sectionKey string) (time.Time, error) {
+	return m.scannedAt[sectionKey], nil
+}
+
+func (m *mockLibraryScanRepo) StoreScannedAt(ctx context.Context, sectionKey string, scannedAt time.Time) error {
+	m.scannedAt[sectionKey] = scannedAt
+	return nil
+}
+
+type mockPlexSettingsService struct {
+	settings *domain.PlexSettings
+	url      string
+}
+
+func (m *mockPlexSettingsService) Store(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) Get(ctx context.Context) (*domain.PlexSettings, error) {
+	return m.settings, nil
+}
+
+func (m *mockPlexSettingsService) Update(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) Delete(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error) {
+	return plex.NewClient(plex.Config{Url: m.url, Token: "token"}), nil
+}
+
+func (m *mockPlexSettingsService) HandlePlexAgent(ctx context.Context, p *domain.Plex, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error) {
+	return "", 0, nil
+}
+
+func (m *mockPlexSettingsService) MigrateLibraries(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) GetForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.Get(ctx)
+}
+
+func (m *mockPlexSettingsService) ListServers(ctx context.Context) ([]*domain.PlexServer, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) StoreServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) UpdateServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) DeleteServer(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) InvalidateGUIDCache(ctx context.Context, p *domain.Plex) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ClearGUIDCache(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ResolveLibrarySection(ctx context.Context, ps *domain.PlexSettings, p *domain.Plex) error {
+	return nil
+}
+
+type mockPlexService struct {
+	stored    []*domain.Plex
+	processed []*domain.Plex
+}
+
+func (m *mockPlexService) Store(ctx context.Context, plex *domain.Plex) error {
+	plex.ID = int64(len(m.stored) + 1)
+	m.stored = append(m.stored, plex)
+	return nil
+}
+
+func (m *mockPlexService) Get(ctx context.Context, req *domain.GetPlexRequest) (*domain.Plex, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
+	m.processed = append(m.processed, plex)
+	return nil
+}
+
+func (m *mockPlexService) PreviewPlex(ctx context.Context, plex *domain.Plex) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) SuppressScrobble(ratingKey string) {}
+
+func (m *mockPlexService) ClearCompleted(ratingKey string) {}
+
+func (m *mockPlexService) GetPlexSettings(ctx context.Context) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.GetPlexSettings(ctx)
+}
+
+func (m *mockPlexService) CheckPlexPreview(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	return nil
+}
+
+func (m *mockPlexService) CheckPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	return nil
+}
+
+func (m *mockPlexService) CountScrobbleEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) CountRateEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) GetPlexHistory(ctx context.Context, limit int) ([]domain.PlexHistoryItem, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) FindAllWithFilters(ctx context.Context, params domain.PlexPayloadQueryParams) (*domain.FindPlexPayloadsResponse, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) Delete(ctx context.Context, req *domain.DeletePlexRequest) error {
+	return nil
+}
+
+func (m *mockPlexService) UpdateStatus(ctx context.Context, plexID int64, success *bool, errorType domain.PlexErrorType, errorMsg string) error {
+	return nil
+}
+
+func newPlexServer(t *testing.T, lastScan time.Time) *httptest.Server {
+	t.Helper()
+	before := lastScan.Add(-time.Hour).Unix()
+	after := lastScan.Add(time.Minute).Unix()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/library/sections":
+			fmt.Fprint(w, `{"MediaContainer":{"Directory":[{"key":"1","type":"show","title":"Anime"},{"key":"2","type":"movie","title":"Anime Movies"},{"key":"3","type":"show","title":"TV Shows"}]}}`)
+		case "/library/sections/1/all":
+			fmt.Fprintf(w, `{"MediaContainer":{"Metadata":[
+				{"ratingKey":"100","title":"Frieren","type":"show","guid":"com.plexapp.agents.hama://tvdb-424536?lang=en","addedAt":%d},
+				{"ratingKey":"200","title":"Dandadan","type":"show","guid":"com.plexapp.agents.hama://anidb-17000?lang=en","addedAt":%d}
+			]}}`, before, after)
+		default:
+			t.Errorf("unexpected plex request %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestService(t *testing.T, repo *mockLibraryScanRepo, lastScan time.Time) (Service, *mockPlexService) {
+	t.Helper()
+	plexSrv := newPlexServer(t, lastScan)
+	t.Cleanup(plexSrv.Close)
+
+	plexSvc := &mockPlexService{}
+	plexsettingsSvc := &mockPlexSettingsService{
+		settings: &domain.PlexSettings{
+			PlexUser:          "TestUser",
+			AnimeLibraries:    []string{"Anime", "Anime Movies"},
+			PlanToWatchLibs:   []string{"Anime"},
+			PlexClientEnabled: true,
+		},
+		url: plexSrv.URL,
+	}
+
+	return NewService(zerolog.Nop(), repo, plexsettingsSvc, plexSvc), plexSvc
+}
+
+func TestService_Scan(t *testing.T) {
+	lastScan := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	repo := &mockLibraryScanRepo{scannedAt: map[string]time.Time{"1": lastScan}}
+	svc, plexSvc := newTestService(t, repo, lastScan)
+
+	require.NoError(t, svc.Scan(context.Background()))
+
+	require.Len(t, plexSvc.processed, 1)
+	p := plexSvc.processed[0]
+	assert.Equal(t, int64(1), p.ID)
+	assert.Equal(t, domain.PlexLibraryNewEvent, p.Event)
+	assert.Equal(t, domain.LibraryScan, p.Source)
+	assert.Equal(t, "TestUser", p.Account.Title)
+	assert.Equal(t, "200", p.Metadata.RatingKey)
+	assert.Equal(t, domain.PlexShow, p.Metadata.Type)
+	assert.Equal(t, "com.plexapp.agents.hama://anidb-17000?lang=en", p.Metadata.GUID.GUID)
+	assert.Equal(t, "Anime", p.Metadata.LibrarySectionTitle)
+	assert.Equal(t, 1, p.Metadata.LibrarySectionID)
+
+	assert.True(t, repo.scannedAt["1"].After(lastScan))
+	assert.NotContains(t, repo.scannedAt, "2")
+}
+
+func TestService_Scan_FirstScanOnlyRecordsBaseline(t *testing.T) {
+	repo := &mockLibraryScanRepo{scannedAt: map[string]time.Time{}}
+	svc, plexSvc := newTestService(t, repo, time.Now())
+
+	require.NoError(t, svc.Scan(context.Background()))
+
+	assert.Empty(t, plexSvc.stored)
+	assert.Contains(t, repo.scannedAt, "1")
+}
+
+func TestService_Scan_NoPlanToWatchLibraries(t *testing.T) {
+	repo := &mockLibraryScanRepo{scannedAt: map[string]time.Time{}}
+	plexSvc := &mockPlexService{}
+	svc := NewService(zerolog.Nop(), repo, &mockPlexSettingsService{
+		settings: &domain.PlexSettings{AnimeLibraries: []string{"Anime"}, PlexClientEnabled: true},
+	}, plexSvc)
+
+	require.NoError(t, svc.Scan(context.Background()))
+
+	assert.Empty(t, plexSvc.stored)
+	assert.Empty(t, repo.scannedAt)
+}
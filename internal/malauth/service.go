@@ -4,9 +4,12 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/nstratos/go-myanimelist/mal"
 	"github.com/pkg/errors"
@@ -21,20 +24,33 @@ type Service interface {
 	Delete(ctx context.Context) error
 	GetMalClient(ctx context.Context) (*mal.Client, error)
 	GetDecrypted(ctx context.Context) (*domain.MalAuth, error)
+	CheckToken(ctx context.Context) (*domain.MalAuthTokenStatus, error)
+	TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error)
+	RateLimitStatus() domain.MALRateLimitStatus
 }
 
+// tokenRefreshWindow is how long before expiry CheckToken refreshes the access token, so a dead
+// refresh token is noticed while the access token still works.
+const tokenRefreshWindow = 7 * 24 * time.Hour
+
 type service struct {
 	config         *domain.Config
 	log            zerolog.Logger
 	repo           domain.MalAuthRepo
 	tokenRefreshMu sync.Mutex // Protects token refresh to prevent concurrent refreshes
+	transport      *transport // Shared by all MAL clients so limits apply across callers
+
+	mu         sync.Mutex
+	lastStatus *domain.MalAuthTokenStatus // result of the last CheckToken
 }
 
 func NewService(config *domain.Config, log zerolog.Logger, repo domain.MalAuthRepo) Service {
+	l := log.With().Str("module", "malauth").Logger()
 	return &service{
-		config: config,
-		log:    log.With().Str("module", "malauth").Logger(),
-		repo:   repo,
+		config:    config,
+		log:       l,
+		repo:      repo,
+		transport: newTransport(l, config),
 	}
 }
 
@@ -78,24 +94,40 @@ func (s *service) GetDecrypted(ctx context.Context) (*domain.MalAuth, error) {
 		return nil, err
 	}
 
+	if err := s.decryptConfig(ma); err != nil {
+		return nil, err
+	}
+
+	return ma, nil
+}
+
+// decryptConfig decrypts the client id and secret of ma in place.
+func (s *service) decryptConfig(ma *domain.MalAuth) error {
 	cid, err := s.decrypt([]byte(ma.Config.ClientID), ma.TokenIV)
 	if err != nil {
 		s.log.Err(errors.Wrap(err, "failed to decrypt client id")).Msg("")
-		return nil, err
+		return err
 	}
 
 	cs, err := s.decrypt([]byte(ma.Config.ClientSecret), ma.TokenIV)
 	if err != nil {
 		s.log.Err(errors.Wrap(err, "failed to decrypt client secret")).Msg("")
-		return nil, err
+		return err
 	}
 
 	ma.Config.ClientID = string(cid)
 	ma.Config.ClientSecret = string(cs)
-	return ma, nil
+	return nil
+}
+
+func (s *service) RateLimitStatus() domain.MALRateLimitStatus {
+	return s.transport.Status()
 }
 
 func (s *service) GetMalClient(ctx context.Context) (*mal.Client, error) {
+	// Route MAL requests, including token refreshes, through the rate limited transport
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: s.transport})
+
 	ma, err := s.GetDecrypted(ctx)
 	if err != nil {
 		s.log.Err(errors.Wrap(err, "failed to get credentials from database")).Msg("")
@@ -166,6 +198,151 @@ func (s *service) GetMalClient(ctx context.Context) (*mal.Client, error) {
 	return mal.NewClient(ma.Config.Client(ctx, freshToken)), nil
 }
 
+// CheckToken refreshes the access token when it expires within tokenRefreshWindow and reports whether
+// re-authentication is needed. Tokens further from expiry are not refreshed. Before MAL is connected
+// it returns sql.ErrNoRows without logging.
+func (s *service) CheckToken(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	status, err := s.checkToken(ctx)
+	if status != nil {
+		s.mu.Lock()
+		s.lastStatus = status
+		s.mu.Unlock()
+	}
+
+	return status, err
+}
+
+// TokenStatus reports the state of the stored access token without refreshing it. A failed
+// CheckToken is reported until a new token is stored, an expired token without refresh token is invalid.
+func (s *service) TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	ma, err := s.repo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dt, err := s.decrypt(ma.AccessToken, ma.TokenIV)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt access token")
+	}
+
+	var token oauth2.Token
+	if err = json.Unmarshal(dt, &token); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal access token")
+	}
+
+	s.mu.Lock()
+	last := s.lastStatus
+	s.mu.Unlock()
+
+	if last != nil && last.State != domain.MalAuthTokenValid && !token.Expiry.After(last.Expiry) {
+		status := *last
+		return &status, nil
+	}
+
+	status := &domain.MalAuthTokenStatus{
+		State:  domain.MalAuthTokenValid,
+		Expiry: token.Expiry,
+	}
+
+	if !token.Expiry.IsZero() && time.Now().After(token.Expiry) && token.RefreshToken == "" {
+		status.State = domain.MalAuthTokenInvalid
+		status.Error = "access token expired and no refresh token stored"
+	}
+
+	return status, nil
+}
+
+func (s *service) checkToken(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: s.transport})
+
+	s.tokenRefreshMu.Lock()
+	defer s.tokenRefreshMu.Unlock()
+
+	ma, err := s.repo.Get(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		// MAL is not connected yet, nothing to check
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credentials from database")
+	}
+
+	if err := s.decryptConfig(ma); err != nil {
+		return nil, err
+	}
+
+	dt, err := s.decrypt(ma.AccessToken, ma.TokenIV)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt access token")
+	}
+
+	var token oauth2.Token
+	if err = json.Unmarshal(dt, &token); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal access token")
+	}
+
+	status := &domain.MalAuthTokenStatus{
+		State:  domain.MalAuthTokenValid,
+		Expiry: token.Expiry,
+	}
+
+	if token.Expiry.IsZero() || time.Until(token.Expiry) > tokenRefreshWindow {
+		return status, nil
+	}
+
+	if token.RefreshToken == "" {
+		status.State = domain.MalAuthTokenInvalid
+		status.Error = "no refresh token stored"
+		return status, nil
+	}
+
+	// without an access token the token source always refreshes
+	freshToken, err := ma.Config.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+	if err != nil {
+		s.log.Warn().Err(err).Msg("failed to refresh access token ahead of expiry")
+
+		status.State = domain.MalAuthTokenExpiring
+		if refreshTokenRejected(err) {
+			status.State = domain.MalAuthTokenInvalid
+		}
+		status.Error = err.Error()
+		return status, nil
+	}
+
+	t, err := json.Marshal(freshToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal access token")
+	}
+
+	ma.AccessToken = t
+	if err = s.Store(ctx, ma); err != nil {
+		return nil, errors.Wrap(err, "failed to store refreshed credentials")
+	}
+
+	s.log.Info().Msgf("refreshed access token, expires at %v", freshToken.Expiry)
+
+	status.Expiry = freshToken.Expiry
+	status.Refreshed = true
+	return status, nil
+}
+
+// refreshTokenRejected reports whether the token endpoint refused the refresh token itself, as
+// opposed to a network or server error that may pass.
+func refreshTokenRejected(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+
+	switch retrieveErr.ErrorCode {
+	case "invalid_grant", "invalid_client", "invalid_token", "unauthorized_client":
+		return true
+	}
+
+	return retrieveErr.Response != nil &&
+		(retrieveErr.Response.StatusCode == http.StatusBadRequest || retrieveErr.Response.StatusCode == http.StatusUnauthorized)
+}
+
 // encrypt encrypts plaintext using AES-GCM with the encryption key from config
 func (s *service) encrypt(plaintext, iv []byte) ([]byte, error) {
 	key, err := s.getEncryptionKey()
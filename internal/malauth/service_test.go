@@ -1,11 +1,15 @@
 package malauth
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -443,3 +447,177 @@ func TestService_GetMalClient_WithValidToken(t *testing.T) {
 	// Verify it's a valid MAL client (has the expected structure)
 	assert.NotNil(t, client)
 }
+
+func TestService_CheckToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		expiry        time.Duration
+		status        int
+		response      string
+		wantState     domain.MalAuthTokenState
+		wantRefreshed bool
+		wantRequests  int
+	}{
+		{
+			name:      "token far from expiry is not refreshed",
+			expiry:    20 * 24 * time.Hour,
+			wantState: domain.MalAuthTokenValid,
+		},
+		{
+			name:          "token close to expiry is refreshed",
+			expiry:        2 * 24 * time.Hour,
+			status:        http.StatusOK,
+			response:      `{"access_token":"fresh-access-token","refresh_token":"fresh-refresh-token","token_type":"Bearer","expires_in":2678400}`,
+			wantState:     domain.MalAuthTokenValid,
+			wantRefreshed: true,
+			wantRequests:  1,
+		},
+		{
+			name:         "rejected refresh token is invalid",
+			expiry:       2 * 24 * time.Hour,
+			status:       http.StatusBadRequest,
+			response:     `{"error":"invalid_grant","message":"The refresh token is invalid."}`,
+			wantState:    domain.MalAuthTokenInvalid,
+			wantRequests: 1,
+		},
+		{
+			name:         "unavailable token endpoint is expiring",
+			expiry:       2 * 24 * time.Hour,
+			status:       http.StatusServiceUnavailable,
+			response:     `service unavailable`,
+			wantState:    domain.MalAuthTokenExpiring,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				require.NoError(t, r.ParseForm())
+				assert.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+				assert.Equal(t, "test-refresh-token", r.Form.Get("refresh_token"))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer ts.Close()
+
+			config := &domain.Config{
+				EncryptionKey: generateTestKey(),
+			}
+			repo := &mockMalAuthRepo{}
+			service := NewService(config, zerolog.Nop(), repo).(*service)
+
+			tokenJSON, err := json.Marshal(&oauth2.Token{
+				AccessToken:  "test-access-token",
+				TokenType:    "Bearer",
+				RefreshToken: "test-refresh-token",
+				Expiry:       time.Now().Add(tt.expiry),
+			})
+			require.NoError(t, err)
+
+			ma := domain.NewMalAuth("test-client-id", "test-client-secret", tokenJSON, generateTestIV())
+			ma.Config.Endpoint.TokenURL = ts.URL
+			require.NoError(t, service.Store(context.Background(), ma))
+
+			status, err := service.CheckToken(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantState, status.State)
+			assert.Equal(t, tt.wantRefreshed, status.Refreshed)
+			assert.Equal(t, tt.wantRequests, requests)
+
+			if tt.wantState != domain.MalAuthTokenValid {
+				assert.NotEmpty(t, status.Error)
+			}
+
+			dt, err := service.decrypt(repo.malAuth.AccessToken, repo.malAuth.TokenIV)
+			require.NoError(t, err)
+
+			var token oauth2.Token
+			require.NoError(t, json.Unmarshal(dt, &token))
+			if tt.wantRefreshed {
+				assert.Equal(t, "fresh-access-token", token.AccessToken)
+				assert.Equal(t, "fresh-refresh-token", token.RefreshToken)
+				assert.True(t, status.Expiry.After(time.Now().Add(tokenRefreshWindow)))
+			} else {
+				assert.Equal(t, "test-access-token", token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestService_CheckToken_NotAuthenticated(t *testing.T) {
+	config := &domain.Config{
+		EncryptionKey: generateTestKey(),
+	}
+	repo := &mockMalAuthRepo{err: sql.ErrNoRows}
+	var logs bytes.Buffer
+	service := NewService(config, zerolog.New(&logs), repo)
+
+	status, err := service.CheckToken(context.Background())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Nil(t, status)
+	assert.Empty(t, logs.String())
+}
+
+func TestService_TokenStatus(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer ts.Close()
+
+	config := &domain.Config{
+		EncryptionKey: generateTestKey(),
+	}
+	repo := &mockMalAuthRepo{}
+	service := NewService(config, zerolog.Nop(), repo)
+
+	store := func(expiry time.Duration, refreshToken string) {
+		tokenJSON, err := json.Marshal(&oauth2.Token{
+			AccessToken:  "test-access-token",
+			RefreshToken: refreshToken,
+			Expiry:       time.Now().Add(expiry),
+		})
+		require.NoError(t, err)
+
+		ma := domain.NewMalAuth("test-client-id", "test-client-secret", tokenJSON, generateTestIV())
+		ma.Config.Endpoint.TokenURL = ts.URL
+		require.NoError(t, service.Store(context.Background(), ma))
+	}
+
+	// an expiring token is not refreshed
+	store(time.Hour, "test-refresh-token")
+	status, err := service.TokenStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.MalAuthTokenValid, status.State)
+	assert.Equal(t, 0, requests)
+
+	// a failed check is reported until a new token is stored
+	_, err = service.CheckToken(context.Background())
+	require.NoError(t, err)
+	status, err = service.TokenStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.MalAuthTokenInvalid, status.State)
+	assert.Equal(t, 1, requests)
+
+	store(30*24*time.Hour, "test-refresh-token")
+	status, err = service.TokenStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.MalAuthTokenValid, status.State)
+
+	store(-time.Hour, "")
+	status, err = service.TokenStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.MalAuthTokenInvalid, status.State)
+
+	repo.err = sql.ErrNoRows
+	_, err = service.TokenStatus(context.Background())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
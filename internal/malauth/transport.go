@@ -0,0 +1,346 @@
+package malauth
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/sharedhttp"
+)
+
+const (
+	baseBackoff = time.Second
+	maxBackoff  = 2 * time.Minute
+)
+
+// ErrCircuitOpen is returned for MyAnimeList requests made while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("myanimelist circuit breaker is open, request rejected")
+
+// transport rate limits MyAnimeList requests with a token bucket, retries throttled and failed
+// requests with exponential backoff and stops sending requests for a while after repeated failures.
+type transport struct {
+	log  zerolog.Logger
+	base http.RoundTripper
+
+	rate       float64
+	burst      int
+	maxRetries int
+	threshold  int
+	cooldown   time.Duration
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu              sync.Mutex
+	tokens          float64
+	lastRefill      time.Time
+	state           domain.MALCircuitState
+	failures        int
+	openUntil       time.Time
+	probing         bool
+	totalRequests   int64
+	totalRetries    int64
+	totalThrottled  int64
+	totalRejected   int64
+	lastThrottledAt time.Time
+}
+
+func newTransport(log zerolog.Logger, config *domain.Config) *transport {
+	t := &transport{
+		log:        log,
+		base:       sharedhttp.Transport,
+		rate:       config.MALRateLimit,
+		burst:      config.MALRateBurst,
+		maxRetries: config.MALMaxRetries,
+		threshold:  config.MALCircuitBreakerThreshold,
+		cooldown:   time.Duration(config.MALCircuitBreakerCooldown) * time.Second,
+		now:        time.Now,
+		sleep:      sleepContext,
+		state:      domain.MALCircuitClosed,
+	}
+
+	if t.burst < 1 {
+		t.burst = 1
+	}
+
+	if t.maxRetries < 0 {
+		t.maxRetries = 0
+	}
+
+	t.tokens = float64(t.burst)
+	t.lastRefill = t.now()
+	return t
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := t.allow(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(ctx); err != nil {
+			t.release()
+			return nil, err
+		}
+
+		r, err := t.attemptRequest(req, attempt)
+		if err != nil {
+			t.recordFailure()
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		t.mu.Lock()
+		t.totalRequests++
+		t.mu.Unlock()
+
+		if ctx.Err() != nil {
+			t.release()
+			return resp, err
+		}
+
+		if !isRetryable(resp, err) {
+			t.recordSuccess()
+			return resp, err
+		}
+
+		if isThrottled(resp) {
+			t.mu.Lock()
+			t.totalThrottled++
+			t.lastThrottledAt = t.now()
+			t.mu.Unlock()
+		}
+
+		if attempt >= t.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			t.recordFailure()
+			return resp, err
+		}
+
+		delay := backoff(attempt, resp)
+		t.log.Debug().Str("url", req.URL.Path).Int("attempt", attempt+1).Dur("delay", delay).Msg("myanimelist request failed, retrying")
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		t.mu.Lock()
+		t.totalRetries++
+		t.mu.Unlock()
+
+		if err := t.sleep(ctx, delay); err != nil {
+			t.release()
+			return nil, err
+		}
+	}
+}
+
+// attemptRequest returns the request to send for the given attempt, rewinding the body for retries.
+func (t *transport) attemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 {
+		return req, nil
+	}
+
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to rewind request body")
+		}
+		r.Body = body
+	}
+
+	return r, nil
+}
+
+// allow checks the circuit breaker, letting a single probe request through once the cooldown has passed.
+func (t *transport) allow() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.state {
+	case domain.MALCircuitOpen:
+		if t.now().Before(t.openUntil) {
+			t.totalRejected++
+			return ErrCircuitOpen
+		}
+		t.state = domain.MALCircuitHalfOpen
+		t.probing = true
+	case domain.MALCircuitHalfOpen:
+		if t.probing {
+			t.totalRejected++
+			return ErrCircuitOpen
+		}
+		t.probing = true
+	}
+
+	return nil
+}
+
+// wait blocks until a token is available or the context is done.
+func (t *transport) wait(ctx context.Context) error {
+	if t.rate <= 0 {
+		return nil
+	}
+
+	for {
+		t.mu.Lock()
+		t.refill()
+		if t.tokens >= 1 {
+			t.tokens--
+			t.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - t.tokens) / t.rate * float64(time.Second))
+		t.mu.Unlock()
+
+		if err := t.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// refill adds the tokens accumulated since the last refill. Must be called with mu held.
+func (t *transport) refill() {
+	now := t.now()
+	if t.rate > 0 {
+		t.tokens = math.Min(float64(t.burst), t.tokens+now.Sub(t.lastRefill).Seconds()*t.rate)
+	}
+	t.lastRefill = now
+}
+
+func (t *transport) recordSuccess() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state != domain.MALCircuitClosed {
+		t.log.Info().Msg("myanimelist requests are succeeding again, circuit breaker closed")
+	}
+
+	t.state = domain.MALCircuitClosed
+	t.failures = 0
+	t.probing = false
+}
+
+func (t *transport) recordFailure() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.failures++
+	t.probing = false
+	if t.threshold <= 0 {
+		return
+	}
+
+	if t.state == domain.MALCircuitHalfOpen || t.failures >= t.threshold {
+		t.state = domain.MALCircuitOpen
+		t.openUntil = t.now().Add(t.cooldown)
+		t.log.Warn().Int("failures", t.failures).Time("until", t.openUntil).Msg("too many failed myanimelist requests, circuit breaker opened")
+	}
+}
+
+// release gives up a half open probe without counting it, e.g. when the caller cancelled the request.
+func (t *transport) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == domain.MALCircuitHalfOpen {
+		t.probing = false
+	}
+}
+
+func (t *transport) Status() domain.MALRateLimitStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.refill()
+	status := domain.MALRateLimitStatus{
+		RequestsPerSecond:   t.rate,
+		Burst:               t.burst,
+		AvailableTokens:     t.tokens,
+		MaxRetries:          t.maxRetries,
+		CircuitState:        t.state,
+		ConsecutiveFailures: t.failures,
+		TotalRequests:       t.totalRequests,
+		TotalRetries:        t.totalRetries,
+		TotalThrottled:      t.totalThrottled,
+		TotalRejected:       t.totalRejected,
+	}
+
+	if t.state == domain.MALCircuitOpen {
+		openUntil := t.openUntil
+		status.CircuitOpenUntil = &openUntil
+	}
+
+	if !t.lastThrottledAt.IsZero() {
+		lastThrottledAt := t.lastThrottledAt
+		status.LastThrottledAt = &lastThrottledAt
+	}
+
+	return status
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return isThrottled(resp) || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// isThrottled reports whether MyAnimeList rejected the request for sending too many. Besides 429,
+// MyAnimeList answers bursts of requests with 403.
+func isThrottled(resp *http.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden)
+}
+
+// backoff returns how long to wait before the next attempt, preferring the Retry-After header when present.
+func backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, maxBackoff)
+		}
+	}
+
+	if attempt >= 8 {
+		return maxBackoff
+	}
+
+	return min(baseBackoff<<attempt, maxBackoff)
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,235 @@
+package malauth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestResponse(status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+}
+
+// newTestTransport returns a transport using a fake clock that only advances while sleeping.
+func newTestTransport(config *domain.Config, base http.RoundTripper) (*transport, *[]time.Duration) {
+	t := newTransport(zerolog.Nop(), config)
+	t.base = base
+
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	slept := &[]time.Duration{}
+	t.now = func() time.Time { return clock }
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		clock = clock.Add(d)
+		return nil
+	}
+	t.lastRefill = clock
+
+	return t, slept
+}
+
+func TestTransport_RetriesWithRetryAfter(t *testing.T) {
+	var bodies []string
+	responses := []*http.Response{
+		newTestResponse(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"7"}}),
+		newTestResponse(http.StatusBadGateway, nil),
+		newTestResponse(http.StatusOK, nil),
+	}
+
+	tr, slept := newTestTransport(&domain.Config{MALMaxRetries: 3}, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		resp := responses[0]
+		responses = responses[1:]
+		return resp, nil
+	}))
+
+	req, err := http.NewRequest(http.MethodPatch, "https://api.myanimelist.net/v2/anime/1/my_list_status", strings.NewReader("status=watching"))
+	require.NoError(t, err)
+
+	resp, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"status=watching", "status=watching", "status=watching"}, bodies)
+	assert.Equal(t, []time.Duration{7 * time.Second, 2 * time.Second}, *slept)
+
+	status := tr.Status()
+	assert.Equal(t, int64(3), status.TotalRequests)
+	assert.Equal(t, int64(2), status.TotalRetries)
+	assert.Equal(t, int64(1), status.TotalThrottled)
+	assert.NotNil(t, status.LastThrottledAt)
+	assert.Equal(t, domain.MALCircuitClosed, status.CircuitState)
+	assert.Zero(t, status.ConsecutiveFailures)
+}
+
+func TestTransport_GivesUpAfterMaxRetries(t *testing.T) {
+	calls := 0
+	tr, _ := newTestTransport(&domain.Config{MALMaxRetries: 2}, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return newTestResponse(http.StatusServiceUnavailable, nil), nil
+	}))
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.myanimelist.net/v2/users/@me", nil)
+	require.NoError(t, err)
+
+	resp, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 1, tr.Status().ConsecutiveFailures)
+}
+
+func TestTransport_ForbiddenIsThrottling(t *testing.T) {
+	calls := 0
+	tr, slept := newTestTransport(&domain.Config{MALMaxRetries: 1}, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return newTestResponse(http.StatusForbidden, nil), nil
+	}))
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.myanimelist.net/v2/users/@me", nil)
+	require.NoError(t, err)
+
+	resp, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []time.Duration{time.Second}, *slept)
+
+	status := tr.Status()
+	assert.Equal(t, int64(2), status.TotalThrottled)
+	assert.Equal(t, 1, status.ConsecutiveFailures)
+}
+
+func TestTransport_CircuitBreaker(t *testing.T) {
+	failing := true
+	tr, _ := newTestTransport(&domain.Config{
+		MALCircuitBreakerThreshold: 2,
+		MALCircuitBreakerCooldown:  60,
+	}, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if failing {
+			return newTestResponse(http.StatusInternalServerError, nil), nil
+		}
+		return newTestResponse(http.StatusOK, nil), nil
+	}))
+
+	newReq := func() *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "https://api.myanimelist.net/v2/users/@me", nil)
+		require.NoError(t, err)
+		return req
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := tr.RoundTrip(newReq())
+		require.NoError(t, err)
+	}
+
+	status := tr.Status()
+	assert.Equal(t, domain.MALCircuitOpen, status.CircuitState)
+	require.NotNil(t, status.CircuitOpenUntil)
+
+	_, err := tr.RoundTrip(newReq())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int64(1), tr.Status().TotalRejected)
+
+	// After the cooldown a single probe is let through; success closes the circuit.
+	require.NoError(t, tr.sleep(context.Background(), time.Minute))
+	failing = false
+
+	resp, err := tr.RoundTrip(newReq())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	status = tr.Status()
+	assert.Equal(t, domain.MALCircuitClosed, status.CircuitState)
+	assert.Nil(t, status.CircuitOpenUntil)
+	assert.Zero(t, status.ConsecutiveFailures)
+}
+
+func TestTransport_FailedProbeReopensCircuit(t *testing.T) {
+	tr, _ := newTestTransport(&domain.Config{
+		MALCircuitBreakerThreshold: 1,
+		MALCircuitBreakerCooldown:  60,
+	}, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return newTestResponse(http.StatusInternalServerError, nil), nil
+	}))
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.myanimelist.net/v2/users/@me", nil)
+	require.NoError(t, err)
+
+	_, err = tr.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, tr.sleep(context.Background(), time.Minute))
+
+	_, err = tr.RoundTrip(req)
+	require.NoError(t, err)
+
+	_, err = tr.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestTransport_TokenBucket(t *testing.T) {
+	tr, slept := newTestTransport(&domain.Config{MALRateLimit: 2, MALRateBurst: 2}, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return newTestResponse(http.StatusOK, nil), nil
+	}))
+
+	for i := 0; i < 4; i++ {
+		req, err := http.NewRequest(http.MethodGet, "https://api.myanimelist.net/v2/users/@me", nil)
+		require.NoError(t, err)
+
+		_, err = tr.RoundTrip(req)
+		require.NoError(t, err)
+	}
+
+	// The burst is used up by the first two requests, the rest wait for a token each.
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, *slept)
+	assert.Equal(t, int64(4), tr.Status().TotalRequests)
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		header  string
+		want    time.Duration
+	}{
+		{name: "first attempt", attempt: 0, want: time.Second},
+		{name: "third attempt", attempt: 2, want: 4 * time.Second},
+		{name: "capped", attempt: 20, want: maxBackoff},
+		{name: "retry after seconds", attempt: 0, header: "30", want: 30 * time.Second},
+		{name: "retry after capped", attempt: 0, header: "3600", want: maxBackoff},
+		{name: "invalid retry after", attempt: 1, header: "soon", want: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Retry-After", tt.header)
+			}
+
+			assert.Equal(t, tt.want, backoff(tt.attempt, newTestResponse(http.StatusTooManyRequests, header)))
+		})
+	}
+}
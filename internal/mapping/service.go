@@ -21,9 +21,11 @@ import (
 type Service interface {
 	NewMap(ctx context.Context) (*domain.AnimeMap, error)
 	CheckForAnimeinMap(ctx context.Context, anime *domain.AnimeUpdate) (*domain.AnimeMapDetails, error)
+	CheckForMALinMap(ctx context.Context, malID, malEp int) (*domain.MappedEpisode, error)
 	ValidateMap(ctx context.Context, yamlPath string, isTVDB bool) error
 	Store(ctx context.Context, m *domain.MapSettings) error
 	Get(ctx context.Context) (*domain.MapSettings, error)
+	IsCached() bool
 }
 
 type service struct {
@@ -122,6 +124,13 @@ func (s *service) getCachedMap(ctx context.Context) (*domain.AnimeMap, error) {
 	return m, nil
 }
 
+// IsCached reports whether the anime map is loaded, it is loaded on first use.
+func (s *service) IsCached() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cachedMap != nil
+}
+
 // reloadMap forces a refresh of the cache
 func (s *service) reloadMap(ctx context.Context) (*domain.AnimeMap, error) {
 	m, err := s.loadMap(ctx)
@@ -161,6 +170,25 @@ func (s *service) CheckForAnimeinMap(ctx context.Context, anime *domain.AnimeUpd
 	return nil, errors.New("anime not found in map")
 }
 
+// CheckForMALinMap finds the TVDB episode or TMDB movie a MAL episode maps to. It only uses the
+// cached map, a miss is expected for most of a user's list and must not trigger a reload.
+func (s *service) CheckForMALinMap(ctx context.Context, malID, malEp int) (*domain.MappedEpisode, error) {
+	animeMap, err := s.getCachedMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if inMap, ep := animeMap.AnimeTVShows.ReverseMap(malID, malEp); inMap {
+		return ep, nil
+	}
+
+	if inMap, movie := animeMap.AnimeMovies.ReverseMap(malID); inMap {
+		return movie, nil
+	}
+
+	return nil, errors.New("anime not found in map")
+}
+
 // checkMap encapsulates the lookup logic
 func (s *service) checkMap(m *domain.AnimeMap, anime *domain.AnimeUpdate) (*domain.AnimeMapDetails, bool) {
 	switch anime.Plex.Metadata.Type {
@@ -0,0 +1,123 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+// deliveryMaxAttempts is the number of times a notification is sent before it is given up.
+const deliveryMaxAttempts = 3
+
+// deliveryRetryDelay is doubled after every failed attempt.
+var deliveryRetryDelay = 10 * time.Second
+
+// attemptLimiter is implemented by senders with a configurable number of attempts.
+type attemptLimiter interface {
+	MaxAttempts() int
+}
+
+func maxAttempts(sender domain.NotificationSender) int {
+	if l, ok := sender.(attemptLimiter); ok {
+		return l.MaxAttempts()
+	}
+
+	return deliveryMaxAttempts
+}
+
+// StatusError is returned by senders when a service responds with an unexpected status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %v body: %v", e.StatusCode, e.Body)
+}
+
+func newStatusError(statusCode int, body []byte) error {
+	return errors.WithStack(&StatusError{StatusCode: statusCode, Body: string(body)})
+}
+
+// retryableStatus reports whether a request that failed with statusCode may succeed later.
+func retryableStatus(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout
+}
+
+// isTransient reports whether err is worth retrying, server errors, rate limits and network errors.
+func isTransient(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func httpStatus(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+
+	return 0
+}
+
+// deliver sends the event with sender and records every attempt. Transient failures are retried
+// with backoff until the attempts of sender are used up or ctx is done. Senders post once per
+// call, all retries happen here.
+func (s *service) deliver(ctx context.Context, notificationID int, sender domain.NotificationSender, event domain.NotificationEvent, payload domain.NotificationPayload) {
+	attempts := maxAttempts(sender)
+	delay := deliveryRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := sender.Send(event, payload)
+
+		delivery := &domain.NotificationDelivery{
+			NotificationID: notificationID,
+			Event:          event,
+			Status:         domain.NotificationDeliveryStatusSuccess,
+			Attempt:        attempt,
+		}
+
+		retry := err != nil && attempt < attempts && isTransient(err)
+		if err != nil {
+			delivery.Status = domain.NotificationDeliveryStatusFailed
+			if retry {
+				delivery.Status = domain.NotificationDeliveryStatusRetrying
+			}
+			delivery.HTTPStatus = httpStatus(err)
+			delivery.Error = err.Error()
+		}
+
+		// stored without ctx, so the last attempt is recorded when a shutdown cancels the job
+		if storeErr := s.deliveryRepo.Store(context.Background(), delivery); storeErr != nil {
+			s.log.Error().Err(storeErr).Msgf("could not store %s notification delivery for %v", sender.Name(), string(event))
+		}
+
+		if err == nil {
+			return
+		}
+
+		if !retry {
+			s.log.Error().Err(err).Msgf("could not send %s notification for %v", sender.Name(), string(event))
+			return
+		}
+
+		s.log.Debug().Err(err).Msgf("%s notification attempt %d for %v failed, retrying in %v", sender.Name(), attempt, string(event), delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDeliveryRepo struct {
+	mu         sync.Mutex
+	deliveries []domain.NotificationDelivery
+}
+
+func (m *mockDeliveryRepo) Store(ctx context.Context, delivery *domain.NotificationDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, *delivery)
+	return nil
+}
+
+func (m *mockDeliveryRepo) FindByNotificationID(ctx context.Context, notificationID int) ([]domain.NotificationDelivery, error) {
+	return m.deliveries, nil
+}
+
+// mockSender returns the queued errors in order and succeeds once they ran out.
+type mockSender struct {
+	errs  []error
+	calls int
+}
+
+func (m *mockSender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
+	m.calls++
+	if len(m.errs) == 0 {
+		return nil
+	}
+
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return err
+}
+
+func (m *mockSender) CanSend(event domain.NotificationEvent) bool {
+	return true
+}
+
+func (m *mockSender) Name() string {
+	return "mock"
+}
+
+func TestService_Deliver(t *testing.T) {
+	deliveryRetryDelay = time.Millisecond
+	defer func() { deliveryRetryDelay = 10 * time.Second }()
+
+	unavailable := newStatusError(http.StatusServiceUnavailable, []byte("unavailable"))
+
+	tests := []struct {
+		name       string
+		errs       []error
+		wantCalls  int
+		wantStatus []domain.NotificationDeliveryStatus
+		wantHTTP   int
+	}{
+		{
+			name:       "success",
+			wantCalls:  1,
+			wantStatus: []domain.NotificationDeliveryStatus{domain.NotificationDeliveryStatusSuccess},
+		},
+		{
+			name:      "transient failure is retried",
+			errs:      []error{errors.Wrap(unavailable, "could not send"), &netError{}},
+			wantCalls: 3,
+			wantStatus: []domain.NotificationDeliveryStatus{
+				domain.NotificationDeliveryStatusRetrying,
+				domain.NotificationDeliveryStatusRetrying,
+				domain.NotificationDeliveryStatusSuccess,
+			},
+		},
+		{
+			name:       "client error is not retried",
+			errs:       []error{newStatusError(http.StatusNotFound, []byte("unknown webhook"))},
+			wantCalls:  1,
+			wantStatus: []domain.NotificationDeliveryStatus{domain.NotificationDeliveryStatusFailed},
+			wantHTTP:   http.StatusNotFound,
+		},
+		{
+			name:      "gives up after max attempts",
+			errs:      []error{unavailable, unavailable, unavailable, unavailable},
+			wantCalls: deliveryMaxAttempts,
+			wantStatus: []domain.NotificationDeliveryStatus{
+				domain.NotificationDeliveryStatusRetrying,
+				domain.NotificationDeliveryStatusRetrying,
+				domain.NotificationDeliveryStatusFailed,
+			},
+			wantHTTP: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockDeliveryRepo{}
+			s := &service{log: zerolog.Nop(), deliveryRepo: repo}
+			sender := &mockSender{errs: tt.errs}
+			payload := testdata.NewMockNotificationPayload()
+
+			s.deliver(context.Background(), 7, sender, payload.Event, payload)
+
+			assert.Equal(t, tt.wantCalls, sender.calls)
+			require.Len(t, repo.deliveries, len(tt.wantStatus))
+
+			for i, d := range repo.deliveries {
+				assert.Equal(t, 7, d.NotificationID)
+				assert.Equal(t, payload.Event, d.Event)
+				assert.Equal(t, i+1, d.Attempt)
+				assert.Equal(t, tt.wantStatus[i], d.Status)
+			}
+
+			last := repo.deliveries[len(repo.deliveries)-1]
+			assert.Equal(t, tt.wantHTTP, last.HTTPStatus)
+			if last.Status == domain.NotificationDeliveryStatusSuccess {
+				assert.Empty(t, last.Error)
+			} else {
+				assert.Contains(t, last.Error, "unexpected status")
+			}
+		})
+	}
+}
+
+func TestService_DeliverStopsOnCancel(t *testing.T) {
+	repo := &mockDeliveryRepo{}
+	s := &service{log: zerolog.Nop(), deliveryRepo: repo}
+	sender := &mockSender{errs: []error{newStatusError(http.StatusBadGateway, nil)}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.deliver(ctx, 1, sender, domain.NotificationEventSuccess, domain.NotificationPayload{})
+
+	assert.Equal(t, 1, sender.calls)
+	require.Len(t, repo.deliveries, 1)
+	assert.Equal(t, domain.NotificationDeliveryStatusRetrying, repo.deliveries[0].Status)
+	assert.Equal(t, http.StatusBadGateway, repo.deliveries[0].HTTPStatus)
+}
+
+type netError struct{}
+
+func (e *netError) Error() string   { return "connection refused" }
+func (e *netError) Timeout() bool   { return false }
+func (e *netError) Temporary() bool { return true }
@@ -0,0 +1,250 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+// digestMaxEntries limits the shows listed in the digest message, the counts include all of them.
+const digestMaxEntries = 25
+
+// digest sends the successful updates of a notification as one summary on its schedule.
+type digest struct {
+	notificationID int
+	sender         domain.NotificationSender
+	entryID        cron.EntryID
+	// cursor is the id of the last anime update included in a digest
+	cursor int64
+}
+
+// ValidateDigestSchedule checks the digest schedule of the notification is a standard cron expression.
+func ValidateDigestSchedule(settings *domain.Notification) error {
+	if settings.DigestSchedule == "" {
+		return nil
+	}
+
+	if _, err := cron.ParseStandard(settings.DigestSchedule); err != nil {
+		return errors.Wrapf(domain.ErrNotificationDigestScheduleInvalid, "%v", err)
+	}
+
+	return nil
+}
+
+// registerDigest schedules the digest of an enabled notification and removes a previous schedule.
+// Updates from the api do not carry the cursor, so it is kept from the previous schedule.
+func (s *service) registerDigest(notification *domain.Notification, sender domain.NotificationSender) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursor := notification.DigestCursor
+	if old, ok := s.digests[notification.ID]; ok {
+		s.cron.Remove(old.entryID)
+		delete(s.digests, notification.ID)
+
+		if cursor == 0 {
+			cursor = old.cursor
+		}
+	}
+
+	if notification.DigestSchedule == "" || sender == nil {
+		return
+	}
+
+	// the first digest starts with the updates from now on
+	if cursor == 0 {
+		latest, err := s.animeUpdateRepo.LatestID(context.Background())
+		if err != nil {
+			s.log.Error().Err(err).Msgf("could not start digest for notification: %v", notification.Name)
+			return
+		}
+
+		if err := s.repo.UpdateDigestCursor(context.Background(), notification.ID, latest); err != nil {
+			s.log.Error().Err(err).Msgf("could not store digest cursor for notification: %v", notification.Name)
+		}
+
+		cursor = latest
+	}
+
+	d := &digest{notificationID: notification.ID, sender: sender, cursor: cursor}
+
+	entryID, err := s.cron.AddFunc(notification.DigestSchedule, func() {
+		s.sendDigest(context.Background(), d)
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msgf("invalid digest schedule for notification: %v", notification.Name)
+		return
+	}
+
+	d.entryID = entryID
+	s.digests[notification.ID] = d
+}
+
+func (s *service) removeDigest(notificationID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := s.digests[notificationID]; ok {
+		s.cron.Remove(d.entryID)
+		delete(s.digests, notificationID)
+	}
+}
+
+func (s *service) hasDigest(notificationID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.digests[notificationID]
+	return ok
+}
+
+// sendDigest sends the anime updates stored since the previous digest. Nothing is sent when there
+// were none, the cursor moves on even if the delivery failed so the next digest is not repeated.
+func (s *service) sendDigest(ctx context.Context, d *digest) {
+	s.mu.Lock()
+	cursor := d.cursor
+	s.mu.Unlock()
+
+	updates, err := s.animeUpdateRepo.FindAfterID(ctx, cursor)
+	if err != nil {
+		s.log.Error().Err(err).Msgf("could not find anime updates for digest of notification: %v", d.notificationID)
+		return
+	}
+
+	if len(updates) == 0 {
+		return
+	}
+
+	summary := buildDigest(updates)
+	if summary.Shows > 0 || summary.Failed > 0 {
+		s.log.Debug().Msgf("sending digest for notification: %v", d.notificationID)
+		s.deliver(ctx, d.notificationID, d.sender, domain.NotificationEventDigest, digestPayload(summary))
+	}
+
+	latest := updates[len(updates)-1].ID
+
+	s.mu.Lock()
+	d.cursor = latest
+	s.mu.Unlock()
+
+	if err := s.repo.UpdateDigestCursor(ctx, d.notificationID, latest); err != nil {
+		s.log.Error().Err(err).Msgf("could not store digest cursor for notification: %v", d.notificationID)
+	}
+}
+
+// buildDigest summarizes anime updates in the order they were stored. Episodes are counted once
+// per show, dry runs and reverts are left out and so are updates that are still pending or did not
+// change the watched episodes, like ratings and plan to watch adds.
+func buildDigest(updates []*domain.AnimeUpdate) *domain.NotificationDigest {
+	summary := &domain.NotificationDigest{Entries: []domain.NotificationDigestEntry{}}
+
+	index := make(map[int]int)
+	episodes := make(map[int]map[[2]int]bool)
+
+	for _, update := range updates {
+		// dry runs did not change the list, reverts undo an update the digest already counted
+		if update.Status == domain.AnimeUpdateStatusDryRun || update.RevertOf != 0 {
+			continue
+		}
+
+		switch update.Status {
+		case domain.AnimeUpdateStatusFailed:
+			summary.Failed++
+			continue
+		case domain.AnimeUpdateStatusSuccess:
+			if !watchedChanged(update) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		i, ok := index[update.MALId]
+		if !ok {
+			i = len(summary.Entries)
+			index[update.MALId] = i
+			episodes[update.MALId] = make(map[[2]int]bool)
+			summary.Entries = append(summary.Entries, domain.NotificationDigestEntry{
+				MALID: update.MALId,
+				Title: update.ListDetails.Title,
+			})
+		}
+
+		entry := &summary.Entries[i]
+		entry.Progress = update.ListStatus.NumEpisodesWatched
+		entry.TotalEpisodes = update.ListDetails.TotalEpisodeNum
+		entry.Completed = update.ListStatus.Status == mal.AnimeStatusCompleted
+
+		episode := [2]int{update.SeasonNum, update.EpisodeNum}
+		if update.EpisodeNum > 0 && !episodes[update.MALId][episode] {
+			episodes[update.MALId][episode] = true
+			entry.Episodes++
+		}
+	}
+
+	for _, entry := range summary.Entries {
+		summary.Episodes += entry.Episodes
+		if entry.Completed {
+			summary.Completed++
+		}
+	}
+	summary.Shows = len(summary.Entries)
+
+	return summary
+}
+
+// watchedChanged reports whether update changed the watched episodes on the list, compared with the
+// list status from before the update. Rewatches start over, so any change counts.
+func watchedChanged(update *domain.AnimeUpdate) bool {
+	previous := 0
+	if update.PreviousStatus != nil {
+		previous = update.PreviousStatus.NumEpisodesWatched
+	}
+
+	return update.ListStatus.NumEpisodesWatched > 0 && update.ListStatus.NumEpisodesWatched != previous
+}
+
+func digestPayload(summary *domain.NotificationDigest) domain.NotificationPayload {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Shows progressed: %d\n", summary.Shows)
+	fmt.Fprintf(&b, "Episodes watched: %d\n", summary.Episodes)
+	fmt.Fprintf(&b, "Completed: %d\n", summary.Completed)
+	fmt.Fprintf(&b, "Failed updates: %d\n", summary.Failed)
+
+	if len(summary.Entries) > 0 {
+		b.WriteString("\n")
+	}
+
+	for i, entry := range summary.Entries {
+		if i == digestMaxEntries {
+			fmt.Fprintf(&b, "and %d more\n", len(summary.Entries)-digestMaxEntries)
+			break
+		}
+
+		total := "?"
+		if entry.TotalEpisodes > 0 {
+			total = fmt.Sprint(entry.TotalEpisodes)
+		}
+
+		fmt.Fprintf(&b, "%v: +%d (%d/%v)", entry.Title, entry.Episodes, entry.Progress, total)
+		if entry.Completed {
+			b.WriteString(" completed")
+		}
+		b.WriteString("\n")
+	}
+
+	return domain.NotificationPayload{
+		Subject:   fmt.Sprintf("%d episodes of %d shows watched", summary.Episodes, summary.Shows),
+		Message:   strings.TrimSuffix(b.String(), "\n"),
+		Event:     domain.NotificationEventDigest,
+		Timestamp: time.Now(),
+		Digest:    summary,
+	}
+}
@@ -0,0 +1,279 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNotificationRepo struct {
+	cursors map[int]int64
+}
+
+func (m *mockNotificationRepo) List(ctx context.Context) ([]domain.Notification, error) {
+	return nil, nil
+}
+
+func (m *mockNotificationRepo) Find(ctx context.Context, params domain.NotificationQueryParams) ([]domain.Notification, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockNotificationRepo) Store(ctx context.Context, notification *domain.Notification) error {
+	return nil
+}
+
+func (m *mockNotificationRepo) Update(ctx context.Context, notification *domain.Notification) error {
+	return nil
+}
+
+func (m *mockNotificationRepo) Delete(ctx context.Context, notificationID int) error {
+	return nil
+}
+
+func (m *mockNotificationRepo) UpdateDigestCursor(ctx context.Context, notificationID int, cursor int64) error {
+	m.cursors[notificationID] = cursor
+	return nil
+}
+
+type mockAnimeUpdateRepo struct {
+	animeUpdates []*domain.AnimeUpdate
+}
+
+func (m *mockAnimeUpdateRepo) Store(ctx context.Context, animeUpdate *domain.AnimeUpdate) error {
+	return nil
+}
+
+func (m *mockAnimeUpdateRepo) GetByID(ctx context.Context, req *domain.GetAnimeUpdateRequest) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateRepo) Count(ctx context.Context) (int, error) {
+	return len(m.animeUpdates), nil
+}
+
+func (m *mockAnimeUpdateRepo) GetRecentUnique(ctx context.Context, limit int) ([]*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateRepo) GetByPlexID(ctx context.Context, plexID int64) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateRepo) GetByPlexIDs(ctx context.Context, plexIDs []int64) ([]*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateRepo) FindAllWithFilters(ctx context.Context, params domain.AnimeUpdateQueryParams) (*domain.FindAnimeUpdatesResponse, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateRepo) FindAfterID(ctx context.Context, id int64) ([]*domain.AnimeUpdate, error) {
+	var result []*domain.AnimeUpdate
+	for _, au := range m.animeUpdates {
+		if au.ID > id {
+			result = append(result, au)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockAnimeUpdateRepo) LatestID(ctx context.Context) (int64, error) {
+	if len(m.animeUpdates) == 0 {
+		return 0, nil
+	}
+	return m.animeUpdates[len(m.animeUpdates)-1].ID, nil
+}
+
+func newDigestAnimeUpdate(id int64, malID int, title string, episode, watched, total int, status domain.AnimeUpdateStatusType) *domain.AnimeUpdate {
+	listStatus := mal.AnimeStatusWatching
+	if watched == total {
+		listStatus = mal.AnimeStatusCompleted
+	}
+
+	previous := watched - 1
+	if previous < 0 {
+		previous = 0
+	}
+
+	return &domain.AnimeUpdate{
+		ID:             id,
+		MALId:          malID,
+		EpisodeNum:     episode,
+		SeasonNum:      1,
+		Status:         status,
+		ListDetails:    domain.ListDetails{Title: title, TotalEpisodeNum: total},
+		ListStatus:     mal.AnimeListStatus{Status: listStatus, NumEpisodesWatched: watched},
+		PreviousStatus: &mal.AnimeListStatus{Status: mal.AnimeStatusWatching, NumEpisodesWatched: previous},
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	revert := newDigestAnimeUpdate(7, 52991, "Sousou no Frieren", 3, 2, 28, domain.AnimeUpdateStatusSuccess)
+	revert.RevertOf = 6
+
+	// rating the same episode again
+	rating := newDigestAnimeUpdate(4, 52991, "Sousou no Frieren", 2, 2, 28, domain.AnimeUpdateStatusSuccess)
+	rating.PreviousStatus.NumEpisodesWatched = 2
+
+	planToWatch := newDigestAnimeUpdate(10, 59978, "Sousou no Frieren 2nd Season", 0, 0, 10, domain.AnimeUpdateStatusSuccess)
+	planToWatch.ListStatus.Status = mal.AnimeStatusPlanToWatch
+	planToWatch.PreviousStatus = nil
+
+	// a rate event carries the episode of the rated item
+	rated := newDigestAnimeUpdate(11, 1575, "Code Geass: Lelouch of the Rebellion", 26, 25, 25, domain.AnimeUpdateStatusSuccess)
+	rated.PreviousStatus.NumEpisodesWatched = 25
+
+	updates := []*domain.AnimeUpdate{
+		newDigestAnimeUpdate(1, 52991, "Sousou no Frieren", 1, 1, 28, domain.AnimeUpdateStatusSuccess),
+		newDigestAnimeUpdate(2, 1575, "Code Geass: Lelouch of the Rebellion", 25, 25, 25, domain.AnimeUpdateStatusSuccess),
+		newDigestAnimeUpdate(3, 52991, "Sousou no Frieren", 2, 2, 28, domain.AnimeUpdateStatusSuccess),
+		rating,
+		newDigestAnimeUpdate(5, 21, "One Piece", 1100, 0, 0, domain.AnimeUpdateStatusFailed),
+		newDigestAnimeUpdate(6, 52991, "Sousou no Frieren", 3, 3, 28, domain.AnimeUpdateStatusSuccess),
+		revert,
+		newDigestAnimeUpdate(8, 40748, "Jujutsu Kaisen", 1, 1, 24, domain.AnimeUpdateStatusDryRun),
+		newDigestAnimeUpdate(9, 16498, "Attack on Titan", 1, 1, 25, domain.AnimeUpdateStatusPending),
+		planToWatch,
+		rated,
+	}
+
+	summary := buildDigest(updates)
+
+	assert.Equal(t, 2, summary.Shows)
+	assert.Equal(t, 4, summary.Episodes)
+	assert.Equal(t, 1, summary.Completed)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, []domain.NotificationDigestEntry{
+		{MALID: 52991, Title: "Sousou no Frieren", Episodes: 3, Progress: 3, TotalEpisodes: 28},
+		{MALID: 1575, Title: "Code Geass: Lelouch of the Rebellion", Episodes: 1, Progress: 25, TotalEpisodes: 25, Completed: true},
+	}, summary.Entries)
+}
+
+func TestDigestPayload(t *testing.T) {
+	payload := digestPayload(&domain.NotificationDigest{
+		Shows:     2,
+		Episodes:  4,
+		Completed: 1,
+		Failed:    1,
+		Entries: []domain.NotificationDigestEntry{
+			{MALID: 52991, Title: "Sousou no Frieren", Episodes: 3, Progress: 3, TotalEpisodes: 28},
+			{MALID: 21, Title: "One Piece", Episodes: 1, Progress: 1100},
+		},
+	})
+
+	assert.Equal(t, domain.NotificationEventDigest, payload.Event)
+	assert.Equal(t, "4 episodes of 2 shows watched", payload.Subject)
+	assert.Equal(t, "Shows progressed: 2\nEpisodes watched: 4\nCompleted: 1\nFailed updates: 1\n\nSousou no Frieren: +3 (3/28)\nOne Piece: +1 (1100/?)", payload.Message)
+	require.NotNil(t, payload.Digest)
+	assert.Equal(t, 2, payload.Digest.Shows)
+}
+
+func TestValidateDigestSchedule(t *testing.T) {
+	assert.NoError(t, ValidateDigestSchedule(&domain.Notification{}))
+	assert.NoError(t, ValidateDigestSchedule(&domain.Notification{DigestSchedule: "0 20 * * SUN"}))
+	assert.ErrorIs(t, ValidateDigestSchedule(&domain.Notification{DigestSchedule: "every sunday"}), domain.ErrNotificationDigestScheduleInvalid)
+}
+
+func newDigestTestService(animeUpdates ...*domain.AnimeUpdate) (*service, *mockNotificationRepo, *mockDeliveryRepo) {
+	repo := &mockNotificationRepo{cursors: make(map[int]int64)}
+	deliveryRepo := &mockDeliveryRepo{}
+
+	s := &service{
+		log:             zerolog.Nop(),
+		repo:            repo,
+		deliveryRepo:    deliveryRepo,
+		animeUpdateRepo: &mockAnimeUpdateRepo{animeUpdates: animeUpdates},
+		senders:         make(map[int]domain.NotificationSender),
+		cron:            cron.New(),
+		digests:         make(map[int]*digest),
+	}
+
+	return s, repo, deliveryRepo
+}
+
+func TestService_SendDigest(t *testing.T) {
+	s, repo, deliveryRepo := newDigestTestService(
+		newDigestAnimeUpdate(1, 52991, "Sousou no Frieren", 1, 1, 28, domain.AnimeUpdateStatusSuccess),
+	)
+
+	sender := &mockSender{}
+	notification := &domain.Notification{ID: 3, Enabled: true, DigestSchedule: "0 20 * * *"}
+	s.registerDigest(notification, sender)
+
+	// starts after the updates stored before the digest was enabled
+	require.Contains(t, s.digests, 3)
+	assert.Equal(t, int64(1), repo.cursors[3])
+
+	s.animeUpdateRepo.(*mockAnimeUpdateRepo).animeUpdates = append(s.animeUpdateRepo.(*mockAnimeUpdateRepo).animeUpdates,
+		newDigestAnimeUpdate(2, 52991, "Sousou no Frieren", 2, 2, 28, domain.AnimeUpdateStatusSuccess),
+		newDigestAnimeUpdate(3, 52991, "Sousou no Frieren", 3, 3, 28, domain.AnimeUpdateStatusSuccess),
+	)
+
+	s.sendDigest(context.Background(), s.digests[3])
+
+	assert.Equal(t, 1, sender.calls)
+	assert.Equal(t, int64(3), repo.cursors[3])
+	require.Len(t, deliveryRepo.deliveries, 1)
+	assert.Equal(t, domain.NotificationEventDigest, deliveryRepo.deliveries[0].Event)
+
+	// nothing new, nothing sent
+	s.sendDigest(context.Background(), s.digests[3])
+	assert.Equal(t, 1, sender.calls)
+
+	// updating the notification keeps the cursor
+	s.registerDigest(&domain.Notification{ID: 3, Enabled: true, DigestSchedule: "0 8 * * *"}, sender)
+	assert.Equal(t, int64(3), s.digests[3].cursor)
+	assert.Len(t, s.cron.Entries(), 1)
+
+	s.removeDigest(3)
+	assert.False(t, s.hasDigest(3))
+	assert.Empty(t, s.cron.Entries())
+}
+
+func TestService_HandleJobSkipsSuccessWithDigest(t *testing.T) {
+	s, _, deliveryRepo := newDigestTestService()
+
+	digestSender := &mockSender{}
+	instantSender := &mockSender{}
+	s.senders[1] = digestSender
+	s.senders[2] = instantSender
+	s.registerDigest(&domain.Notification{ID: 1, Enabled: true, DigestSchedule: "@daily"}, digestSender)
+
+	for _, event := range []domain.NotificationEvent{domain.NotificationEventSuccess, domain.NotificationEventAnimeUpdateError} {
+		data, err := json.Marshal(domain.NotificationJob{Event: event})
+		require.NoError(t, err)
+		require.NoError(t, s.handleJob(context.Background(), data))
+	}
+
+	assert.Equal(t, 1, digestSender.calls, "only the error is sent immediately")
+	assert.Equal(t, 2, instantSender.calls)
+	assert.Len(t, deliveryRepo.deliveries, 3)
+}
+
+func TestService_HandleJobWhileSendersChange(t *testing.T) {
+	s, _, _ := newDigestTestService()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.registerSender(&domain.Notification{ID: 2, Type: domain.NotificationTypeWebhook, Enabled: i%2 == 0})
+		}
+	}()
+
+	data, err := json.Marshal(domain.NotificationJob{Event: domain.NotificationEventAnimeUpdateError})
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, s.handleJob(context.Background(), data))
+	}
+
+	<-done
+}
@@ -54,6 +54,7 @@ const (
 type discordSender struct {
 	log      zerolog.Logger
 	Settings *domain.Notification
+	tmpl     *messageTemplate
 
 	httpClient *http.Client
 }
@@ -63,9 +64,12 @@ func (a *discordSender) Name() string {
 }
 
 func NewDiscordSender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "discord").Logger()
+
 	return &discordSender{
-		log:      log.With().Str("sender", "discord").Logger(),
+		log:      log,
 		Settings: settings,
+		tmpl:     newMessageTemplate(log, settings),
 		httpClient: &http.Client{
 			Timeout:   time.Second * 30,
 			Transport: sharedhttp.Transport,
@@ -108,7 +112,7 @@ func (a *discordSender) Send(event domain.NotificationEvent, payload domain.Noti
 			return errors.Wrap(err, fmt.Sprintf("could not read body for event: %v payload: %v", event, payload))
 		}
 
-		return errors.New(fmt.Sprintf("unexpected status: %v body: %v", res.StatusCode, string(body)))
+		return newStatusError(res.StatusCode, body)
 	}
 
 	a.log.Debug().Msg("notification successfully sent to discord")
@@ -152,7 +156,8 @@ func (a *discordSender) buildEmbed(event domain.NotificationEvent, payload domai
 	switch event {
 	case domain.NotificationEventSuccess:
 		color = GREEN
-	case domain.NotificationEventPlexProcessingError, domain.NotificationEventAnimeUpdateError:
+	case domain.NotificationEventPlexProcessingError, domain.NotificationEventAnimeUpdateError,
+		domain.NotificationEventMalAuthExpiring, domain.NotificationEventMalAuthInvalid:
 		color = RED
 	case domain.NotificationEventTest:
 		color = LIGHT_BLUE
@@ -267,6 +272,14 @@ func (a *discordSender) buildEmbed(event domain.NotificationEvent, payload domai
 		embed.Title = payload.MediaName
 	}
 
+	if title, ok := a.tmpl.RenderTitle(event, payload); ok {
+		embed.Title = title
+	}
+
+	if body, ok := a.tmpl.RenderBody(event, payload); ok {
+		embed.Description = body
+	}
+
 	if payload.PictureURL != "" {
 		embed.Image = Image{
 			URL: payload.PictureURL,
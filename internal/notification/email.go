@@ -0,0 +1,353 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+const (
+	EmailTLSNone     = "NONE"
+	EmailTLSStartTLS = "STARTTLS"
+	EmailTLSImplicit = "TLS"
+
+	emailTimeout = 30 * time.Second
+)
+
+var emailTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, Helvetica, sans-serif; color: #222; margin: 0; padding: 16px;">
+<h2 style="margin: 0 0 16px 0;">{{.Title}}</h2>
+{{- if .Success}}
+<table cellpadding="0" cellspacing="0" border="0">
+<tr>
+{{- if .PictureURL}}
+<td valign="top" style="padding-right: 16px;"><img src="{{.PictureURL}}" alt="{{.MediaName}}" width="150" style="border-radius: 6px;"></td>
+{{- end}}
+<td valign="top">
+<h3 style="margin: 0 0 8px 0;">{{.MediaName}}</h3>
+{{- if .EpisodesWatched}}
+<p style="margin: 4px 0;"><b>Progress:</b> {{.EpisodesWatched}}{{if .EpisodesTotal}} / {{.EpisodesTotal}}{{end}} episodes</p>
+{{- end}}
+{{- if .AnimeStatus}}
+<p style="margin: 4px 0;"><b>MAL Watch Status:</b> {{.AnimeStatus}}</p>
+{{- end}}
+{{- if .Score}}
+<p style="margin: 4px 0;"><b>Score:</b> {{.Score}}</p>
+{{- end}}
+{{- if .TimesRewatched}}
+<p style="margin: 4px 0;"><b>Times Rewatched:</b> {{.TimesRewatched}}</p>
+{{- end}}
+{{- if .AnimeLibrary}}
+<p style="margin: 4px 0;"><b>Anime Library:</b> {{.AnimeLibrary}}</p>
+{{- end}}
+</td>
+</tr>
+</table>
+{{- else if .Body}}
+<p style="white-space: pre-wrap; margin: 0 0 8px 0;">{{.Body}}</p>
+{{- else}}
+{{- if .Subject}}
+<h3 style="margin: 0 0 8px 0;">{{.Subject}}</h3>
+{{- end}}
+{{- if .Message}}
+<p style="white-space: pre-wrap; margin: 0 0 8px 0;">{{.Message}}</p>
+{{- end}}
+{{- if .AnimeLibrary}}
+<p style="margin: 4px 0;"><b>Anime Library:</b> {{.AnimeLibrary}}</p>
+{{- end}}
+{{- end}}
+{{- if .MALURL}}
+<p style="margin: 16px 0 0 0;"><a href="{{.MALURL}}">View on MyAnimeList</a></p>
+{{- end}}
+</body>
+</html>
+`))
+
+type emailData struct {
+	domain.NotificationPayload
+	Title   string
+	Body    string
+	Success bool
+	MALURL  string
+}
+
+// emailSender sends over SMTP. Host and Port are the server, TLSMode one of NONE, STARTTLS (default)
+// or TLS, From the sender address, defaulting to Username, and Targets the comma separated recipients.
+type emailSender struct {
+	log      zerolog.Logger
+	Settings *domain.Notification
+	tmpl     *messageTemplate
+	builder  MessageBuilderPlainText
+}
+
+func (s *emailSender) Name() string {
+	return "email"
+}
+
+func NewEmailSender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "email").Logger()
+
+	return &emailSender{
+		log:      log,
+		Settings: settings,
+		tmpl:     newMessageTemplate(log, settings),
+		builder:  MessageBuilderPlainText{},
+	}
+}
+
+func (s *emailSender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
+	from, err := mail.ParseAddress(s.from())
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("invalid from address: %v", s.from()))
+	}
+
+	to, err := mail.ParseAddressList(s.Settings.Targets)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("invalid recipients: %v", s.Settings.Targets))
+	}
+
+	msg, err := s.buildEmail(event, payload, from, to)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not build email for event: %v", event))
+	}
+
+	if err := s.send(from, to, msg); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not send email for event: %v", event))
+	}
+
+	s.log.Debug().Msg("notification successfully sent by email")
+
+	return nil
+}
+
+func (s *emailSender) buildEmail(event domain.NotificationEvent, payload domain.NotificationPayload, from *mail.Address, to []*mail.Address) ([]byte, error) {
+	data := emailData{
+		NotificationPayload: payload,
+		Title:               s.tmpl.Title(event, payload),
+		Success:             event == domain.NotificationEventSuccess,
+	}
+
+	// a body template replaces the built-in content of the email
+	if body, ok := s.tmpl.RenderBody(event, payload); ok {
+		data.Body = body
+		data.Success = false
+	}
+
+	if payload.MALID > 0 {
+		data.MALURL = fmt.Sprintf(MAlAnimeURL, payload.MALID)
+	}
+
+	var htmlBody bytes.Buffer
+	if err := emailTemplate.Execute(&htmlBody, data); err != nil {
+		return nil, err
+	}
+
+	textBody := s.tmpl.Body(event, payload, &s.builder)
+	if data.MALURL != "" {
+		textBody += "\n" + data.MALURL + "\n"
+	}
+
+	subject := data.Title
+	if _, ok := s.tmpl.RenderTitle(event, payload); !ok && payload.MediaName != "" {
+		subject += ": " + payload.MediaName
+	}
+
+	recipients := make([]string, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, addr.String())
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", textBody},
+		{"text/html; charset=utf-8", htmlBody.String()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *emailSender) send(from *mail.Address, to []*mail.Address, msg []byte) error {
+	host := s.Settings.Host
+	addr := net.JoinHostPort(host, strconv.Itoa(s.port()))
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var conn net.Conn
+	var err error
+	if s.tlsMode() == EmailTLSImplicit {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: emailTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, emailTimeout)
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not connect to smtp server")
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(emailTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "could not create smtp client")
+	}
+	defer c.Close()
+
+	if s.tlsMode() == EmailTLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return errors.Wrap(err, "could not start tls")
+		}
+	}
+
+	if s.Settings.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Settings.Username, s.Settings.Password, host)); err != nil {
+			return errors.Wrap(err, "could not authenticate")
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+
+	for _, addr := range to {
+		if err := c.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func (s *emailSender) from() string {
+	if s.Settings.From != "" {
+		return s.Settings.From
+	}
+
+	return s.Settings.Username
+}
+
+func (s *emailSender) tlsMode() string {
+	switch strings.ToUpper(s.Settings.TLSMode) {
+	case EmailTLSNone:
+		return EmailTLSNone
+	case EmailTLSImplicit:
+		return EmailTLSImplicit
+	default:
+		return EmailTLSStartTLS
+	}
+}
+
+func (s *emailSender) port() int {
+	if s.Settings.Port > 0 {
+		return s.Settings.Port
+	}
+
+	switch s.tlsMode() {
+	case EmailTLSNone:
+		return 25
+	case EmailTLSImplicit:
+		return 465
+	default:
+		return 587
+	}
+}
+
+func (s *emailSender) CanSend(event domain.NotificationEvent) bool {
+	if s.isEnabled() && s.isEnabledEvent(event) {
+		return true
+	}
+	return false
+}
+
+func (s *emailSender) isEnabled() bool {
+	if s.Settings.Enabled {
+		if s.Settings.Host == "" {
+			s.log.Warn().Msg("email missing smtp host")
+			return false
+		}
+
+		if s.from() == "" {
+			s.log.Warn().Msg("email missing from address")
+			return false
+		}
+
+		if s.Settings.Targets == "" {
+			s.log.Warn().Msg("email missing recipients")
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (s *emailSender) isEnabledEvent(event domain.NotificationEvent) bool {
+	return enabledEvent(s.Settings.Events, event)
+}
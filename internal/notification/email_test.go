@@ -0,0 +1,184 @@
+package notification
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseEmail returns the subject and the text and html parts of msg.
+func parseEmail(t *testing.T, msg []byte) (string, string, string) {
+	m, err := mail.ReadMessage(strings.NewReader(string(msg)))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(p)
+		require.NoError(t, err)
+		parts[strings.Split(p.Header.Get("Content-Type"), ";")[0]] = string(body)
+	}
+
+	return subject, parts["text/plain"], parts["text/html"]
+}
+
+func TestEmailSender_buildEmail(t *testing.T) {
+	s := NewEmailSender(zerolog.Nop(), &domain.Notification{}).(*emailSender)
+	from := &mail.Address{Name: "shinkro", Address: "shinkro@example.com"}
+	to := []*mail.Address{{Address: "user@example.com"}}
+
+	t.Run("success", func(t *testing.T) {
+		payload := testdata.NewMockNotificationPayload()
+		msg, err := s.buildEmail(payload.Event, payload, from, to)
+		require.NoError(t, err)
+
+		subject, text, html := parseEmail(t, msg)
+		assert.Equal(t, "MAL Update Successful: Attack on Titan", subject)
+		assert.Contains(t, text, "Show: Attack on Titan")
+		assert.Contains(t, text, "https://myanimelist.net/anime/1575")
+		assert.Contains(t, html, `<img src="https://cdn.myanimelist.net/images/anime/10/47347.jpg"`)
+		assert.Contains(t, html, "<b>Progress:</b> 5 / 25 episodes")
+		assert.Contains(t, html, `<a href="https://myanimelist.net/anime/1575">`)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		payload := testdata.NewMockNotificationPayloadError(domain.NotificationEventAnimeUpdateError)
+		payload.Message += "\n\nAction Required: Add a mapping for <this> anime."
+		msg, err := s.buildEmail(payload.Event, payload, from, to)
+		require.NoError(t, err)
+
+		_, text, html := parseEmail(t, msg)
+		assert.Contains(t, text, "Action Required: Add a mapping")
+		assert.Contains(t, html, "<h3 style=\"margin: 0 0 8px 0;\">Mapping Not Found</h3>")
+		assert.Contains(t, html, "Action Required: Add a mapping for &lt;this&gt; anime.")
+		assert.NotContains(t, html, "<img")
+	})
+}
+
+// fakeSMTPServer accepts a single plain text smtp session and returns the recipients and message.
+func fakeSMTPServer(t *testing.T) (string, <-chan []string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	rcpts := make(chan []string, 1)
+	data := make(chan string, 1)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+
+		var to []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"), strings.HasPrefix(cmd, "MAIL"):
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT"):
+				to = append(to, strings.Trim(strings.TrimPrefix(strings.TrimSpace(line), "RCPT TO:"), "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var msg strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				rcpts <- to
+				data <- msg.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	return l.Addr().String(), rcpts, data
+}
+
+func TestEmailSender_Send(t *testing.T) {
+	addr, rcpts, data := fakeSMTPServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	settings := &domain.Notification{
+		Type:    domain.NotificationTypeEmail,
+		Enabled: true,
+		Host:    host,
+		TLSMode: EmailTLSNone,
+		From:    "shinkro <shinkro@example.com>",
+		Targets: "user@example.com, other@example.com",
+	}
+	settings.Port, err = net.LookupPort("tcp", port)
+	require.NoError(t, err)
+
+	sender := NewEmailSender(zerolog.Nop(), settings)
+	assert.False(t, sender.CanSend(domain.NotificationEventTest), "no events enabled")
+
+	payload := testdata.NewMockNotificationPayload()
+	require.NoError(t, sender.Send(payload.Event, payload))
+
+	assert.Equal(t, []string{"user@example.com", "other@example.com"}, <-rcpts)
+	msg := <-data
+	assert.Contains(t, msg, "From: \"shinkro\" <shinkro@example.com>")
+	assert.Contains(t, msg, "To: <user@example.com>, <other@example.com>")
+}
+
+func TestEmailSender_port(t *testing.T) {
+	tests := []struct {
+		settings domain.Notification
+		want     int
+	}{
+		{settings: domain.Notification{}, want: 587},
+		{settings: domain.Notification{TLSMode: "tls"}, want: 465},
+		{settings: domain.Notification{TLSMode: EmailTLSNone}, want: 25},
+		{settings: domain.Notification{TLSMode: EmailTLSImplicit, Port: 2465}, want: 2465},
+	}
+
+	for _, tt := range tests {
+		s := &emailSender{Settings: &tt.settings}
+		assert.Equal(t, tt.want, s.port())
+	}
+}
@@ -24,6 +24,7 @@ type gotifyMessage struct {
 type gotifySender struct {
 	log      zerolog.Logger
 	Settings *domain.Notification
+	tmpl     *messageTemplate
 	builder  MessageBuilderPlainText
 
 	httpClient *http.Client
@@ -34,9 +35,12 @@ func (s *gotifySender) Name() string {
 }
 
 func NewGotifySender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "gotify").Logger()
+
 	return &gotifySender{
-		log:      log.With().Str("sender", "gotify").Logger(),
+		log:      log,
 		Settings: settings,
+		tmpl:     newMessageTemplate(log, settings),
 		builder:  MessageBuilderPlainText{},
 		httpClient: &http.Client{
 			Timeout:   time.Second * 30,
@@ -47,8 +51,8 @@ func NewGotifySender(log zerolog.Logger, settings *domain.Notification) domain.N
 
 func (s *gotifySender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
 	m := gotifyMessage{
-		Message: s.builder.BuildBody(payload),
-		Title:   BuildTitle(event),
+		Message: s.tmpl.Body(event, payload, &s.builder),
+		Title:   s.tmpl.Title(event, payload),
 	}
 
 	data := url.Values{}
@@ -78,7 +82,7 @@ func (s *gotifySender) Send(event domain.NotificationEvent, payload domain.Notif
 			return errors.Wrap(err, fmt.Sprintf("could not read body for event: %v payload: %v", event, payload))
 		}
 
-		return errors.New(fmt.Sprintf("unexpected status: %v body: %v", res.StatusCode, string(body)))
+		return newStatusError(res.StatusCode, body)
 	}
 
 	s.log.Debug().Msg("notification successfully sent to gotify")
@@ -0,0 +1,227 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/sharedhttp"
+)
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// matrixSender posts to rooms with the client-server API. Host is the homeserver url, Token the
+// access token of the sending user and Rooms the comma separated room ids or aliases it has joined.
+type matrixSender struct {
+	log          zerolog.Logger
+	Settings     *domain.Notification
+	tmpl         *messageTemplate
+	builder      MessageBuilderHTML
+	plainBuilder MessageBuilderPlainText
+
+	httpClient *http.Client
+}
+
+func (s *matrixSender) Name() string {
+	return "matrix"
+}
+
+func NewMatrixSender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "matrix").Logger()
+
+	return &matrixSender{
+		log:          log,
+		Settings:     settings,
+		tmpl:         newMessageTemplate(log, settings),
+		builder:      MessageBuilderHTML{},
+		plainBuilder: MessageBuilderPlainText{},
+		httpClient: &http.Client{
+			Timeout:   time.Second * 30,
+			Transport: sharedhttp.Transport,
+		},
+	}
+}
+
+func (s *matrixSender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
+	title := s.tmpl.Title(event, payload)
+	formattedBody := s.tmpl.HTMLBody(event, payload, &s.builder)
+
+	m := matrixMessage{
+		MsgType:       "m.text",
+		Body:          fmt.Sprintf("%v\n\n%v", title, s.tmpl.Body(event, payload, &s.plainBuilder)),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: fmt.Sprintf("<h4>%v</h4>%v", html.EscapeString(title), strings.ReplaceAll(strings.TrimSuffix(formattedBody, "\n"), "\n", "<br>")),
+	}
+
+	if payload.MALID > 0 {
+		malURL := fmt.Sprintf(MAlAnimeURL, payload.MALID)
+		m.Body += malURL + "\n"
+		m.FormattedBody += fmt.Sprintf("<br><a href=\"%v\">View on MyAnimeList</a>", malURL)
+	}
+
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not marshal json request for event: %v payload: %v", event, payload))
+	}
+
+	// send to every room, a failing room does not stop the others. The returned error keeps the
+	// first transient failure, so the delivery is retried; rooms that already got the message
+	// ignore the retry by its transaction id.
+	var failed []string
+	var sendErr error
+	for _, room := range s.rooms() {
+		if err := s.sendToRoom(room, matrixTxnID(room, payload, jsonData), jsonData); err != nil {
+			s.log.Error().Err(err).Msgf("could not send %v notification to room %v", event, room)
+			failed = append(failed, room)
+			if sendErr == nil || (!isTransient(sendErr) && isTransient(err)) {
+				sendErr = err
+			}
+		}
+	}
+
+	if sendErr != nil {
+		return errors.Wrap(sendErr, fmt.Sprintf("could not send to rooms: %v", strings.Join(failed, ", ")))
+	}
+
+	s.log.Debug().Msg("notification successfully sent to matrix")
+
+	return nil
+}
+
+// matrixTxnID returns the same transaction id for every attempt of sending payload to room, the
+// homeserver drops a message it already got with that id.
+func matrixTxnID(room string, payload domain.NotificationPayload, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(room))
+	h.Write([]byte(payload.Timestamp.UTC().Format(time.RFC3339Nano)))
+	h.Write(body)
+
+	return "shinkro" + hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+func (s *matrixSender) sendToRoom(room, txnID string, body []byte) error {
+	roomID, err := s.resolveRoom(room)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("/_matrix/client/v3/rooms/%v/send/m.room.message/%v", url.PathEscape(roomID), txnID)
+
+	_, err = s.do(http.MethodPut, endpoint, body)
+
+	return err
+}
+
+// resolveRoom returns the room id of room aliases, which start with #.
+func (s *matrixSender) resolveRoom(room string) (string, error) {
+	if !strings.HasPrefix(room, "#") {
+		return room, nil
+	}
+
+	body, err := s.do(http.MethodGet, "/_matrix/client/v3/directory/room/"+url.PathEscape(room), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "could not resolve room alias")
+	}
+
+	var res struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", errors.Wrap(err, "could not unmarshal room alias")
+	}
+
+	return res.RoomID, nil
+}
+
+func (s *matrixSender) do(method, endpoint string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(s.Settings.Host, "/")+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create request")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.Settings.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", sharedhttp.UserAgent)
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "client request error")
+	}
+
+	defer res.Body.Close()
+
+	s.log.Trace().Msgf("matrix status: %d", res.StatusCode)
+
+	resBody, err := io.ReadAll(bufio.NewReader(res.Body))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read body")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, newStatusError(res.StatusCode, resBody)
+	}
+
+	return resBody, nil
+}
+
+func (s *matrixSender) rooms() []string {
+	var rooms []string
+	for _, r := range strings.Split(s.Settings.Rooms, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			rooms = append(rooms, r)
+		}
+	}
+
+	return rooms
+}
+
+func (s *matrixSender) CanSend(event domain.NotificationEvent) bool {
+	if s.isEnabled() && s.isEnabledEvent(event) {
+		return true
+	}
+	return false
+}
+
+func (s *matrixSender) isEnabled() bool {
+	if s.Settings.Enabled {
+		if s.Settings.Host == "" {
+			s.log.Warn().Msg("matrix missing homeserver url")
+			return false
+		}
+
+		if s.Settings.Token == "" {
+			s.log.Warn().Msg("matrix missing access token")
+			return false
+		}
+
+		if len(s.rooms()) == 0 {
+			s.log.Warn().Msg("matrix missing rooms")
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (s *matrixSender) isEnabledEvent(event domain.NotificationEvent) bool {
+	return enabledEvent(s.Settings.Events, event)
+}
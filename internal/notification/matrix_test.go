@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatrixSender_Send(t *testing.T) {
+	sent := map[string]matrixMessage{}
+	txnIDs := map[string][]string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer syt_token", r.Header.Get("Authorization"))
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/_matrix/client/v3/directory/room/#anime:example.org":
+			w.Write([]byte(`{"room_id":"!resolved:example.org"}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/"):
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/_matrix/client/v3/rooms/"), "/")
+			room := parts[0]
+			txnIDs[room] = append(txnIDs[room], parts[len(parts)-1])
+			switch room {
+			case "!forbidden:example.org":
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errcode":"M_FORBIDDEN"}`))
+				return
+			case "!busy:example.org":
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+
+			var m matrixMessage
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+			sent[room] = m
+			w.Write([]byte(`{"event_id":"$abc"}`))
+		default:
+			t.Errorf("unexpected request: %v %v", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	settings := &domain.Notification{
+		Type:    domain.NotificationTypeMatrix,
+		Enabled: true,
+		Host:    srv.URL + "/",
+		Token:   "syt_token",
+		Rooms:   "!room:example.org, #anime:example.org",
+	}
+	sender := NewMatrixSender(zerolog.Nop(), settings)
+
+	payload := testdata.NewMockNotificationPayload()
+	require.NoError(t, sender.Send(payload.Event, payload))
+
+	require.Len(t, sent, 2)
+	m := sent["!resolved:example.org"]
+	assert.Equal(t, "m.text", m.MsgType)
+	assert.Equal(t, "org.matrix.custom.html", m.Format)
+	assert.Contains(t, m.Body, "Show: Attack on Titan")
+	assert.Contains(t, m.FormattedBody, "<h4>MAL Update Successful</h4>")
+	assert.Contains(t, m.FormattedBody, "<b>Show:</b> Attack on Titan<br>")
+	assert.Contains(t, m.FormattedBody, `<a href="https://myanimelist.net/anime/1575">`)
+	assert.NotContains(t, m.FormattedBody, "\n")
+
+	// a failing room is reported after the others were sent
+	sent = map[string]matrixMessage{}
+	settings.Rooms = "!forbidden:example.org,!room:example.org"
+	err := sender.Send(payload.Event, payload)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "!forbidden:example.org")
+	assert.Contains(t, sent, "!room:example.org")
+	assert.Equal(t, http.StatusForbidden, httpStatus(err))
+	assert.False(t, isTransient(err))
+
+	// a transient failure of any room retries the delivery, with the same transaction ids
+	settings.Rooms = "!forbidden:example.org,!busy:example.org,!room:example.org"
+	txnIDs = map[string][]string{}
+	for i := 0; i < 2; i++ {
+		err = sender.Send(payload.Event, payload)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadGateway, httpStatus(err))
+		assert.True(t, isTransient(err))
+	}
+
+	require.Len(t, txnIDs["!room:example.org"], 2)
+	assert.Equal(t, txnIDs["!room:example.org"][0], txnIDs["!room:example.org"][1])
+	assert.NotEqual(t, txnIDs["!room:example.org"][0], txnIDs["!busy:example.org"][0])
+}
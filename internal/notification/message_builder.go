@@ -82,6 +82,9 @@ func BuildTitle(event domain.NotificationEvent) string {
 		domain.NotificationEventPlexProcessingError: "Plex Processing Error",
 		domain.NotificationEventAnimeUpdateError:    "Anime Update Error",
 		domain.NotificationEventTest:               "TEST",
+		domain.NotificationEventDigest:             "shinkro Digest",
+		domain.NotificationEventMalAuthExpiring:    "MAL Authentication Expiring",
+		domain.NotificationEventMalAuthInvalid:     "MAL Authentication Invalid",
 	}
 
 	if title, ok := titles[event]; ok {
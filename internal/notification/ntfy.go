@@ -0,0 +1,191 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/sharedhttp"
+)
+
+const (
+	ntfyDefaultHost = "https://ntfy.sh"
+
+	ntfyPriorityDefault = 3
+	ntfyPriorityHigh    = 4
+	ntfyPriorityMax     = 5
+)
+
+type ntfyMessage struct {
+	Topic    string   `json:"topic"`
+	Message  string   `json:"message"`
+	Title    string   `json:"title"`
+	Tags     []string `json:"tags,omitempty"`
+	Priority int      `json:"priority,omitempty"`
+	Click    string   `json:"click,omitempty"`
+	Icon     string   `json:"icon,omitempty"`
+	Attach   string   `json:"attach,omitempty"`
+}
+
+// ntfyTags are shown as emojis in front of the title.
+var ntfyTags = map[domain.NotificationEvent][]string{
+	domain.NotificationEventSuccess:             {"white_check_mark"},
+	domain.NotificationEventAppUpdateAvailable:  {"arrow_up"},
+	domain.NotificationEventPlexProcessingError: {"warning"},
+	domain.NotificationEventAnimeUpdateError:    {"warning"},
+	domain.NotificationEventTest:                {"test_tube"},
+	domain.NotificationEventDigest:              {"bar_chart"},
+	domain.NotificationEventMalAuthExpiring:     {"hourglass"},
+	domain.NotificationEventMalAuthInvalid:      {"no_entry"},
+}
+
+// ntfySender publishes to a topic on an ntfy server. Host defaults to ntfy.sh, Token is an access token,
+// otherwise Username and Password are used for basic auth when set.
+type ntfySender struct {
+	log      zerolog.Logger
+	Settings *domain.Notification
+	tmpl     *messageTemplate
+	builder  MessageBuilderPlainText
+
+	httpClient *http.Client
+}
+
+func (s *ntfySender) Name() string {
+	return "ntfy"
+}
+
+func NewNtfySender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "ntfy").Logger()
+
+	return &ntfySender{
+		log:      log,
+		Settings: settings,
+		tmpl:     newMessageTemplate(log, settings),
+		builder:  MessageBuilderPlainText{},
+		httpClient: &http.Client{
+			Timeout:   time.Second * 30,
+			Transport: sharedhttp.Transport,
+		},
+	}
+}
+
+func (s *ntfySender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
+	m := ntfyMessage{
+		Topic:    s.Settings.Topic,
+		Message:  s.tmpl.Body(event, payload, &s.builder),
+		Title:    s.tmpl.Title(event, payload),
+		Tags:     ntfyTags[event],
+		Priority: s.priority(event),
+		Icon:     payload.PictureURL,
+	}
+
+	if payload.MALID > 0 {
+		m.Click = fmt.Sprintf(MAlAnimeURL, payload.MALID)
+	}
+
+	if event == domain.NotificationEventSuccess {
+		m.Attach = payload.PictureURL
+	}
+
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not marshal json request for event: %v payload: %v", event, payload))
+	}
+
+	// messages published as json go to the root of the server, the topic is part of the body
+	host := strings.TrimSuffix(s.Settings.Host, "/")
+	if host == "" {
+		host = ntfyDefaultHost
+	}
+
+	req, err := http.NewRequest(http.MethodPost, host, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not create request for event: %v payload: %v", event, payload))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", sharedhttp.UserAgent)
+
+	switch {
+	case s.Settings.Token != "":
+		req.Header.Set("Authorization", "Bearer "+s.Settings.Token)
+	case s.Settings.Username != "" && s.Settings.Password != "":
+		req.SetBasicAuth(s.Settings.Username, s.Settings.Password)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("client request error for event: %v payload: %v", event, payload))
+	}
+
+	defer res.Body.Close()
+
+	s.log.Trace().Msgf("ntfy status: %d", res.StatusCode)
+
+	if res.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(bufio.NewReader(res.Body))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("could not read body for event: %v payload: %v", event, payload))
+		}
+
+		return newStatusError(res.StatusCode, body)
+	}
+
+	s.log.Debug().Msg("notification successfully sent to ntfy")
+
+	return nil
+}
+
+// priority uses the configured priority, errors and MAL authentication problems are sent with at
+// least high priority.
+func (s *ntfySender) priority(event domain.NotificationEvent) int {
+	p := int(s.Settings.Priority)
+	if p <= 0 {
+		p = ntfyPriorityDefault
+	}
+	if p > ntfyPriorityMax {
+		p = ntfyPriorityMax
+	}
+
+	switch event {
+	case domain.NotificationEventPlexProcessingError, domain.NotificationEventAnimeUpdateError,
+		domain.NotificationEventMalAuthExpiring, domain.NotificationEventMalAuthInvalid:
+		if p < ntfyPriorityHigh {
+			p = ntfyPriorityHigh
+		}
+	}
+
+	return p
+}
+
+func (s *ntfySender) CanSend(event domain.NotificationEvent) bool {
+	if s.isEnabled() && s.isEnabledEvent(event) {
+		return true
+	}
+	return false
+}
+
+func (s *ntfySender) isEnabled() bool {
+	if s.Settings.Enabled {
+		if s.Settings.Topic == "" {
+			s.log.Warn().Msg("ntfy missing topic")
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (s *ntfySender) isEnabledEvent(event domain.NotificationEvent) bool {
+	return enabledEvent(s.Settings.Events, event)
+}
@@ -0,0 +1,95 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNtfySender_Send(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings domain.Notification
+		payload  domain.NotificationPayload
+		wantAuth func(*testing.T, *http.Request)
+		validate func(*testing.T, ntfyMessage)
+	}{
+		{
+			name:     "success with token",
+			settings: domain.Notification{Token: "tk_secret", Priority: 2},
+			payload:  testdata.NewMockNotificationPayload(),
+			wantAuth: func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "Bearer tk_secret", r.Header.Get("Authorization"))
+			},
+			validate: func(t *testing.T, m ntfyMessage) {
+				assert.Equal(t, "MAL Update Successful", m.Title)
+				assert.Equal(t, []string{"white_check_mark"}, m.Tags)
+				assert.Equal(t, 2, m.Priority)
+				assert.Equal(t, "https://myanimelist.net/anime/1575", m.Click)
+				assert.Equal(t, "https://cdn.myanimelist.net/images/anime/10/47347.jpg", m.Icon)
+				assert.Equal(t, "https://cdn.myanimelist.net/images/anime/10/47347.jpg", m.Attach)
+				assert.Contains(t, m.Message, "Show: Attack on Titan")
+			},
+		},
+		{
+			name:     "error with basic auth",
+			settings: domain.Notification{Username: "shinkro", Password: "hunter2"},
+			payload:  testdata.NewMockNotificationPayloadError(domain.NotificationEventAnimeUpdateError),
+			wantAuth: func(t *testing.T, r *http.Request) {
+				user, pass, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, "shinkro", user)
+				assert.Equal(t, "hunter2", pass)
+			},
+			validate: func(t *testing.T, m ntfyMessage) {
+				assert.Equal(t, []string{"warning"}, m.Tags)
+				assert.Equal(t, ntfyPriorityHigh, m.Priority)
+				assert.Empty(t, m.Attach)
+				assert.Contains(t, m.Message, "mapping not found")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m ntfyMessage
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/", r.URL.Path)
+				tt.wantAuth(t, r)
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+				w.Write([]byte(`{"id":"abc"}`))
+			}))
+			defer srv.Close()
+
+			settings := tt.settings
+			settings.Enabled = true
+			settings.Topic = "shinkro"
+			settings.Host = srv.URL
+
+			sender := NewNtfySender(zerolog.Nop(), &settings)
+			require.NoError(t, sender.Send(tt.payload.Event, tt.payload))
+			assert.Equal(t, "shinkro", m.Topic)
+			tt.validate(t, m)
+		})
+	}
+}
+
+func TestNtfySender_priority(t *testing.T) {
+	s := &ntfySender{Settings: &domain.Notification{}}
+	assert.Equal(t, ntfyPriorityDefault, s.priority(domain.NotificationEventSuccess))
+	assert.Equal(t, ntfyPriorityHigh, s.priority(domain.NotificationEventPlexProcessingError))
+	assert.Equal(t, ntfyPriorityHigh, s.priority(domain.NotificationEventMalAuthInvalid))
+
+	s.Settings.Priority = 9
+	assert.Equal(t, ntfyPriorityMax, s.priority(domain.NotificationEventSuccess))
+	assert.Equal(t, ntfyPriorityMax, s.priority(domain.NotificationEventAnimeUpdateError))
+}
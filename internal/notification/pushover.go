@@ -0,0 +1,198 @@
+package notification
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/sharedhttp"
+)
+
+const (
+	pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+	// pushoverMessageLimit is the longest message the API accepts.
+	pushoverMessageLimit = 1024
+
+	pushoverPriorityLowest    = -2
+	pushoverPriorityNormal    = 0
+	pushoverPriorityHigh      = 1
+	pushoverPriorityEmergency = 2
+)
+
+// pushoverSender sends to the Pushover API. APIKey is the application token, Token the user or
+// group key and Devices an optional comma separated list of device names.
+type pushoverSender struct {
+	log      zerolog.Logger
+	Settings *domain.Notification
+	tmpl     *messageTemplate
+	builder  MessageBuilderHTML
+
+	apiURL     string
+	httpClient *http.Client
+}
+
+func (s *pushoverSender) Name() string {
+	return "pushover"
+}
+
+func NewPushoverSender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "pushover").Logger()
+
+	return &pushoverSender{
+		log:      log,
+		Settings: settings,
+		tmpl:     newMessageTemplate(log, settings),
+		builder:  MessageBuilderHTML{},
+		apiURL:   pushoverAPIURL,
+		httpClient: &http.Client{
+			Timeout:   time.Second * 30,
+			Transport: sharedhttp.Transport,
+		},
+	}
+}
+
+func (s *pushoverSender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
+	message := s.tmpl.HTMLBody(event, payload, &s.builder)
+	if r := []rune(message); len(r) > pushoverMessageLimit {
+		message = string(r[:pushoverMessageLimit])
+	}
+
+	priority := s.priority(event, payload)
+
+	data := url.Values{}
+	data.Set("token", s.Settings.APIKey)
+	data.Set("user", s.Settings.Token)
+	data.Set("title", s.tmpl.Title(event, payload))
+	data.Set("message", message)
+	data.Set("html", "1")
+	data.Set("priority", strconv.Itoa(priority))
+
+	// emergency notifications are repeated until acknowledged
+	if priority == pushoverPriorityEmergency {
+		data.Set("retry", "60")
+		data.Set("expire", "3600")
+	}
+
+	if devices := s.devices(); devices != "" {
+		data.Set("device", devices)
+	}
+
+	if payload.MALID > 0 {
+		data.Set("url", fmt.Sprintf(MAlAnimeURL, payload.MALID))
+		data.Set("url_title", "View on MyAnimeList")
+	}
+
+	if !payload.Timestamp.IsZero() {
+		data.Set("timestamp", strconv.FormatInt(payload.Timestamp.Unix(), 10))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.apiURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not create request for event: %v payload: %v", event, payload))
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", sharedhttp.UserAgent)
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("client request error for event: %v payload: %v", event, payload))
+	}
+
+	defer res.Body.Close()
+
+	s.log.Trace().Msgf("pushover status: %d", res.StatusCode)
+
+	if res.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(bufio.NewReader(res.Body))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("could not read body for event: %v payload: %v", event, payload))
+		}
+
+		return newStatusError(res.StatusCode, body)
+	}
+
+	s.log.Debug().Msg("notification successfully sent to pushover")
+
+	return nil
+}
+
+// priority uses the configured priority, errors are never sent below normal priority and MAL
+// authentication failures, which stop all updates until fixed, are sent with high priority.
+func (s *pushoverSender) priority(event domain.NotificationEvent, payload domain.NotificationPayload) int {
+	p := int(s.Settings.Priority)
+	if p < pushoverPriorityLowest {
+		p = pushoverPriorityLowest
+	}
+	if p > pushoverPriorityEmergency {
+		p = pushoverPriorityEmergency
+	}
+
+	minimum := pushoverPriorityLowest
+	switch event {
+	case domain.NotificationEventAnimeUpdateError:
+		minimum = pushoverPriorityNormal
+		if payload.ErrorType == string(domain.AnimeUpdateErrorMALAuthFailed) {
+			minimum = pushoverPriorityHigh
+		}
+	case domain.NotificationEventPlexProcessingError, domain.NotificationEventMalAuthExpiring:
+		minimum = pushoverPriorityNormal
+	case domain.NotificationEventMalAuthInvalid:
+		minimum = pushoverPriorityHigh
+	}
+
+	if p < minimum {
+		p = minimum
+	}
+
+	return p
+}
+
+func (s *pushoverSender) devices() string {
+	var devices []string
+	for _, d := range strings.Split(s.Settings.Devices, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			devices = append(devices, d)
+		}
+	}
+
+	return strings.Join(devices, ",")
+}
+
+func (s *pushoverSender) CanSend(event domain.NotificationEvent) bool {
+	if s.isEnabled() && s.isEnabledEvent(event) {
+		return true
+	}
+	return false
+}
+
+func (s *pushoverSender) isEnabled() bool {
+	if s.Settings.Enabled {
+		if s.Settings.APIKey == "" {
+			s.log.Warn().Msg("pushover missing application token")
+			return false
+		}
+
+		if s.Settings.Token == "" {
+			s.log.Warn().Msg("pushover missing user key")
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (s *pushoverSender) isEnabledEvent(event domain.NotificationEvent) bool {
+	return enabledEvent(s.Settings.Events, event)
+}
@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushoverSender_Send(t *testing.T) {
+	var form url.Values
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		w.Write([]byte(`{"status":1,"request":"abc"}`))
+	}))
+	defer srv.Close()
+
+	sender := NewPushoverSender(zerolog.Nop(), &domain.Notification{
+		Type:     domain.NotificationTypePushover,
+		Enabled:  true,
+		APIKey:   "app-token",
+		Token:    "user-key",
+		Devices:  "phone, tablet ,",
+		Priority: -1,
+	}).(*pushoverSender)
+	sender.apiURL = srv.URL
+
+	payload := testdata.NewMockNotificationPayload()
+	require.NoError(t, sender.Send(payload.Event, payload))
+
+	assert.Equal(t, "app-token", form.Get("token"))
+	assert.Equal(t, "user-key", form.Get("user"))
+	assert.Equal(t, "MAL Update Successful", form.Get("title"))
+	assert.Equal(t, "1", form.Get("html"))
+	assert.Equal(t, "-1", form.Get("priority"))
+	assert.Equal(t, "phone,tablet", form.Get("device"))
+	assert.Equal(t, "https://myanimelist.net/anime/1575", form.Get("url"))
+	assert.Contains(t, form.Get("message"), "<b>Show:</b> Attack on Titan")
+}
+
+func TestPushoverSender_priority(t *testing.T) {
+	authFailed := domain.NotificationPayload{ErrorType: string(domain.AnimeUpdateErrorMALAuthFailed)}
+	mappingNotFound := domain.NotificationPayload{ErrorType: string(domain.AnimeUpdateErrorMappingNotFound)}
+
+	tests := []struct {
+		name     string
+		priority int32
+		event    domain.NotificationEvent
+		payload  domain.NotificationPayload
+		want     int
+	}{
+		{name: "success uses setting", priority: -2, event: domain.NotificationEventSuccess, want: -2},
+		{name: "setting is clamped", priority: 5, event: domain.NotificationEventSuccess, want: 2},
+		{name: "errors are at least normal", priority: -2, event: domain.NotificationEventAnimeUpdateError, payload: mappingNotFound, want: 0},
+		{name: "plex errors are at least normal", priority: -1, event: domain.NotificationEventPlexProcessingError, want: 0},
+		{name: "mal auth failure is high", event: domain.NotificationEventAnimeUpdateError, payload: authFailed, want: 1},
+		{name: "mal token expiring is at least normal", priority: -2, event: domain.NotificationEventMalAuthExpiring, want: 0},
+		{name: "mal token invalid is high", event: domain.NotificationEventMalAuthInvalid, want: 1},
+		{name: "higher setting wins", priority: 2, event: domain.NotificationEventAnimeUpdateError, payload: authFailed, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &pushoverSender{Settings: &domain.Notification{Priority: tt.priority}}
+			assert.Equal(t, tt.want, s.priority(tt.event, tt.payload))
+		})
+	}
+}
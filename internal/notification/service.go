@@ -2,12 +2,16 @@ package notification
 
 import (
 	"context"
+	"encoding/json"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
 	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/jobqueue"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -18,22 +22,42 @@ type Service interface {
 	Delete(ctx context.Context, id int) error
 	Send(event domain.NotificationEvent, payload domain.NotificationPayload)
 	Test(ctx context.Context, notification *domain.Notification) error
+	Preview(ctx context.Context, req *domain.NotificationPreviewRequest) (*domain.NotificationPreview, error)
+	FindDeliveries(ctx context.Context, notificationID int) ([]domain.NotificationDelivery, error)
 }
 
+// notificationWorkers is the number of job workers for notifications. They are kept apart from the
+// shared workers, so deliveries waiting for a retry do not hold up anime updates.
+const notificationWorkers = 2
+
 type service struct {
-	log     zerolog.Logger
-	repo    domain.NotificationRepo
-	senders map[int]domain.NotificationSender
+	log             zerolog.Logger
+	repo            domain.NotificationRepo
+	deliveryRepo    domain.NotificationDeliveryRepo
+	animeUpdateRepo domain.AnimeUpdateRepo
+	jobQueue        jobqueue.Service
+	senders         map[int]domain.NotificationSender
+
+	mu      sync.Mutex
+	cron    *cron.Cron
+	digests map[int]*digest
 }
 
-func NewService(log zerolog.Logger, repo domain.NotificationRepo) Service {
+func NewService(log zerolog.Logger, repo domain.NotificationRepo, deliveryRepo domain.NotificationDeliveryRepo, animeUpdateRepo domain.AnimeUpdateRepo, jobQueue jobqueue.Service) Service {
 	s := &service{
-		log:     log.With().Str("module", "notification").Logger(),
-		repo:    repo,
-		senders: make(map[int]domain.NotificationSender),
+		log:             log.With().Str("module", "notification").Logger(),
+		repo:            repo,
+		deliveryRepo:    deliveryRepo,
+		animeUpdateRepo: animeUpdateRepo,
+		jobQueue:        jobQueue,
+		senders:         make(map[int]domain.NotificationSender),
+		cron:            cron.New(cron.WithLocation(time.UTC)),
+		digests:         make(map[int]*digest),
 	}
 
 	s.registerSenders()
+	jobQueue.RegisterWorkers(domain.JobTypeNotification, s.handleJob, notificationWorkers)
+	s.cron.Start()
 
 	return s
 }
@@ -49,6 +73,14 @@ func (s *service) Find(ctx context.Context, params domain.NotificationQueryParam
 }
 
 func (s *service) Store(ctx context.Context, notification *domain.Notification) error {
+	if err := ValidateTemplates(notification); err != nil {
+		return err
+	}
+
+	if err := ValidateDigestSchedule(notification); err != nil {
+		return err
+	}
+
 	err := s.repo.Store(ctx, notification)
 	if err != nil {
 		s.log.Error().Err(err).Msgf("could not store notification: %+v", notification)
@@ -62,6 +94,14 @@ func (s *service) Store(ctx context.Context, notification *domain.Notification)
 }
 
 func (s *service) Update(ctx context.Context, notification *domain.Notification) error {
+	if err := ValidateTemplates(notification); err != nil {
+		return err
+	}
+
+	if err := ValidateDigestSchedule(notification); err != nil {
+		return err
+	}
+
 	err := s.repo.Update(ctx, notification)
 	if err != nil {
 		s.log.Error().Err(err).Msgf("could not update notification: %+v", notification)
@@ -82,7 +122,8 @@ func (s *service) Delete(ctx context.Context, id int) error {
 	}
 
 	// delete sender
-	delete(s.senders, id)
+	s.removeSender(id)
+	s.removeDigest(id)
 
 	return nil
 }
@@ -104,45 +145,238 @@ func (s *service) registerSenders() {
 // registerSender registers an enabled notification via it's id
 func (s *service) registerSender(notification *domain.Notification) {
 	if !notification.Enabled {
-		delete(s.senders, notification.ID)
+		s.removeSender(notification.ID)
+		s.removeDigest(notification.ID)
 		return
 	}
 
+	sender := newSender(s.log, notification)
+	if sender != nil {
+		s.mu.Lock()
+		s.senders[notification.ID] = sender
+		s.mu.Unlock()
+	}
+
+	s.registerDigest(notification, sender)
+
+	return
+}
+
+func (s *service) removeSender(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.senders, id)
+}
+
+// activeSenders returns a copy of the registered senders, safe to range over while notifications
+// are added or removed.
+func (s *service) activeSenders() map[int]domain.NotificationSender {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	senders := make(map[int]domain.NotificationSender, len(s.senders))
+	for id, sender := range s.senders {
+		senders[id] = sender
+	}
+
+	return senders
+}
+
+// newSender returns the sender for the type of the notification, nil for unsupported types
+func newSender(log zerolog.Logger, notification *domain.Notification) domain.NotificationSender {
 	switch notification.Type {
 	case domain.NotificationTypeDiscord:
-		s.senders[notification.ID] = NewDiscordSender(s.log, notification)
+		return NewDiscordSender(log, notification)
 	case domain.NotificationTypeGotify:
-		s.senders[notification.ID] = NewGotifySender(s.log, notification)
+		return NewGotifySender(log, notification)
+	case domain.NotificationTypeTelegram:
+		return NewTelegramSender(log, notification)
+	case domain.NotificationTypeNtfy:
+		return NewNtfySender(log, notification)
+	case domain.NotificationTypePushover:
+		return NewPushoverSender(log, notification)
+	case domain.NotificationTypeWebhook:
+		return NewWebhookSender(log, notification)
+	case domain.NotificationTypeEmail:
+		return NewEmailSender(log, notification)
+	case domain.NotificationTypeMatrix:
+		return NewMatrixSender(log, notification)
 	}
 
-	return
+	return nil
 }
 
-// Send notifications
+// Send queues notifications for the registered senders
 func (s *service) Send(event domain.NotificationEvent, payload domain.NotificationPayload) {
-	if len(s.senders) > 0 {
-		s.log.Debug().Msgf("sending notification for %v", string(event))
+	if len(s.activeSenders()) == 0 {
+		return
 	}
 
-	go func() {
-		for _, sender := range s.senders {
-			// check if sender is active and have notification types
-			if sender.CanSend(event) {
-				if err := sender.Send(event, payload); err != nil {
-					s.log.Error().Err(err).Msgf("could not send %s notification for %v", sender.Name(), string(event))
-				}
-			}
+	s.log.Debug().Msgf("sending notification for %v", string(event))
+
+	job := domain.NotificationJob{Event: event, Payload: payload}
+	if err := s.jobQueue.Enqueue(context.Background(), domain.JobTypeNotification, "", job); err != nil {
+		s.log.Error().Err(err).Msgf("could not queue notification for %v", string(event))
+	}
+}
+
+func (s *service) handleJob(ctx context.Context, data json.RawMessage) error {
+	var job domain.NotificationJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return errors.Wrap(err, "could not unmarshal notification job")
+	}
+
+	// senders are delivered to in parallel, so one retrying sender does not hold back the others
+	var wg sync.WaitGroup
+	for id, sender := range s.activeSenders() {
+		// successful updates of notifications with a digest are sent with the digest
+		if job.Event == domain.NotificationEventSuccess && s.hasDigest(id) {
+			continue
 		}
-	}()
 
-	return
+		// check if sender is active and have notification types
+		if sender.CanSend(job.Event) {
+			wg.Add(1)
+			go func(id int, sender domain.NotificationSender) {
+				defer wg.Done()
+				s.deliver(ctx, id, sender, job.Event, job.Payload)
+			}(id, sender)
+		}
+	}
+
+	wg.Wait()
+
+	return nil
 }
 
-func (s *service) Test(ctx context.Context, notification *domain.Notification) error {
-	var agent domain.NotificationSender
+// FindDeliveries returns the recorded delivery attempts of a notification, newest first.
+func (s *service) FindDeliveries(ctx context.Context, notificationID int) ([]domain.NotificationDelivery, error) {
+	deliveries, err := s.deliveryRepo.FindByNotificationID(ctx, notificationID)
+	if err != nil {
+		s.log.Error().Err(err).Msgf("could not find deliveries for notification: %v", notificationID)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
 
+func (s *service) Test(ctx context.Context, notification *domain.Notification) error {
 	// send test events
-	events := []domain.NotificationPayload{
+	events := samplePayloads()
+
+	agent := newSender(s.log, notification)
+	if agent == nil {
+		s.log.Error().Msgf("unsupported notification type: %v", notification.Type)
+		return errors.New("unsupported notification type")
+	}
+
+	g, _ := errgroup.WithContext(ctx)
+
+	for _, event := range events {
+		e := event
+
+		if e.Event == domain.NotificationEventDigest {
+			if notification.DigestSchedule == "" {
+				continue
+			}
+		} else if !enabledEvent(notification.Events, e.Event) {
+			continue
+		}
+
+		if err := agent.Send(e.Event, e); err != nil {
+			s.log.Error().Err(err).Msgf("error sending test notification: %#v", notification)
+			return err
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+
+	if err := g.Wait(); err != nil {
+		s.log.Error().Err(err).Msgf("Something went wrong sending test notifications to %v", notification.Type)
+		return err
+	}
+
+	return nil
+}
+
+// Preview renders the templates of req against a sample payload or a stored anime update.
+func (s *service) Preview(ctx context.Context, req *domain.NotificationPreviewRequest) (*domain.NotificationPreview, error) {
+	if req.AnimeUpdateID > 0 {
+		animeUpdate, err := s.animeUpdateRepo.GetByID(ctx, &domain.GetAnimeUpdateRequest{Id: req.AnimeUpdateID})
+		if err != nil {
+			return nil, err
+		}
+
+		event, payload := payloadFromAnimeUpdate(animeUpdate)
+		return RenderPreview(req, event, payload)
+	}
+
+	event := req.Event
+	if event == "" {
+		event = domain.NotificationEventSuccess
+	}
+
+	for _, payload := range samplePayloads() {
+		if payload.Event == event {
+			return RenderPreview(req, event, payload)
+		}
+	}
+
+	return nil, errors.Errorf("unsupported notification event: %v", event)
+}
+
+// payloadFromAnimeUpdate builds the payload the anime update notified, plex details are not stored
+// with it.
+func payloadFromAnimeUpdate(animeUpdate *domain.AnimeUpdate) (domain.NotificationEvent, domain.NotificationPayload) {
+	payload := domain.NotificationPayload{
+		MediaName:   animeUpdate.ListDetails.Title,
+		MALID:       animeUpdate.MALId,
+		PlexID:      animeUpdate.PlexId,
+		AnimeUpdate: animeUpdate,
+		Timestamp:   animeUpdate.Timestamp,
+	}
+
+	if animeUpdate.Status == domain.AnimeUpdateStatusFailed {
+		payload.Event = domain.NotificationEventAnimeUpdateError
+		payload.ErrorType = string(animeUpdate.ErrorType)
+		payload.Subject = "Anime Update Failed"
+		payload.Message = animeUpdate.ErrorMessage
+		return payload.Event, payload
+	}
+
+	payload.Event = domain.NotificationEventSuccess
+	payload.EpisodesWatched = animeUpdate.ListStatus.NumEpisodesWatched
+	payload.EpisodesTotal = animeUpdate.ListDetails.TotalEpisodeNum
+	payload.TimesRewatched = animeUpdate.ListStatus.NumTimesRewatched
+	payload.PictureURL = animeUpdate.ListDetails.PictureURL
+	payload.StartDate = animeUpdate.ListStatus.StartDate
+	payload.FinishDate = animeUpdate.ListStatus.FinishDate
+	payload.AnimeStatus = string(animeUpdate.ListStatus.Status)
+	payload.Score = animeUpdate.ListStatus.Score
+
+	return payload.Event, payload
+}
+
+func enabledEvent(events []string, e domain.NotificationEvent) bool {
+	for _, v := range events {
+		if v == string(e) {
+			return true
+		}
+		// Backward compatibility: "ERROR" matches both error types
+		if v == "ERROR" {
+			if e == domain.NotificationEventPlexProcessingError || e == domain.NotificationEventAnimeUpdateError {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// samplePayloads are sent by Test and used to validate templates.
+func samplePayloads() []domain.NotificationPayload {
+	return []domain.NotificationPayload{
 		{
 			Subject:   "Test Notification",
 			Message:   "If you had the strength, you could live. This is our contract. In return for my gift of power, you must grant one of my wishes. If you enter this contract, you will live as a human, but also as one completely different. Different rules, different time, a different life... The power of the king will make you lonely indeed. If you are prepared for that, then...",
@@ -174,6 +408,7 @@ func (s *service) Test(ctx context.Context, notification *domain.Notification) e
 		},
 		{
 			Subject:      "Unsupported Metadata Agent",
+			ErrorType:    string(domain.PlexErrorAgentNotSupported),
 			Message:      "Failed to process Plex payload for: Code Geass: Lelouch of the Rebellion\n\nError: metadata agent not supported\n\nAction Required: Please configure a supported agent (HAMA, MAL Agent, or Plex Agent) in your Plex library settings.",
 			Event:        domain.NotificationEventPlexProcessingError,
 			AnimeLibrary: "Anime",
@@ -184,6 +419,7 @@ func (s *service) Test(ctx context.Context, notification *domain.Notification) e
 		},
 		{
 			Subject:      "Failed to Extract Anime Info",
+			ErrorType:    string(domain.PlexErrorExtractionFailed),
 			Message:      "Failed to process Plex payload for: Attack on Titan\n\nError: failed to parse GUID\n\nThis indicates an issue with extracting the anime ID from the metadata. Create issue on github.com/varoOP/shinkro",
 			Event:        domain.NotificationEventPlexProcessingError,
 			AnimeLibrary: "Anime",
@@ -194,6 +430,7 @@ func (s *service) Test(ctx context.Context, notification *domain.Notification) e
 		},
 		{
 			Subject:      "MAL Authentication Failed",
+			ErrorType:    string(domain.AnimeUpdateErrorMALAuthFailed),
 			Message:      "Failed to update MyAnimeList for: One Piece\n\nError: failed to get MAL client: token expired\n\nAction Required: Please re-authenticate with MyAnimeList in settings.",
 			Event:        domain.NotificationEventAnimeUpdateError,
 			AnimeLibrary: "Anime",
@@ -205,6 +442,7 @@ func (s *service) Test(ctx context.Context, notification *domain.Notification) e
 		},
 		{
 			Subject:      "Mapping Not Found",
+			ErrorType:    string(domain.AnimeUpdateErrorMappingNotFound),
 			Message:      "Failed to update MyAnimeList for: Demon Slayer\n\nError: mapping not found\n\nSource: TVDB ID 362753 (Season 2)\n\nAction Required: Add a mapping for this anime.",
 			Event:        domain.NotificationEventAnimeUpdateError,
 			AnimeLibrary: "Anime",
@@ -216,6 +454,7 @@ func (s *service) Test(ctx context.Context, notification *domain.Notification) e
 		},
 		{
 			Subject:      "MAL API Error",
+			ErrorType:    string(domain.AnimeUpdateErrorMALAPIFetchFailed),
 			Message:      "Failed to update MyAnimeList for: Jujutsu Kaisen\n\nError: Failed to fetch anime details from MAL API\n\nDetails: rate limit exceeded\n\nThis might be a temporary MAL API issue.",
 			Event:        domain.NotificationEventAnimeUpdateError,
 			AnimeLibrary: "Anime",
@@ -225,55 +464,27 @@ func (s *service) Test(ctx context.Context, notification *domain.Notification) e
 			PlexSource:   "Plex Webhook",
 			Timestamp:    time.Now(),
 		},
+		{
+			Subject:   "MAL Authentication Expiring",
+			Message:   "The MyAnimeList access token expires on 2024-05-01 09:00 UTC and could not be refreshed.\n\nError: dial tcp: lookup myanimelist.net: no such host\n\nAction Required: Please re-authenticate with MyAnimeList: http://localhost:7011/settings/mal",
+			Event:     domain.NotificationEventMalAuthExpiring,
+			Timestamp: time.Now(),
+		},
+		{
+			Subject:   "MAL Authentication Invalid",
+			Message:   "MyAnimeList rejected the refresh token, updates will fail once the access token expires on 2024-05-01 09:00 UTC.\n\nError: oauth2: \"invalid_grant\"\n\nAction Required: Please re-authenticate with MyAnimeList: http://localhost:7011/settings/mal",
+			Event:     domain.NotificationEventMalAuthInvalid,
+			Timestamp: time.Now(),
+		},
+		digestPayload(&domain.NotificationDigest{
+			Shows:     2,
+			Episodes:  7,
+			Completed: 1,
+			Failed:    1,
+			Entries: []domain.NotificationDigestEntry{
+				{MALID: 1575, Title: "Code Geass: Lelouch of the Rebellion", Episodes: 4, Progress: 25, TotalEpisodes: 25, Completed: true},
+				{MALID: 52991, Title: "Sousou no Frieren", Episodes: 3, Progress: 12, TotalEpisodes: 28},
+			},
+		}),
 	}
-
-	switch notification.Type {
-	case domain.NotificationTypeDiscord:
-		agent = NewDiscordSender(s.log, notification)
-	case domain.NotificationTypeGotify:
-		agent = NewGotifySender(s.log, notification)
-	default:
-		s.log.Error().Msgf("unsupported notification type: %v", notification.Type)
-		return errors.New("unsupported notification type")
-	}
-
-	g, _ := errgroup.WithContext(ctx)
-
-	for _, event := range events {
-		e := event
-
-		if !enabledEvent(notification.Events, e.Event) {
-			continue
-		}
-
-		if err := agent.Send(e.Event, e); err != nil {
-			s.log.Error().Err(err).Msgf("error sending test notification: %#v", notification)
-			return err
-		}
-
-		time.Sleep(1 * time.Second)
-	}
-
-	if err := g.Wait(); err != nil {
-		s.log.Error().Err(err).Msgf("Something went wrong sending test notifications to %v", notification.Type)
-		return err
-	}
-
-	return nil
-}
-
-func enabledEvent(events []string, e domain.NotificationEvent) bool {
-	for _, v := range events {
-		if v == string(e) {
-			return true
-		}
-		// Backward compatibility: "ERROR" matches both error types
-		if v == "ERROR" {
-			if e == domain.NotificationEventPlexProcessingError || e == domain.NotificationEventAnimeUpdateError {
-				return true
-			}
-		}
-	}
-
-	return false
 }
@@ -0,0 +1,200 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/sharedhttp"
+)
+
+const (
+	telegramDefaultHost = "https://api.telegram.org"
+
+	// telegramCaptionLimit is the longest photo caption the Bot API accepts, longer messages are sent
+	// without the photo.
+	telegramCaptionLimit = 1024
+)
+
+type TelegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	MessageThreadID       int    `json:"message_thread_id,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview,omitempty"`
+}
+
+type TelegramPhoto struct {
+	ChatID          string `json:"chat_id"`
+	Photo           string `json:"photo"`
+	Caption         string `json:"caption"`
+	ParseMode       string `json:"parse_mode"`
+	MessageThreadID int    `json:"message_thread_id,omitempty"`
+}
+
+// telegramSender posts to a chat with the Bot API. Token is the bot token, Channel the chat id,
+// Topic the optional message thread id of a forum topic and Host an optional self-hosted Bot API server.
+type telegramSender struct {
+	log      zerolog.Logger
+	Settings *domain.Notification
+	tmpl     *messageTemplate
+	builder  MessageBuilderHTML
+
+	httpClient *http.Client
+}
+
+func (s *telegramSender) Name() string {
+	return "telegram"
+}
+
+func NewTelegramSender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "telegram").Logger()
+
+	return &telegramSender{
+		log:      log,
+		Settings: settings,
+		tmpl:     newMessageTemplate(log, settings),
+		builder:  MessageBuilderHTML{},
+		httpClient: &http.Client{
+			Timeout:   time.Second * 30,
+			Transport: sharedhttp.Transport,
+		},
+	}
+}
+
+func (s *telegramSender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
+	threadID, err := s.threadID()
+	if err != nil {
+		return err
+	}
+
+	text := s.buildMessage(event, payload)
+
+	if event == domain.NotificationEventSuccess && payload.PictureURL != "" && len([]rune(text)) <= telegramCaptionLimit {
+		return s.post(event, "sendPhoto", TelegramPhoto{
+			ChatID:          s.Settings.Channel,
+			Photo:           payload.PictureURL,
+			Caption:         text,
+			ParseMode:       "HTML",
+			MessageThreadID: threadID,
+		})
+	}
+
+	return s.post(event, "sendMessage", TelegramMessage{
+		ChatID:                s.Settings.Channel,
+		Text:                  text,
+		ParseMode:             "HTML",
+		MessageThreadID:       threadID,
+		DisableWebPagePreview: true,
+	})
+}
+
+func (s *telegramSender) post(event domain.NotificationEvent, method string, m interface{}) error {
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not marshal json request for event: %v", event))
+	}
+
+	host := strings.TrimSuffix(s.Settings.Host, "/")
+	if host == "" {
+		host = telegramDefaultHost
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%v/bot%v/%v", host, s.Settings.Token, method), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not create request for event: %v", event))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", sharedhttp.UserAgent)
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		// the request url contains the bot token, keep it out of the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return errors.Wrap(err, fmt.Sprintf("client request error for event: %v", event))
+	}
+
+	defer res.Body.Close()
+
+	s.log.Trace().Msgf("telegram %s status: %d", method, res.StatusCode)
+
+	if res.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(bufio.NewReader(res.Body))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("could not read body for event: %v", event))
+		}
+
+		return newStatusError(res.StatusCode, body)
+	}
+
+	s.log.Debug().Msg("notification successfully sent to telegram")
+
+	return nil
+}
+
+func (s *telegramSender) buildMessage(event domain.NotificationEvent, payload domain.NotificationPayload) string {
+	msg := fmt.Sprintf("<b>%v</b>\n\n%v", html.EscapeString(s.tmpl.Title(event, payload)), s.tmpl.HTMLBody(event, payload, &s.builder))
+
+	if payload.MALID > 0 {
+		msg += fmt.Sprintf("\n<a href=\"%v\">View on MyAnimeList</a>", fmt.Sprintf(MAlAnimeURL, payload.MALID))
+	}
+
+	return msg
+}
+
+func (s *telegramSender) threadID() (int, error) {
+	if s.Settings.Topic == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.Atoi(s.Settings.Topic)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("invalid telegram message thread id: %v", s.Settings.Topic))
+	}
+
+	return id, nil
+}
+
+func (s *telegramSender) CanSend(event domain.NotificationEvent) bool {
+	if s.isEnabled() && s.isEnabledEvent(event) {
+		return true
+	}
+	return false
+}
+
+func (s *telegramSender) isEnabled() bool {
+	if s.Settings.Enabled {
+		if s.Settings.Token == "" {
+			s.log.Warn().Msg("telegram missing bot token")
+			return false
+		}
+
+		if s.Settings.Channel == "" {
+			s.log.Warn().Msg("telegram missing chat id")
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (s *telegramSender) isEnabledEvent(event domain.NotificationEvent) bool {
+	return enabledEvent(s.Settings.Events, event)
+}
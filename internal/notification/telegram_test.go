@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegramSender_Send(t *testing.T) {
+	tests := []struct {
+		name       string
+		topic      string
+		payload    domain.NotificationPayload
+		wantPath   string
+		wantThread float64
+		validate   func(*testing.T, map[string]interface{})
+	}{
+		{
+			name:     "success with picture sends photo",
+			payload:  testdata.NewMockNotificationPayload(),
+			wantPath: "/bot123:abc/sendPhoto",
+			validate: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "https://cdn.myanimelist.net/images/anime/10/47347.jpg", body["photo"])
+				assert.Contains(t, body["caption"], "<b>MAL Update Successful</b>")
+				assert.Contains(t, body["caption"], "https://myanimelist.net/anime/1575")
+			},
+		},
+		{
+			name:       "error sends message to thread",
+			topic:      "42",
+			payload:    testdata.NewMockNotificationPayloadError(domain.NotificationEventAnimeUpdateError),
+			wantPath:   "/bot123:abc/sendMessage",
+			wantThread: 42,
+			validate: func(t *testing.T, body map[string]interface{}) {
+				assert.Contains(t, body["text"], "mapping not found")
+				assert.NotContains(t, body, "photo")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			var body map[string]interface{}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			defer srv.Close()
+
+			sender := NewTelegramSender(zerolog.Nop(), &domain.Notification{
+				Type:    domain.NotificationTypeTelegram,
+				Enabled: true,
+				Token:   "123:abc",
+				Channel: "-1001234",
+				Topic:   tt.topic,
+				Host:    srv.URL,
+			})
+
+			require.NoError(t, sender.Send(tt.payload.Event, tt.payload))
+			assert.Equal(t, tt.wantPath, gotPath)
+			assert.Equal(t, "-1001234", body["chat_id"])
+			assert.Equal(t, "HTML", body["parse_mode"])
+			if tt.wantThread > 0 {
+				assert.Equal(t, tt.wantThread, body["message_thread_id"])
+			} else {
+				assert.NotContains(t, body, "message_thread_id")
+			}
+			tt.validate(t, body)
+		})
+	}
+}
+
+func TestTelegramSender_SendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+	}))
+	defer srv.Close()
+
+	sender := NewTelegramSender(zerolog.Nop(), &domain.Notification{
+		Enabled: true,
+		Token:   "123:abc",
+		Channel: "1",
+		Host:    srv.URL,
+	})
+
+	err := sender.Send(domain.NotificationEventTest, domain.NotificationPayload{Event: domain.NotificationEventTest})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chat not found")
+}
+
+func TestTelegramSender_CanSend(t *testing.T) {
+	settings := &domain.Notification{Enabled: true, Token: "123:abc", Events: []string{string(domain.NotificationEventSuccess)}}
+	sender := NewTelegramSender(zerolog.Nop(), settings)
+	assert.False(t, sender.CanSend(domain.NotificationEventSuccess), "missing chat id")
+
+	settings.Channel = "1"
+	assert.True(t, sender.CanSend(domain.NotificationEventSuccess))
+	assert.False(t, sender.CanSend(domain.NotificationEventAnimeUpdateError))
+}
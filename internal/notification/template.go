@@ -0,0 +1,188 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+// TemplateData is passed to notification templates, it has all NotificationPayload fields.
+type TemplateData struct {
+	domain.NotificationPayload
+	// Title is the built-in title of the event
+	Title  string
+	MALURL string
+}
+
+func newTemplateData(event domain.NotificationEvent, payload domain.NotificationPayload) TemplateData {
+	data := TemplateData{
+		NotificationPayload: payload,
+		Title:               BuildTitle(event),
+	}
+
+	data.Event = event
+
+	if payload.MALID > 0 {
+		data.MALURL = fmt.Sprintf(MAlAnimeURL, payload.MALID)
+	}
+
+	return data
+}
+
+// messageTemplate renders the title and body templates of a notification. Templates render plain
+// text, senders with html messages escape the output. Unset templates keep the built-in messages.
+type messageTemplate struct {
+	log   zerolog.Logger
+	title *template.Template
+	body  *template.Template
+}
+
+func newMessageTemplate(log zerolog.Logger, settings *domain.Notification) *messageTemplate {
+	t := &messageTemplate{log: log}
+
+	var err error
+	if t.title, err = parseTemplate("title", settings.TitleTemplate); err != nil {
+		log.Error().Err(err).Msg("invalid title template, using built-in title")
+	}
+
+	if t.body, err = parseTemplate("body", settings.BodyTemplate); err != nil {
+		log.Error().Err(err).Msg("invalid body template, using built-in body")
+	}
+
+	return t
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+func executeTemplate(t *template.Template, event domain.NotificationEvent, payload domain.NotificationPayload) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newTemplateData(event, payload)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// render returns the output of t, false when t is unset or fails.
+func (m *messageTemplate) render(t *template.Template, event domain.NotificationEvent, payload domain.NotificationPayload) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+
+	out, err := executeTemplate(t, event, payload)
+	if err != nil {
+		m.log.Error().Err(err).Msgf("could not render %s template for event: %v", t.Name(), event)
+		return "", false
+	}
+
+	return out, true
+}
+
+// RenderTitle returns the rendered title template, false when unset.
+func (m *messageTemplate) RenderTitle(event domain.NotificationEvent, payload domain.NotificationPayload) (string, bool) {
+	return m.render(m.title, event, payload)
+}
+
+// RenderBody returns the rendered body template, false when unset.
+func (m *messageTemplate) RenderBody(event domain.NotificationEvent, payload domain.NotificationPayload) (string, bool) {
+	return m.render(m.body, event, payload)
+}
+
+// Title returns the rendered title template or the built-in title.
+func (m *messageTemplate) Title(event domain.NotificationEvent, payload domain.NotificationPayload) string {
+	if title, ok := m.RenderTitle(event, payload); ok {
+		return title
+	}
+
+	return BuildTitle(event)
+}
+
+// Body returns the rendered body template or the body of builder.
+func (m *messageTemplate) Body(event domain.NotificationEvent, payload domain.NotificationPayload, builder MessageBuilder) string {
+	if body, ok := m.RenderBody(event, payload); ok {
+		return body
+	}
+
+	return builder.BuildBody(payload)
+}
+
+// HTMLBody returns the escaped body template or the html body of builder.
+func (m *messageTemplate) HTMLBody(event domain.NotificationEvent, payload domain.NotificationPayload, builder MessageBuilder) string {
+	if body, ok := m.RenderBody(event, payload); ok {
+		return html.EscapeString(body)
+	}
+
+	return builder.BuildBody(payload)
+}
+
+// ValidateTemplates parses the templates of the notification and renders them for every sample
+// payload, so unknown fields are reported on save instead of when sending.
+func ValidateTemplates(settings *domain.Notification) error {
+	for name, text := range map[string]string{"title": settings.TitleTemplate, "body": settings.BodyTemplate} {
+		t, err := parseTemplate(name, text)
+		if err != nil {
+			return errors.Wrapf(domain.ErrNotificationTemplateInvalid, "%v", err)
+		}
+
+		if t == nil {
+			continue
+		}
+
+		for _, payload := range samplePayloads() {
+			if _, err := executeTemplate(t, payload.Event, payload); err != nil {
+				return errors.Wrapf(domain.ErrNotificationTemplateInvalid, "%v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RenderPreview renders the templates of req for payload, unset templates render the built-in
+// plain text message.
+func RenderPreview(req *domain.NotificationPreviewRequest, event domain.NotificationEvent, payload domain.NotificationPayload) (*domain.NotificationPreview, error) {
+	preview := &domain.NotificationPreview{
+		Event: event,
+		Title: BuildTitle(event),
+		Body:  (&MessageBuilderPlainText{}).BuildBody(payload),
+	}
+
+	for _, tpl := range []struct {
+		name string
+		text string
+		out  *string
+	}{
+		{"title", req.TitleTemplate, &preview.Title},
+		{"body", req.BodyTemplate, &preview.Body},
+	} {
+		t, err := parseTemplate(tpl.name, tpl.text)
+		if err != nil {
+			return nil, errors.Wrapf(domain.ErrNotificationTemplateInvalid, "%v", err)
+		}
+
+		if t == nil {
+			continue
+		}
+
+		out, err := executeTemplate(t, event, payload)
+		if err != nil {
+			return nil, errors.Wrapf(domain.ErrNotificationTemplateInvalid, "%v", err)
+		}
+
+		*tpl.out = out
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,133 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings domain.Notification
+		wantErr  bool
+	}{
+		{
+			name:     "no templates",
+			settings: domain.Notification{},
+		},
+		{
+			name: "valid templates",
+			settings: domain.Notification{
+				TitleTemplate: "{{.Title}}: {{.MediaName}}",
+				BodyTemplate:  "{{if .MALID}}{{.MediaName}} {{.EpisodesWatched}}/{{.EpisodesTotal}} {{.MALURL}}{{else}}{{.Message}}{{end}}",
+			},
+		},
+		{
+			name:     "parse error",
+			settings: domain.Notification{BodyTemplate: "{{.MediaName"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field",
+			settings: domain.Notification{TitleTemplate: "{{.Anime}}"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplates(&tt.settings)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, domain.ErrNotificationTemplateInvalid))
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRenderPreview(t *testing.T) {
+	payload := testdata.NewMockNotificationPayload()
+
+	t.Run("built-in messages", func(t *testing.T) {
+		preview, err := RenderPreview(&domain.NotificationPreviewRequest{}, payload.Event, payload)
+		require.NoError(t, err)
+
+		assert.Equal(t, domain.NotificationEventSuccess, preview.Event)
+		assert.Equal(t, "MAL Update Successful", preview.Title)
+		assert.Contains(t, preview.Body, "Show: Attack on Titan")
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		req := &domain.NotificationPreviewRequest{
+			TitleTemplate: "Watched {{.MediaName}}",
+			BodyTemplate:  "{{.Event}} {{.MALURL}}",
+		}
+
+		preview, err := RenderPreview(req, payload.Event, payload)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Watched Attack on Titan", preview.Title)
+		assert.Equal(t, "SUCCESS https://myanimelist.net/anime/1575", preview.Body)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := RenderPreview(&domain.NotificationPreviewRequest{BodyTemplate: "{{.Nope}}"}, payload.Event, payload)
+		assert.True(t, errors.Is(err, domain.ErrNotificationTemplateInvalid))
+	})
+}
+
+func TestMessageTemplate_HTMLBody(t *testing.T) {
+	payload := testdata.NewMockNotificationPayload()
+	payload.MediaName = "<b>Attack on Titan</b>"
+
+	tmpl := newMessageTemplate(zerolog.Nop(), &domain.Notification{BodyTemplate: "{{.MediaName}} & more"})
+
+	assert.Equal(t, "&lt;b&gt;Attack on Titan&lt;/b&gt; &amp; more", tmpl.HTMLBody(payload.Event, payload, &MessageBuilderHTML{}))
+}
+
+func TestMessageTemplate_FallbackOnRenderError(t *testing.T) {
+	payload := testdata.NewMockNotificationPayload()
+
+	tmpl := newMessageTemplate(zerolog.Nop(), &domain.Notification{TitleTemplate: "{{.Nope}}", BodyTemplate: "{{.MediaName"})
+
+	assert.Equal(t, "MAL Update Successful", tmpl.Title(payload.Event, payload))
+	assert.Contains(t, tmpl.Body(payload.Event, payload, &MessageBuilderPlainText{}), "Show: Attack on Titan")
+}
+
+func TestNtfySender_SendTemplates(t *testing.T) {
+	var m ntfyMessage
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		w.Write([]byte(`{"id":"abc"}`))
+	}))
+	defer srv.Close()
+
+	settings := &domain.Notification{
+		Enabled:       true,
+		Topic:         "shinkro",
+		Host:          srv.URL,
+		TitleTemplate: "{{.MediaName}}",
+		BodyTemplate:  "Episode {{.EpisodesWatched}} of {{.EpisodesTotal}}",
+	}
+
+	payload := testdata.NewMockNotificationPayload()
+
+	sender := NewNtfySender(zerolog.Nop(), settings)
+	require.NoError(t, sender.Send(payload.Event, payload))
+
+	assert.Equal(t, "Attack on Titan", m.Title)
+	assert.Equal(t, "Episode 5 of 25", m.Message)
+}
@@ -0,0 +1,273 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/sharedhttp"
+)
+
+const (
+	// WebhookVersion is bumped on breaking changes of WebhookMessage.
+	WebhookVersion = 1
+
+	WebhookSignatureHeader = "X-Shinkro-Signature"
+	WebhookEventHeader     = "X-Shinkro-Event"
+
+	webhookDefaultTimeout = 30
+	webhookMaxTimeout     = 120
+	webhookMaxRetries     = 5
+)
+
+// WebhookMessage is the document posted by webhook notifications. Fields are only added within a
+// version, so receivers can rely on it. Body is the rendered body template, if the notification has one.
+type WebhookMessage struct {
+	Version         int                        `json:"version"`
+	Event           domain.NotificationEvent   `json:"event"`
+	Title           string                     `json:"title"`
+	Subject         string                     `json:"subject,omitempty"`
+	Message         string                     `json:"message,omitempty"`
+	Body            string                     `json:"body,omitempty"`
+	ErrorType       string                     `json:"error_type,omitempty"`
+	MediaName       string                     `json:"media_name,omitempty"`
+	MALID           int                        `json:"mal_id,omitempty"`
+	MALURL          string                     `json:"mal_url,omitempty"`
+	AnimeLibrary    string                     `json:"anime_library,omitempty"`
+	EpisodesWatched int                        `json:"episodes_watched,omitempty"`
+	EpisodesTotal   int                        `json:"episodes_total,omitempty"`
+	TimesRewatched  int                        `json:"times_rewatched,omitempty"`
+	PictureURL      string                     `json:"picture_url,omitempty"`
+	StartDate       string                     `json:"start_date,omitempty"`
+	FinishDate      string                     `json:"finish_date,omitempty"`
+	AnimeStatus     string                     `json:"anime_status,omitempty"`
+	Score           int                        `json:"score,omitempty"`
+	PlexEvent       domain.PlexEvent           `json:"plex_event,omitempty"`
+	PlexSource      domain.PlexPayloadSource   `json:"plex_source,omitempty"`
+	PlexID          int64                      `json:"plex_id,omitempty"`
+	AnimeUpdate     *domain.AnimeUpdate        `json:"anime_update,omitempty"`
+	Digest          *domain.NotificationDigest `json:"digest,omitempty"`
+	Timestamp       time.Time                  `json:"timestamp"`
+	Sender          string                     `json:"sender,omitempty"`
+}
+
+func NewWebhookMessage(event domain.NotificationEvent, payload domain.NotificationPayload) WebhookMessage {
+	m := WebhookMessage{
+		Version:         WebhookVersion,
+		Event:           event,
+		Title:           BuildTitle(event),
+		Subject:         payload.Subject,
+		Message:         payload.Message,
+		ErrorType:       payload.ErrorType,
+		MediaName:       payload.MediaName,
+		MALID:           payload.MALID,
+		AnimeLibrary:    payload.AnimeLibrary,
+		EpisodesWatched: payload.EpisodesWatched,
+		EpisodesTotal:   payload.EpisodesTotal,
+		TimesRewatched:  payload.TimesRewatched,
+		PictureURL:      payload.PictureURL,
+		StartDate:       payload.StartDate,
+		FinishDate:      payload.FinishDate,
+		AnimeStatus:     payload.AnimeStatus,
+		Score:           payload.Score,
+		PlexEvent:       payload.PlexEvent,
+		PlexSource:      payload.PlexSource,
+		PlexID:          payload.PlexID,
+		AnimeUpdate:     payload.AnimeUpdate,
+		Digest:          payload.Digest,
+		Timestamp:       payload.Timestamp,
+		Sender:          payload.Sender,
+	}
+
+	if payload.MALID > 0 {
+		m.MALURL = fmt.Sprintf(MAlAnimeURL, payload.MALID)
+	}
+
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now()
+	}
+
+	return m
+}
+
+// webhookSender posts a WebhookMessage to the Webhook url. The body is signed with HMAC-SHA256 when
+// Token is set as the secret, Headers holds extra "Name: value" headers, one per line.
+type webhookSender struct {
+	log      zerolog.Logger
+	Settings *domain.Notification
+	tmpl     *messageTemplate
+
+	httpClient *http.Client
+}
+
+func (s *webhookSender) Name() string {
+	return "webhook"
+}
+
+func NewWebhookSender(log zerolog.Logger, settings *domain.Notification) domain.NotificationSender {
+	log = log.With().Str("sender", "webhook").Logger()
+
+	timeout := settings.Timeout
+	if timeout <= 0 {
+		timeout = webhookDefaultTimeout
+	}
+	if timeout > webhookMaxTimeout {
+		timeout = webhookMaxTimeout
+	}
+
+	return &webhookSender{
+		log:      log,
+		Settings: settings,
+		tmpl:     newMessageTemplate(log, settings),
+		httpClient: &http.Client{
+			Timeout:   time.Duration(timeout) * time.Second,
+			Transport: sharedhttp.Transport,
+		},
+	}
+}
+
+func (s *webhookSender) Send(event domain.NotificationEvent, payload domain.NotificationPayload) error {
+	m := NewWebhookMessage(event, payload)
+	m.Title = s.tmpl.Title(event, payload)
+	m.Body, _ = s.tmpl.RenderBody(event, payload)
+
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not marshal json request for event: %v", event))
+	}
+
+	headers, err := ParseWebhookHeaders(s.Settings.Headers)
+	if err != nil {
+		return err
+	}
+
+	if err := s.post(event, jsonData, headers); err != nil {
+		return err
+	}
+
+	s.log.Debug().Msg("notification successfully sent to webhook")
+
+	return nil
+}
+
+// MaxAttempts returns the configured retries plus the first attempt, deliver retries transient
+// failures that often. Without configured retries the delivery default is used.
+func (s *webhookSender) MaxAttempts() int {
+	retries := s.Settings.Retries
+	if retries <= 0 {
+		return deliveryMaxAttempts
+	}
+	if retries > webhookMaxRetries {
+		retries = webhookMaxRetries
+	}
+
+	return retries + 1
+}
+
+// post sends the request once.
+func (s *webhookSender) post(event domain.NotificationEvent, body []byte, headers http.Header) error {
+	req, err := http.NewRequest(http.MethodPost, s.Settings.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("could not create request for event: %v", event))
+	}
+
+	for name, values := range headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", sharedhttp.UserAgent)
+	req.Header.Set(WebhookEventHeader, string(event))
+
+	if s.Settings.Token != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(s.Settings.Token, body))
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("client request error for event: %v", event))
+	}
+
+	defer res.Body.Close()
+
+	s.log.Trace().Msgf("webhook status: %d", res.StatusCode)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		body, err := io.ReadAll(bufio.NewReader(res.Body))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("could not read body for event: %v", event))
+		}
+
+		return newStatusError(res.StatusCode, body)
+	}
+
+	return nil
+}
+
+// SignWebhook returns the signature header value for body, the hex encoded HMAC-SHA256 prefixed
+// with "sha256=".
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseWebhookHeaders parses "Name: value" lines, empty lines are ignored.
+func ParseWebhookHeaders(s string) (http.Header, error) {
+	headers := http.Header{}
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, errors.Errorf("invalid webhook header: %q", line)
+		}
+
+		headers.Add(name, strings.TrimSpace(value))
+	}
+
+	return headers, nil
+}
+
+func (s *webhookSender) CanSend(event domain.NotificationEvent) bool {
+	if s.isEnabled() && s.isEnabledEvent(event) {
+		return true
+	}
+	return false
+}
+
+func (s *webhookSender) isEnabled() bool {
+	if s.Settings.Enabled {
+		if s.Settings.Webhook == "" {
+			s.log.Warn().Msg("webhook missing url")
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (s *webhookSender) isEnabledEvent(event domain.NotificationEvent) bool {
+	return enabledEvent(s.Settings.Events, event)
+}
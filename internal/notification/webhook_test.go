@@ -0,0 +1,138 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/testdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSender_Send(t *testing.T) {
+	var body []byte
+	var header http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		header = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sender := NewWebhookSender(zerolog.Nop(), &domain.Notification{
+		Type:    domain.NotificationTypeWebhook,
+		Enabled: true,
+		Webhook: srv.URL,
+		Token:   "secret",
+		Headers: "Authorization: Bearer abc\n\nX-Custom: one: two",
+	})
+
+	payload := testdata.NewMockNotificationPayload()
+	payload.PlexID = 42
+	payload.AnimeUpdate = &domain.AnimeUpdate{MALId: 1575, EpisodeNum: 5}
+	require.NoError(t, sender.Send(payload.Event, payload))
+
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "Bearer abc", header.Get("Authorization"))
+	assert.Equal(t, "one: two", header.Get("X-Custom"))
+	assert.Equal(t, "SUCCESS", header.Get(WebhookEventHeader))
+	assert.Equal(t, SignWebhook("secret", body), header.Get(WebhookSignatureHeader))
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, float64(WebhookVersion), m["version"])
+	assert.Equal(t, "SUCCESS", m["event"])
+	assert.Equal(t, "Attack on Titan", m["media_name"])
+	assert.Equal(t, "https://myanimelist.net/anime/1575", m["mal_url"])
+	assert.Equal(t, float64(42), m["plex_id"])
+	assert.Equal(t, float64(5), m["anime_update"].(map[string]interface{})["episodeNum"])
+}
+
+func TestWebhookSender_SendFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wantTransient bool
+	}{
+		{name: "server error", status: http.StatusBadGateway, wantTransient: true},
+		{name: "rate limited", status: http.StatusTooManyRequests, wantTransient: true},
+		{name: "client error", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			sender := NewWebhookSender(zerolog.Nop(), &domain.Notification{Enabled: true, Webhook: srv.URL, Retries: 3})
+
+			err := sender.Send(domain.NotificationEventTest, domain.NotificationPayload{Event: domain.NotificationEventTest})
+			require.Error(t, err)
+			assert.Equal(t, tt.wantTransient, isTransient(err))
+			assert.Equal(t, tt.status, httpStatus(err))
+			assert.Equal(t, 1, calls)
+		})
+	}
+}
+
+func TestService_DeliverWebhookRetries(t *testing.T) {
+	deliveryRetryDelay = time.Millisecond
+	defer func() { deliveryRetryDelay = 10 * time.Second }()
+
+	tests := []struct {
+		name      string
+		retries   int
+		statuses  []int
+		wantCalls int
+	}{
+		{name: "succeeds after server error", retries: 2, statuses: []int{500, 502, 200}, wantCalls: 3},
+		{name: "gives up after retries", retries: 1, statuses: []int{503, 503, 200}, wantCalls: 2},
+		{name: "client errors are not retried", retries: 3, statuses: []int{400, 200}, wantCalls: 1},
+		{name: "retries are capped", retries: 9, statuses: []int{500, 500, 500, 500, 500, 500, 500, 200}, wantCalls: webhookMaxRetries + 1},
+		{name: "default attempts", statuses: []int{500, 500, 500, 200}, wantCalls: deliveryMaxAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[calls])
+				calls++
+			}))
+			defer srv.Close()
+
+			repo := &mockDeliveryRepo{}
+			s := &service{log: zerolog.Nop(), deliveryRepo: repo}
+			sender := NewWebhookSender(zerolog.Nop(), &domain.Notification{Enabled: true, Webhook: srv.URL, Retries: tt.retries})
+
+			s.deliver(context.Background(), 1, sender, domain.NotificationEventTest, domain.NotificationPayload{Event: domain.NotificationEventTest})
+
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Len(t, repo.deliveries, tt.wantCalls)
+		})
+	}
+}
+
+func TestParseWebhookHeaders(t *testing.T) {
+	headers, err := ParseWebhookHeaders("X-Api-Key: abc\r\nX-Api-Key: def\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abc", "def"}, headers.Values("X-Api-Key"))
+
+	_, err = ParseWebhookHeaders("not a header")
+	assert.Error(t, err)
+}
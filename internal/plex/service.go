@@ -3,6 +3,7 @@ package plex
 import (
 	"context"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/asaskevich/EventBus"
@@ -14,43 +15,66 @@ import (
 	"github.com/varoOP/shinkro/internal/malauth"
 	"github.com/varoOP/shinkro/internal/mapping"
 	"github.com/varoOP/shinkro/internal/plexsettings"
+	"github.com/varoOP/shinkro/internal/syncrule"
 )
 
 type Service interface {
 	Store(ctx context.Context, plex *domain.Plex) error
 	Get(ctx context.Context, req *domain.GetPlexRequest) (*domain.Plex, error)
 	ProcessPlex(ctx context.Context, plex *domain.Plex) error
+	PreviewPlex(ctx context.Context, plex *domain.Plex) (*domain.AnimeUpdate, error)
 	GetPlexSettings(ctx context.Context) (*domain.PlexSettings, error)
+	GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error)
 	CheckPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error
+	CheckPlexPreview(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error
 	CountScrobbleEvents(ctx context.Context) (int, error)
 	CountRateEvents(ctx context.Context) (int, error)
 	GetPlexHistory(ctx context.Context, limit int) ([]domain.PlexHistoryItem, error)
 	FindAllWithFilters(ctx context.Context, params domain.PlexPayloadQueryParams) (*domain.FindPlexPayloadsResponse, error)
 	Delete(ctx context.Context, req *domain.DeletePlexRequest) error
 	UpdateStatus(ctx context.Context, plexID int64, success *bool, errorType domain.PlexErrorType, errorMsg string) error
+	SuppressScrobble(ratingKey string)
+	ClearCompleted(ratingKey string)
 }
 
+// suppressScrobbleTTL is how long a scrobble made by shinkro itself is ignored when Plex reports it back.
+const suppressScrobbleTTL = 24 * time.Hour
+
+// completedTTL is how long an item counted as watched ignores further playback events, so a
+// media.stop after the scrobble (or the other way round) does not update MAL twice.
+const completedTTL = 3 * time.Hour
+
 type service struct {
 	log                zerolog.Logger
+	config             *domain.Config
 	repo               domain.PlexRepo
 	plexettingsService plexsettings.Service
+	syncRuleService    syncrule.Service
 	animeService       anime.Service
 	mapService         mapping.Service
 	malauthService     malauth.Service
 	animeUpdateService animeupdate.Service
 	bus                EventBus.Bus
+
+	mu         sync.Mutex
+	suppressed map[string]time.Time
+	completed  map[string]time.Time
 }
 
-func NewService(log zerolog.Logger, plexsettingsSvc plexsettings.Service, repo domain.PlexRepo, animeSvc anime.Service, mapSvc mapping.Service, malauthSvc malauth.Service, animeUpdateSvc animeupdate.Service, bus EventBus.Bus) Service {
+func NewService(log zerolog.Logger, config *domain.Config, plexsettingsSvc plexsettings.Service, syncRuleSvc syncrule.Service, repo domain.PlexRepo, animeSvc anime.Service, mapSvc mapping.Service, malauthSvc malauth.Service, animeUpdateSvc animeupdate.Service, bus EventBus.Bus) Service {
 	return &service{
 		log:                log.With().Str("module", "plex").Logger(),
+		config:             config,
 		repo:               repo,
 		plexettingsService: plexsettingsSvc,
+		syncRuleService:    syncRuleSvc,
 		animeService:       animeSvc,
 		mapService:         mapSvc,
 		malauthService:     malauthSvc,
 		animeUpdateService: animeUpdateSvc,
 		bus:                bus,
+		suppressed:         make(map[string]time.Time),
+		completed:          make(map[string]time.Time),
 	}
 }
 
@@ -66,32 +90,183 @@ func (s *service) GetPlexSettings(ctx context.Context) (*domain.PlexSettings, er
 	return s.plexettingsService.Get(ctx)
 }
 
+// GetPlexSettingsForServer returns the settings used for payloads sent by the Plex server with uuid.
+func (s *service) GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return s.plexettingsService.GetForServer(ctx, uuid)
+}
+
 // CheckPlex validates a Plex payload (user, event, library, media type, rating).
 func (s *service) CheckPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
-	if !plex.IsPlexUserAllowed(ps) {
+	return s.checkPlex(ctx, plex, ps, true)
+}
+
+// CheckPlexPreview validates a Plex payload like CheckPlex for previews, a scrobble skipped because
+// reverse sync made it stays skipped for the real payload.
+func (s *service) CheckPlexPreview(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	return s.checkPlex(ctx, plex, ps, false)
+}
+
+// checkPlex validates plex, consume removes the reverse sync suppression of a skipped scrobble.
+func (s *service) checkPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings, consume bool) error {
+	// library.new is sent by the server, not on behalf of the configured user
+	if plex.Event != domain.PlexLibraryNewEvent && !plex.IsPlexUserAllowed(ps) {
 		return errors.Wrap(errors.New("unauthorized plex user"), plex.Account.Title)
 	}
 
+	if !ps.IsServerAllowed(plex.Server.UUID) {
+		return errors.Wrap(errors.New("plex server not configured"), plex.Server.UUID)
+	}
+
 	if !plex.IsEventAllowed() {
 		return errors.Wrap(errors.New("plex event not supported"), string(plex.Event))
 	}
 
+	if err := s.plexettingsService.ResolveLibrarySection(ctx, ps, plex); err != nil {
+		s.log.Debug().Err(err).Msgf("could not resolve the section id of plex library %s", plex.Metadata.LibrarySectionTitle)
+	}
+
 	if !plex.IsAnimeLibrary(ps) {
 		return errors.Wrap(errors.New("plex library not set as an anime library"), plex.Metadata.LibrarySectionTitle)
 	}
 
+	if plex.Event == domain.PlexLibraryNewEvent && !plex.IsPlanToWatchLibrary(ps) {
+		return errors.Wrap(errors.New("plex library not set to add new anime as plan to watch"), plex.Metadata.LibrarySectionTitle)
+	}
+
 	if !plex.IsMediaTypeAllowed() {
 		return errors.Wrap(errors.New("plex media type not supported"), string(plex.Metadata.Type))
 	}
 
+	if rule := s.matchSyncRule(ctx, plex, 0); rule != nil && !rule.Allows(plex) {
+		if rule.Ignore {
+			return errors.Wrap(errors.New("ignored by sync rule"), strconv.Itoa(rule.ID))
+		}
+		return errors.Wrap(errors.New("plex event disabled by sync rule"), string(plex.Event))
+	}
+
 	if !plex.IsRatingAllowed() {
 		return errors.Wrap(errors.New("rating was unset, skipped"), strconv.FormatFloat(float64(plex.Rating), 'f', -1, 64))
 	}
 
+	if plex.Event == domain.PlexScrobbleEvent && s.isSuppressed(plex.Metadata.RatingKey, consume) {
+		return errors.Wrap(errors.New("scrobble was made by reverse sync, skipped"), plex.Metadata.RatingKey)
+	}
+
+	if !plex.IsPlaybackComplete(ps) {
+		return errors.Wrap(errors.New("playback did not reach the completion threshold, skipped"), strconv.Itoa(plex.Metadata.ViewOffset))
+	}
+
+	if dedupeCompleted(plex, ps) && s.isCompleted(plex.Metadata.RatingKey) {
+		return errors.Wrap(errors.New("playback was already counted as watched, skipped"), plex.Metadata.RatingKey)
+	}
+
 	return nil
 }
 
+// dedupeCompleted reports whether playback events of plex are counted once within completedTTL,
+// which is needed when stops and pauses can count as watched besides the scrobble.
+func dedupeCompleted(plex *domain.Plex, ps *domain.PlexSettings) bool {
+	return (ps.CompletionThreshold > 0 || ps.CompleteAtCredits) && plex.IsPlaybackEvent()
+}
+
+// SuppressScrobble ignores the next scrobble of ratingKey, so that episodes marked watched by
+// reverse sync are not sent back to MyAnimeList.
+func (s *service) SuppressScrobble(ratingKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.suppressed[ratingKey] = time.Now().Add(suppressScrobbleTTL)
+}
+
+// isSuppressed reports whether the scrobble of ratingKey was made by reverse sync, consume forgets
+// it so later plays are processed again.
+func (s *service) isSuppressed(ratingKey string, consume bool) bool {
+	if ratingKey == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, expires := range s.suppressed {
+		if now.After(expires) {
+			delete(s.suppressed, key)
+		}
+	}
+
+	if _, ok := s.suppressed[ratingKey]; !ok {
+		return false
+	}
+
+	if consume {
+		delete(s.suppressed, ratingKey)
+	}
+	return true
+}
+
+// isCompleted reports whether ratingKey was counted as watched within completedTTL.
+func (s *service) isCompleted(ratingKey string) bool {
+	if ratingKey == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneCompleted(time.Now())
+
+	_, ok := s.completed[ratingKey]
+	return ok
+}
+
+// markCompleted records ratingKey as watched and returns false when it already was within completedTTL.
+func (s *service) markCompleted(ratingKey string) bool {
+	if ratingKey == "" {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneCompleted(now)
+
+	if _, ok := s.completed[ratingKey]; ok {
+		return false
+	}
+
+	s.completed[ratingKey] = now.Add(completedTTL)
+	return true
+}
+
+// ClearCompleted forgets that ratingKey was counted as watched, so the next playback event of it
+// is processed again after the MAL update failed.
+func (s *service) ClearCompleted(ratingKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.completed, ratingKey)
+}
+
+func (s *service) pruneCompleted(now time.Time) {
+	for key, expires := range s.completed {
+		if now.After(expires) {
+			delete(s.completed, key)
+		}
+	}
+}
+
 func (s *service) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
+	if plex.Event == domain.PlexLibraryNewEvent {
+		// New episodes come with refreshed show metadata, so its guids are looked up again
+		if err := s.plexettingsService.InvalidateGUIDCache(ctx, plex); err != nil {
+			s.log.Debug().Err(err).Msg("could not invalidate plex guid cache")
+		}
+	}
+
+	plex = plex.LibraryNewAsEpisode()
+
 	// Check if metadata agent is supported
 	allowed, agent := plex.IsMetadataAgentAllowed()
 	if !allowed {
@@ -106,7 +281,8 @@ func (s *service) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
 		return err
 	}
 
-	a, err := s.extractSourceIdForAnime(ctx, plex, &agent)
+	rule := s.matchSyncRule(ctx, plex, 0)
+	a, err := s.extractSourceIdForAnime(ctx, plex, &agent, rule)
 	if err != nil {
 		s.bus.Publish(domain.EventPlexProcessedError, &domain.PlexProcessedErrorEvent{
 			PlexID:       plex.ID,
@@ -118,6 +294,36 @@ func (s *service) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
 		return err
 	}
 
+	if r := s.matchSyncRuleByMALID(ctx, plex, a); r != nil {
+		rule = r
+	}
+
+	if rule != nil {
+		if !rule.Allows(plex) {
+			success := false
+			msg := "skipped by sync rule " + strconv.Itoa(rule.ID)
+			s.log.Info().Int("rule", rule.ID).Str("event", string(plex.Event)).Msg(msg)
+			return s.UpdateStatus(ctx, plex.ID, &success, domain.PlexErrorSkippedByRule, msg)
+		}
+
+		a.EpisodeOffset = rule.EpisodeOffset
+	}
+
+	ps, err := s.plexettingsService.GetForServer(ctx, plex.Server.UUID)
+	if err != nil {
+		s.log.Debug().Err(err).Msg("could not get plex settings to check for dry run libraries")
+	}
+
+	a.DryRun = s.isDryRun(plex, ps)
+
+	// counted as watched only now, a payload rejected or failed before this is processed again
+	if ps != nil && dedupeCompleted(plex, ps) && !s.markCompleted(plex.Metadata.RatingKey) {
+		success := false
+		msg := "playback was already counted as watched, skipped"
+		s.log.Debug().Str("ratingKey", plex.Metadata.RatingKey).Msg(msg)
+		return s.UpdateStatus(ctx, plex.ID, &success, domain.PlexErrorAlreadyCompleted, msg)
+	}
+
 	// Publish success event - Plex processing succeeded (metadata extraction worked)
 	// Event subscriber will trigger MAL update asynchronously
 	s.bus.Publish(domain.EventPlexProcessedSuccess, &domain.PlexProcessedSuccessEvent{
@@ -130,8 +336,108 @@ func (s *service) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
 	return nil
 }
 
-func (s *service) extractSourceIdForAnime(ctx context.Context, plex *domain.Plex, agent *domain.PlexSupportedAgents) (*domain.AnimeUpdate, error) {
-	source, id, err := s.getSourceIDFromAgent(ctx, plex, agent)
+// isDryRun reports whether updates for plex should only be recorded, either globally or for its
+// library in ps, which is nil when the settings could not be loaded.
+func (s *service) isDryRun(plex *domain.Plex, ps *domain.PlexSettings) bool {
+	if s.config.DryRun {
+		return true
+	}
+
+	if ps == nil {
+		return false
+	}
+
+	return plex.IsDryRunLibrary(ps)
+}
+
+// PreviewPlex runs the same metadata extraction as ProcessPlex and returns the resulting
+// MAL change, without publishing events or updating MyAnimeList.
+func (s *service) PreviewPlex(ctx context.Context, plex *domain.Plex) (*domain.AnimeUpdate, error) {
+	allowed, agent := plex.IsMetadataAgentAllowed()
+	if !allowed {
+		return nil, errors.New("metadata agent not supported")
+	}
+
+	rule := s.matchSyncRule(ctx, plex, 0)
+	a, err := s.extractSourceIdForAnime(ctx, plex, &agent, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	if r := s.matchSyncRuleByMALID(ctx, plex, a); r != nil {
+		rule = r
+	}
+
+	if rule != nil {
+		a.EpisodeOffset = rule.EpisodeOffset
+	}
+
+	if err := s.animeUpdateService.PreviewAnimeList(ctx, a, plex.Event); err != nil {
+		return a, err
+	}
+
+	return a, nil
+}
+
+// syncRules returns the configured sync rules, loaded once per payload, or none when they could
+// not be loaded.
+func (s *service) syncRules(ctx context.Context, plex *domain.Plex) []*domain.SyncRule {
+	if rules, ok := plex.SyncRules(); ok {
+		return rules
+	}
+
+	rules, err := s.syncRuleService.List(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("could not get sync rules")
+		return nil
+	}
+
+	plex.SetSyncRules(rules)
+	return rules
+}
+
+func (s *service) matchSyncRule(ctx context.Context, plex *domain.Plex, malID int) *domain.SyncRule {
+	return domain.MatchSyncRule(s.syncRules(ctx, plex), plex, malID)
+}
+
+// matchSyncRuleByMALID returns the rule matching the MAL id of a, resolving the MAL id only when
+// rules by MAL id exist.
+func (s *service) matchSyncRuleByMALID(ctx context.Context, plex *domain.Plex, a *domain.AnimeUpdate) *domain.SyncRule {
+	rules := s.syncRules(ctx, plex)
+	if !domain.HasMALIDRules(rules) {
+		return nil
+	}
+
+	malID := a.SourceId
+	if a.SourceDB != domain.MAL {
+		resolved := *a
+		if err := s.animeUpdateService.ResolveMALID(ctx, &resolved); err != nil {
+			s.log.Debug().Err(err).Msg("could not resolve MAL id for sync rules")
+			return nil
+		}
+		malID = resolved.MALId
+	}
+
+	rule := domain.MatchSyncRule(rules, plex, malID)
+	if rule == nil || rule.MALID != malID {
+		return nil
+	}
+
+	return rule
+}
+
+func (s *service) extractSourceIdForAnime(ctx context.Context, plex *domain.Plex, agent *domain.PlexSupportedAgents, rule *domain.SyncRule) (*domain.AnimeUpdate, error) {
+	if rule != nil && rule.ForceMALID > 0 {
+		a := plex.SetAnimeFields(domain.MAL, rule.ForceMALID)
+		return &a, nil
+	}
+
+	var provider domain.PlexSupportedDBs
+	if rule != nil {
+		provider = rule.Provider
+	}
+
+	source, id, err := s.getSourceIDFromAgent(ctx, plex, agent, provider)
 	if err != nil {
 		return nil, err
 	}
@@ -140,12 +446,12 @@ func (s *service) extractSourceIdForAnime(ctx context.Context, plex *domain.Plex
 	return &a, nil
 }
 
-func (s *service) getSourceIDFromAgent(ctx context.Context, p *domain.Plex, agent *domain.PlexSupportedAgents) (domain.PlexSupportedDBs, int, error) {
+func (s *service) getSourceIDFromAgent(ctx context.Context, p *domain.Plex, agent *domain.PlexSupportedAgents, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error) {
 	switch *agent {
 	case domain.HAMA, domain.MALAgent:
 		return p.Metadata.GUID.HamaMALAgent(*agent)
 	case domain.PlexAgent:
-		return s.plexettingsService.HandlePlexAgent(ctx, p)
+		return s.plexettingsService.HandlePlexAgent(ctx, p, provider)
 	}
 	return "", 0, errors.New("unknown agent")
 }
@@ -44,10 +44,68 @@ func (m *mockPlexSettingsService) GetClient(ctx context.Context, ps *domain.Plex
 	return nil, nil
 }
 
-func (m *mockPlexSettingsService) HandlePlexAgent(ctx context.Context, p *domain.Plex) (domain.PlexSupportedDBs, int, error) {
+func (m *mockPlexSettingsService) HandlePlexAgent(ctx context.Context, p *domain.Plex, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error) {
 	return "", 0, nil
 }
 
+func (m *mockPlexSettingsService) MigrateLibraries(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) GetForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.Get(ctx)
+}
+
+func (m *mockPlexSettingsService) ListServers(ctx context.Context) ([]*domain.PlexServer, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) StoreServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) UpdateServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) DeleteServer(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) InvalidateGUIDCache(ctx context.Context, p *domain.Plex) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ClearGUIDCache(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ResolveLibrarySection(ctx context.Context, ps *domain.PlexSettings, p *domain.Plex) error {
+	return nil
+}
+
+type mockSyncRuleService struct {
+	rules []*domain.SyncRule
+	calls int
+}
+
+func (m *mockSyncRuleService) List(ctx context.Context) ([]*domain.SyncRule, error) {
+	m.calls++
+	return m.rules, nil
+}
+
+func (m *mockSyncRuleService) Store(ctx context.Context, rule *domain.SyncRule) error {
+	return nil
+}
+
+func (m *mockSyncRuleService) Update(ctx context.Context, rule *domain.SyncRule) error {
+	return nil
+}
+
+func (m *mockSyncRuleService) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
 type mockPlexRepo struct{}
 
 func (m *mockPlexRepo) Store(ctx context.Context, plex *domain.Plex) error {
@@ -90,7 +148,9 @@ func TestService_CheckPlex(t *testing.T) {
 	bus := EventBus.New()
 	service := NewService(
 		zerolog.Nop(),
+		&domain.Config{},
 		&mockPlexSettingsService{},
+		&mockSyncRuleService{},
 		&mockPlexRepo{},
 		nil, // anime service
 		nil, // mapping service
@@ -123,6 +183,17 @@ func TestService_CheckPlex(t *testing.T) {
 			expectedError: true,
 			errContains:   "unauthorized plex user",
 		},
+		{
+			name: "other plex server",
+			plex: testdata.NewMockPlex(),
+			settings: func() *domain.PlexSettings {
+				ps := testdata.NewMockPlexSettings()
+				ps.ServerUUID = "other-server-uuid"
+				return ps
+			}(),
+			expectedError: true,
+			errContains:   "plex server not configured",
+		},
 		{
 			name: "unsupported event",
 			plex: func() *domain.Plex {
@@ -189,6 +260,34 @@ func TestService_CheckPlex(t *testing.T) {
 			settings:      testdata.NewMockPlexSettings(),
 			expectedError: false,
 		},
+		{
+			name: "library new in library not set to plan to watch",
+			plex: func() *domain.Plex {
+				p := testdata.NewMockPlex()
+				p.Event = domain.PlexLibraryNewEvent
+				p.Metadata.Type = domain.PlexShow
+				return p
+			}(),
+			settings:      testdata.NewMockPlexSettings(),
+			expectedError: true,
+			errContains:   "plex library not set to add new anime as plan to watch",
+		},
+		{
+			name: "library new is not sent for the configured user",
+			plex: func() *domain.Plex {
+				p := testdata.NewMockPlex()
+				p.Event = domain.PlexLibraryNewEvent
+				p.Metadata.Type = domain.PlexShow
+				p.Account.Title = "ServerOwner"
+				return p
+			}(),
+			settings: func() *domain.PlexSettings {
+				ps := testdata.NewMockPlexSettings()
+				ps.PlanToWatchLibs = ps.AnimeLibraries
+				return ps
+			}(),
+			expectedError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -205,3 +304,216 @@ func TestService_CheckPlex(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CheckPlex_SuppressedScrobble(t *testing.T) {
+	service := NewService(
+		zerolog.Nop(),
+		&domain.Config{},
+		&mockPlexSettingsService{},
+		&mockSyncRuleService{},
+		&mockPlexRepo{},
+		nil, // anime service
+		nil, // mapping service
+		nil, // malauth service
+		nil, // animeupdate service
+		EventBus.New(),
+	)
+
+	p := testdata.NewMockPlex()
+	p.Metadata.RatingKey = "31444"
+	settings := testdata.NewMockPlexSettings()
+
+	service.SuppressScrobble("31444")
+
+	// Previews skip the scrobble without forgetting it
+	for i := 0; i < 2; i++ {
+		err := service.CheckPlexPreview(context.Background(), p, settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "scrobble was made by reverse sync")
+	}
+
+	err := service.CheckPlex(context.Background(), p, settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "scrobble was made by reverse sync")
+
+	// Only the scrobble reported back is skipped, later plays are processed again
+	err = service.CheckPlex(context.Background(), p, settings)
+	assert.NoError(t, err)
+}
+
+func TestService_CheckPlex_PlaybackThreshold(t *testing.T) {
+	service := NewService(
+		zerolog.Nop(),
+		&domain.Config{},
+		&mockPlexSettingsService{},
+		&mockSyncRuleService{},
+		&mockPlexRepo{},
+		nil, // anime service
+		nil, // mapping service
+		nil, // malauth service
+		nil, // animeupdate service
+		EventBus.New(),
+	)
+
+	settings := testdata.NewMockPlexSettings()
+	settings.CompletionThreshold = 80
+
+	stop := testdata.NewMockPlex()
+	stop.Event = domain.PlexStopEvent
+	stop.Metadata.RatingKey = "31444"
+	stop.Metadata.Duration = 1440000
+	stop.Metadata.ViewOffset = 600000
+
+	err := service.CheckPlex(context.Background(), stop, settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "playback did not reach the completion threshold")
+
+	stop.Metadata.ViewOffset = 1200000
+	err = service.CheckPlex(context.Background(), stop, settings)
+	assert.NoError(t, err)
+
+	// Checking alone does not count the playback, it may still fail to be stored or processed
+	scrobble := testdata.NewMockPlex()
+	scrobble.Metadata.RatingKey = "31444"
+	assert.NoError(t, service.CheckPlex(context.Background(), scrobble, settings))
+
+	// The scrobble Plex sends for the same playback was already counted by the stop
+	require.True(t, service.(interface{ markCompleted(string) bool }).markCompleted("31444"))
+	err = service.CheckPlex(context.Background(), scrobble, settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "playback was already counted as watched")
+
+	// A failed MAL update lets the episode through again
+	service.ClearCompleted("31444")
+	assert.NoError(t, service.CheckPlex(context.Background(), scrobble, settings))
+}
+
+func TestService_ProcessPlex_MarksCompleted(t *testing.T) {
+	bus := EventBus.New()
+	processed := 0
+	require.NoError(t, bus.Subscribe(domain.EventPlexProcessedSuccess, func(e *domain.PlexProcessedSuccessEvent) {
+		processed++
+	}))
+
+	settings := testdata.NewMockPlexSettings()
+	settings.CompletionThreshold = 80
+	rules := &mockSyncRuleService{}
+	service := NewService(zerolog.Nop(), &domain.Config{}, &mockPlexSettingsService{settings: settings}, rules, &mockPlexRepo{}, nil, nil, nil, nil, bus)
+
+	scrobble := testdata.NewMockPlexWithMALAgent(52991)
+	scrobble.Metadata.RatingKey = "31444"
+	require.NoError(t, service.CheckPlex(context.Background(), scrobble, settings))
+	require.NoError(t, service.ProcessPlex(context.Background(), scrobble))
+	assert.Equal(t, 1, processed)
+
+	// sync rules are loaded once for checking and processing the webhook
+	assert.Equal(t, 1, rules.calls)
+
+	// a second event for the same playback that passed the check concurrently is skipped
+	again := testdata.NewMockPlexWithMALAgent(52991)
+	again.Metadata.RatingKey = "31444"
+	require.NoError(t, service.ProcessPlex(context.Background(), again))
+	assert.Equal(t, 1, processed)
+}
+
+func TestService_IsDryRun(t *testing.T) {
+	settings := &domain.PlexSettings{AnimeLibraries: []string{"Anime", "Anime Movies"}, DryRunLibs: []string{"Anime Movies"}}
+	anime := &domain.Plex{Metadata: domain.Metadata{LibrarySectionTitle: "Anime"}}
+	movies := &domain.Plex{Metadata: domain.Metadata{LibrarySectionTitle: "Anime Movies"}}
+
+	svc := NewService(zerolog.Nop(), &domain.Config{}, &mockPlexSettingsService{settings: settings}, &mockSyncRuleService{}, &mockPlexRepo{}, nil, nil, nil, nil, EventBus.New()).(*service)
+	assert.False(t, svc.isDryRun(anime, settings))
+	assert.True(t, svc.isDryRun(movies, settings))
+
+	svc.config.DryRun = true
+	assert.True(t, svc.isDryRun(anime, settings))
+	assert.True(t, svc.isDryRun(anime, nil))
+
+	// settings that could not be loaded
+	svc.config.DryRun = false
+	assert.False(t, svc.isDryRun(movies, nil))
+}
+
+func TestService_CheckPlex_SyncRules(t *testing.T) {
+	rules := &mockSyncRuleService{}
+	service := NewService(zerolog.Nop(), &domain.Config{}, &mockPlexSettingsService{}, rules, &mockPlexRepo{}, nil, nil, nil, nil, EventBus.New())
+	settings := testdata.NewMockPlexSettings()
+
+	rules.rules = []*domain.SyncRule{{ID: 1, Library: "Anime", Ignore: true}}
+	err := service.CheckPlex(context.Background(), testdata.NewMockPlex(), settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ignored by sync rule")
+
+	rules.rules = []*domain.SyncRule{{ID: 1, Library: "Anime", Events: []domain.PlexEvent{domain.PlexRateEvent}}}
+	err = service.CheckPlex(context.Background(), testdata.NewMockPlex(), settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plex event disabled by sync rule")
+
+	rate := testdata.NewMockPlex()
+	rate.Event = domain.PlexRateEvent
+	assert.NoError(t, service.CheckPlex(context.Background(), rate, settings))
+}
+
+func TestService_ProcessPlex_SyncRules(t *testing.T) {
+	offset := -12
+	tests := []struct {
+		name          string
+		plex          *domain.Plex
+		rules         []*domain.SyncRule
+		expectSkipped bool
+		expectSource  domain.PlexSupportedDBs
+		expectID      int
+		expectOffset  *int
+	}{
+		{
+			name:         "no rules",
+			plex:         testdata.NewMockPlexWithMALAgent(52991),
+			expectSource: domain.MAL,
+			expectID:     52991,
+		},
+		{
+			name:         "force MAL id",
+			plex:         testdata.NewMockPlex(),
+			rules:        []*domain.SyncRule{{ID: 1, Library: "Anime", ForceMALID: 16498, EpisodeOffset: &offset}},
+			expectSource: domain.MAL,
+			expectID:     16498,
+			expectOffset: &offset,
+		},
+		{
+			name:          "ignored by MAL id rule",
+			plex:          testdata.NewMockPlexWithMALAgent(52991),
+			rules:         []*domain.SyncRule{{ID: 1, Library: "Anime"}, {ID: 2, MALID: 52991, Ignore: true}},
+			expectSkipped: true,
+		},
+		{
+			name:         "MAL id rule for another show",
+			plex:         testdata.NewMockPlexWithMALAgent(52991),
+			rules:        []*domain.SyncRule{{ID: 2, MALID: 1, Ignore: true}},
+			expectSource: domain.MAL,
+			expectID:     52991,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := EventBus.New()
+			var processed *domain.AnimeUpdate
+			require.NoError(t, bus.Subscribe(domain.EventPlexProcessedSuccess, func(e *domain.PlexProcessedSuccessEvent) {
+				processed = e.AnimeUpdate
+			}))
+
+			service := NewService(zerolog.Nop(), &domain.Config{}, &mockPlexSettingsService{settings: testdata.NewMockPlexSettings()}, &mockSyncRuleService{rules: tt.rules}, &mockPlexRepo{}, nil, nil, nil, nil, bus)
+
+			require.NoError(t, service.ProcessPlex(context.Background(), tt.plex))
+			if tt.expectSkipped {
+				assert.Nil(t, processed)
+				return
+			}
+
+			require.NotNil(t, processed)
+			assert.Equal(t, tt.expectSource, processed.SourceDB)
+			assert.Equal(t, tt.expectID, processed.SourceId)
+			assert.Equal(t, tt.expectOffset, processed.EpisodeOffset)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package plexsettings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+// guidRetryDelays are the waits between background attempts to refresh a show guid after the live
+// lookup failed.
+var guidRetryDelays = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute}
+
+// guidLookupTimeout limits a single background lookup.
+const guidLookupTimeout = 30 * time.Second
+
+// guidRetry is a background lookup of the guids of a show and the payloads that failed without them.
+type guidRetry struct {
+	payloads []*domain.Plex
+}
+
+// add records a stored payload to process again once the guids are known.
+func (r *guidRetry) add(p *domain.Plex) {
+	if p != nil && p.ID > 0 {
+		r.payloads = append(r.payloads, p)
+	}
+}
+
+// showGUID returns the guids of the show of an episode. Fresh cached guids are used without asking
+// Plex; when Plex cannot be reached a stale entry is used and the lookup is retried in the background.
+// Without an entry p fails and is processed again once the background lookup succeeds.
+func (s *service) showGUID(ctx context.Context, ps *domain.PlexSettings, p *domain.Plex) (*domain.GUID, error) {
+	key := showRatingKey(p)
+	ttl := time.Duration(s.config.PlexGUIDCacheTTL) * time.Hour
+
+	cached, err := s.guidCache.Get(ctx, ps.ServerUUID, key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.log.Debug().Err(err).Str("ratingKey", key).Msg("could not read plex guid cache")
+	}
+
+	if cached != nil && cached.IsFresh(ttl, time.Now()) {
+		s.log.Trace().Str("ratingKey", key).Msg("using cached plex guids")
+		return &cached.GUID, nil
+	}
+
+	guid, err := s.fetchShowGUID(ctx, ps, p.Metadata.GrandparentKey, key)
+	if err == nil {
+		return guid, nil
+	}
+
+	if cached != nil {
+		s.retryShowGUID(ps, p.Metadata.GrandparentKey, key, nil)
+		s.log.Warn().Err(err).Str("ratingKey", key).Msg("plex lookup failed, using stale cached guids")
+		return &cached.GUID, nil
+	}
+
+	s.retryShowGUID(ps, p.Metadata.GrandparentKey, key, p)
+	return nil, err
+}
+
+// fetchShowGUID looks up the guids of a show on Plex and caches them.
+func (s *service) fetchShowGUID(ctx context.Context, ps *domain.PlexSettings, grandparentKey, ratingKey string) (*domain.GUID, error) {
+	pc, err := s.GetClient(ctx, ps)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := pc.GetShowID(ctx, grandparentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	guid := domain.GUID{
+		GUIDS: g.GUIDS,
+		GUID:  g.GUID,
+	}
+
+	if ratingKey != "" {
+		if err := s.guidCache.Store(ctx, &domain.PlexGUIDCache{
+			ServerUUID: ps.ServerUUID,
+			RatingKey:  ratingKey,
+			GUID:       guid,
+			UpdatedAt:  time.Now(),
+		}); err != nil {
+			s.log.Error().Err(err).Str("ratingKey", ratingKey).Msg("could not cache plex guids")
+		}
+	}
+
+	return &guid, nil
+}
+
+// retryShowGUID refreshes the cached guids of a show in the background, at most one retry per show.
+// Once they are found the failed payloads are published with EventPlexGUIDResolved to be processed
+// again.
+func (s *service) retryShowGUID(ps *domain.PlexSettings, grandparentKey, ratingKey string, failed *domain.Plex) {
+	if ratingKey == "" {
+		return
+	}
+
+	id := ps.ServerUUID + "/" + ratingKey
+
+	s.mu.Lock()
+	if r, ok := s.retrying[id]; ok {
+		r.add(failed)
+		s.mu.Unlock()
+		return
+	}
+	r := &guidRetry{}
+	r.add(failed)
+	s.retrying[id] = r
+	s.mu.Unlock()
+
+	delays := guidRetryDelays
+	go func() {
+		for _, delay := range delays {
+			time.Sleep(delay)
+
+			ctx, cancel := context.WithTimeout(context.Background(), guidLookupTimeout)
+			_, err := s.fetchShowGUID(ctx, ps, grandparentKey, ratingKey)
+			cancel()
+			if err == nil {
+				s.log.Debug().Str("ratingKey", ratingKey).Msg("refreshed plex guids in the background")
+				s.replayPayloads(id, ratingKey)
+				return
+			}
+
+			s.log.Debug().Err(err).Str("ratingKey", ratingKey).Msg("background plex guid lookup failed")
+		}
+
+		if payloads := s.endRetry(id); len(payloads) > 0 {
+			s.log.Warn().Str("ratingKey", ratingKey).Int("payloads", len(payloads)).Msg("could not look up plex guids, failed payloads are not processed again")
+		}
+	}()
+}
+
+// replayPayloads ends the retry of a show and publishes the payloads that failed without its guids.
+func (s *service) replayPayloads(id, ratingKey string) {
+	payloads := s.endRetry(id)
+	if len(payloads) == 0 {
+		return
+	}
+
+	s.log.Info().Str("ratingKey", ratingKey).Int("payloads", len(payloads)).Msg("plex guids found, processing failed payloads again")
+	s.bus.Publish(domain.EventPlexGUIDResolved, &domain.PlexGUIDResolvedEvent{
+		RatingKey: ratingKey,
+		Plex:      payloads,
+		Timestamp: time.Now(),
+	})
+}
+
+// endRetry removes the retry of a show and returns its failed payloads.
+func (s *service) endRetry(id string) []*domain.Plex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.retrying[id]
+	delete(s.retrying, id)
+	if r == nil {
+		return nil
+	}
+
+	return r.payloads
+}
+
+// InvalidateGUIDCache drops the cached guids of the show of p, so the next lookup asks Plex again.
+func (s *service) InvalidateGUIDCache(ctx context.Context, p *domain.Plex) error {
+	key := showRatingKey(p)
+	if key == "" {
+		return nil
+	}
+
+	ps, err := s.GetForServer(ctx, p.Server.UUID)
+	if err != nil {
+		return err
+	}
+
+	return s.guidCache.Delete(ctx, ps.ServerUUID, key)
+}
+
+// ClearGUIDCache drops every cached guid, for example after fixing matches in Plex.
+func (s *service) ClearGUIDCache(ctx context.Context) error {
+	return s.guidCache.DeleteAll(ctx)
+}
+
+// showRatingKey returns the rating key of the show of p. Payloads without the grandparent rating
+// key, like Tautulli webhooks, still carry the grandparent key "/library/metadata/<ratingKey>".
+func showRatingKey(p *domain.Plex) string {
+	if key := p.ShowRatingKey(); key != "" {
+		return key
+	}
+
+	if p.Metadata.Type == domain.PlexEpisode && p.Metadata.GrandparentKey != "" {
+		return path.Base(p.Metadata.GrandparentKey)
+	}
+
+	return ""
+}
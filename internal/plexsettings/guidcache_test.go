@@ -0,0 +1,186 @@
+package plexsettings
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/varoOP/shinkro/internal/domain"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPlexGUIDCacheRepo struct {
+	entries map[string]*domain.PlexGUIDCache
+}
+
+func newMockPlexGUIDCacheRepo() *mockPlexGUIDCacheRepo {
+	return &mockPlexGUIDCacheRepo{entries: make(map[string]*domain.PlexGUIDCache)}
+}
+
+func (m *mockPlexGUIDCacheRepo) Get(ctx context.Context, serverUUID, ratingKey string) (*domain.PlexGUIDCache, error) {
+	c, ok := m.entries[serverUUID+"/"+ratingKey]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (m *mockPlexGUIDCacheRepo) Store(ctx context.Context, c *domain.PlexGUIDCache) error {
+	m.entries[c.ServerUUID+"/"+c.RatingKey] = c
+	return nil
+}
+
+func (m *mockPlexGUIDCacheRepo) Delete(ctx context.Context, serverUUID, ratingKey string) error {
+	delete(m.entries, serverUUID+"/"+ratingKey)
+	return nil
+}
+
+func (m *mockPlexGUIDCacheRepo) DeleteAll(ctx context.Context) error {
+	m.entries = make(map[string]*domain.PlexGUIDCache)
+	return nil
+}
+
+func newGUIDCacheTestService(t *testing.T, host string) (*service, *mockPlexGUIDCacheRepo) {
+	u, err := url.Parse(host)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	cache := newMockPlexGUIDCacheRepo()
+	repo := &mockPlexSettingsRepo{}
+	config := &domain.Config{EncryptionKey: generateTestKey(), PlexGUIDCacheTTL: 1}
+	svc := NewService(config, zerolog.Nop(), repo, &mockPlexServerRepo{}, cache, EventBus.New()).(*service)
+
+	iv := generateTestIV()
+	token, err := svc.encrypt([]byte("test-plex-token"), iv)
+	require.NoError(t, err)
+
+	repo.settings = &domain.PlexSettings{
+		Host:       u.Hostname(),
+		Port:       port,
+		Token:      token,
+		TokenIV:    iv,
+		ServerUUID: "server-uuid",
+	}
+
+	return svc, cache
+}
+
+func newGUIDCacheTestEpisode() *domain.Plex {
+	p := &domain.Plex{}
+	p.Server.UUID = "server-uuid"
+	p.Metadata.Type = domain.PlexEpisode
+	p.Metadata.GrandparentKey = "/library/metadata/100"
+	return p
+}
+
+func TestService_HandlePlexAgent_GUIDCache(t *testing.T) {
+	guidRetryDelays = nil
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"MediaContainer":{"metadata":[{"guid":"plex://show/abc","Guid":[{"id":"tvdb://12345"}]}]}}`))
+	}))
+
+	svc, cache := newGUIDCacheTestService(t, ts.URL)
+	p := newGUIDCacheTestEpisode()
+
+	db, id, err := svc.HandlePlexAgent(context.Background(), p, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.TVDB, db)
+	assert.Equal(t, 12345, id)
+	assert.Equal(t, 1, requests)
+
+	cached, err := cache.Get(context.Background(), "server-uuid", "100")
+	require.NoError(t, err)
+	assert.Equal(t, "plex://show/abc", cached.GUID.GUID)
+
+	// Fresh entries are used without asking Plex
+	_, id, err = svc.HandlePlexAgent(context.Background(), p, "")
+	require.NoError(t, err)
+	assert.Equal(t, 12345, id)
+	assert.Equal(t, 1, requests)
+
+	// Stale entries are used while Plex is unreachable
+	ts.Close()
+	cached.UpdatedAt = time.Now().Add(-2 * time.Hour)
+	_, id, err = svc.HandlePlexAgent(context.Background(), p, "")
+	require.NoError(t, err)
+	assert.Equal(t, 12345, id)
+
+	// Without a cached entry the lookup error is returned
+	require.NoError(t, svc.InvalidateGUIDCache(context.Background(), p))
+	_, _, err = svc.HandlePlexAgent(context.Background(), p, "")
+	assert.Error(t, err)
+}
+
+func TestService_HandlePlexAgent_ReplaysAfterRetry(t *testing.T) {
+	guidRetryDelays = []time.Duration{50 * time.Millisecond}
+	t.Cleanup(func() { guidRetryDelays = nil })
+
+	var up atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"MediaContainer":{"metadata":[{"guid":"plex://show/abc","Guid":[{"id":"tvdb://12345"}]}]}}`))
+	}))
+	defer ts.Close()
+
+	svc, cache := newGUIDCacheTestService(t, ts.URL)
+
+	resolved := make(chan *domain.PlexGUIDResolvedEvent, 1)
+	require.NoError(t, svc.bus.Subscribe(domain.EventPlexGUIDResolved, func(event *domain.PlexGUIDResolvedEvent) {
+		resolved <- event
+	}))
+
+	// both episodes fail while Plex is down, one background lookup is shared
+	first := newGUIDCacheTestEpisode()
+	first.ID = 1
+	second := newGUIDCacheTestEpisode()
+	second.ID = 2
+	for _, p := range []*domain.Plex{first, second} {
+		_, _, err := svc.HandlePlexAgent(context.Background(), p, "")
+		require.Error(t, err)
+	}
+
+	up.Store(true)
+
+	select {
+	case event := <-resolved:
+		assert.Equal(t, "100", event.RatingKey)
+		assert.Equal(t, []*domain.Plex{first, second}, event.Plex)
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed payloads were not published")
+	}
+
+	_, err := cache.Get(context.Background(), "server-uuid", "100")
+	assert.NoError(t, err)
+}
+
+func TestShowRatingKey(t *testing.T) {
+	p := newGUIDCacheTestEpisode()
+	assert.Equal(t, "100", showRatingKey(p))
+
+	p.Metadata.GrandparentRatingKey = "200"
+	assert.Equal(t, "200", showRatingKey(p))
+
+	show := &domain.Plex{}
+	show.Metadata.Type = domain.PlexShow
+	show.Metadata.RatingKey = "300"
+	assert.Equal(t, "300", showRatingKey(show))
+}
@@ -4,10 +4,16 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/asaskevich/EventBus"
 	"github.com/dcarbone/zadapters/zstdlog"
 	"github.com/rs/zerolog"
 	"github.com/varoOP/shinkro/internal/domain"
@@ -20,24 +26,58 @@ type Service interface {
 	Update(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error)
 	Delete(ctx context.Context) error
 	GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error)
-	HandlePlexAgent(ctx context.Context, p *domain.Plex) (domain.PlexSupportedDBs, int, error)
+	HandlePlexAgent(ctx context.Context, p *domain.Plex, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error)
+	MigrateLibraries(ctx context.Context) error
+	GetForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error)
+	ListServers(ctx context.Context) ([]*domain.PlexServer, error)
+	StoreServer(ctx context.Context, server *domain.PlexServer) error
+	UpdateServer(ctx context.Context, server *domain.PlexServer) error
+	DeleteServer(ctx context.Context, id int) error
+	InvalidateGUIDCache(ctx context.Context, p *domain.Plex) error
+	ClearGUIDCache(ctx context.Context) error
+	ResolveLibrarySection(ctx context.Context, ps *domain.PlexSettings, p *domain.Plex) error
+}
+
+// librarySectionsTTL is how long the libraries of a server are cached before an unknown library
+// title loads them again.
+const librarySectionsTTL = 5 * time.Minute
+
+type librarySections struct {
+	ids    map[string]string
+	loaded time.Time
 }
 
 type service struct {
-	config *domain.Config
-	log    zerolog.Logger
-	repo   domain.PlexSettingsRepo
+	config     *domain.Config
+	log        zerolog.Logger
+	repo       domain.PlexSettingsRepo
+	serverRepo domain.PlexServerRepo
+	guidCache  domain.PlexGUIDCacheRepo
+	bus        EventBus.Bus
+
+	mu       sync.Mutex
+	retrying map[string]*guidRetry
+	sections map[string]*librarySections
 }
 
-func NewService(config *domain.Config, log zerolog.Logger, repo domain.PlexSettingsRepo) Service {
+func NewService(config *domain.Config, log zerolog.Logger, repo domain.PlexSettingsRepo, serverRepo domain.PlexServerRepo, guidCache domain.PlexGUIDCacheRepo, bus EventBus.Bus) Service {
 	return &service{
-		config: config,
-		log:    log.With().Str("module", "plexsettings").Logger(),
-		repo:   repo,
+		config:     config,
+		log:        log.With().Str("module", "plexsettings").Logger(),
+		repo:       repo,
+		serverRepo: serverRepo,
+		guidCache:  guidCache,
+		bus:        bus,
+		retrying:   make(map[string]*guidRetry),
+		sections:   make(map[string]*librarySections),
 	}
 }
 
 func (s *service) Store(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	if err := validateCompletionThreshold(ps.CompletionThreshold); err != nil {
+		return nil, err
+	}
+
 	eToken, err := s.encrypt(ps.Token, ps.TokenIV)
 	if err != nil {
 		s.log.Error().Err(err).Msg("error encrypting token")
@@ -45,13 +85,49 @@ func (s *service) Store(ctx context.Context, ps domain.PlexSettings) (*domain.Pl
 	}
 
 	ps.Token = eToken
+	s.resolveLibraries(ctx, &ps)
 	return s.repo.Store(ctx, ps)
 }
 
 func (s *service) Update(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	if err := validateCompletionThreshold(ps.CompletionThreshold); err != nil {
+		return nil, err
+	}
+
+	s.resolveLibraries(ctx, &ps)
 	return s.repo.Update(ctx, ps)
 }
 
+// resolveLibraries replaces library titles in ps with section ids. When Plex can't be reached the
+// titles are stored as sent, they keep matching until MigrateLibraries replaces them.
+func (s *service) resolveLibraries(ctx context.Context, ps *domain.PlexSettings) {
+	if !ps.HasLibraryTitles() {
+		return
+	}
+
+	pc, err := s.GetClient(ctx, ps)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("could not resolve plex libraries to section ids")
+		return
+	}
+
+	sectionIDs, err := librarySectionIDs(ctx, pc)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("could not resolve plex libraries to section ids")
+		return
+	}
+
+	ps.MigrateLibraries(sectionIDs)
+}
+
+func validateCompletionThreshold(threshold int) error {
+	if threshold < 0 || threshold > 100 {
+		return fmt.Errorf("completion threshold must be between 0 and 100, got %d", threshold)
+	}
+
+	return nil
+}
+
 func (s *service) Get(ctx context.Context) (*domain.PlexSettings, error) {
 	return s.repo.Get(ctx)
 }
@@ -60,6 +136,134 @@ func (s *service) Delete(ctx context.Context) error {
 	return s.repo.Delete(ctx)
 }
 
+// GetForServer returns the settings for payloads from the server with uuid. Additional servers use
+// their own connection and libraries, every other server uses the main settings.
+func (s *service) GetForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	ps, err := s.repo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if uuid == "" || uuid == ps.ServerUUID {
+		return ps, nil
+	}
+
+	server, err := s.serverRepo.GetByUUID(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ps, nil
+		}
+		return nil, err
+	}
+
+	return server.Settings(ps), nil
+}
+
+func (s *service) ListServers(ctx context.Context) ([]*domain.PlexServer, error) {
+	return s.serverRepo.FindAll(ctx)
+}
+
+func (s *service) StoreServer(ctx context.Context, server *domain.PlexServer) error {
+	if err := server.Validate(); err != nil {
+		return err
+	}
+
+	if server.PlainToken == "" {
+		return errors.New("plex server token is required")
+	}
+
+	if err := s.encryptServerToken(server); err != nil {
+		return err
+	}
+
+	s.resolveServerLibraries(ctx, server)
+	return s.serverRepo.Store(ctx, server)
+}
+
+// UpdateServer updates an additional server, the stored token is kept when no new token is sent.
+func (s *service) UpdateServer(ctx context.Context, server *domain.PlexServer) error {
+	if err := server.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.encryptServerToken(server); err != nil {
+		return err
+	}
+
+	s.resolveServerLibraries(ctx, server)
+	return s.serverRepo.Update(ctx, server)
+}
+
+func (s *service) DeleteServer(ctx context.Context, id int) error {
+	return s.serverRepo.Delete(ctx, id)
+}
+
+// resolveServerLibraries replaces library titles of an additional server with section ids, like
+// resolveLibraries.
+func (s *service) resolveServerLibraries(ctx context.Context, server *domain.PlexServer) {
+	if !server.HasLibraryTitles() {
+		return
+	}
+
+	if _, err := s.migrateServerLibraries(ctx, server); err != nil {
+		s.log.Warn().Err(err).Str("server", server.Name).Msg("could not resolve plex libraries to section ids")
+	}
+}
+
+// migrateServerLibraries replaces the library titles of server with section ids and reports whether
+// anything changed. Servers updated without a new token use the stored one.
+func (s *service) migrateServerLibraries(ctx context.Context, server *domain.PlexServer) (bool, error) {
+	token, tokenIV := server.Token, server.TokenIV
+	if len(tokenIV) == 0 {
+		stored, err := s.serverRepo.GetByUUID(ctx, server.ServerUUID)
+		if err != nil {
+			return false, err
+		}
+		token, tokenIV = stored.Token, stored.TokenIV
+	}
+
+	if len(token) == 0 || len(tokenIV) == 0 {
+		return false, errors.New("token or tokenIV is empty")
+	}
+
+	ps := server.Settings(&domain.PlexSettings{})
+	ps.Token, ps.TokenIV = token, tokenIV
+
+	pc, err := s.GetClient(ctx, ps)
+	if err != nil {
+		return false, err
+	}
+
+	sectionIDs, err := librarySectionIDs(ctx, pc)
+	if err != nil {
+		return false, err
+	}
+
+	return server.MigrateLibraries(sectionIDs), nil
+}
+
+// encryptServerToken encrypts the plain token of server with a new IV and clears it.
+func (s *service) encryptServerToken(server *domain.PlexServer) error {
+	if server.PlainToken == "" {
+		server.Token, server.TokenIV = nil, nil
+		return nil
+	}
+
+	iv := make([]byte, 12)
+	if _, err := rand.Read(iv); err != nil {
+		return err
+	}
+
+	token, err := s.encrypt([]byte(server.PlainToken), iv)
+	if err != nil {
+		s.log.Error().Err(err).Msg("error encrypting plex server token")
+		return err
+	}
+
+	server.Token, server.TokenIV, server.PlainToken = token, iv, ""
+	return nil
+}
+
 func (s *service) GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error) {
 	if len(ps.TokenIV) == 0 {
 		tempPs, err := s.repo.Get(ctx)
@@ -97,31 +301,165 @@ func (s *service) GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex
 	return c, nil
 }
 
-func (s *service) HandlePlexAgent(ctx context.Context, p *domain.Plex) (domain.PlexSupportedDBs, int, error) {
+// HandlePlexAgent looks up the show of an episode on the Plex server and returns its id from provider,
+// or from the default database when provider is empty.
+func (s *service) HandlePlexAgent(ctx context.Context, p *domain.Plex, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error) {
 	if p.Metadata.Type == domain.PlexEpisode {
-		ps, err := s.repo.Get(ctx)
+		ps, err := s.GetForServer(ctx, p.Server.UUID)
 		if err != nil {
 			return "", 0, err
 		}
 
-		pc, err := s.GetClient(ctx, ps)
+		id, err := s.showGUID(ctx, ps, p)
 		if err != nil {
 			return "", 0, err
 		}
 
-		guid, err := pc.GetShowID(ctx, p.Metadata.GrandparentKey)
+		if provider != "" {
+			return id.PlexAgentProvider(provider)
+		}
+
+		return id.PlexAgent(p.Metadata.Type)
+	}
+	return "", 0, nil
+}
+
+// MigrateLibraries replaces library titles in the settings and the additional servers with library
+// section ids and records the server uuid, so renamed libraries keep syncing and webhooks from other
+// servers are rejected.
+func (s *service) MigrateLibraries(ctx context.Context) error {
+	servers, err := s.serverRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	// an unreachable server is retried on the next start, it does not hold back the others
+	for _, server := range servers {
+		if !server.HasLibraryTitles() {
+			continue
+		}
+
+		changed, err := s.migrateServerLibraries(ctx, server)
 		if err != nil {
-			return "", 0, err
+			s.log.Warn().Err(err).Str("server", server.Name).Msg("could not migrate plex libraries to section ids")
+			continue
 		}
 
-		id := domain.GUID{
-			GUIDS: guid.GUIDS,
-			GUID:  guid.GUID,
+		if !changed {
+			continue
 		}
 
-		return id.PlexAgent(p.Metadata.Type)
+		if err := s.serverRepo.Update(ctx, server); err != nil {
+			return err
+		}
+
+		s.log.Info().Strs("animeLibraries", server.AnimeLibraries).Str("server", server.ServerUUID).Msg("migrated plex libraries to section ids")
 	}
-	return "", 0, nil
+
+	ps, err := s.repo.Get(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if len(ps.Token) == 0 {
+		return nil
+	}
+
+	pc, err := s.GetClient(ctx, ps)
+	if err != nil {
+		return err
+	}
+
+	sectionIDs, err := librarySectionIDs(ctx, pc)
+	if err != nil {
+		return err
+	}
+
+	changed := ps.MigrateLibraries(sectionIDs)
+	if ps.ServerUUID == "" {
+		identity, err := pc.GetIdentity(ctx)
+		if err != nil {
+			return err
+		}
+
+		if identity.MediaContainer.MachineIdentifier != "" {
+			ps.ServerUUID = identity.MediaContainer.MachineIdentifier
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if _, err := s.repo.Update(ctx, *ps); err != nil {
+		return err
+	}
+
+	s.log.Info().Strs("animeLibraries", ps.AnimeLibraries).Str("server", ps.ServerUUID).Msg("migrated plex libraries to section ids")
+	return nil
+}
+
+// librarySectionIDs returns the section id of every library by its title and uuid.
+func librarySectionIDs(ctx context.Context, pc *plex.Client) (map[string]string, error) {
+	libraries, err := pc.GetLibraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sectionIDs := make(map[string]string)
+	for _, section := range libraries.MediaContainer.Directory {
+		sectionIDs[section.Title] = section.Key
+		if section.UUID != "" {
+			sectionIDs[section.UUID] = section.Key
+		}
+	}
+
+	return sectionIDs, nil
+}
+
+// ResolveLibrarySection sets the section id of payloads that only carry the library title, like
+// Tautulli webhooks without librarySectionID, so they match libraries stored by section id.
+func (s *service) ResolveLibrarySection(ctx context.Context, ps *domain.PlexSettings, p *domain.Plex) error {
+	title := p.Metadata.LibrarySectionTitle
+	if p.Metadata.LibrarySectionID > 0 || title == "" {
+		return nil
+	}
+
+	key := fmt.Sprintf("%s:%d", ps.Host, ps.Port)
+
+	s.mu.Lock()
+	cached, ok := s.sections[key]
+	s.mu.Unlock()
+
+	if !ok || (cached.ids[title] == "" && time.Since(cached.loaded) > librarySectionsTTL) {
+		pc, err := s.GetClient(ctx, ps)
+		if err != nil {
+			return err
+		}
+
+		ids, err := librarySectionIDs(ctx, pc)
+		if err != nil {
+			return err
+		}
+
+		cached = &librarySections{ids: ids, loaded: time.Now()}
+
+		s.mu.Lock()
+		s.sections[key] = cached
+		s.mu.Unlock()
+	}
+
+	id, err := strconv.Atoi(cached.ids[title])
+	if err != nil {
+		return fmt.Errorf("plex library %q not found", title)
+	}
+
+	p.Metadata.LibrarySectionID = id
+	return nil
 }
 
 // encrypt encrypts plaintext using AES-GCM with the encryption key from config
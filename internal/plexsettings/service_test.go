@@ -3,13 +3,19 @@ package plexsettings
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
 
 	"github.com/varoOP/shinkro/internal/domain"
 	"github.com/varoOP/shinkro/internal/testdata"
 
+	"github.com/asaskevich/EventBus"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -54,6 +60,43 @@ func (m *mockPlexSettingsRepo) Delete(ctx context.Context) error {
 	return nil
 }
 
+type mockPlexServerRepo struct {
+	servers []*domain.PlexServer
+}
+
+func (m *mockPlexServerRepo) Store(ctx context.Context, server *domain.PlexServer) error {
+	server.ID = len(m.servers) + 1
+	m.servers = append(m.servers, server)
+	return nil
+}
+
+func (m *mockPlexServerRepo) Update(ctx context.Context, server *domain.PlexServer) error {
+	for i, s := range m.servers {
+		if s.ID == server.ID {
+			m.servers[i] = server
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockPlexServerRepo) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockPlexServerRepo) FindAll(ctx context.Context) ([]*domain.PlexServer, error) {
+	return m.servers, nil
+}
+
+func (m *mockPlexServerRepo) GetByUUID(ctx context.Context, uuid string) (*domain.PlexServer, error) {
+	for _, s := range m.servers {
+		if s.ServerUUID == uuid {
+			return s, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
 // Helper to generate a valid 32-byte hex key
 func generateTestKey() string {
 	key := make([]byte, 32)
@@ -73,7 +116,7 @@ func TestService_EncryptDecrypt(t *testing.T) {
 		EncryptionKey: generateTestKey(),
 	}
 	repo := &mockPlexSettingsRepo{}
-	service := NewService(config, zerolog.Nop(), repo).(*service)
+	service := NewService(config, zerolog.Nop(), repo, &mockPlexServerRepo{}, newMockPlexGUIDCacheRepo(), EventBus.New()).(*service)
 
 	tests := []struct {
 		name          string
@@ -175,7 +218,7 @@ func TestService_GetEncryptionKey(t *testing.T) {
 			config := &domain.Config{
 				EncryptionKey: tt.encryptionKey,
 			}
-			service := NewService(config, zerolog.Nop(), &mockPlexSettingsRepo{}).(*service)
+			service := NewService(config, zerolog.Nop(), &mockPlexSettingsRepo{}, &mockPlexServerRepo{}, newMockPlexGUIDCacheRepo(), EventBus.New()).(*service)
 
 			key, err := service.getEncryptionKey()
 			if tt.expectedError {
@@ -198,7 +241,7 @@ func TestService_Store(t *testing.T) {
 		EncryptionKey: generateTestKey(),
 	}
 	repo := &mockPlexSettingsRepo{}
-	service := NewService(config, zerolog.Nop(), repo)
+	service := NewService(config, zerolog.Nop(), repo, &mockPlexServerRepo{}, newMockPlexGUIDCacheRepo(), EventBus.New())
 
 	tests := []struct {
 		name          string
@@ -233,6 +276,17 @@ func TestService_Store(t *testing.T) {
 			repoError:     errors.New("database error"),
 			expectedError: true,
 		},
+		{
+			name: "completion threshold out of range",
+			settings: func() domain.PlexSettings {
+				ps := *testdata.NewMockPlexSettings()
+				ps.Token = []byte("test-token")
+				ps.TokenIV = generateTestIV()
+				ps.CompletionThreshold = 120
+				return ps
+			}(),
+			expectedError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -253,12 +307,27 @@ func TestService_Store(t *testing.T) {
 	}
 }
 
+func TestService_Update_CompletionThreshold(t *testing.T) {
+	repo := &mockPlexSettingsRepo{}
+	service := NewService(&domain.Config{}, zerolog.Nop(), repo, &mockPlexServerRepo{}, newMockPlexGUIDCacheRepo(), EventBus.New())
+
+	ps := *testdata.NewMockPlexSettings()
+	ps.CompletionThreshold = 80
+	updated, err := service.Update(context.Background(), ps)
+	require.NoError(t, err)
+	assert.Equal(t, 80, updated.CompletionThreshold)
+
+	ps.CompletionThreshold = -1
+	_, err = service.Update(context.Background(), ps)
+	assert.Error(t, err)
+}
+
 func TestService_GetClient(t *testing.T) {
 	config := &domain.Config{
 		EncryptionKey: generateTestKey(),
 	}
 	repo := &mockPlexSettingsRepo{}
-	service := NewService(config, zerolog.Nop(), repo).(*service)
+	service := NewService(config, zerolog.Nop(), repo, &mockPlexServerRepo{}, newMockPlexGUIDCacheRepo(), EventBus.New()).(*service)
 
 	// Create encrypted token for test
 	testToken := []byte("test-plex-token")
@@ -362,3 +431,142 @@ func TestService_GetClient(t *testing.T) {
 		})
 	}
 }
+
+func TestService_MigrateLibraries(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/library/sections":
+			_, _ = w.Write([]byte(`{"MediaContainer":{"Directory":[{"key":"1","title":"Anime","type":"show","uuid":"uuid-1"},{"key":"2","title":"Anime Movies","type":"movie","uuid":"uuid-2"}]}}`))
+		case "/identity":
+			_, _ = w.Write([]byte(`{"MediaContainer":{"machineIdentifier":"server-uuid"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	config := &domain.Config{EncryptionKey: generateTestKey()}
+	repo := &mockPlexSettingsRepo{}
+	servers := &mockPlexServerRepo{}
+	svc := NewService(config, zerolog.Nop(), repo, servers, newMockPlexGUIDCacheRepo(), EventBus.New()).(*service)
+
+	iv := generateTestIV()
+	token, err := svc.encrypt([]byte("test-plex-token"), iv)
+	require.NoError(t, err)
+
+	servers.servers = []*domain.PlexServer{
+		{ID: 1, ServerUUID: "friend-uuid", Host: u.Hostname(), Port: port, Token: token, TokenIV: iv, AnimeLibraries: []string{"Anime Movies"}},
+		// unreachable servers are skipped
+		{ID: 2, ServerUUID: "offline-uuid", Host: "127.0.0.1", Port: 1, Token: token, TokenIV: iv, AnimeLibraries: []string{"Anime"}},
+	}
+
+	repo.settings = &domain.PlexSettings{
+		Host:            u.Hostname(),
+		Port:            port,
+		Token:           token,
+		TokenIV:         iv,
+		PlexUser:        "TestUser",
+		AnimeLibraries:  []string{"Anime", "uuid-2"},
+		PlanToWatchLibs: []string{"Anime"},
+	}
+
+	require.NoError(t, svc.MigrateLibraries(context.Background()))
+	assert.Equal(t, []string{"1", "2"}, repo.settings.AnimeLibraries)
+	assert.Equal(t, []string{"1"}, repo.settings.PlanToWatchLibs)
+	assert.Equal(t, "server-uuid", repo.settings.ServerUUID)
+	assert.Equal(t, []string{"2"}, servers.servers[0].AnimeLibraries)
+	assert.Equal(t, []string{"Anime"}, servers.servers[1].AnimeLibraries)
+
+	// Tautulli payloads without a section id still match the migrated libraries by title
+	p, err := domain.ToPlex([]byte(`{"event":"media.scrobble","Account":{"title":"TestUser"},"Metadata":{"librarySectionTitle":"Anime","parentIndex":"1","index":"2","type":"episode"}}`))
+	require.NoError(t, err)
+	assert.False(t, p.IsAnimeLibrary(repo.settings))
+	require.NoError(t, svc.ResolveLibrarySection(context.Background(), repo.settings, p))
+	assert.Equal(t, 1, p.Metadata.LibrarySectionID)
+	assert.True(t, p.IsAnimeLibrary(repo.settings))
+
+	p.Metadata.LibrarySectionID = 0
+	p.Metadata.LibrarySectionTitle = "Unknown"
+	assert.Error(t, svc.ResolveLibrarySection(context.Background(), repo.settings, p))
+	assert.False(t, p.IsAnimeLibrary(repo.settings))
+
+	// titles sent when saving are stored as section ids
+	ps := *repo.settings
+	ps.AnimeLibraries = []string{"Anime Movies"}
+	ps.DryRunLibs = []string{"1"}
+	updated, err := svc.Update(context.Background(), ps)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, updated.AnimeLibraries)
+	assert.Equal(t, []string{"1"}, updated.DryRunLibs)
+
+	// servers updated without a new token use the stored one
+	server := &domain.PlexServer{ID: 1, ServerUUID: "friend-uuid", Host: u.Hostname(), Port: port, AnimeLibraries: []string{"Anime"}}
+	require.NoError(t, svc.UpdateServer(context.Background(), server))
+	assert.Equal(t, []string{"1"}, server.AnimeLibraries)
+}
+
+func TestService_GetForServer(t *testing.T) {
+	primary := testdata.NewMockPlexSettings()
+	primary.ServerUUID = "main-uuid"
+	servers := &mockPlexServerRepo{servers: []*domain.PlexServer{{
+		ID:             1,
+		ServerUUID:     "friend-uuid",
+		Host:           "friend.example.com",
+		Port:           32400,
+		AnimeLibraries: []string{"5"},
+	}}}
+	service := NewService(&domain.Config{}, zerolog.Nop(), &mockPlexSettingsRepo{settings: primary}, servers, newMockPlexGUIDCacheRepo(), EventBus.New())
+
+	ps, err := service.GetForServer(context.Background(), "main-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, primary.Host, ps.Host)
+
+	ps, err = service.GetForServer(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "main-uuid", ps.ServerUUID)
+
+	ps, err = service.GetForServer(context.Background(), "friend-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "friend.example.com", ps.Host)
+	assert.Equal(t, []string{"5"}, ps.AnimeLibraries)
+	assert.Equal(t, "friend-uuid", ps.ServerUUID)
+	assert.Equal(t, primary.PlexUser, ps.PlexUser)
+
+	// Unknown servers get the primary settings and are rejected by their server uuid
+	ps, err = service.GetForServer(context.Background(), "unknown-uuid")
+	require.NoError(t, err)
+	assert.False(t, ps.IsServerAllowed("unknown-uuid"))
+}
+
+func TestService_StoreServer(t *testing.T) {
+	servers := &mockPlexServerRepo{}
+	svc := NewService(&domain.Config{EncryptionKey: generateTestKey()}, zerolog.Nop(), &mockPlexSettingsRepo{}, servers, newMockPlexGUIDCacheRepo(), EventBus.New()).(*service)
+
+	err := svc.StoreServer(context.Background(), &domain.PlexServer{ServerUUID: "friend-uuid", Host: "friend.example.com", Port: 32400})
+	assert.Error(t, err)
+
+	err = svc.StoreServer(context.Background(), &domain.PlexServer{Host: "friend.example.com", Port: 32400, PlainToken: "friend-token"})
+	assert.Error(t, err)
+
+	server := &domain.PlexServer{ServerUUID: "friend-uuid", Host: "friend.example.com", Port: 32400, PlainToken: "friend-token"}
+	require.NoError(t, svc.StoreServer(context.Background(), server))
+	assert.Empty(t, server.PlainToken)
+	require.Len(t, servers.servers, 1)
+
+	token, err := svc.decrypt(server.Token, server.TokenIV)
+	require.NoError(t, err)
+	assert.Equal(t, "friend-token", string(token))
+
+	// Updating without a token keeps the stored one
+	update := &domain.PlexServer{ID: server.ID, ServerUUID: "friend-uuid", Host: "friend.example.com", Port: 32401}
+	require.NoError(t, svc.UpdateServer(context.Background(), update))
+	assert.Empty(t, update.Token)
+
+	assert.ErrorIs(t, svc.UpdateServer(context.Background(), &domain.PlexServer{ID: 9, ServerUUID: "x", Host: "x", Port: 1}), sql.ErrNoRows)
+}
@@ -0,0 +1,367 @@
+package reconcile
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/animeupdate"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/malauth"
+	"github.com/varoOP/shinkro/internal/plex"
+	"github.com/varoOP/shinkro/internal/plexsettings"
+	"github.com/varoOP/shinkro/internal/syncrule"
+	plexclient "github.com/varoOP/shinkro/pkg/plex"
+)
+
+const malListPageSize = 1000
+
+type Service interface {
+	Run(ctx context.Context) error
+	FindAll(ctx context.Context) ([]*domain.ReconcileItem, error)
+	SetIgnored(ctx context.Context, malID int, ignored bool) error
+}
+
+type service struct {
+	log                 zerolog.Logger
+	config              *domain.Config
+	repo                domain.ReconcileRepo
+	plexsettingsService plexsettings.Service
+	plexService         plex.Service
+	malauthService      malauth.Service
+	animeUpdateService  animeupdate.Service
+	syncRuleService     syncrule.Service
+	bus                 EventBus.Bus
+
+	running sync.Mutex
+}
+
+func NewService(log zerolog.Logger, config *domain.Config, repo domain.ReconcileRepo, plexsettingsSvc plexsettings.Service, plexSvc plex.Service, malauthSvc malauth.Service, animeUpdateSvc animeupdate.Service, syncRuleSvc syncrule.Service, bus EventBus.Bus) Service {
+	return &service{
+		log:                 log.With().Str("module", "reconcile").Logger(),
+		config:              config,
+		repo:                repo,
+		plexsettingsService: plexsettingsSvc,
+		plexService:         plexSvc,
+		malauthService:      malauthSvc,
+		animeUpdateService:  animeUpdateSvc,
+		syncRuleService:     syncRuleSvc,
+		bus:                 bus,
+	}
+}
+
+func (s *service) FindAll(ctx context.Context) ([]*domain.ReconcileItem, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *service) SetIgnored(ctx context.Context, malID int, ignored bool) error {
+	return s.repo.SetIgnored(ctx, malID, ignored)
+}
+
+// candidate is a Plex show season resolved to its MAL entry.
+type candidate struct {
+	plex      *domain.Plex
+	anime     domain.AnimeUpdate
+	ratingKey string
+}
+
+// Run compares the watched episodes of every show in the anime libraries with the MAL list and
+// records each MAL entry that is behind. With ReconcileAutoCorrect enabled a MAL update moving it
+// forward is queued.
+func (s *service) Run(ctx context.Context) error {
+	if !s.running.TryLock() {
+		return errors.New("reconciliation already running")
+	}
+	defer s.running.Unlock()
+
+	start := time.Now()
+
+	ps, err := s.plexsettingsService.Get(ctx)
+	if err != nil {
+		return errors.Wrap(err, "plex settings not found")
+	}
+
+	if !ps.PlexClientEnabled {
+		return errors.New("plex client is not enabled")
+	}
+
+	pc, err := s.plexsettingsService.GetClient(ctx, ps)
+	if err != nil {
+		return err
+	}
+
+	rules, err := s.syncRuleService.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not get sync rules")
+	}
+
+	candidates, err := s.getCandidates(ctx, pc, ps, rules)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.malauthService.GetMalClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	list, err := s.getMALList(ctx, client)
+	if err != nil {
+		return errors.Wrap(err, "could not fetch MAL anime list")
+	}
+
+	var behind int
+	for _, c := range candidates {
+		var status *mal.AnimeListStatus
+		if ls, ok := list[c.anime.MALId]; ok {
+			status = &ls
+		}
+
+		if !domain.IsMALBehind(c.anime.EpisodeNum, status) {
+			continue
+		}
+
+		item := &domain.ReconcileItem{
+			MALId:         c.anime.MALId,
+			Title:         c.anime.ListDetails.Title,
+			PlexRatingKey: c.ratingKey,
+			SourceDB:      c.anime.SourceDB,
+			SourceId:      c.anime.SourceId,
+			SeasonNum:     c.anime.SeasonNum,
+			PlexWatched:   c.anime.EpisodeNum,
+			Status:        domain.ReconcileStatusDiscrepancy,
+		}
+
+		if status != nil {
+			item.MALWatched = status.NumEpisodesWatched
+			item.MALStatus = status.Status
+		}
+
+		if err := s.repo.Store(ctx, item); err != nil {
+			return err
+		}
+
+		behind++
+		if !s.config.ReconcileAutoCorrect || item.Ignored {
+			continue
+		}
+
+		if err := s.correct(ctx, ps, c); err != nil {
+			item.Status = domain.ReconcileStatusFailed
+			item.Message = err.Error()
+		} else {
+			item.Status = domain.ReconcileStatusQueued
+		}
+
+		if err := s.repo.Store(ctx, item); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.DeleteStale(ctx, start); err != nil {
+		return err
+	}
+
+	s.log.Info().Int("shows", len(candidates)).Int("behind", behind).Msg("reconciliation finished")
+	return nil
+}
+
+// correct stores a synthetic scrobble for the season and hands it on like a processed Plex payload,
+// so the MAL update is queued behind other updates of the same anime. Dry runs and the sync rule
+// of the candidate are respected like for webhooks.
+func (s *service) correct(ctx context.Context, ps *domain.PlexSettings, c candidate) error {
+	if err := s.plexService.Store(ctx, c.plex); err != nil {
+		return err
+	}
+
+	a := c.plex.SetAnimeFields(c.anime.SourceDB, c.anime.SourceId)
+	a.EpisodeOffset = c.anime.EpisodeOffset
+	a.DryRun = s.config.DryRun || c.plex.IsDryRunLibrary(ps)
+	s.bus.Publish(domain.EventPlexProcessedSuccess, &domain.PlexProcessedSuccessEvent{
+		PlexID:      c.plex.ID,
+		Plex:        c.plex,
+		AnimeUpdate: &a,
+		Timestamp:   time.Now(),
+	})
+
+	return nil
+}
+
+// getCandidates walks every show season of the anime libraries that has watched episodes. Seasons
+// skipped by a sync rule are left out.
+func (s *service) getCandidates(ctx context.Context, pc *plexclient.Client, ps *domain.PlexSettings, rules []*domain.SyncRule) ([]candidate, error) {
+	libraries, err := pc.GetLibraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byMALId := make(map[int]candidate)
+	for _, library := range libraries.MediaContainer.Directory {
+		if library.Type != "show" || !ps.IsAnimeLibrary(library.SectionID(), library.UUID, library.Title) {
+			continue
+		}
+
+		shows, err := pc.GetLibraryItems(ctx, library.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, show := range shows.MediaContainer.Metadata {
+			if show.ViewedLeafCount == 0 {
+				continue
+			}
+
+			seasons, err := pc.GetChildren(ctx, show.RatingKey)
+			if err != nil {
+				s.log.Debug().Err(err).Str("show", show.Title).Msg("could not get seasons")
+				continue
+			}
+
+			for _, season := range seasons.MediaContainer.Metadata {
+				if season.Index < 1 || season.ViewedLeafCount == 0 {
+					continue
+				}
+
+				c, err := s.newCandidate(ctx, ps, rules, library, show, season)
+				if err != nil {
+					s.log.Debug().Err(err).Str("show", show.Title).Int("season", season.Index).Msg("skipping season")
+					continue
+				}
+
+				if prev, ok := byMALId[c.anime.MALId]; ok && prev.anime.EpisodeNum >= c.anime.EpisodeNum {
+					continue
+				}
+
+				byMALId[c.anime.MALId] = c
+			}
+		}
+	}
+
+	candidates := make([]candidate, 0, len(byMALId))
+	for _, c := range byMALId {
+		candidates = append(candidates, c)
+	}
+
+	return candidates, nil
+}
+
+// newCandidate resolves the season to its MAL entry. Sync rules apply as for webhooks, a rule can
+// skip the season, force the MAL id, pick the provider or set the episode offset.
+func (s *service) newCandidate(ctx context.Context, ps *domain.PlexSettings, rules []*domain.SyncRule, library plexclient.Directory, show, season plexclient.Metadata) (candidate, error) {
+	p := &domain.Plex{
+		Event:     domain.PlexScrobbleEvent,
+		Source:    domain.Reconciliation,
+		TimeStamp: time.Now(),
+		Metadata: domain.Metadata{
+			GUID: domain.GUID{
+				GUID:  show.GUID.GUID,
+				GUIDS: show.GUID.GUIDS,
+			},
+			GrandparentKey:       "/library/metadata/" + show.RatingKey,
+			GrandparentRatingKey: show.RatingKey,
+			GrandparentTitle:     show.Title,
+			Title:                season.Title,
+			Index:                season.ViewedLeafCount,
+			ParentIndex:          season.Index,
+			LibrarySectionTitle:  library.Title,
+			LibrarySectionID:     library.SectionID(),
+			LibrarySectionUUID:   library.UUID,
+			Type:                 domain.PlexEpisode,
+		},
+	}
+	p.Account.Title = ps.PlexUser
+
+	rule := domain.MatchSyncRule(rules, p, 0)
+
+	var a domain.AnimeUpdate
+	if rule != nil && rule.ForceMALID > 0 {
+		a = p.SetAnimeFields(domain.MAL, rule.ForceMALID)
+	} else {
+		var provider domain.PlexSupportedDBs
+		if rule != nil {
+			provider = rule.Provider
+		}
+
+		source, id, err := sourceFromGUID(p, provider)
+		if err != nil {
+			return candidate{}, err
+		}
+
+		a = p.SetAnimeFields(source, id)
+	}
+
+	if domain.HasMALIDRules(rules) {
+		resolved := a
+		if err := s.animeUpdateService.ResolveMALID(ctx, &resolved); err == nil {
+			if r := domain.MatchSyncRule(rules, p, resolved.MALId); r != nil && r.MALID == resolved.MALId {
+				rule = r
+			}
+		}
+	}
+
+	if rule != nil {
+		if !rule.Allows(p) {
+			return candidate{}, errors.Errorf("skipped by sync rule %d", rule.ID)
+		}
+
+		a.EpisodeOffset = rule.EpisodeOffset
+	}
+
+	if err := s.animeUpdateService.ResolveMALID(ctx, &a); err != nil {
+		return candidate{}, err
+	}
+
+	return candidate{
+		plex:      p,
+		anime:     a,
+		ratingKey: show.RatingKey,
+	}, nil
+}
+
+func (s *service) getMALList(ctx context.Context, client *mal.Client) (map[int]mal.AnimeListStatus, error) {
+	list := make(map[int]mal.AnimeListStatus)
+	offset := 0
+	for {
+		items, resp, err := client.User.AnimeList(ctx, "@me", mal.Fields{"list_status"}, mal.NSFW(true), mal.Limit(malListPageSize), mal.Offset(offset))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			list[item.Anime.ID] = item.Status
+		}
+
+		if resp.NextOffset == 0 {
+			break
+		}
+
+		offset = resp.NextOffset
+	}
+
+	return list, nil
+}
+
+// sourceFromGUID extracts the source database and id from a show level guid, provider picks the
+// database of the Plex agent when set.
+func sourceFromGUID(p *domain.Plex, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error) {
+	allowed, agent := p.IsMetadataAgentAllowed()
+	if !allowed {
+		return "", 0, errors.New("metadata agent not supported")
+	}
+
+	switch agent {
+	case domain.HAMA, domain.MALAgent:
+		return p.Metadata.GUID.HamaMALAgent(agent)
+	case domain.PlexAgent:
+		if provider != "" {
+			return p.Metadata.GUID.PlexAgentProvider(provider)
+		}
+		return p.Metadata.GUID.PlexAgent(domain.PlexEpisode)
+	}
+
+	return "", 0, errors.New("unknown agent")
+}
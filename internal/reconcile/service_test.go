@@ -0,0 +1,489 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/plex"
+)
+
+type mockReconcileRepo struct {
+	items map[int]*domain.ReconcileItem
+}
+
+func (m *mockReconcileRepo) Store(ctx context.Context, item *domain.ReconcileItem) error {
+	if prev, ok := m.items[item.MALId]; ok {
+		item.Ignored = prev.Ignored
+	}
+	stored := *item
+	m.items[item.MALId] = &stored
+	return nil
+}
+
+func (m *mockReconcileRepo) FindAll(ctx context.Context) ([]*domain.ReconcileItem, error) {
+	items := make([]*domain.ReconcileItem, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (m *mockReconcileRepo) DeleteStale(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func (m *mockReconcileRepo) SetIgnored(ctx context.Context, malID int, ignored bool) error {
+	if item, ok := m.items[malID]; ok {
+		item.Ignored = ignored
+		return nil
+	}
+	m.items[malID] = &domain.ReconcileItem{MALId: malID, Ignored: ignored}
+	return nil
+}
+
+type mockPlexSettingsService struct {
+	settings *domain.PlexSettings
+	url      string
+}
+
+func (m *mockPlexSettingsService) Store(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) Get(ctx context.Context) (*domain.PlexSettings, error) {
+	return m.settings, nil
+}
+
+func (m *mockPlexSettingsService) Update(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) Delete(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error) {
+	return plex.NewClient(plex.Config{Url: m.url, Token: "token"}), nil
+}
+
+func (m *mockPlexSettingsService) HandlePlexAgent(ctx context.Context, p *domain.Plex, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error) {
+	return "", 0, nil
+}
+
+func (m *mockPlexSettingsService) MigrateLibraries(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) GetForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.Get(ctx)
+}
+
+func (m *mockPlexSettingsService) ListServers(ctx context.Context) ([]*domain.PlexServer, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) StoreServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) UpdateServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) DeleteServer(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) InvalidateGUIDCache(ctx context.Context, p *domain.Plex) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ClearGUIDCache(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ResolveLibrarySection(ctx context.Context, ps *domain.PlexSettings, p *domain.Plex) error {
+	return nil
+}
+
+type mockMALAuthService struct {
+	url string
+}
+
+func (m *mockMALAuthService) Store(ctx context.Context, ma *domain.MalAuth) error {
+	return nil
+}
+
+func (m *mockMALAuthService) Get(ctx context.Context) (*domain.MalAuth, error) {
+	return nil, nil
+}
+
+func (m *mockMALAuthService) Delete(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockMALAuthService) GetMalClient(ctx context.Context) (*mal.Client, error) {
+	c := mal.NewClient(http.DefaultClient)
+	c.BaseURL, _ = url.Parse(m.url + "/")
+	return c, nil
+}
+
+func (m *mockMALAuthService) GetDecrypted(ctx context.Context) (*domain.MalAuth, error) {
+	return nil, nil
+}
+
+func (m *mockMALAuthService) CheckToken(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+}
+
+func (m *mockMALAuthService) TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+}
+
+func (m *mockMALAuthService) RateLimitStatus() domain.MALRateLimitStatus {
+	return domain.MALRateLimitStatus{}
+}
+
+type mockAnimeUpdateService struct {
+	tvdbToMAL map[int]int
+	updated   []*domain.AnimeUpdate
+	queued    []*domain.AnimeUpdate
+}
+
+func (m *mockAnimeUpdateService) Store(ctx context.Context, animeupdate *domain.AnimeUpdate) error {
+	return nil
+}
+
+func (m *mockAnimeUpdateService) GetByID(ctx context.Context, req *domain.GetAnimeUpdateRequest) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateService) UpdateAnimeList(ctx context.Context, anime *domain.AnimeUpdate, event domain.PlexEvent) error {
+	m.updated = append(m.updated, anime)
+	return nil
+}
+
+func (m *mockAnimeUpdateService) PreviewAnimeList(ctx context.Context, anime *domain.AnimeUpdate, event domain.PlexEvent) error {
+	return nil
+}
+
+func (m *mockAnimeUpdateService) ResolveMALID(ctx context.Context, anime *domain.AnimeUpdate) error {
+	malID, ok := m.tvdbToMAL[anime.SourceId]
+	if !ok {
+		return fmt.Errorf("anime not found in map")
+	}
+	anime.MALId = malID
+	if anime.EpisodeOffset != nil {
+		anime.EpisodeNum += *anime.EpisodeOffset
+	}
+	return nil
+}
+
+func (m *mockAnimeUpdateService) Count(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockAnimeUpdateService) GetRecentUnique(ctx context.Context, limit int) ([]*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateService) GetByPlexID(ctx context.Context, plexID int64) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateService) GetByPlexIDs(ctx context.Context, plexIDs []int64) ([]*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateService) FindAllWithFilters(ctx context.Context, params domain.AnimeUpdateQueryParams) (*domain.FindAnimeUpdatesResponse, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeUpdateService) Revert(ctx context.Context, id int) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+type mockSyncRuleService struct {
+	rules []*domain.SyncRule
+}
+
+func (m *mockSyncRuleService) List(ctx context.Context) ([]*domain.SyncRule, error) {
+	return m.rules, nil
+}
+
+func (m *mockSyncRuleService) Store(ctx context.Context, rule *domain.SyncRule) error {
+	return nil
+}
+
+func (m *mockSyncRuleService) Update(ctx context.Context, rule *domain.SyncRule) error {
+	return nil
+}
+
+func (m *mockSyncRuleService) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+type mockPlexService struct {
+	stored []*domain.Plex
+}
+
+func (m *mockPlexService) Store(ctx context.Context, plex *domain.Plex) error {
+	plex.ID = int64(len(m.stored) + 1)
+	m.stored = append(m.stored, plex)
+	return nil
+}
+
+func (m *mockPlexService) Get(ctx context.Context, req *domain.GetPlexRequest) (*domain.Plex, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
+	return nil
+}
+
+func (m *mockPlexService) PreviewPlex(ctx context.Context, plex *domain.Plex) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) SuppressScrobble(ratingKey string) {}
+
+func (m *mockPlexService) ClearCompleted(ratingKey string) {}
+
+func (m *mockPlexService) GetPlexSettings(ctx context.Context) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.GetPlexSettings(ctx)
+}
+
+func (m *mockPlexService) CheckPlexPreview(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	return nil
+}
+
+func (m *mockPlexService) CheckPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	return nil
+}
+
+func (m *mockPlexService) CountScrobbleEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) CountRateEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) GetPlexHistory(ctx context.Context, limit int) ([]domain.PlexHistoryItem, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) FindAllWithFilters(ctx context.Context, params domain.PlexPayloadQueryParams) (*domain.FindPlexPayloadsResponse, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) Delete(ctx context.Context, req *domain.DeletePlexRequest) error {
+	return nil
+}
+
+func (m *mockPlexService) UpdateStatus(ctx context.Context, plexID int64, success *bool, errorType domain.PlexErrorType, errorMsg string) error {
+	return nil
+}
+
+func newPlexServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/library/sections":
+			fmt.Fprint(w, `{"MediaContainer":{"Directory":[{"key":"1","type":"show","title":"Anime"},{"key":"2","type":"show","title":"TV Shows"}]}}`)
+		case "/library/sections/1/all":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[
+				{"ratingKey":"100","title":"Frieren","guid":"com.plexapp.agents.hama://tvdb-424536?lang=en","viewedLeafCount":5},
+				{"ratingKey":"200","title":"Dandadan","guid":"com.plexapp.agents.hama://tvdb-432832?lang=en","viewedLeafCount":3},
+				{"ratingKey":"300","title":"Unwatched","guid":"com.plexapp.agents.hama://tvdb-1?lang=en","viewedLeafCount":0}
+			]}}`)
+		case "/library/metadata/100/children":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"101","title":"Season 1","index":1,"viewedLeafCount":5}]}}`)
+		case "/library/metadata/200/children":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"201","title":"Specials","index":0,"viewedLeafCount":1},{"ratingKey":"202","title":"Season 1","index":1,"viewedLeafCount":3}]}}`)
+		default:
+			t.Errorf("unexpected plex request %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newMALServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/users/@me/animelist", r.URL.Path)
+		fmt.Fprint(w, `{"data":[
+			{"node":{"id":52991},"list_status":{"status":"watching","num_episodes_watched":2}},
+			{"node":{"id":57334},"list_status":{"status":"watching","num_episodes_watched":3}}
+		],"paging":{}}`)
+	}))
+}
+
+func newTestService(t *testing.T, autoCorrect bool) (*service, *mockReconcileRepo, *mockAnimeUpdateService, *mockPlexService) {
+	t.Helper()
+	plexServer := newPlexServer(t)
+	t.Cleanup(plexServer.Close)
+	malServer := newMALServer(t)
+	t.Cleanup(malServer.Close)
+
+	repo := &mockReconcileRepo{items: make(map[int]*domain.ReconcileItem)}
+	animeUpdateSvc := &mockAnimeUpdateService{tvdbToMAL: map[int]int{424536: 52991, 432832: 57334}}
+	plexSvc := &mockPlexService{}
+	plexsettingsSvc := &mockPlexSettingsService{
+		settings: &domain.PlexSettings{PlexUser: "TestUser", AnimeLibraries: []string{"Anime"}, PlexClientEnabled: true},
+		url:      plexServer.URL,
+	}
+
+	bus := EventBus.New()
+	require.NoError(t, bus.Subscribe(domain.EventPlexProcessedSuccess, func(event *domain.PlexProcessedSuccessEvent) {
+		animeUpdateSvc.queued = append(animeUpdateSvc.queued, event.AnimeUpdate)
+	}))
+
+	svc := NewService(zerolog.Nop(), &domain.Config{ReconcileAutoCorrect: autoCorrect}, repo, plexsettingsSvc, plexSvc, &mockMALAuthService{url: malServer.URL}, animeUpdateSvc, &mockSyncRuleService{}, bus).(*service)
+	return svc, repo, animeUpdateSvc, plexSvc
+}
+
+func TestService_Run_ReportsDiscrepancies(t *testing.T) {
+	svc, repo, animeUpdateSvc, plexSvc := newTestService(t, false)
+
+	require.NoError(t, svc.Run(context.Background()))
+
+	require.Len(t, repo.items, 1)
+	item := repo.items[52991]
+	require.NotNil(t, item)
+	assert.Equal(t, "Frieren", item.Title)
+	assert.Equal(t, "100", item.PlexRatingKey)
+	assert.Equal(t, domain.TVDB, item.SourceDB)
+	assert.Equal(t, 424536, item.SourceId)
+	assert.Equal(t, 5, item.PlexWatched)
+	assert.Equal(t, 2, item.MALWatched)
+	assert.Equal(t, mal.AnimeStatusWatching, item.MALStatus)
+	assert.Equal(t, domain.ReconcileStatusDiscrepancy, item.Status)
+
+	assert.Empty(t, animeUpdateSvc.queued)
+	assert.Empty(t, plexSvc.stored)
+}
+
+func TestService_Run_AutoCorrect(t *testing.T) {
+	svc, repo, animeUpdateSvc, plexSvc := newTestService(t, true)
+
+	require.NoError(t, svc.Run(context.Background()))
+
+	assert.Equal(t, domain.ReconcileStatusQueued, repo.items[52991].Status)
+
+	require.Len(t, plexSvc.stored, 1)
+	assert.Equal(t, domain.Reconciliation, plexSvc.stored[0].Source)
+	assert.Equal(t, "TestUser", plexSvc.stored[0].Account.Title)
+
+	// the update goes through the job queue, never straight to MAL
+	assert.Empty(t, animeUpdateSvc.updated)
+	require.Len(t, animeUpdateSvc.queued, 1)
+	assert.Equal(t, int64(1), animeUpdateSvc.queued[0].PlexId)
+	assert.Equal(t, 5, animeUpdateSvc.queued[0].EpisodeNum)
+	assert.Equal(t, 1, animeUpdateSvc.queued[0].SeasonNum)
+	assert.False(t, animeUpdateSvc.queued[0].DryRun)
+}
+
+func TestService_Run_AutoCorrectDryRun(t *testing.T) {
+	t.Run("global", func(t *testing.T) {
+		svc, _, animeUpdateSvc, _ := newTestService(t, true)
+		svc.config.DryRun = true
+
+		require.NoError(t, svc.Run(context.Background()))
+
+		require.Len(t, animeUpdateSvc.queued, 1)
+		assert.True(t, animeUpdateSvc.queued[0].DryRun)
+	})
+
+	t.Run("library", func(t *testing.T) {
+		svc, _, animeUpdateSvc, _ := newTestService(t, true)
+		svc.plexsettingsService.(*mockPlexSettingsService).settings.DryRunLibs = []string{"Anime"}
+
+		require.NoError(t, svc.Run(context.Background()))
+
+		require.Len(t, animeUpdateSvc.queued, 1)
+		assert.True(t, animeUpdateSvc.queued[0].DryRun)
+	})
+}
+
+func TestService_Run_SkipsIgnored(t *testing.T) {
+	svc, repo, animeUpdateSvc, _ := newTestService(t, true)
+	require.NoError(t, svc.SetIgnored(context.Background(), 52991, true))
+
+	require.NoError(t, svc.Run(context.Background()))
+
+	assert.True(t, repo.items[52991].Ignored)
+	assert.Equal(t, domain.ReconcileStatusDiscrepancy, repo.items[52991].Status)
+	assert.Empty(t, animeUpdateSvc.queued)
+}
+
+func TestService_Run_SyncRules(t *testing.T) {
+	svc, repo, animeUpdateSvc, _ := newTestService(t, true)
+	offset := 2
+	svc.syncRuleService.(*mockSyncRuleService).rules = []*domain.SyncRule{
+		{ID: 1, RatingKey: "100", Ignore: true},
+		{ID: 2, MALID: 57334, EpisodeOffset: &offset},
+	}
+
+	require.NoError(t, svc.Run(context.Background()))
+
+	// Frieren is ignored, Dandadan is behind on MAL once the offset is added
+	require.Len(t, repo.items, 1)
+	item := repo.items[57334]
+	require.NotNil(t, item)
+	assert.Equal(t, 5, item.PlexWatched)
+	assert.Equal(t, domain.ReconcileStatusQueued, item.Status)
+
+	require.Len(t, animeUpdateSvc.queued, 1)
+	assert.Equal(t, 432832, animeUpdateSvc.queued[0].SourceId)
+	assert.Equal(t, &offset, animeUpdateSvc.queued[0].EpisodeOffset)
+}
+
+func TestService_Run_SyncRuleForcesMALID(t *testing.T) {
+	svc, repo, animeUpdateSvc, _ := newTestService(t, true)
+	svc.syncRuleService.(*mockSyncRuleService).rules = []*domain.SyncRule{
+		{ID: 1, RatingKey: "200", ForceMALID: 40748},
+	}
+	animeUpdateSvc.tvdbToMAL[40748] = 40748
+
+	require.NoError(t, svc.Run(context.Background()))
+
+	item := repo.items[40748]
+	require.NotNil(t, item)
+	assert.Equal(t, domain.MAL, item.SourceDB)
+	assert.Equal(t, 3, item.PlexWatched)
+	assert.Equal(t, 0, item.MALWatched)
+
+	require.Len(t, animeUpdateSvc.queued, 2)
+	forced := 0
+	for _, a := range animeUpdateSvc.queued {
+		if a.SourceDB == domain.MAL && a.SourceId == 40748 {
+			forced++
+		}
+	}
+	assert.Equal(t, 1, forced)
+}
+
+func TestService_Run_PlexClientDisabled(t *testing.T) {
+	svc, _, _, _ := newTestService(t, false)
+	svc.plexsettingsService.(*mockPlexSettingsService).settings.PlexClientEnabled = false
+
+	err := svc.Run(context.Background())
+	assert.Error(t, err)
+}
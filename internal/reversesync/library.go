@@ -0,0 +1,153 @@
+package reversesync
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/varoOP/shinkro/internal/domain"
+	plexclient "github.com/varoOP/shinkro/pkg/plex"
+)
+
+type sourceKey struct {
+	db domain.PlexSupportedDBs
+	id int
+}
+
+// target is a MAL episode located in Plex, season and episode are unset for movies.
+type target struct {
+	show    *plexclient.Metadata
+	season  int
+	episode int
+	movie   bool
+}
+
+func newTarget(item *plexclient.Metadata, season, episode int) target {
+	if item.Type == string(domain.PlexMovie) {
+		return target{show: item, movie: true}
+	}
+
+	return target{show: item, season: season, episode: episode}
+}
+
+// library indexes the shows and movies of the anime libraries by their source id. Seasons and
+// episodes are only fetched from Plex when a MAL episode maps to them.
+type library struct {
+	items    map[sourceKey]*plexclient.Metadata
+	seasons  map[string]map[int]string
+	episodes map[string]map[int]*plexclient.Metadata
+}
+
+func newLibrary(ctx context.Context, pc *plexclient.Client, ps *domain.PlexSettings) (*library, error) {
+	libraries, err := pc.GetLibraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lib := &library{
+		items:    make(map[sourceKey]*plexclient.Metadata),
+		seasons:  make(map[string]map[int]string),
+		episodes: make(map[string]map[int]*plexclient.Metadata),
+	}
+
+	for _, section := range libraries.MediaContainer.Directory {
+		if (section.Type != "show" && section.Type != "movie") || !ps.IsAnimeLibrary(section.SectionID(), section.UUID, section.Title) {
+			continue
+		}
+
+		resp, err := pc.GetLibraryItems(ctx, section.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		mediaType := domain.PlexEpisode
+		if section.Type == "movie" {
+			mediaType = domain.PlexMovie
+		}
+
+		for i := range resp.MediaContainer.Metadata {
+			item := &resp.MediaContainer.Metadata[i]
+			if item.Type == "" {
+				item.Type = section.Type
+			}
+
+			db, id, err := sourceFromGUID(item.GUID, mediaType)
+			if err != nil {
+				continue
+			}
+
+			lib.items[sourceKey{db: db, id: id}] = item
+		}
+	}
+
+	return lib, nil
+}
+
+// item returns the Plex episode or movie of t, or nil when the episode is not in Plex.
+func (l *library) item(ctx context.Context, pc *plexclient.Client, t target) (*plexclient.Metadata, error) {
+	if t.movie {
+		return t.show, nil
+	}
+
+	seasons, ok := l.seasons[t.show.RatingKey]
+	if !ok {
+		resp, err := pc.GetChildren(ctx, t.show.RatingKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get seasons of %v", t.show.Title)
+		}
+
+		seasons = make(map[int]string)
+		for _, season := range resp.MediaContainer.Metadata {
+			seasons[season.Index] = season.RatingKey
+		}
+		l.seasons[t.show.RatingKey] = seasons
+	}
+
+	seasonKey, ok := seasons[t.season]
+	if !ok {
+		return nil, nil
+	}
+
+	episodes, ok := l.episodes[seasonKey]
+	if !ok {
+		resp, err := pc.GetChildren(ctx, seasonKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get episodes of %v season %v", t.show.Title, t.season)
+		}
+
+		episodes = make(map[int]*plexclient.Metadata)
+		for i := range resp.MediaContainer.Metadata {
+			episode := &resp.MediaContainer.Metadata[i]
+			episodes[episode.Index] = episode
+		}
+		l.episodes[seasonKey] = episodes
+	}
+
+	return episodes[t.episode], nil
+}
+
+// sourceFromGUID extracts the source database and id from a show or movie guid.
+func sourceFromGUID(guid plexclient.GUID, mediaType domain.PlexMediaType) (domain.PlexSupportedDBs, int, error) {
+	p := &domain.Plex{
+		Metadata: domain.Metadata{
+			GUID: domain.GUID{
+				GUID:  guid.GUID,
+				GUIDS: guid.GUIDS,
+			},
+			Type: mediaType,
+		},
+	}
+
+	allowed, agent := p.IsMetadataAgentAllowed()
+	if !allowed {
+		return "", 0, errors.New("metadata agent not supported")
+	}
+
+	switch agent {
+	case domain.HAMA, domain.MALAgent:
+		return p.Metadata.GUID.HamaMALAgent(agent)
+	case domain.PlexAgent:
+		return p.Metadata.GUID.PlexAgent(mediaType)
+	}
+
+	return "", 0, errors.New("unknown agent")
+}
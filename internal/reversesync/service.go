@@ -0,0 +1,249 @@
+package reversesync
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/anime"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/malauth"
+	"github.com/varoOP/shinkro/internal/mapping"
+	"github.com/varoOP/shinkro/internal/plex"
+	"github.com/varoOP/shinkro/internal/plexsettings"
+	plexclient "github.com/varoOP/shinkro/pkg/plex"
+)
+
+const malListPageSize = 1000
+
+type Service interface {
+	Run(ctx context.Context, dryRun bool) ([]*domain.ReverseSyncAction, error)
+}
+
+type service struct {
+	log                 zerolog.Logger
+	repo                domain.ReverseSyncRepo
+	plexsettingsService plexsettings.Service
+	plexService         plex.Service
+	malauthService      malauth.Service
+	animeService        anime.Service
+	mapService          mapping.Service
+
+	running sync.Mutex
+}
+
+func NewService(log zerolog.Logger, repo domain.ReverseSyncRepo, plexsettingsSvc plexsettings.Service, plexSvc plex.Service, malauthSvc malauth.Service, animeSvc anime.Service, mapSvc mapping.Service) Service {
+	return &service{
+		log:                 log.With().Str("module", "reversesync").Logger(),
+		repo:                repo,
+		plexsettingsService: plexsettingsSvc,
+		plexService:         plexSvc,
+		malauthService:      malauthSvc,
+		animeService:        animeSvc,
+		mapService:          mapSvc,
+	}
+}
+
+// Run marks the Plex episodes of every MAL episode watched since the last run as watched. In a dry
+// run nothing is changed in Plex and the sync progress is not stored.
+func (s *service) Run(ctx context.Context, dryRun bool) ([]*domain.ReverseSyncAction, error) {
+	if !s.running.TryLock() {
+		return nil, errors.New("reverse sync already running")
+	}
+	defer s.running.Unlock()
+
+	ps, err := s.plexsettingsService.Get(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "plex settings not found")
+	}
+
+	if !ps.PlexClientEnabled {
+		return nil, errors.New("plex client is not enabled")
+	}
+
+	pc, err := s.plexsettingsService.GetClient(ctx, ps)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.malauthService.GetMalClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.getMALList(ctx, client)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch MAL anime list")
+	}
+
+	stored, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make(map[int]int, len(stored))
+	for _, p := range stored {
+		progress[p.MALId] = p.EpisodesWatched
+	}
+
+	lib, err := newLibrary(ctx, pc, ps)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]*domain.ReverseSyncAction, 0)
+	for _, entry := range list {
+		watched := watchedEpisodes(entry)
+		if watched <= progress[entry.Anime.ID] {
+			continue
+		}
+
+		entryActions, found, err := s.syncEntry(ctx, pc, lib, entry, progress[entry.Anime.ID], watched, dryRun)
+		actions = append(actions, entryActions...)
+		if err != nil {
+			s.log.Error().Err(err).Int("malid", entry.Anime.ID).Msg("reverse sync failed")
+			continue
+		}
+
+		// Entries that are not in Plex yet are picked up again once they are added.
+		if !found || dryRun {
+			continue
+		}
+
+		if err := s.repo.Store(ctx, &domain.ReverseSyncProgress{MALId: entry.Anime.ID, EpisodesWatched: watched}); err != nil {
+			return actions, err
+		}
+	}
+
+	s.log.Info().Int("scrobbled", len(actions)).Bool("dryRun", dryRun).Msg("reverse sync finished")
+	return actions, nil
+}
+
+// syncEntry scrobbles the Plex items of the MAL episodes after synced up to watched. found is false
+// when the entry is not in any anime library.
+func (s *service) syncEntry(ctx context.Context, pc *plexclient.Client, lib *library, entry mal.UserAnime, synced, watched int, dryRun bool) ([]*domain.ReverseSyncAction, bool, error) {
+	var dbAnime *domain.Anime
+	if a, err := s.animeService.GetByID(ctx, &domain.GetAnimeRequest{IDtype: domain.MAL, Id: entry.Anime.ID}); err == nil {
+		dbAnime = a
+	}
+
+	actions := make([]*domain.ReverseSyncAction, 0)
+	found := false
+	var failed error
+	for malEp := synced + 1; malEp <= watched; malEp++ {
+		t, ok := s.locate(ctx, lib, entry.Anime.ID, malEp, dbAnime)
+		if !ok {
+			continue
+		}
+		found = true
+
+		item, err := lib.item(ctx, pc, t)
+		if err != nil {
+			failed = err
+			continue
+		}
+
+		if item == nil || item.ViewCount > 0 {
+			continue
+		}
+
+		action := &domain.ReverseSyncAction{
+			MALId:         entry.Anime.ID,
+			Title:         entry.Anime.Title,
+			MALEpisode:    malEp,
+			PlexRatingKey: item.RatingKey,
+			PlexTitle:     t.show.Title,
+			SeasonNum:     t.season,
+			EpisodeNum:    t.episode,
+			DryRun:        dryRun,
+		}
+		actions = append(actions, action)
+
+		if t.movie {
+			// A movie has a single Plex item, only the first MAL episode needs to mark it.
+			item.ViewCount = 1
+		}
+
+		if dryRun {
+			s.log.Info().Str("title", entry.Anime.Title).Int("malEpisode", malEp).Str("ratingKey", item.RatingKey).Msg("dry run: would mark as watched in plex")
+			continue
+		}
+
+		s.plexService.SuppressScrobble(item.RatingKey)
+		if err := pc.Scrobble(ctx, item.RatingKey); err != nil {
+			action.Error = err.Error()
+			failed = err
+			continue
+		}
+
+		item.ViewCount = 1
+		s.log.Info().Str("title", entry.Anime.Title).Int("malEpisode", malEp).Str("ratingKey", item.RatingKey).Msg("marked as watched in plex")
+	}
+
+	return actions, found, failed
+}
+
+// locate finds the Plex show or movie of a MAL episode through the MAL id itself, the anime map
+// or the internal database, the reverse of how MAL ids are resolved for scrobbles.
+func (s *service) locate(ctx context.Context, lib *library, malID, malEp int, dbAnime *domain.Anime) (target, bool) {
+	if show, ok := lib.items[sourceKey{db: domain.MAL, id: malID}]; ok {
+		return newTarget(show, 1, malEp), true
+	}
+
+	if m, err := s.mapService.CheckForMALinMap(ctx, malID, malEp); err == nil {
+		if show, ok := lib.items[sourceKey{db: m.SourceDB, id: m.SourceId}]; ok {
+			return newTarget(show, m.Season, m.Episode), true
+		}
+	}
+
+	if dbAnime == nil {
+		return target{}, false
+	}
+
+	for _, key := range []sourceKey{
+		{db: domain.AniDB, id: dbAnime.AniDBId},
+		{db: domain.TVDB, id: dbAnime.TVDBId},
+		{db: domain.TMDB, id: dbAnime.TMDBId},
+	} {
+		if key.id <= 0 {
+			continue
+		}
+
+		if show, ok := lib.items[key]; ok {
+			return newTarget(show, 1, malEp), true
+		}
+	}
+
+	return target{}, false
+}
+
+func (s *service) getMALList(ctx context.Context, client *mal.Client) ([]mal.UserAnime, error) {
+	list := make([]mal.UserAnime, 0)
+	offset := 0
+	for {
+		items, resp, err := client.User.AnimeList(ctx, "@me", mal.Fields{"num_episodes", "list_status"}, mal.NSFW(true), mal.Limit(malListPageSize), mal.Offset(offset))
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, items...)
+		if resp.NextOffset == 0 {
+			break
+		}
+
+		offset = resp.NextOffset
+	}
+
+	return list, nil
+}
+
+// watchedEpisodes treats completed entries as fully watched, MAL keeps the count at 0 for some of them.
+func watchedEpisodes(entry mal.UserAnime) int {
+	if entry.Status.Status == mal.AnimeStatusCompleted && entry.Anime.NumEpisodes > entry.Status.NumEpisodesWatched {
+		return entry.Anime.NumEpisodes
+	}
+
+	return entry.Status.NumEpisodesWatched
+}
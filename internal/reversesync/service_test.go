@@ -0,0 +1,412 @@
+package reversesync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nstratos/go-myanimelist/mal"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/pkg/plex"
+)
+
+type mockReverseSyncRepo struct {
+	progress map[int]int
+}
+
+func (m *mockReverseSyncRepo) Store(ctx context.Context, progress *domain.ReverseSyncProgress) error {
+	m.progress[progress.MALId] = progress.EpisodesWatched
+	return nil
+}
+
+func (m *mockReverseSyncRepo) FindAll(ctx context.Context) ([]*domain.ReverseSyncProgress, error) {
+	items := make([]*domain.ReverseSyncProgress, 0, len(m.progress))
+	for malID, watched := range m.progress {
+		items = append(items, &domain.ReverseSyncProgress{MALId: malID, EpisodesWatched: watched})
+	}
+	return items, nil
+}
+
+type mockPlexSettingsService struct {
+	settings *domain.PlexSettings
+	url      string
+}
+
+func (m *mockPlexSettingsService) Store(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) Get(ctx context.Context) (*domain.PlexSettings, error) {
+	return m.settings, nil
+}
+
+func (m *mockPlexSettingsService) Update(ctx context.Context, ps domain.PlexSettings) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) Delete(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) GetClient(ctx context.Context, ps *domain.PlexSettings) (*plex.Client, error) {
+	return plex.NewClient(plex.Config{Url: m.url, Token: "token"}), nil
+}
+
+func (m *mockPlexSettingsService) HandlePlexAgent(ctx context.Context, p *domain.Plex, provider domain.PlexSupportedDBs) (domain.PlexSupportedDBs, int, error) {
+	return "", 0, nil
+}
+
+func (m *mockPlexSettingsService) MigrateLibraries(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) GetForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.Get(ctx)
+}
+
+func (m *mockPlexSettingsService) ListServers(ctx context.Context) ([]*domain.PlexServer, error) {
+	return nil, nil
+}
+
+func (m *mockPlexSettingsService) StoreServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) UpdateServer(ctx context.Context, server *domain.PlexServer) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) DeleteServer(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) InvalidateGUIDCache(ctx context.Context, p *domain.Plex) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ClearGUIDCache(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockPlexSettingsService) ResolveLibrarySection(ctx context.Context, ps *domain.PlexSettings, p *domain.Plex) error {
+	return nil
+}
+
+type mockMALAuthService struct {
+	url string
+}
+
+func (m *mockMALAuthService) Store(ctx context.Context, ma *domain.MalAuth) error {
+	return nil
+}
+
+func (m *mockMALAuthService) Get(ctx context.Context) (*domain.MalAuth, error) {
+	return nil, nil
+}
+
+func (m *mockMALAuthService) Delete(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockMALAuthService) GetMalClient(ctx context.Context) (*mal.Client, error) {
+	c := mal.NewClient(http.DefaultClient)
+	c.BaseURL, _ = url.Parse(m.url + "/")
+	return c, nil
+}
+
+func (m *mockMALAuthService) GetDecrypted(ctx context.Context) (*domain.MalAuth, error) {
+	return nil, nil
+}
+
+func (m *mockMALAuthService) CheckToken(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+}
+
+func (m *mockMALAuthService) TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+}
+
+func (m *mockMALAuthService) RateLimitStatus() domain.MALRateLimitStatus {
+	return domain.MALRateLimitStatus{}
+}
+
+type mockAnimeService struct {
+	anime map[int]*domain.Anime
+}
+
+func (m *mockAnimeService) GetByID(ctx context.Context, req *domain.GetAnimeRequest) (*domain.Anime, error) {
+	if a, ok := m.anime[req.Id]; ok && req.IDtype == domain.MAL {
+		return a, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *mockAnimeService) StoreMultiple(ctx context.Context, anime []*domain.Anime) error {
+	return nil
+}
+
+func (m *mockAnimeService) GetAnime(ctx context.Context) ([]*domain.Anime, error) {
+	return nil, nil
+}
+
+func (m *mockAnimeService) UpdateAnime(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockAnimeService) LastUpdated(ctx context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+type mockMappingService struct {
+	animeMap *domain.AnimeMap
+}
+
+func (m *mockMappingService) NewMap(ctx context.Context) (*domain.AnimeMap, error) {
+	return m.animeMap, nil
+}
+
+func (m *mockMappingService) CheckForAnimeinMap(ctx context.Context, anime *domain.AnimeUpdate) (*domain.AnimeMapDetails, error) {
+	return nil, nil
+}
+
+func (m *mockMappingService) CheckForMALinMap(ctx context.Context, malID, malEp int) (*domain.MappedEpisode, error) {
+	if inMap, ep := m.animeMap.AnimeTVShows.ReverseMap(malID, malEp); inMap {
+		return ep, nil
+	}
+
+	if inMap, movie := m.animeMap.AnimeMovies.ReverseMap(malID); inMap {
+		return movie, nil
+	}
+
+	return nil, errors.New("anime not found in map")
+}
+
+func (m *mockMappingService) ValidateMap(ctx context.Context, yamlPath string, isTVDB bool) error {
+	return nil
+}
+
+func (m *mockMappingService) IsCached() bool {
+	return false
+}
+
+func (m *mockMappingService) Store(ctx context.Context, ms *domain.MapSettings) error {
+	return nil
+}
+
+func (m *mockMappingService) Get(ctx context.Context) (*domain.MapSettings, error) {
+	return nil, nil
+}
+
+type mockPlexService struct {
+	suppressed []string
+}
+
+func (m *mockPlexService) Store(ctx context.Context, plex *domain.Plex) error {
+	return nil
+}
+
+func (m *mockPlexService) Get(ctx context.Context, req *domain.GetPlexRequest) (*domain.Plex, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
+	return nil
+}
+
+func (m *mockPlexService) PreviewPlex(ctx context.Context, plex *domain.Plex) (*domain.AnimeUpdate, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) SuppressScrobble(ratingKey string) {
+	m.suppressed = append(m.suppressed, ratingKey)
+}
+
+func (m *mockPlexService) ClearCompleted(ratingKey string) {}
+
+func (m *mockPlexService) GetPlexSettings(ctx context.Context) (*domain.PlexSettings, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.GetPlexSettings(ctx)
+}
+
+func (m *mockPlexService) CheckPlexPreview(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	return nil
+}
+
+func (m *mockPlexService) CheckPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	return nil
+}
+
+func (m *mockPlexService) CountScrobbleEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) CountRateEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) GetPlexHistory(ctx context.Context, limit int) ([]domain.PlexHistoryItem, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) FindAllWithFilters(ctx context.Context, params domain.PlexPayloadQueryParams) (*domain.FindPlexPayloadsResponse, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) Delete(ctx context.Context, req *domain.DeletePlexRequest) error {
+	return nil
+}
+
+func (m *mockPlexService) UpdateStatus(ctx context.Context, plexID int64, success *bool, errorType domain.PlexErrorType, errorMsg string) error {
+	return nil
+}
+
+type plexServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	scrobbled []string
+}
+
+func newPlexServer(t *testing.T) *plexServer {
+	t.Helper()
+	ps := &plexServer{}
+	ps.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/library/sections":
+			fmt.Fprint(w, `{"MediaContainer":{"Directory":[{"key":"1","type":"show","title":"Anime"},{"key":"2","type":"movie","title":"Anime Movies"},{"key":"3","type":"show","title":"TV Shows"}]}}`)
+		case "/library/sections/1/all":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[
+				{"ratingKey":"100","title":"Frieren","type":"show","guid":"com.plexapp.agents.hama://tvdb-424536?lang=en"},
+				{"ratingKey":"200","title":"Dandadan","type":"show","guid":"com.plexapp.agents.hama://anidb-17000?lang=en"}
+			]}}`)
+		case "/library/sections/2/all":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[
+				{"ratingKey":"300","title":"Suzume","type":"movie","guid":"plex://movie/5d776","Guid":[{"id":"tmdb://916224"}],"viewCount":0}
+			]}}`)
+		case "/library/metadata/100/children":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"101","title":"Season 1","index":1}]}}`)
+		case "/library/metadata/101/children":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[
+				{"ratingKey":"1011","index":1,"viewCount":1},
+				{"ratingKey":"1012","index":2},
+				{"ratingKey":"1013","index":3,"viewCount":2}
+			]}}`)
+		case "/library/metadata/200/children":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"201","title":"Season 1","index":1}]}}`)
+		case "/library/metadata/201/children":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"2011","index":1},{"ratingKey":"2012","index":2}]}}`)
+		case "/:/scrobble":
+			assert.Equal(t, "com.plexapp.plugins.library", r.URL.Query().Get("identifier"))
+			ps.mu.Lock()
+			ps.scrobbled = append(ps.scrobbled, r.URL.Query().Get("key"))
+			ps.mu.Unlock()
+		default:
+			t.Errorf("unexpected plex request %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ps
+}
+
+func newMALServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/users/@me/animelist", r.URL.Path)
+		fmt.Fprint(w, `{"data":[
+			{"node":{"id":52991,"title":"Sousou no Frieren","num_episodes":28},"list_status":{"status":"watching","num_episodes_watched":3}},
+			{"node":{"id":57334,"title":"Dandadan","num_episodes":12},"list_status":{"status":"watching","num_episodes_watched":2}},
+			{"node":{"id":50594,"title":"Suzume no Tojimari","num_episodes":1},"list_status":{"status":"completed","num_episodes_watched":0}},
+			{"node":{"id":5,"title":"Not in Plex","num_episodes":12},"list_status":{"status":"watching","num_episodes_watched":4}}
+		],"paging":{}}`)
+	}))
+}
+
+func newTestService(t *testing.T) (*service, *plexServer, *mockReverseSyncRepo, *mockPlexService) {
+	t.Helper()
+	plexSrv := newPlexServer(t)
+	t.Cleanup(plexSrv.Close)
+	malSrv := newMALServer(t)
+	t.Cleanup(malSrv.Close)
+
+	repo := &mockReverseSyncRepo{progress: map[int]int{52991: 1}}
+	plexSvc := &mockPlexService{}
+	plexsettingsSvc := &mockPlexSettingsService{
+		settings: &domain.PlexSettings{PlexUser: "TestUser", AnimeLibraries: []string{"Anime", "Anime Movies"}, PlexClientEnabled: true},
+		url:      plexSrv.URL,
+	}
+	animeSvc := &mockAnimeService{anime: map[int]*domain.Anime{
+		57334: {MALId: 57334, AniDBId: 17000, TVDBId: 432832},
+	}}
+	mapSvc := &mockMappingService{animeMap: &domain.AnimeMap{
+		AnimeTVShows: &domain.AnimeTVShows{Anime: []domain.AnimeTV{{Malid: 52991, Tvdbid: 424536, TvdbSeason: 1}}},
+		AnimeMovies:  &domain.AnimeMovies{AnimeMovie: []domain.AnimeMovie{{MainTitle: "Suzume", TMDBID: 916224, MALID: 50594}}},
+	}}
+
+	svc := NewService(zerolog.Nop(), repo, plexsettingsSvc, plexSvc, &mockMALAuthService{url: malSrv.URL}, animeSvc, mapSvc).(*service)
+	return svc, plexSrv, repo, plexSvc
+}
+
+func TestService_Run(t *testing.T) {
+	svc, plexSrv, repo, plexSvc := newTestService(t)
+
+	actions, err := svc.Run(context.Background(), false)
+	require.NoError(t, err)
+
+	expected := []string{"1012", "2011", "2012", "300"}
+	assert.ElementsMatch(t, expected, plexSrv.scrobbled)
+	assert.ElementsMatch(t, expected, plexSvc.suppressed)
+	require.Len(t, actions, 4)
+	for _, action := range actions {
+		assert.False(t, action.DryRun)
+		assert.Empty(t, action.Error)
+	}
+
+	assert.Equal(t, map[int]int{52991: 3, 57334: 2, 50594: 1}, repo.progress)
+}
+
+func TestService_Run_DryRun(t *testing.T) {
+	svc, plexSrv, repo, plexSvc := newTestService(t)
+
+	actions, err := svc.Run(context.Background(), true)
+	require.NoError(t, err)
+
+	require.Len(t, actions, 4)
+	for _, action := range actions {
+		assert.True(t, action.DryRun)
+	}
+
+	assert.Empty(t, plexSrv.scrobbled)
+	assert.Empty(t, plexSvc.suppressed)
+	assert.Equal(t, map[int]int{52991: 1}, repo.progress)
+}
+
+func TestService_Run_AlreadySynced(t *testing.T) {
+	svc, plexSrv, repo, _ := newTestService(t)
+	repo.progress = map[int]int{52991: 3, 57334: 2, 50594: 1}
+
+	actions, err := svc.Run(context.Background(), false)
+	require.NoError(t, err)
+
+	assert.Empty(t, actions)
+	assert.Empty(t, plexSrv.scrobbled)
+}
+
+func TestService_Run_PlexClientDisabled(t *testing.T) {
+	svc, _, _, _ := newTestService(t)
+	svc.plexsettingsService.(*mockPlexSettingsService).settings.PlexClientEnabled = false
+
+	_, err := svc.Run(context.Background(), false)
+	assert.Error(t, err)
+}
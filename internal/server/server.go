@@ -3,6 +3,10 @@ package server
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,7 +16,11 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/varoOP/shinkro/internal/anime"
 	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/libraryscan"
+	"github.com/varoOP/shinkro/internal/malauth"
 	"github.com/varoOP/shinkro/internal/mapping"
+	"github.com/varoOP/shinkro/internal/reconcile"
+	"github.com/varoOP/shinkro/internal/reversesync"
 	"github.com/varoOP/shinkro/internal/update"
 )
 
@@ -21,17 +29,26 @@ type Server struct {
 	config             *domain.Config
 	animeService       anime.Service
 	mappingService     mapping.Service
+	reconcileService   reconcile.Service
+	reverseSyncService reversesync.Service
+	libraryScanService libraryscan.Service
+	malauthService     malauth.Service
 	bus                EventBus.Bus
 	lastUpdateNotified string
+	lastMalAuthState   domain.MalAuthTokenState
 }
 
-func NewServer(log zerolog.Logger, config *domain.Config, animeSvc anime.Service, mappingSvc mapping.Service, bus EventBus.Bus) *Server {
+func NewServer(log zerolog.Logger, config *domain.Config, animeSvc anime.Service, mappingSvc mapping.Service, reconcileSvc reconcile.Service, reverseSyncSvc reversesync.Service, libraryScanSvc libraryscan.Service, malauthSvc malauth.Service, bus EventBus.Bus) *Server {
 	return &Server{
-		log:            log.With().Str("module", "server").Logger(),
-		config:         config,
-		animeService:   animeSvc,
-		mappingService: mappingSvc,
-		bus:            bus,
+		log:                log.With().Str("module", "server").Logger(),
+		config:             config,
+		animeService:       animeSvc,
+		mappingService:     mappingSvc,
+		reconcileService:   reconcileSvc,
+		reverseSyncService: reverseSyncSvc,
+		libraryScanService: libraryScanSvc,
+		malauthService:     malauthSvc,
+		bus:                bus,
 	}
 }
 
@@ -60,17 +77,60 @@ func (s *Server) Start() error {
 	}
 
 	c.Start()
-	// Trigger update check after start and schedule a daily check
+	// Trigger update and MAL token checks after start and schedule them
 	go func() {
 		time.Sleep(5 * time.Second)
 		s.checkAndNotifyUpdate()
+		s.checkMalAuth()
 	}()
 	_, _ = c.AddFunc("0 9 * * *", func() {
 		s.checkAndNotifyUpdate()
 	})
+	_, _ = c.AddFunc("0 */6 * * *", func() {
+		s.checkMalAuth()
+	})
+
+	if s.config.ReconcileSchedule != "" {
+		if _, err := c.AddFunc(s.config.ReconcileSchedule, s.reconcile); err != nil {
+			return errors.Wrap(err, "invalid ReconcileSchedule")
+		}
+	}
+
+	if s.config.ReverseSyncSchedule != "" {
+		if _, err := c.AddFunc(s.config.ReverseSyncSchedule, s.reverseSync); err != nil {
+			return errors.Wrap(err, "invalid ReverseSyncSchedule")
+		}
+	}
+
+	if s.config.LibraryScanSchedule != "" {
+		if _, err := c.AddFunc(s.config.LibraryScanSchedule, s.libraryScan); err != nil {
+			return errors.Wrap(err, "invalid LibraryScanSchedule")
+		}
+	}
 	return nil
 }
 
+// reconcile compares Plex watch progress with MAL, see reconcile.Service
+func (s *Server) reconcile() {
+	if err := s.reconcileService.Run(context.Background()); err != nil {
+		s.log.Error().Err(err).Msg("reconciliation failed")
+	}
+}
+
+// reverseSync marks episodes watched on MAL as watched in Plex, see reversesync.Service
+func (s *Server) reverseSync() {
+	if _, err := s.reverseSyncService.Run(context.Background(), s.config.ReverseSyncDryRun); err != nil {
+		s.log.Error().Err(err).Msg("reverse sync failed")
+	}
+}
+
+// libraryScan adds anime new to the plan to watch libraries to MAL, see libraryscan.Service
+func (s *Server) libraryScan() {
+	if err := s.libraryScanService.Scan(context.Background()); err != nil {
+		s.log.Error().Err(err).Msg("library scan failed")
+	}
+}
+
 // checkAndNotifyUpdate sends APP_UPDATE_AVAILABLE once per version in-memory
 func (s *Server) checkAndNotifyUpdate() {
 	if !s.config.CheckForUpdates {
@@ -102,6 +162,76 @@ func (s *Server) checkAndNotifyUpdate() {
 	s.lastUpdateNotified = latest
 }
 
+// checkMalAuth refreshes the MAL token ahead of expiry, see malauth.Service.CheckToken. Expiring and
+// invalid tokens are notified once until the state changes.
+func (s *Server) checkMalAuth() {
+	status, err := s.malauthService.CheckToken(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		// not authenticated yet
+		return
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("MAL token check failed")
+		return
+	}
+
+	if status.State == s.lastMalAuthState {
+		return
+	}
+	s.lastMalAuthState = status.State
+
+	var event domain.NotificationEvent
+	switch status.State {
+	case domain.MalAuthTokenExpiring:
+		event = domain.NotificationEventMalAuthExpiring
+	case domain.MalAuthTokenInvalid:
+		event = domain.NotificationEventMalAuthInvalid
+	default:
+		return
+	}
+
+	s.log.Warn().Msgf("MAL authentication needs to be renewed: %v", status.Error)
+	s.bus.Publish(domain.EventNotificationSend, &domain.NotificationSendEvent{
+		Event:   event,
+		Payload: malAuthPayload(event, status, s.malAuthSettingsURL()),
+	})
+}
+
+func malAuthPayload(event domain.NotificationEvent, status *domain.MalAuthTokenStatus, settingsURL string) domain.NotificationPayload {
+	expiry := status.Expiry.UTC().Format("2006-01-02 15:04 MST")
+
+	payload := domain.NotificationPayload{
+		Event:     event,
+		Timestamp: time.Now(),
+	}
+
+	if event == domain.NotificationEventMalAuthInvalid {
+		payload.Subject = "MAL Authentication Invalid"
+		payload.Message = fmt.Sprintf("MyAnimeList rejected the refresh token, updates will fail once the access token expires on %v.", expiry)
+	} else {
+		payload.Subject = "MAL Authentication Expiring"
+		payload.Message = fmt.Sprintf("The MyAnimeList access token expires on %v and could not be refreshed.", expiry)
+	}
+
+	if status.Error != "" {
+		payload.Message += "\n\nError: " + status.Error
+	}
+	payload.Message += "\n\nAction Required: Please re-authenticate with MyAnimeList: " + settingsURL
+
+	return payload
+}
+
+// malAuthSettingsURL links to the MyAnimeList settings, an unspecified listen address is replaced
+// with localhost.
+func (s *Server) malAuthSettingsURL() string {
+	host := s.config.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(s.config.Port)) + path.Join("/", s.config.BaseUrl, "settings", "mal")
+}
+
 func isUpdateAvailable(current, latest string) bool {
 	normalize := func(s string) []int {
 		if len(s) > 0 && (s[0] == 'v' || s[0] == 'V') {
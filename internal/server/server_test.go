@@ -5,12 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/nstratos/go-myanimelist/mal"
 	"github.com/varoOP/shinkro/internal/domain"
 
 	"github.com/asaskevich/EventBus"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockAnimeService struct {
@@ -35,6 +38,10 @@ func (m *mockAnimeService) UpdateAnime(ctx context.Context) error {
 	return m.updateErr
 }
 
+func (m *mockAnimeService) LastUpdated(ctx context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
 type mockMappingService struct {
 	getErr      error
 	storeErr    error
@@ -63,10 +70,66 @@ func (m *mockMappingService) CheckForAnimeinMap(ctx context.Context, au *domain.
 	return nil, nil
 }
 
+func (m *mockMappingService) CheckForMALinMap(ctx context.Context, malID, malEp int) (*domain.MappedEpisode, error) {
+	return nil, nil
+}
+
 func (m *mockMappingService) ValidateMap(ctx context.Context, yamlPath string, isTVDB bool) error {
 	return nil
 }
 
+func (m *mockMappingService) IsCached() bool {
+	return false
+}
+
+// mockMalAuthService returns the queued token states in order.
+type mockMalAuthService struct {
+	states []domain.MalAuthTokenState
+	err    error
+}
+
+func (m *mockMalAuthService) Store(ctx context.Context, ma *domain.MalAuth) error {
+	return nil
+}
+
+func (m *mockMalAuthService) Get(ctx context.Context) (*domain.MalAuth, error) {
+	return nil, nil
+}
+
+func (m *mockMalAuthService) Delete(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockMalAuthService) GetMalClient(ctx context.Context) (*mal.Client, error) {
+	return nil, nil
+}
+
+func (m *mockMalAuthService) GetDecrypted(ctx context.Context) (*domain.MalAuth, error) {
+	return nil, nil
+}
+
+func (m *mockMalAuthService) CheckToken(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	state := m.states[0]
+	m.states = m.states[1:]
+	return &domain.MalAuthTokenStatus{
+		State:  state,
+		Expiry: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		Error:  "oauth2: \"invalid_grant\"",
+	}, nil
+}
+
+func (m *mockMalAuthService) TokenStatus(ctx context.Context) (*domain.MalAuthTokenStatus, error) {
+	return &domain.MalAuthTokenStatus{State: domain.MalAuthTokenValid}, nil
+}
+
+func (m *mockMalAuthService) RateLimitStatus() domain.MALRateLimitStatus {
+	return domain.MALRateLimitStatus{}
+}
+
 func TestIsUpdateAvailable(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -166,7 +229,7 @@ func TestServer_Start(t *testing.T) {
 		Version:         "v1.0.0",
 	}
 
-	server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, bus)
+	server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, nil, nil, nil, nil, bus)
 
 	tests := []struct {
 		name          string
@@ -223,7 +286,7 @@ func TestServer_Start_InitializesMapSettings(t *testing.T) {
 		Version:         "v1.0.0",
 	}
 
-	server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, bus)
+	server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, nil, nil, nil, nil, bus)
 
 	err := server.Start()
 	assert.NoError(t, err)
@@ -232,6 +295,60 @@ func TestServer_Start_InitializesMapSettings(t *testing.T) {
 	assert.True(t, mappingSvc.storeCalled)
 }
 
+func TestServer_Start_InvalidReconcileSchedule(t *testing.T) {
+	bus := EventBus.New()
+	animeSvc := &mockAnimeService{}
+	mappingSvc := &mockMappingService{}
+
+	config := &domain.Config{
+		CheckForUpdates:   false,
+		Version:           "v1.0.0",
+		ReconcileSchedule: "not a schedule",
+	}
+
+	server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, nil, nil, nil, nil, bus)
+
+	err := server.Start()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid ReconcileSchedule")
+}
+
+func TestServer_Start_InvalidReverseSyncSchedule(t *testing.T) {
+	bus := EventBus.New()
+	animeSvc := &mockAnimeService{}
+	mappingSvc := &mockMappingService{}
+
+	config := &domain.Config{
+		CheckForUpdates:     false,
+		Version:             "v1.0.0",
+		ReverseSyncSchedule: "every now and then",
+	}
+
+	server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, nil, nil, nil, nil, bus)
+
+	err := server.Start()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid ReverseSyncSchedule")
+}
+
+func TestServer_Start_InvalidLibraryScanSchedule(t *testing.T) {
+	bus := EventBus.New()
+	animeSvc := &mockAnimeService{}
+	mappingSvc := &mockMappingService{}
+
+	config := &domain.Config{
+		CheckForUpdates:     false,
+		Version:             "v1.0.0",
+		LibraryScanSchedule: "hourly-ish",
+	}
+
+	server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, nil, nil, nil, nil, bus)
+
+	err := server.Start()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid LibraryScanSchedule")
+}
+
 func TestServer_CheckAndNotifyUpdate(t *testing.T) {
 	bus := EventBus.New()
 	animeSvc := &mockAnimeService{}
@@ -285,7 +402,7 @@ func TestServer_CheckAndNotifyUpdate(t *testing.T) {
 				Version:         tt.version,
 			}
 
-			server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, bus)
+			server := NewServer(zerolog.Nop(), config, animeSvc, mappingSvc, nil, nil, nil, nil, bus)
 			server.lastUpdateNotified = ""
 
 			// We can't easily test the actual update check without mocking update.LatestTag
@@ -296,3 +413,74 @@ func TestServer_CheckAndNotifyUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_CheckMalAuth(t *testing.T) {
+	bus := EventBus.New()
+
+	var events []*domain.NotificationSendEvent
+	require.NoError(t, bus.Subscribe(domain.EventNotificationSend, func(e *domain.NotificationSendEvent) {
+		events = append(events, e)
+	}))
+
+	malauthSvc := &mockMalAuthService{states: []domain.MalAuthTokenState{
+		domain.MalAuthTokenValid,
+		domain.MalAuthTokenExpiring,
+		domain.MalAuthTokenExpiring,
+		domain.MalAuthTokenInvalid,
+		domain.MalAuthTokenValid,
+		domain.MalAuthTokenInvalid,
+	}}
+
+	config := &domain.Config{Host: "0.0.0.0", Port: 7011, BaseUrl: "/shinkro/"}
+	server := NewServer(zerolog.Nop(), config, nil, nil, nil, nil, nil, malauthSvc, bus)
+
+	for range 6 {
+		server.checkMalAuth()
+	}
+
+	// notified once per state change, valid tokens are not notified
+	require.Len(t, events, 3)
+	assert.Equal(t, domain.NotificationEventMalAuthExpiring, events[0].Event)
+	assert.Equal(t, domain.NotificationEventMalAuthInvalid, events[1].Event)
+	assert.Equal(t, domain.NotificationEventMalAuthInvalid, events[2].Event)
+
+	assert.Equal(t, "MAL Authentication Expiring", events[0].Payload.Subject)
+	assert.Equal(t, events[0].Event, events[0].Payload.Event)
+	assert.Contains(t, events[0].Payload.Message, "2024-05-01 09:00 UTC")
+	assert.Contains(t, events[1].Payload.Message, "Error: oauth2: \"invalid_grant\"")
+	assert.Contains(t, events[1].Payload.Message, "http://localhost:7011/shinkro/settings/mal")
+}
+
+func TestServer_CheckMalAuth_NotAuthenticated(t *testing.T) {
+	bus := EventBus.New()
+
+	published := false
+	require.NoError(t, bus.Subscribe(domain.EventNotificationSend, func(e *domain.NotificationSendEvent) {
+		published = true
+	}))
+
+	server := NewServer(zerolog.Nop(), &domain.Config{}, nil, nil, nil, nil, nil, &mockMalAuthService{err: sql.ErrNoRows}, bus)
+	server.checkMalAuth()
+
+	assert.False(t, published)
+}
+
+func TestServer_MalAuthSettingsURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *domain.Config
+		want   string
+	}{
+		{name: "default", config: &domain.Config{Host: "localhost", Port: 7011, BaseUrl: "/"}, want: "http://localhost:7011/settings/mal"},
+		{name: "unspecified address", config: &domain.Config{Host: "0.0.0.0", Port: 7011, BaseUrl: "/"}, want: "http://localhost:7011/settings/mal"},
+		{name: "base url", config: &domain.Config{Host: "192.168.1.10", Port: 8080, BaseUrl: "/shinkro"}, want: "http://192.168.1.10:8080/shinkro/settings/mal"},
+		{name: "ipv6", config: &domain.Config{Host: "::1", Port: 7011, BaseUrl: "/"}, want: "http://[::1]:7011/settings/mal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(zerolog.Nop(), tt.config, nil, nil, nil, nil, nil, nil, nil)
+			assert.Equal(t, tt.want, server.malAuthSettingsURL())
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package syncrule
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type Service interface {
+	List(ctx context.Context) ([]*domain.SyncRule, error)
+	Store(ctx context.Context, rule *domain.SyncRule) error
+	Update(ctx context.Context, rule *domain.SyncRule) error
+	Delete(ctx context.Context, id int) error
+}
+
+type service struct {
+	log  zerolog.Logger
+	repo domain.SyncRuleRepo
+}
+
+func NewService(log zerolog.Logger, repo domain.SyncRuleRepo) Service {
+	return &service{
+		log:  log.With().Str("module", "syncrule").Logger(),
+		repo: repo,
+	}
+}
+
+func (s *service) List(ctx context.Context) ([]*domain.SyncRule, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *service) Store(ctx context.Context, rule *domain.SyncRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.repo.Store(ctx, rule); err != nil {
+		s.log.Error().Err(err).Msgf("could not store sync rule: %+v", rule)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) Update(ctx context.Context, rule *domain.SyncRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(ctx, rule); err != nil {
+		s.log.Error().Err(err).Msgf("could not update sync rule: %+v", rule)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) Delete(ctx context.Context, id int) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Error().Err(err).Msgf("could not delete sync rule: %d", id)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package tautulli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/varoOP/shinkro/internal/domain"
+	"github.com/varoOP/shinkro/internal/plex"
+	"github.com/varoOP/shinkro/pkg/tautulli"
+)
+
+const historyPageSize = 1000
+
+type Service interface {
+	Preview(ctx context.Context, req *domain.TautulliImportRequest) ([]domain.TautulliImportItem, error)
+	Import(ctx context.Context, req *domain.TautulliImportRequest) ([]domain.TautulliImportItem, error)
+}
+
+type service struct {
+	log         zerolog.Logger
+	plexService plex.Service
+}
+
+func NewService(log zerolog.Logger, plexSvc plex.Service) Service {
+	return &service{
+		log:         log.With().Str("module", "tautulli").Logger(),
+		plexService: plexSvc,
+	}
+}
+
+// Preview converts the watched Tautulli history into Plex payloads and resolves the MAL change
+// each one would make. Nothing is stored and MyAnimeList is not updated.
+func (s *service) Preview(ctx context.Context, req *domain.TautulliImportRequest) ([]domain.TautulliImportItem, error) {
+	ps, items, err := s.getHistory(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		if err := s.plexService.CheckPlexPreview(ctx, item.Plex, ps); err != nil {
+			item.Error = err.Error()
+			continue
+		}
+
+		a, err := s.plexService.PreviewPlex(ctx, item.Plex)
+		item.AnimeUpdate = a
+		if err != nil {
+			item.Error = err.Error()
+		}
+	}
+
+	return items, nil
+}
+
+// Import stores the selected Tautulli history entries and feeds them through ProcessPlex,
+// the same way a live webhook would be handled.
+func (s *service) Import(ctx context.Context, req *domain.TautulliImportRequest) ([]domain.TautulliImportItem, error) {
+	ps, history, err := s.getHistory(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]domain.TautulliImportItem, 0, len(history))
+	for _, item := range history {
+		if !req.IsSelected(item.RowID) {
+			continue
+		}
+
+		if err := s.plexService.CheckPlex(ctx, item.Plex, ps); err != nil {
+			item.Error = err.Error()
+			items = append(items, item)
+			continue
+		}
+
+		if err := s.plexService.Store(ctx, item.Plex); err != nil {
+			item.Error = err.Error()
+			items = append(items, item)
+			continue
+		}
+
+		if err := s.plexService.ProcessPlex(ctx, item.Plex); err != nil {
+			item.Error = err.Error()
+		}
+
+		items = append(items, item)
+	}
+
+	s.log.Info().Int("count", len(items)).Msg("imported tautulli history")
+	return items, nil
+}
+
+// getHistory fetches the watched history of the configured Plex user from every anime library,
+// keeping only the furthest episode per show season (or the last play per movie).
+func (s *service) getHistory(ctx context.Context, req *domain.TautulliImportRequest) (*domain.PlexSettings, []domain.TautulliImportItem, error) {
+	if req.Host == "" || req.APIKey == "" {
+		return nil, nil, errors.New("tautulli host and api key are required")
+	}
+
+	if req.After != "" {
+		if _, err := time.Parse("2006-01-02", req.After); err != nil {
+			return nil, nil, errors.Wrap(err, "after must be formatted as YYYY-MM-DD")
+		}
+	}
+
+	ps, err := s.plexService.GetPlexSettings(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "plex settings not found")
+	}
+
+	client := tautulli.NewClient(tautulli.Config{
+		Url:           req.Host,
+		APIKey:        req.APIKey,
+		TLSSkipVerify: req.TLSSkipVerify,
+	})
+
+	libraries, err := client.GetLibraryNames(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	latest := make(map[string]domain.TautulliImportItem)
+	for _, library := range libraries {
+		if !ps.IsAnimeLibrary(int(library.SectionID), "", library.SectionName) {
+			continue
+		}
+
+		history, err := s.getLibraryHistory(ctx, client, req, ps, library)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, h := range history {
+			if !h.IsWatched() {
+				continue
+			}
+
+			p, err := toPlex(h, library, ps.PlexUser)
+			if err != nil {
+				s.log.Debug().Err(err).Int("rowID", int(h.RowID)).Msg("skipping tautulli history item")
+				continue
+			}
+
+			key := historyKey(h)
+			if prev, ok := latest[key]; ok && prev.Plex.Metadata.Index > p.Metadata.Index {
+				continue
+			}
+
+			latest[key] = domain.TautulliImportItem{RowID: int(h.RowID), Plex: p}
+		}
+	}
+
+	items := make([]domain.TautulliImportItem, 0, len(latest))
+	for _, item := range latest {
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Plex.TimeStamp.Before(items[j].Plex.TimeStamp)
+	})
+
+	return ps, items, nil
+}
+
+func (s *service) getLibraryHistory(ctx context.Context, client *tautulli.Client, req *domain.TautulliImportRequest, ps *domain.PlexSettings, library tautulli.Library) ([]tautulli.HistoryItem, error) {
+	var items []tautulli.HistoryItem
+	for start := 0; ; start += historyPageSize {
+		history, err := client.GetHistory(ctx, tautulli.HistoryRequest{
+			User:      ps.PlexUser,
+			SectionID: int(library.SectionID),
+			After:     req.After,
+			Start:     start,
+			Length:    historyPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, history.Data...)
+		if len(history.Data) < historyPageSize || len(items) >= history.RecordsFiltered {
+			break
+		}
+	}
+
+	s.log.Debug().Str("library", library.SectionName).Int("count", len(items)).Msg("fetched tautulli history")
+	return items, nil
+}
+
+func historyKey(h tautulli.HistoryItem) string {
+	if h.MediaType == string(domain.PlexMovie) {
+		return fmt.Sprintf("movie-%d", h.RatingKey)
+	}
+
+	return fmt.Sprintf("show-%d-%d", h.GrandparentRatingKey, h.ParentMediaIndex)
+}
+
+// toPlex builds a Tautulli webhook payload from a history item and converts it with domain.ToPlex.
+func toPlex(h tautulli.HistoryItem, library tautulli.Library, user string) (*domain.Plex, error) {
+	if h.MediaType != string(domain.PlexEpisode) && h.MediaType != string(domain.PlexMovie) {
+		return nil, errors.Errorf("media type not supported: %v", h.MediaType)
+	}
+
+	index, parentIndex := int(h.MediaIndex), int(h.ParentMediaIndex)
+	if h.MediaType == string(domain.PlexMovie) {
+		index, parentIndex = 1, 1
+	}
+
+	grandparentKey := ""
+	if h.GrandparentRatingKey != 0 {
+		grandparentKey = fmt.Sprintf("/library/metadata/%d", h.GrandparentRatingKey)
+	}
+
+	payload := map[string]interface{}{
+		"Account": map[string]interface{}{
+			"title": user,
+		},
+		"Metadata": map[string]interface{}{
+			"grandparentKey":      grandparentKey,
+			"grandparentTitle":    h.GrandparentTitle,
+			"guid":                h.GUID,
+			"index":               strconv.Itoa(index),
+			"librarySectionTitle": library.SectionName,
+			"librarySectionID":    strconv.Itoa(int(library.SectionID)),
+			"parentIndex":         strconv.Itoa(parentIndex),
+			"title":               h.Title,
+			"type":                h.MediaType,
+		},
+		"event": domain.PlexScrobbleEvent,
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := domain.ToPlex(b)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Source = domain.TautulliImport
+	if h.Stopped != 0 {
+		p.TimeStamp = time.Unix(h.Stopped, 0)
+	} else if h.Date != 0 {
+		p.TimeStamp = time.Unix(h.Date, 0)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,199 @@
+package tautulli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/varoOP/shinkro/internal/domain"
+)
+
+type mockPlexService struct {
+	settings  *domain.PlexSettings
+	stored    []*domain.Plex
+	processed []*domain.Plex
+	previewed []*domain.Plex
+	// checked counts the CheckPlex calls, previews must not use it
+	checked int
+}
+
+func (m *mockPlexService) Store(ctx context.Context, plex *domain.Plex) error {
+	plex.ID = int64(len(m.stored) + 1)
+	m.stored = append(m.stored, plex)
+	return nil
+}
+
+func (m *mockPlexService) Get(ctx context.Context, req *domain.GetPlexRequest) (*domain.Plex, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) ProcessPlex(ctx context.Context, plex *domain.Plex) error {
+	m.processed = append(m.processed, plex)
+	return nil
+}
+
+func (m *mockPlexService) PreviewPlex(ctx context.Context, plex *domain.Plex) (*domain.AnimeUpdate, error) {
+	m.previewed = append(m.previewed, plex)
+	return &domain.AnimeUpdate{MALId: 1, EpisodeNum: plex.Metadata.Index}, nil
+}
+
+func (m *mockPlexService) SuppressScrobble(ratingKey string) {}
+
+func (m *mockPlexService) ClearCompleted(ratingKey string) {}
+
+func (m *mockPlexService) GetPlexSettings(ctx context.Context) (*domain.PlexSettings, error) {
+	return m.settings, nil
+}
+
+func (m *mockPlexService) GetPlexSettingsForServer(ctx context.Context, uuid string) (*domain.PlexSettings, error) {
+	return m.GetPlexSettings(ctx)
+}
+
+func (m *mockPlexService) CheckPlex(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	m.checked++
+	return m.CheckPlexPreview(ctx, plex, ps)
+}
+
+func (m *mockPlexService) CheckPlexPreview(ctx context.Context, plex *domain.Plex, ps *domain.PlexSettings) error {
+	if !plex.IsAnimeLibrary(ps) {
+		return fmt.Errorf("plex library not set as an anime library")
+	}
+	return nil
+}
+
+func (m *mockPlexService) CountScrobbleEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) CountRateEvents(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPlexService) GetPlexHistory(ctx context.Context, limit int) ([]domain.PlexHistoryItem, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) FindAllWithFilters(ctx context.Context, params domain.PlexPayloadQueryParams) (*domain.FindPlexPayloadsResponse, error) {
+	return nil, nil
+}
+
+func (m *mockPlexService) Delete(ctx context.Context, req *domain.DeletePlexRequest) error {
+	return nil
+}
+
+func (m *mockPlexService) UpdateStatus(ctx context.Context, plexID int64, success *bool, errorType domain.PlexErrorType, errorMsg string) error {
+	return nil
+}
+
+const libraryNamesResponse = `{"response":{"result":"success","message":null,"data":[
+	{"section_id":1,"section_name":"Anime","section_type":"show"},
+	{"section_id":2,"section_name":"TV Shows","section_type":"show"}
+]}}`
+
+const historyResponse = `{"response":{"result":"success","message":null,"data":{"recordsTotal":4,"recordsFiltered":4,"data":[
+	{"row_id":10,"date":1700000000,"stopped":1700001400,"media_type":"episode","rating_key":101,"grandparent_rating_key":100,"title":"Episode 1","grandparent_title":"Frieren","media_index":1,"parent_media_index":1,"watched_status":1,"guid":"com.plexapp.agents.hama://tvdb-424536/1/1?lang=en"},
+	{"row_id":11,"date":1700100000,"stopped":1700101400,"media_type":"episode","rating_key":102,"grandparent_rating_key":100,"title":"Episode 2","grandparent_title":"Frieren","media_index":"2","parent_media_index":"1","watched_status":1,"guid":"com.plexapp.agents.hama://tvdb-424536/1/2?lang=en"},
+	{"row_id":12,"date":1700200000,"stopped":1700200600,"media_type":"episode","rating_key":103,"grandparent_rating_key":100,"title":"Episode 3","grandparent_title":"Frieren","media_index":3,"parent_media_index":1,"watched_status":0.5,"guid":"com.plexapp.agents.hama://tvdb-424536/1/3?lang=en"},
+	{"row_id":13,"date":1700300000,"stopped":1700301400,"media_type":"episode","rating_key":201,"grandparent_rating_key":200,"title":"Episode 5","grandparent_title":"Dandadan","media_index":5,"parent_media_index":1,"watched_status":1,"guid":"com.plexapp.agents.hama://tvdb-432832/1/5?lang=en"}
+]}}}`
+
+func newTautulliServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("apikey") != "secret" {
+			fmt.Fprint(w, `{"response":{"result":"error","message":"Invalid apikey","data":{}}}`)
+			return
+		}
+
+		switch q.Get("cmd") {
+		case "get_library_names":
+			fmt.Fprint(w, libraryNamesResponse)
+		case "get_history":
+			assert.Equal(t, "TestUser", q.Get("user"))
+			assert.Equal(t, "1", q.Get("section_id"))
+			fmt.Fprint(w, historyResponse)
+		default:
+			t.Fatalf("unexpected cmd %q", q.Get("cmd"))
+		}
+	}))
+}
+
+func TestService_Preview(t *testing.T) {
+	ts := newTautulliServer(t)
+	defer ts.Close()
+
+	plexSvc := &mockPlexService{settings: &domain.PlexSettings{PlexUser: "TestUser", AnimeLibraries: []string{"Anime"}}}
+	svc := NewService(zerolog.Nop(), plexSvc)
+
+	items, err := svc.Preview(context.Background(), &domain.TautulliImportRequest{Host: ts.URL, APIKey: "secret"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	// Latest watched episode per show season, oldest first
+	assert.Equal(t, 11, items[0].RowID)
+	assert.Equal(t, "Frieren", items[0].Plex.Metadata.GrandparentTitle)
+	assert.Equal(t, 2, items[0].Plex.Metadata.Index)
+	assert.Equal(t, "/library/metadata/100", items[0].Plex.Metadata.GrandparentKey)
+	assert.Equal(t, domain.TautulliImport, items[0].Plex.Source)
+	assert.Equal(t, domain.PlexScrobbleEvent, items[0].Plex.Event)
+	assert.Equal(t, int64(1700101400), items[0].Plex.TimeStamp.Unix())
+	require.NotNil(t, items[0].AnimeUpdate)
+
+	assert.Equal(t, 13, items[1].RowID)
+	assert.Equal(t, 5, items[1].Plex.Metadata.Index)
+
+	assert.Len(t, plexSvc.previewed, 2)
+	assert.Empty(t, plexSvc.stored)
+	assert.Empty(t, plexSvc.processed)
+	assert.Zero(t, plexSvc.checked)
+}
+
+func TestService_Import(t *testing.T) {
+	ts := newTautulliServer(t)
+	defer ts.Close()
+
+	plexSvc := &mockPlexService{settings: &domain.PlexSettings{PlexUser: "TestUser", AnimeLibraries: []string{"Anime"}}}
+	svc := NewService(zerolog.Nop(), plexSvc)
+
+	items, err := svc.Import(context.Background(), &domain.TautulliImportRequest{Host: ts.URL, APIKey: "secret", RowIDs: []int{13}})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, 13, items[0].RowID)
+	assert.Empty(t, items[0].Error)
+
+	require.Len(t, plexSvc.stored, 1)
+	require.Len(t, plexSvc.processed, 1)
+	assert.Equal(t, int64(1), plexSvc.processed[0].ID)
+	assert.Empty(t, plexSvc.previewed)
+}
+
+func TestService_Preview_Errors(t *testing.T) {
+	ts := newTautulliServer(t)
+	defer ts.Close()
+
+	plexSvc := &mockPlexService{settings: &domain.PlexSettings{PlexUser: "TestUser", AnimeLibraries: []string{"Anime"}}}
+	svc := NewService(zerolog.Nop(), plexSvc)
+
+	tests := []struct {
+		name string
+		req  *domain.TautulliImportRequest
+	}{
+		{name: "missing api key", req: &domain.TautulliImportRequest{Host: ts.URL}},
+		{name: "invalid api key", req: &domain.TautulliImportRequest{Host: ts.URL, APIKey: "wrong"}},
+		{name: "invalid after date", req: &domain.TautulliImportRequest{Host: ts.URL, APIKey: "secret", After: "01/02/2024"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Preview(context.Background(), tt.req)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package plex
 
 import (
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -56,6 +57,9 @@ type Metadata struct {
 	Summary               string  `json:"summary"`
 	Index                 int     `json:"index"`
 	ParentIndex           int     `json:"parentIndex"`
+	LeafCount             int     `json:"leafCount"`
+	ViewedLeafCount       int     `json:"viewedLeafCount"`
+	ViewCount             int     `json:"viewCount"`
 	AudienceRating        float64 `json:"audienceRating"`
 	UserRating            float64 `json:"userRating"`
 	LastRatedAt           int     `json:"lastRatedAt"`
@@ -222,6 +226,13 @@ type Server struct {
 	Connections            []Connection `json:"connections"`
 }
 
+type IdentityResponse struct {
+	MediaContainer struct {
+		MachineIdentifier string `json:"machineIdentifier"`
+		Version           string `json:"version"`
+	} `json:"MediaContainer"`
+}
+
 type LibraryResponse struct {
 	MediaContainer struct {
 		Size      int         `json:"size"`
@@ -255,6 +266,12 @@ type Directory struct {
 	Location         []Location `json:"Location"`
 }
 
+// SectionID returns the library section id, which is the key of the library directory.
+func (d Directory) SectionID() int {
+	id, _ := strconv.Atoi(d.Key)
+	return id
+}
+
 type Location struct {
 	ID   int    `json:"id"`
 	Path string `json:"path"`
@@ -245,3 +245,150 @@ func (c *Client) GetLibraries(ctx context.Context) (*LibraryResponse, error) {
 
 	return &libResp, nil
 }
+
+// GetIdentity returns the machine identifier of the server, which Plex webhooks send as the server uuid.
+func (c *Client) GetIdentity(ctx context.Context) (*IdentityResponse, error) {
+	baseUrl, err := url.Parse(c.config.Url)
+	if err != nil {
+		return nil, errors.Wrap(err, "plex url invalid")
+	}
+
+	baseUrl = baseUrl.JoinPath("/identity")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseUrl.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "plex request invalid")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.Log.Print("method: getIdentity, error: ", err)
+		return nil, errors.Wrap(err, "network error")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading response body")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unknown or invalid Plex response, response status: %v, response body: %v", resp.StatusCode, string(body))
+	}
+
+	var identity IdentityResponse
+	if err := json.Unmarshal(body, &identity); err != nil {
+		return nil, errors.Wrap(err, "error decoding identity response body")
+	}
+
+	return &identity, nil
+}
+
+// GetLibraryItems returns the top level items (shows or movies) of a library section, including their external guids.
+func (c *Client) GetLibraryItems(ctx context.Context, sectionKey string) (*PlexResponse, error) {
+	resp, err := c.getMetadata(ctx, "/library/sections/"+sectionKey+"/all")
+	if err != nil {
+		c.Log.Print("method: getLibraryItems, error: ", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetChildren returns the children of a metadata item, e.g. the seasons of a show with their watched counts.
+func (c *Client) GetChildren(ctx context.Context, ratingKey string) (*PlexResponse, error) {
+	resp, err := c.getMetadata(ctx, "/library/metadata/"+ratingKey+"/children")
+	if err != nil {
+		c.Log.Print("method: getChildren, error: ", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Scrobble marks a library item as watched for the owner of the token.
+func (c *Client) Scrobble(ctx context.Context, ratingKey string) error {
+	baseUrl, err := url.Parse(c.config.Url)
+	if err != nil {
+		c.Log.Print("method: scrobble, error: ", err)
+		return errors.Wrap(err, "plex url invalid")
+	}
+
+	baseUrl = baseUrl.JoinPath("/:/scrobble")
+	q := baseUrl.Query()
+	q.Set("identifier", "com.plexapp.plugins.library")
+	q.Set("key", ratingKey)
+	baseUrl.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseUrl.String(), nil)
+	if err != nil {
+		c.Log.Print("method: scrobble, error: ", err)
+		return errors.Wrap(err, "plex request invalid")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.Log.Print("method: scrobble, error: ", err)
+		return errors.Wrap(err, "network error")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		err = errors.New("unauthorized: check plex token")
+		c.Log.Print("method: scrobble, error: ", err)
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err = errors.Errorf("unknown or invalid Plex response, response status: %v", resp.StatusCode)
+		c.Log.Print("method: scrobble, error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) getMetadata(ctx context.Context, path string) (*PlexResponse, error) {
+	baseUrl, err := url.Parse(c.config.Url)
+	if err != nil {
+		return nil, errors.Wrap(err, "plex url invalid")
+	}
+
+	baseUrl = baseUrl.JoinPath(path)
+	q := baseUrl.Query()
+	q.Set("includeGuids", "1")
+	baseUrl.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseUrl.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "plex request invalid")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "network error")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading response body")
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("unauthorized: check plex token")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unknown or invalid Plex response, response status: %v, response body: %v", resp.StatusCode, string(body))
+	}
+
+	var plexResp PlexResponse
+	if err := json.Unmarshal(body, &plexResp); err != nil {
+		return nil, errors.Wrap(err, "error decoding metadata response body")
+	}
+
+	return &plexResp, nil
+}
@@ -0,0 +1,61 @@
+package tautulli
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/varoOP/shinkro/pkg/sharedhttp"
+)
+
+type Config struct {
+	Url           string
+	APIKey        string
+	TLSSkipVerify bool
+	Log           *log.Logger
+}
+
+type Client struct {
+	config Config
+	http   *http.Client
+
+	Log *log.Logger
+}
+
+type apiTransport struct {
+	base http.RoundTripper
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", sharedhttp.UserAgent)
+	return t.base.RoundTrip(req)
+}
+
+func NewClient(config Config) *Client {
+	httpClient := &http.Client{
+		Timeout:   time.Second * 30,
+		Transport: sharedhttp.Transport,
+	}
+
+	if config.TLSSkipVerify {
+		httpClient.Transport = sharedhttp.TransportTLSInsecure
+	}
+
+	httpClient.Transport = &apiTransport{
+		base: httpClient.Transport,
+	}
+
+	c := &Client{
+		config: config,
+		http:   httpClient,
+		Log:    log.New(io.Discard, "", log.LstdFlags),
+	}
+
+	if config.Log != nil {
+		c.Log = config.Log
+	}
+
+	return c
+}
@@ -0,0 +1,77 @@
+package tautulli
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type apiResponse struct {
+	Response struct {
+		Result  string          `json:"result"`
+		Message *string         `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"response"`
+}
+
+// FlexInt decodes values that Tautulli returns either as numbers or as strings.
+type FlexInt int
+
+func (i *FlexInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*i = 0
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+
+	*i = FlexInt(f)
+	return nil
+}
+
+type Library struct {
+	SectionID   FlexInt `json:"section_id"`
+	SectionName string  `json:"section_name"`
+	SectionType string  `json:"section_type"`
+}
+
+type HistoryRequest struct {
+	User      string
+	SectionID int
+	After     string
+	Start     int
+	Length    int
+}
+
+type History struct {
+	RecordsTotal    int           `json:"recordsTotal"`
+	RecordsFiltered int           `json:"recordsFiltered"`
+	Data            []HistoryItem `json:"data"`
+}
+
+type HistoryItem struct {
+	RowID                FlexInt `json:"row_id"`
+	Date                 int64   `json:"date"`
+	Stopped              int64   `json:"stopped"`
+	User                 string  `json:"user"`
+	MediaType            string  `json:"media_type"`
+	RatingKey            FlexInt `json:"rating_key"`
+	GrandparentRatingKey FlexInt `json:"grandparent_rating_key"`
+	Title                string  `json:"title"`
+	FullTitle            string  `json:"full_title"`
+	GrandparentTitle     string  `json:"grandparent_title"`
+	MediaIndex           FlexInt `json:"media_index"`
+	ParentMediaIndex     FlexInt `json:"parent_media_index"`
+	WatchedStatus        float64 `json:"watched_status"`
+	GUID                 string  `json:"guid"`
+	SectionID            FlexInt `json:"section_id"`
+}
+
+// IsWatched reports whether Tautulli counted the play as fully watched.
+func (h HistoryItem) IsWatched() bool {
+	return h.WatchedStatus >= 1
+}
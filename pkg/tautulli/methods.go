@@ -0,0 +1,105 @@
+package tautulli
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+func (c *Client) GetLibraryNames(ctx context.Context) ([]Library, error) {
+	var libraries []Library
+	if err := c.call(ctx, "get_library_names", url.Values{}, &libraries); err != nil {
+		c.Log.Print("method: getLibraryNames, error: ", err)
+		return nil, err
+	}
+
+	return libraries, nil
+}
+
+func (c *Client) GetHistory(ctx context.Context, req HistoryRequest) (*History, error) {
+	params := url.Values{}
+	if req.User != "" {
+		params.Set("user", req.User)
+	}
+
+	if req.SectionID != 0 {
+		params.Set("section_id", strconv.Itoa(req.SectionID))
+	}
+
+	if req.After != "" {
+		params.Set("after", req.After)
+	}
+
+	if req.Length != 0 {
+		params.Set("length", strconv.Itoa(req.Length))
+	}
+
+	params.Set("start", strconv.Itoa(req.Start))
+	params.Set("order_column", "date")
+	params.Set("order_dir", "asc")
+
+	var history History
+	if err := c.call(ctx, "get_history", params, &history); err != nil {
+		c.Log.Print("method: getHistory, error: ", err)
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+func (c *Client) call(ctx context.Context, cmd string, params url.Values, data interface{}) error {
+	baseUrl, err := url.Parse(c.config.Url)
+	if err != nil {
+		return errors.Wrap(err, "tautulli url invalid")
+	}
+
+	baseUrl = baseUrl.JoinPath("/api/v2")
+	params.Set("apikey", c.config.APIKey)
+	params.Set("cmd", cmd)
+	baseUrl.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseUrl.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "tautulli request invalid")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "network error")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New("unauthorized: check tautulli api key")
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return errors.Errorf("%v, response status: %v, response body: %v", err, resp.StatusCode, string(body))
+	}
+
+	if apiResp.Response.Result != "success" {
+		msg := "unknown error"
+		if apiResp.Response.Message != nil {
+			msg = *apiResp.Response.Message
+		}
+		return errors.Errorf("tautulli %v failed: %v", cmd, msg)
+	}
+
+	if err := json.Unmarshal(apiResp.Response.Data, data); err != nil {
+		return errors.Wrapf(err, "could not decode tautulli %v response", cmd)
+	}
+
+	return nil
+}
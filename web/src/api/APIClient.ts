@@ -447,6 +447,12 @@ export const APIClient = {
             appClient.Post("api/notification/test", {
                 body: notification,
             }),
+        deliveries: (id: number) =>
+            appClient.Get<NotificationDelivery[]>(`api/notification/${id}/deliveries`),
+        preview: (req: NotificationPreviewRequest) =>
+            appClient.Post<NotificationPreview>("api/notification/preview", {
+                body: req,
+            }),
     },
     updates: {
         getLatestRelease: () => appClient.Get<any>("api/updates/latest"),
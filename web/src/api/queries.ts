@@ -78,6 +78,12 @@ export const NotificationsQueryOptions = () =>
         queryFn: () => APIClient.notifications.getAll(),
     });
 
+export const NotificationDeliveriesQueryOptions = (id: number) =>
+    queryOptions({
+        queryKey: NotificationKeys.deliveries(id),
+        queryFn: () => APIClient.notifications.deliveries(id),
+    });
+
 export const ApikeysQueryOptions = () =>
     queryOptions({
         queryKey: ApiKeys.lists(),
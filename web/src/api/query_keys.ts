@@ -38,6 +38,7 @@ export const NotificationKeys = {
     lists: () => [...NotificationKeys.all, "list"] as const,
     details: () => [...NotificationKeys.all, "detail"] as const,
     detail: (id: number) => [...NotificationKeys.details(), id] as const,
+    deliveries: (id: number) => [...NotificationKeys.detail(id), "deliveries"] as const,
 };
 
 export const PlexKeys = {
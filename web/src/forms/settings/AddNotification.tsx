@@ -1,10 +1,10 @@
 import {useMutation, useQueryClient} from "@tanstack/react-query";
 import {useForm} from "@mantine/form";
-import {Modal, TextInput, Select, Switch, MultiSelect, Button, Stack, Group} from "@mantine/core";
+import {Modal, TextInput, Select, Switch, MultiSelect, Button, Stack, Group, PasswordInput, Textarea, NumberInput, Code, Text} from "@mantine/core";
 import {APIClient} from "@api/APIClient.ts";
 import {NotificationKeys} from "@api/query_keys.ts";
 import {displayNotification} from "@components/notifications";
-import {useEffect} from "react";
+import {useEffect, useState} from "react";
 
 interface AddNotificationProps {
     opened: boolean;
@@ -14,6 +14,8 @@ interface AddNotificationProps {
 
 export const AddNotification = ({opened, onClose, defaultValues}: AddNotificationProps) => {
     const queryClient = useQueryClient();
+    const [preview, setPreview] = useState<NotificationPreview | null>(null);
+    const [previewEvent, setPreviewEvent] = useState<string>("SUCCESS");
     
     const form = useForm<ServiceNotification>({
         initialValues: {
@@ -26,12 +28,42 @@ export const AddNotification = ({opened, onClose, defaultValues}: AddNotificatio
         validate: {
             name: (value) => (value ? null : "Required"),
             type: (value) => (value ? null : "Required"),
-            webhook: (value, values) => 
-                values.type === "DISCORD" && !value ? "Webhook URL is required for Discord" : null,
-            token: (value, values) => 
-                values.type === "GOTIFY" && !value ? "Token is required for Gotify" : null,
-            host: (value, values) => 
-                values.type === "GOTIFY" && !value ? "Host is required for Gotify" : null,
+            webhook: (value, values) => {
+                if (values.type === "DISCORD" && !value) return "Webhook URL is required for Discord";
+                if (values.type === "WEBHOOK" && !value) return "URL is required for webhooks";
+                return null;
+            },
+            headers: (value, values) => 
+                values.type === "WEBHOOK" && value && value.split("\n").some((line) => line.trim() !== "" && !/^[^\s:]+:/.test(line.trim()))
+                    ? "Headers must be one \"Name: value\" per line" : null,
+            token: (value, values) => {
+                if (values.type === "GOTIFY" && !value) return "Token is required for Gotify";
+                if (values.type === "TELEGRAM" && !value) return "Bot token is required for Telegram";
+                if (values.type === "PUSHOVER" && !value) return "User key is required for Pushover";
+                if (values.type === "MATRIX" && !value) return "Access token is required for Matrix";
+                return null;
+            },
+            api_key: (value, values) => 
+                values.type === "PUSHOVER" && !value ? "Application token is required for Pushover" : null,
+            channel: (value, values) => 
+                values.type === "TELEGRAM" && !value ? "Chat ID is required for Telegram" : null,
+            topic: (value, values) => {
+                if (values.type === "TELEGRAM" && value && !/^\d+$/.test(value)) return "Message thread ID must be a number";
+                if (values.type === "NTFY" && !value) return "Topic is required for ntfy";
+                return null;
+            },
+            host: (value, values) => {
+                if (values.type === "GOTIFY" && !value) return "Host is required for Gotify";
+                if (values.type === "EMAIL" && !value) return "SMTP host is required for email";
+                if (values.type === "MATRIX" && !value) return "Homeserver URL is required for Matrix";
+                return null;
+            },
+            rooms: (value, values) => 
+                values.type === "MATRIX" && !value ? "At least one room is required for Matrix" : null,
+            targets: (value, values) => 
+                values.type === "EMAIL" && !value ? "At least one recipient is required for email" : null,
+            from: (value, values) => 
+                values.type === "EMAIL" && !value && !values.username ? "From address is required for email" : null,
         },
     });
 
@@ -41,6 +73,7 @@ export const AddNotification = ({opened, onClose, defaultValues}: AddNotificatio
         } else {
             form.reset();
         }
+        setPreview(null);
     }, [defaultValues]);
 
     const createMutation = useMutation({
@@ -87,6 +120,27 @@ export const AddNotification = ({opened, onClose, defaultValues}: AddNotificatio
         },
     });
 
+    const previewMutation = useMutation({
+        mutationFn: (req: NotificationPreviewRequest) => APIClient.notifications.preview(req),
+        onSuccess: (data: NotificationPreview) => setPreview(data),
+        onError: (error: Error) => {
+            setPreview(null);
+            displayNotification({
+                title: "Preview Failed",
+                message: error.message,
+                type: "error",
+            });
+        },
+    });
+
+    const handlePreview = () => {
+        previewMutation.mutate({
+            title_template: form.values.title_template,
+            body_template: form.values.body_template,
+            event: previewEvent as NotificationPreviewRequest["event"],
+        });
+    };
+
     const handleSubmit = (values: typeof form.values) => {
         const isEditing = !!defaultValues?.id;
         if (isEditing) {
@@ -114,9 +168,13 @@ export const AddNotification = ({opened, onClose, defaultValues}: AddNotificatio
         { value: "SUCCESS", label: "MAL Update Successful" },
         { value: "PLEX_PROCESSING_ERROR", label: "Plex Processing Error" },
         { value: "ANIME_UPDATE_ERROR", label: "Anime Update Error" },
+        { value: "MAL_AUTH_EXPIRING", label: "MAL Authentication Expiring" },
+        { value: "MAL_AUTH_INVALID", label: "MAL Authentication Invalid" },
         { value: "TEST", label: "Test" },
     ];
 
+    const previewEvents = [...notificationEvents, { value: "DIGEST", label: "Digest" }];
+
     const isEditing = !!defaultValues?.id;
 
     return (
@@ -135,6 +193,12 @@ export const AddNotification = ({opened, onClose, defaultValues}: AddNotificatio
                         data={[
                             { value: "DISCORD", label: "Discord" },
                             { value: "GOTIFY", label: "Gotify" },
+                            { value: "TELEGRAM", label: "Telegram" },
+                            { value: "NTFY", label: "ntfy" },
+                            { value: "PUSHOVER", label: "Pushover" },
+                            { value: "WEBHOOK", label: "Webhook" },
+                            { value: "EMAIL", label: "Email (SMTP)" },
+                            { value: "MATRIX", label: "Matrix" },
                         ]}
                         {...form.getInputProps("type")}
                     />
@@ -153,6 +217,13 @@ export const AddNotification = ({opened, onClose, defaultValues}: AddNotificatio
                         {...form.getInputProps("events")}
                     />
 
+                    <TextInput
+                        label="Digest Schedule"
+                        description="Optional cron schedule in UTC, e.g. 0 20 * * * daily or 0 20 * * SUN weekly. Successful updates are then sent as one summary, errors are still sent right away."
+                        placeholder="Leave empty to notify every update"
+                        {...form.getInputProps("digest_schedule")}
+                    />
+
                     {form.values.type === "DISCORD" && (
                         <TextInput
                             label="Webhook URL"
@@ -182,6 +253,228 @@ export const AddNotification = ({opened, onClose, defaultValues}: AddNotificatio
                         </>
                     )}
 
+                    {form.values.type === "TELEGRAM" && (
+                        <>
+                            <TextInput
+                                label="Bot Token"
+                                placeholder="Enter Telegram bot token"
+                                {...form.getInputProps("token")}
+                            />
+                            <TextInput
+                                label="Chat ID"
+                                placeholder="Enter Telegram chat ID"
+                                {...form.getInputProps("channel")}
+                            />
+                            <TextInput
+                                label="Message Thread ID"
+                                placeholder="Optional forum topic ID"
+                                {...form.getInputProps("topic")}
+                            />
+                            <TextInput
+                                label="Bot API Server"
+                                placeholder="Optional, defaults to https://api.telegram.org"
+                                {...form.getInputProps("host")}
+                            />
+                        </>
+                    )}
+
+                    {form.values.type === "NTFY" && (
+                        <>
+                            <TextInput
+                                label="Server URL"
+                                placeholder="Optional, defaults to https://ntfy.sh"
+                                {...form.getInputProps("host")}
+                            />
+                            <TextInput
+                                label="Topic"
+                                placeholder="Enter ntfy topic"
+                                {...form.getInputProps("topic")}
+                            />
+                            <NumberInput
+                                label="Priority"
+                                placeholder="Enter default priority (1-5), errors are sent as high"
+                                {...form.getInputProps("priority")}
+                            />
+                            <TextInput
+                                label="Access Token"
+                                placeholder="Optional access token"
+                                {...form.getInputProps("token")}
+                            />
+                            <TextInput
+                                label="Username"
+                                placeholder="Optional username"
+                                {...form.getInputProps("username")}
+                            />
+                            <PasswordInput
+                                label="Password"
+                                placeholder="Optional password"
+                                {...form.getInputProps("password")}
+                            />
+                        </>
+                    )}
+
+                    {form.values.type === "PUSHOVER" && (
+                        <>
+                            <TextInput
+                                label="Application Token"
+                                placeholder="Enter Pushover application API token"
+                                {...form.getInputProps("api_key")}
+                            />
+                            <TextInput
+                                label="User Key"
+                                placeholder="Enter Pushover user or group key"
+                                {...form.getInputProps("token")}
+                            />
+                            <TextInput
+                                label="Devices"
+                                placeholder="Optional comma separated device names, defaults to all devices"
+                                {...form.getInputProps("devices")}
+                            />
+                            <NumberInput
+                                label="Priority"
+                                placeholder="Enter priority (-2 to 2), errors are sent as at least normal"
+                                {...form.getInputProps("priority")}
+                            />
+                        </>
+                    )}
+
+                    {form.values.type === "WEBHOOK" && (
+                        <>
+                            <TextInput
+                                label="URL"
+                                placeholder="Enter webhook URL"
+                                {...form.getInputProps("webhook")}
+                            />
+                            <PasswordInput
+                                label="Signing Secret"
+                                placeholder="Optional, signs the body with HMAC-SHA256 in X-Shinkro-Signature"
+                                {...form.getInputProps("token")}
+                            />
+                            <Textarea
+                                label="Headers"
+                                placeholder={"Optional, one per line\nAuthorization: Bearer token"}
+                                autosize
+                                minRows={2}
+                                {...form.getInputProps("headers")}
+                            />
+                            <NumberInput
+                                label="Timeout"
+                                min={1}
+                                max={120}
+                                placeholder="Request timeout in seconds, defaults to 30"
+                                {...form.getInputProps("timeout")}
+                            />
+                            <NumberInput
+                                label="Retries"
+                                min={0}
+                                max={5}
+                                placeholder="Retries on network and server errors (0-5)"
+                                {...form.getInputProps("retries")}
+                            />
+                        </>
+                    )}
+
+                    {form.values.type === "EMAIL" && (
+                        <>
+                            <TextInput
+                                label="SMTP Host"
+                                placeholder="Enter SMTP server host"
+                                {...form.getInputProps("host")}
+                            />
+                            <Select
+                                label="TLS Mode"
+                                data={[
+                                    { value: "STARTTLS", label: "STARTTLS" },
+                                    { value: "TLS", label: "TLS" },
+                                    { value: "NONE", label: "None" },
+                                ]}
+                                placeholder="STARTTLS"
+                                {...form.getInputProps("tls_mode")}
+                            />
+                            <NumberInput
+                                label="Port"
+                                min={1}
+                                max={65535}
+                                placeholder="Defaults to 587 for STARTTLS, 465 for TLS and 25 without TLS"
+                                {...form.getInputProps("port")}
+                            />
+                            <TextInput
+                                label="Username"
+                                placeholder="Optional SMTP username"
+                                {...form.getInputProps("username")}
+                            />
+                            <PasswordInput
+                                label="Password"
+                                placeholder="Optional SMTP password"
+                                {...form.getInputProps("password")}
+                            />
+                            <TextInput
+                                label="From"
+                                placeholder="shinkro <shinkro@example.com>, defaults to the username"
+                                {...form.getInputProps("from")}
+                            />
+                            <TextInput
+                                label="Recipients"
+                                placeholder="Comma separated email addresses"
+                                {...form.getInputProps("targets")}
+                            />
+                        </>
+                    )}
+
+                    {form.values.type === "MATRIX" && (
+                        <>
+                            <TextInput
+                                label="Homeserver URL"
+                                placeholder="https://matrix.example.org"
+                                {...form.getInputProps("host")}
+                            />
+                            <PasswordInput
+                                label="Access Token"
+                                placeholder="Enter the access token of the sending user"
+                                {...form.getInputProps("token")}
+                            />
+                            <TextInput
+                                label="Rooms"
+                                placeholder="Comma separated room IDs or aliases, e.g. !abc:example.org, #anime:example.org"
+                                {...form.getInputProps("rooms")}
+                            />
+                        </>
+                    )}
+
+                    <Textarea
+                        label="Title Template"
+                        description="Optional Go template replacing the title, e.g. {{.Title}}: {{.MediaName}}"
+                        autosize
+                        minRows={1}
+                        {...form.getInputProps("title_template")}
+                    />
+                    <Textarea
+                        label="Body Template"
+                        description="Optional Go template replacing the message, all payload fields like {{.MediaName}}, {{.EpisodesWatched}}, {{.Message}} and {{.MALURL}} are available"
+                        autosize
+                        minRows={3}
+                        {...form.getInputProps("body_template")}
+                    />
+                    <Group align="flex-end">
+                        <Select
+                            label="Preview Event"
+                            data={previewEvents}
+                            value={previewEvent}
+                            onChange={(value) => setPreviewEvent(value || "SUCCESS")}
+                            allowDeselect={false}
+                            style={{flex: 1}}
+                        />
+                        <Button variant="light" onClick={handlePreview} loading={previewMutation.isPending}>
+                            Preview
+                        </Button>
+                    </Group>
+                    {preview && (
+                        <Stack gap={4}>
+                            <Text fw={600} size="sm">{preview.title}</Text>
+                            <Code block>{preview.body}</Code>
+                        </Stack>
+                    )}
+
                     <Group justify="flex-end" mt="md">
                         <Button variant="default" onClick={onClose}>
                             Cancel
@@ -107,6 +107,18 @@ export const PlexSettings = ({
         }
     }, [defaultValues]);
 
+    // Load the libraries of saved settings, so the selected section keys show their titles
+    useEffect(() => {
+        if (!opened || !defaultValues?.host || !defaultValues?.port) {
+            return;
+        }
+
+        APIClient.plex
+            .libraries({...form.getValues(), ...defaultValues})
+            .then((response) => setLibraries(response.MediaContainer.Directory || []))
+            .catch(() => setLibraries([]));
+    }, [opened, defaultValues]);
+
     const handlePlexLogin = async () => {
         try {
             await APIClient.plex.testToken()
@@ -380,10 +392,14 @@ export const PlexSettings = ({
     };
 
 
-    // Prepare options for the MultiSelect (deduplicated)
+    // Prepare options for the MultiSelect (deduplicated). Libraries are selected by section key, selected
+    // libraries the server did not return are kept as they are.
     const libraryOptions = Array.from(
-        new Set(libraries.map((lib) => lib.title))
-    ).map((title) => ({value: title, label: title}));
+        new Map(libraries.map((lib) => [lib.key, lib.title] as [string, string]))
+    ).map(([key, title]) => ({value: key, label: title}));
+    (form.getValues().anime_libs || [])
+        .filter((lib) => !libraryOptions.some((option) => option.value === lib))
+        .forEach((lib) => libraryOptions.push({value: lib, label: lib}));
 
     const testPlex = async () => {
         try {
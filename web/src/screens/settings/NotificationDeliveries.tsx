@@ -0,0 +1,60 @@
+import {Modal, Table, Badge, Text, Loader, Center} from "@mantine/core";
+import {useQuery} from "@tanstack/react-query";
+import {NotificationDeliveriesQueryOptions} from "@api/queries.ts";
+
+interface NotificationDeliveriesProps {
+    notification?: ServiceNotification;
+    onClose: () => void;
+}
+
+const statusColor: Record<NotificationDelivery["status"], string> = {
+    SUCCESS: "green",
+    RETRYING: "yellow",
+    FAILED: "red",
+};
+
+export const NotificationDeliveries = ({notification, onClose}: NotificationDeliveriesProps) => {
+    const {data: deliveries, isLoading} = useQuery({
+        ...NotificationDeliveriesQueryOptions(notification?.id ?? 0),
+        enabled: !!notification,
+    });
+
+    return (
+        <Modal opened={!!notification} onClose={onClose} title={`Deliveries of ${notification?.name ?? ""}`} size="xl">
+            {isLoading ? (
+                <Center><Loader size="sm"/></Center>
+            ) : deliveries && deliveries.length > 0 ? (
+                <Table striped>
+                    <Table.Thead>
+                        <Table.Tr>
+                            <Table.Th>Time</Table.Th>
+                            <Table.Th>Event</Table.Th>
+                            <Table.Th>Status</Table.Th>
+                            <Table.Th>Attempt</Table.Th>
+                            <Table.Th>HTTP</Table.Th>
+                            <Table.Th>Error</Table.Th>
+                        </Table.Tr>
+                    </Table.Thead>
+                    <Table.Tbody>
+                        {deliveries.map((delivery) => (
+                            <Table.Tr key={delivery.id}>
+                                <Table.Td>{new Date(delivery.created_at).toLocaleString()}</Table.Td>
+                                <Table.Td>{delivery.event.replace(/_/g, ' ')}</Table.Td>
+                                <Table.Td>
+                                    <Badge color={statusColor[delivery.status]} variant="light">{delivery.status}</Badge>
+                                </Table.Td>
+                                <Table.Td>{delivery.attempt}</Table.Td>
+                                <Table.Td>{delivery.http_status || "-"}</Table.Td>
+                                <Table.Td>
+                                    <Text size="xs" style={{wordBreak: "break-word"}}>{delivery.error}</Text>
+                                </Table.Td>
+                            </Table.Tr>
+                        ))}
+                    </Table.Tbody>
+                </Table>
+            ) : (
+                <Text c="dimmed" ta="center">No deliveries yet</Text>
+            )}
+        </Modal>
+    );
+};
@@ -7,12 +7,14 @@ import {useState} from "react";
 import {APIClient} from "@api/APIClient.ts";
 import {NotificationKeys} from "@api/query_keys.ts";
 import {displayNotification} from "@components/notifications";
-import { FaEdit } from "react-icons/fa";
+import { FaEdit, FaHistory } from "react-icons/fa";
 import {ConfirmDeleteIcon} from "@components/alerts/ConfirmDeleteIcon";
+import {NotificationDeliveries} from "@screens/settings/NotificationDeliveries.tsx";
 
 export const Notifications = () => {
     const [modalOpened, setModalOpened] = useState(false);
     const [editingNotification, setEditingNotification] = useState<ServiceNotification | undefined>();
+    const [deliveriesNotification, setDeliveriesNotification] = useState<ServiceNotification | undefined>();
     const queryClient = useQueryClient();
     const {data: notifications} = useSuspenseQuery(NotificationsQueryOptions());
 
@@ -98,6 +100,13 @@ export const Notifications = () => {
                                     </Group>
                                 </Stack>
                                 <Group>
+                                    <ActionIcon
+                                        variant="outline"
+                                        color="gray"
+                                        onClick={() => setDeliveriesNotification(notification)}
+                                    >
+                                        <FaHistory size={16} />
+                                    </ActionIcon>
                                     <ActionIcon 
                                         variant="outline" 
                                         color="blue" 
@@ -133,6 +142,11 @@ export const Notifications = () => {
                 onClose={handleCloseModal}
                 defaultValues={editingNotification}
             />
+
+            <NotificationDeliveries
+                notification={deliveriesNotification}
+                onClose={() => setDeliveriesNotification(undefined)}
+            />
         </main>
     );
 }
\ No newline at end of file
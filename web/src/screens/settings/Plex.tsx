@@ -78,6 +78,8 @@ const TruncatedText = ({ value }: { value: string }) => {
 export const Plex = () => {
     const queryClient = useQueryClient();
     const [isReachable, setIsReachable] = useState<boolean | null>(null);
+    // Libraries are stored by section key, titles are looked up from the server for display
+    const [libraryTitles, setLibraryTitles] = useState<Record<string, string>>({});
     const [opened, {open, close}] = useDisclosure(false);
     const {data: settings} = useSuspenseQuery(PlexSettingsQueryOptions());
     const isEmptySettings = !settings || Object.keys(settings).length === 0;
@@ -88,8 +90,15 @@ export const Plex = () => {
                 .test(settings)
                 .then(() => setIsReachable(true))
                 .catch(() => setIsReachable(false));
+            APIClient.plex
+                .libraries(settings)
+                .then((response) => setLibraryTitles(Object.fromEntries(
+                    (response.MediaContainer.Directory || []).map((lib) => [lib.key, lib.title])
+                )))
+                .catch(() => setLibraryTitles({}));
         } else {
             setIsReachable(null);
+            setLibraryTitles({});
         }
     }, [settings, isEmptySettings]);
 
@@ -115,7 +124,7 @@ export const Plex = () => {
     const rows = settings && !isEmptySettings
         ? [
             {label: 'Plex User', value: settings.plex_user},
-            {label: 'Anime Libraries', value: settings.anime_libs.join(', ')},
+            {label: 'Anime Libraries', value: settings.anime_libs.map((lib) => libraryTitles[lib] ?? lib).join(', ')},
             {label: 'Host', value: settings.host},
             {label: 'Port', value: settings.port.toString()},
             {label: 'TLS', value: settings.tls ? 'Enabled' : 'Disabled'},
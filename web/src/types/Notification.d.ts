@@ -1,5 +1,5 @@
-type NotificationType = "DISCORD" | "GOTIFY";
-type NotificationEvent = "SUCCESS" | "APP_UPDATE_AVAILABLE" | "PLEX_PROCESSING_ERROR" | "ANIME_UPDATE_ERROR";
+type NotificationType = "DISCORD" | "GOTIFY" | "TELEGRAM" | "NTFY" | "PUSHOVER" | "WEBHOOK" | "EMAIL" | "MATRIX";
+type NotificationEvent = "SUCCESS" | "APP_UPDATE_AVAILABLE" | "PLEX_PROCESSING_ERROR" | "ANIME_UPDATE_ERROR" | "MAL_AUTH_EXPIRING" | "MAL_AUTH_INVALID";
 
 interface ServiceNotification {
     id: number;
@@ -15,4 +15,41 @@ interface ServiceNotification {
     topic?: string;
     host?: string;
     username?: string;
+    password?: string;
+    devices?: string;
+    headers?: string;
+    timeout?: number;
+    retries?: number;
+    rooms?: string;
+    targets?: string;
+    port?: number;
+    tls_mode?: "NONE" | "STARTTLS" | "TLS";
+    from?: string;
+    title_template?: string;
+    body_template?: string;
+    digest_schedule?: string;
+}
+
+interface NotificationDelivery {
+    id: number;
+    notification_id: number;
+    event: NotificationEvent | "TEST" | "DIGEST";
+    status: "SUCCESS" | "RETRYING" | "FAILED";
+    attempt: number;
+    http_status: number;
+    error?: string;
+    created_at: string;
+}
+
+interface NotificationPreviewRequest {
+    title_template?: string;
+    body_template?: string;
+    event?: NotificationEvent | "TEST" | "DIGEST";
+    anime_update_id?: number;
+}
+
+interface NotificationPreview {
+    event: string;
+    title: string;
+    body: string;
 }